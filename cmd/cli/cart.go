@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+	cartSvc "github.com/yauritux/cartsvc/pkg/usecase/carts"
+)
+
+func runCart(sub string, args []string) int {
+	switch sub {
+	case "expire":
+		return runCartExpire(args)
+	case "remind":
+		return runCartRemind(args)
+	}
+
+	cmd := newCommand("cart " + sub)
+	user := cmd.flags.String("user", "", "id of the cart owner")
+	product := cmd.flags.String("product", "", "id of the product")
+	sku := cmd.flags.String("sku", "", "sku of the product variant")
+	qty := cmd.flags.Int("qty", 0, "quantity")
+	points := cmd.flags.Int("points", 0, "loyalty points redeemed upon checkout")
+	cartID := cmd.flags.String("cart", "", "id of the checked out cart")
+	if code := cmd.parse(args); code != exitOK {
+		return code
+	}
+	if code := cmd.require("user"); code != exitOK {
+		return code
+	}
+	if err := openCart(*user); err != nil {
+		return cmd.fail(err)
+	}
+
+	switch sub {
+	case "show":
+	case "add":
+		if code := cmd.require("product"); code != exitOK {
+			return code
+		}
+		if *qty <= 0 {
+			return cmd.fail(e.NewErrInvalidData("'qty' should be greater than zero"))
+		}
+		if err := cartUsecase.AddToCart(*user, &cartSvc.CartItem{ID: *product, SKU: *sku, Qty: *qty}); err != nil {
+			return cmd.fail(err)
+		}
+	case "remove":
+		if *sku == "" {
+			*sku = *product
+		}
+		if *sku == "" {
+			fmt.Fprintf(os.Stderr, "%s: --sku is required\n", cmd.flags.Name())
+			return exitUsage
+		}
+		if err := cartUsecase.RemoveFromCart(*user, *sku); err != nil {
+			return cmd.fail(err)
+		}
+	case "refresh":
+		res, err := cartUsecase.RefreshCart(*user)
+		if err != nil {
+			return cmd.fail(err)
+		}
+		view := buildCartRefreshView(res.(*cartSvc.CartRefresh))
+		if *cmd.output == outputJSON {
+			printJSON(view)
+		} else {
+			printCartTable(view.Cart)
+			printCartChanges(view.Changes)
+		}
+		return exitOK
+	case "checkout":
+		c, err := cartUsecase.CheckoutWithPoints(*user, *points)
+		if err != nil {
+			return cmd.fail(err)
+		}
+		return printCart(cmd, c.(*cartSvc.Cart))
+	case "close", "republish", "refund":
+		if code := cmd.require("cart"); code != exitOK {
+			return code
+		}
+		settle := cartUsecase.CloseCart
+		switch sub {
+		case "republish":
+			settle = cartUsecase.RepublishClosing
+		case "refund":
+			settle = cartUsecase.RefundCart
+		}
+		//the cart is settled even when its loyalty points could not follow, republishing its closing earns them
+		c, err := settle(*user, *cartID)
+		if c == nil {
+			return cmd.fail(err)
+		}
+		printCart(cmd, c.(*cartSvc.Cart))
+		if err != nil {
+			return cmd.fail(err)
+		}
+		return exitOK
+	default:
+		fmt.Fprintf(os.Stderr, "unknown subcommand cart %s\n\n%s\n", sub, usage)
+		return exitUsage
+	}
+
+	c, err := cartUsecase.FetchUserCart(*user)
+	if err != nil {
+		return cmd.fail(err)
+	}
+	return printCart(cmd, c.(*cartSvc.Cart))
+}
+
+// runCartExpire cancels the carts left idle beyond the cart TTL, which a cron job may run
+// in place of the sweeper of the HTTP server
+func runCartExpire(args []string) int {
+	return runCartBatch("cart expire", "expired", cartUsecase.ExpireIdleCarts, args)
+}
+
+// runCartRemind sends the recovery reminders due for the idle carts
+func runCartRemind(args []string) int {
+	return runCartBatch("cart remind", "reminded", cartUsecase.SendRecoveryReminders, args)
+}
+
+// runCartBatch prints the carts processed by the batch even when some of the other ones failed
+func runCartBatch(name string, verb string, batch func() (interface{}, error), args []string) int {
+	cmd := newCommand(name)
+	if code := cmd.parse(args); code != exitOK {
+		return code
+	}
+
+	res, batchErr := batch()
+	if res == nil {
+		return cmd.fail(batchErr)
+	}
+	views := make([]*cartView, 0)
+	for _, c := range res.([]*cartSvc.Cart) {
+		views = append(views, buildCartView(c))
+	}
+	if *cmd.output == outputJSON {
+		printJSON(views)
+	} else {
+		fmt.Printf("%d cart(s) %s\n", len(views), verb)
+		for _, v := range views {
+			fmt.Printf("  cart %s of %s\n", v.ID, v.UserID)
+		}
+	}
+	if batchErr != nil {
+		return cmd.fail(batchErr)
+	}
+	return exitOK
+}
+
+// openCart makes sure the known user owns an open cart
+func openCart(userID string) error {
+	if _, err := container.UserRepository.FindByUserID(userID); err != nil {
+		return err
+	}
+	return container.OpenCart(userID)
+}
+
+func printCart(cmd *command, c *cartSvc.Cart) int {
+	view := buildCartView(c)
+	if *cmd.output == outputJSON {
+		printJSON(view)
+	} else {
+		printCartTable(view)
+	}
+	return exitOK
+}
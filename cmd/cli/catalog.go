@@ -0,0 +1,120 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/yauritux/cartsvc/pkg/adapter/catalogfile"
+	productSvc "github.com/yauritux/cartsvc/pkg/usecase/products"
+)
+
+func runCatalog(args []string) int {
+	switch args[0] {
+	case "import":
+		return importCatalog(args[1:])
+	case "export":
+		return exportCatalog(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown subcommand catalog %s\n\n%s\n", args[0], usage)
+		return exitUsage
+	}
+}
+
+func importCatalog(args []string) int {
+	fs := flag.NewFlagSet("catalog import", flag.ContinueOnError)
+	path := fs.String("file", "", "catalog file to import")
+	format := fs.String("format", "", "csv or json, guessed from the file extension when omitted")
+	upsert := fs.Bool("upsert", false, "overwrite the existing products instead of rejecting them")
+	dryRun := fs.Bool("dry-run", false, "validate the file without saving anything")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *path == "" {
+		fmt.Fprintln(os.Stderr, "--file is required")
+		return exitUsage
+	}
+
+	f, err := catalogfile.ParseFormat(*format, *path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	file, err := os.Open(*path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitCode(err)
+	}
+	defer file.Close()
+
+	rows, err := catalogfile.Decode(file, f)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitCode(err)
+	}
+	mode := productSvc.InsertOnly
+	if *upsert {
+		mode = productSvc.Upsert
+	}
+	res, err := prodUsecase.ImportProducts(rows, mode, *dryRun)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitCode(err)
+	}
+
+	report := res.(*productSvc.ImportReport)
+	for _, r := range report.Results {
+		if r.Err != nil {
+			fmt.Fprintf(os.Stderr, "line %d (%s): %v\n", r.Line, r.ProductID, r.Err)
+		}
+	}
+	prefix := ""
+	if report.DryRun {
+		prefix = "[dry-run] "
+	}
+	fmt.Printf("%s%d created, %d updated, %d failed\n", prefix, report.Created, report.Updated, report.Failed)
+	if report.Failed > 0 {
+		return exitInvalidData
+	}
+	return exitOK
+}
+
+func exportCatalog(args []string) int {
+	fs := flag.NewFlagSet("catalog export", flag.ContinueOnError)
+	path := fs.String("file", "", "file to write the catalog into, stdout when omitted")
+	format := fs.String("format", "", "csv or json, guessed from the file extension when omitted")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *path == "" && *format == "" {
+		*format = string(catalogfile.JSON)
+	}
+
+	f, err := catalogfile.ParseFormat(*format, *path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	res, err := prodUsecase.ExportProducts()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitCode(err)
+	}
+
+	var w io.Writer = os.Stdout
+	if *path != "" {
+		file, err := os.Create(*path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitFailure
+		}
+		defer file.Close()
+		w = file
+	}
+	if err := catalogfile.Encode(w, f, res.([]*productSvc.Product)); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitCode(err)
+	}
+	return exitOK
+}
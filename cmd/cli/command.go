@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+)
+
+// exit codes of the non interactive commands, scripts may rely on them
+const (
+	exitOK            = 0
+	exitFailure       = 1
+	exitUsage         = 2
+	exitNotFound      = 3
+	exitInvalidData   = 4
+	exitConflict      = 5
+	exitUnauthorized  = 6
+	exitForbidden     = 7
+	exitOrderLimit    = 8
+	exitCustomerLimit = 9
+	exitOutOfStock    = 10
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+const usage = `usage: cli [global flags] [command] [subcommand] [flags]
+
+Runs the interactive shell when no command is given.
+
+global flags:
+  --config <path>      JSON config file, also read from $CARTSVC_CONFIG
+  --backend <name>     repository backend, inmem, file or bolt ($CARTSVC_BACKEND)
+  --data <path>        data directory of the file backend, database file of the bolt one ($CARTSVC_DATA_PATH)
+  --currency <code>    default currency ($CARTSVC_CURRENCY)
+  --cart-ttl <dur>     idle time after which an open cart expires ($CARTSVC_CART_TTL)
+  --log-level <level>  debug, info, warn or error ($CARTSVC_LOG_LEVEL)
+  --user <id>          user of the interactive shell ($CARTSVC_USER)
+  --remind-after <dur> idle time after which a cart owner is reminded ($CARTSVC_REMIND_AFTER)
+  --reminder-limit <n> maximum number of reminders per cart ($CARTSVC_REMINDER_LIMIT)
+  --notification-file <path>  file receiving the reminders, stdout by default ($CARTSVC_NOTIFICATION_FILE)
+  --point-value <n>    discount a loyalty point is worth ($CARTSVC_POINT_VALUE)
+  --points-validity <dur>  how long the earned loyalty points remain redeemable ($CARTSVC_POINTS_VALIDITY)
+
+commands:
+  cart show     --user <id>
+  cart add      --user <id> --product <id> [--sku <sku>] --qty <n>
+  cart remove   --user <id> --sku <sku>
+  cart refresh  --user <id>, reprices the cart and tells what has changed
+  cart checkout --user <id> [--points <n>], redeems the loyalty points as a discount
+  cart close    --user <id> --cart <id>, settles the payment and earns the loyalty points
+  cart republish --user <id> --cart <id>, publishes the closing of a closed cart once more, earning the missed points
+  cart refund   --user <id> --cart <id>, refunds a closed cart and reverses its loyalty points
+  cart expire   cancels the carts idle for longer than the cart TTL
+  cart remind   reminds the owners of the idle carts, see --remind-after
+  product get   --id <id>
+  product list  [--q <keyword>] [--sort name|price] [--desc] [--cursor <cursor>] [--limit <n>]
+  product limit --id <id> [--max-per-order <n>] [--max-per-customer <n>] [--period <dur>], 0 lifts a limit
+  product schedule   --id <id> [--sku <sku>] [--price <n>] [--disc <n>] [--from <time>] --until <time> [--qty <n>]
+  product unschedule --id <id> --schedule <id>
+  product stock --id <id> [--sku <sku>] [--warehouse <id>] --delta <n>
+  product warehouses
+  product availability --id <id> [--mode backorder|preorder] [--available-at <time>] [--limit <n>], no mode lifts it
+  subscription add    --user <id> --product <id> [--sku <sku>], notifies the user once it is restocked
+  subscription remove --user <id> --sku <sku>
+  subscription list   --user <id>
+  loyalty balance --user <id>, the loyalty points along with their ledger
+  catalog import --file <path> [--format csv|json] [--upsert] [--dry-run]
+  catalog export [--file <path>] [--format csv|json]
+  user register --id <id> --email <email> --password <password> [--name <name>] [--phone <phone>]
+                [--street <street> --city <city> --postal <code> --province <province> --region <region> --country <country>]
+                [--admin], the address is used for both the shipping and the billing
+
+Every cart, product, subscription, loyalty and user command accepts --output table|json (table by default).
+
+exit codes:
+  0 success, 1 failure, 2 invalid usage, 3 not found, 4 invalid data,
+  5 conflict, 6 unauthorized, 7 forbidden, 8 order limit exceeded,
+  9 customer limit exceeded, 10 out of stock`
+
+type command struct {
+	flags  *flag.FlagSet
+	output *string
+}
+
+// errorView is written to stderr when the json output is requested
+type errorView struct {
+	Error string `json:"error"`
+	Code  int    `json:"code"`
+}
+
+func run(args []string) int {
+	switch args[0] {
+	case "help", "-h", "--help":
+		fmt.Println(usage)
+		return exitOK
+	}
+	if len(args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		return exitUsage
+	}
+	switch args[0] {
+	case "cart":
+		return runCart(args[1], args[2:])
+	case "product":
+		return runProduct(args[1], args[2:])
+	case "catalog":
+		return runCatalog(args[1:])
+	case "subscription":
+		return runSubscription(args[1], args[2:])
+	case "loyalty":
+		return runLoyalty(args[1], args[2:])
+	case "user":
+		return runUser(args[1], args[2:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %s\n\n%s\n", args[0], usage)
+		return exitUsage
+	}
+}
+
+func newCommand(name string) *command {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	return &command{
+		flags:  fs,
+		output: fs.String("output", outputTable, "output format, table or json"),
+	}
+}
+
+// parse returns a non zero exit code when the flags are invalid
+func (c *command) parse(args []string) int {
+	if err := c.flags.Parse(args); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", c.flags.Name(), err)
+		return exitUsage
+	}
+	if *c.output != outputTable && *c.output != outputJSON {
+		fmt.Fprintf(os.Stderr, "%s: unknown output format %s\n", c.flags.Name(), *c.output)
+		return exitUsage
+	}
+	return exitOK
+}
+
+// require returns a non zero exit code when one of the given flags is left empty
+func (c *command) require(names ...string) int {
+	for _, name := range names {
+		if f := c.flags.Lookup(name); f == nil || f.Value.String() == "" {
+			fmt.Fprintf(os.Stderr, "%s: --%s is required\n", c.flags.Name(), name)
+			return exitUsage
+		}
+	}
+	return exitOK
+}
+
+func (c *command) fail(err error) int {
+	code := exitCode(err)
+	if *c.output == outputJSON {
+		json.NewEncoder(os.Stderr).Encode(&errorView{Error: err.Error(), Code: code})
+	} else {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+	}
+	return code
+}
+
+func exitCode(err error) int {
+	switch err.(type) {
+	case nil:
+		return exitOK
+	case *e.ErrNoData:
+		return exitNotFound
+	case *e.ErrInvalidData, *e.ErrConversion:
+		return exitInvalidData
+	case *e.ErrDuplicateData, *e.ErrConflict:
+		return exitConflict
+	case *e.ErrUnauthorized:
+		return exitUnauthorized
+	case *e.ErrForbidden:
+		return exitForbidden
+	case *e.ErrOrderLimitExceeded:
+		return exitOrderLimit
+	case *e.ErrCustomerLimitExceeded:
+		return exitCustomerLimit
+	case *e.ErrOutOfStock:
+		return exitOutOfStock
+	default:
+		return exitFailure
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	loyaltySvc "github.com/yauritux/cartsvc/pkg/usecase/loyalty"
+)
+
+func runLoyalty(sub string, args []string) int {
+	cmd := newCommand("loyalty " + sub)
+	user := cmd.flags.String("user", "", "id of the loyalty member")
+	if code := cmd.parse(args); code != exitOK {
+		return code
+	}
+
+	switch sub {
+	case "balance":
+	default:
+		fmt.Fprintf(os.Stderr, "unknown subcommand loyalty %s\n\n%s\n", sub, usage)
+		return exitUsage
+	}
+	if code := cmd.require("user"); code != exitOK {
+		return code
+	}
+
+	res, err := loyaltyUsecase.FetchBalance(*user)
+	if err != nil {
+		return cmd.fail(err)
+	}
+	view := buildBalanceView(res.(*loyaltySvc.Balance))
+	if *cmd.output == outputJSON {
+		printJSON(view)
+	} else {
+		printBalanceTable(view)
+	}
+	return exitOK
+}
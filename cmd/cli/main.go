@@ -1,111 +1,66 @@
 package main
 
 import (
-	"bufio"
-	"errors"
 	"fmt"
 	"os"
-	"strconv"
+	"sort"
+	"strings"
 
-	"github.com/yauritux/cartsvc/pkg/adapter/repository/inmem"
+	"github.com/yauritux/cartsvc/pkg/app"
+	"github.com/yauritux/cartsvc/pkg/config"
 	cartSvc "github.com/yauritux/cartsvc/pkg/usecase/carts"
+	loyaltySvc "github.com/yauritux/cartsvc/pkg/usecase/loyalty"
 	productSvc "github.com/yauritux/cartsvc/pkg/usecase/products"
+	subscriptionSvc "github.com/yauritux/cartsvc/pkg/usecase/subscriptions"
+	userSvc "github.com/yauritux/cartsvc/pkg/usecase/users"
 )
 
+var container *app.Container
 var prodUsecase *productSvc.ProductUsecase
-var cartRepository *inmem.CartRepository
 var cartUsecase *cartSvc.CartUsecase
-
-func init() {
-	prodRepository := inmem.NewProductRepository()
-	cartRepository = inmem.NewCartRepository("yauritux")
-	prodUsecase = productSvc.NewProductUsecase(prodRepository)
-	cartUsecase = cartSvc.NewCartUsecase(cartRepository, prodRepository)
-}
+var subscriptionUsecase *subscriptionSvc.SubscriptionUsecase
+var loyaltyUsecase *loyaltySvc.LoyaltyUsecase
+var userUsecase *userSvc.UserUsecase
 
 func main() {
-	scanner := bufio.NewScanner(os.Stdin)
-
-	showMenu()
-
-	for scanner.Scan() {
-		text := scanner.Text()
-		switch text {
-		case "1":
-			if err := addItemToCart(scanner); err != nil {
-				fmt.Printf("failed to add item... %v\n", err)
-			}
-			fmt.Println()
-			showMenu()
-		case "2":
-			if err := showCartItems(); err != nil {
-				fmt.Printf("failed to show cart items...%v\n", err)
-			}
-			fmt.Println()
-			showMenu()
-		case "3":
-			os.Exit(0)
-		default:
-			fmt.Println()
-			showMenu()
-		}
-	}
-}
-
-func showMenu() {
-	fmt.Println("1. Add item to cart")
-	fmt.Println("2. Show items in cart")
-	fmt.Println("3. Exit")
-	fmt.Print("Your choice [1 or 3]: ")
-}
-
-func addItemToCart(r *bufio.Scanner) error {
-	fmt.Print("Enter product ID: ")
-	r.Scan()
-	prodID := r.Text()
-	prodFound, err := prodUsecase.FindByProductID(prodID)
+	cfg, args, err := config.Load("cli", os.Args[1:])
 	if err != nil {
-		return err
-	}
-	prod, ok := prodFound.(*productSvc.Product)
-	if !ok {
-		return errors.New("failed to get product from the repository...invalid type of product usecase model")
+		fmt.Fprintf(os.Stderr, "error: %v\n\n%s\n", err, usage)
+		os.Exit(exitUsage)
 	}
-
-	fmt.Print("Enter amount: ")
-	r.Scan()
-	amt := r.Text()
-	qty, err := strconv.Atoi(amt)
-	if err != nil {
-		return errors.New("invalid amount")
+	if container, err = app.New(cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(exitCode(err))
 	}
+	prodUsecase = container.ProductUsecase
+	cartUsecase = container.CartUsecase
+	subscriptionUsecase = container.SubscriptionUsecase
+	loyaltyUsecase = container.LoyaltyUsecase
+	userUsecase = container.UserUsecase
 
-	if err := cartUsecase.AddToCart("yauritux", &cartSvc.CartItem{
-		ID:    prod.ID,
-		Name:  prod.Name,
-		Qty:   qty,
-		Price: prod.Price,
-		Disc:  prod.Disc,
-	}); err != nil {
-		fmt.Println("error = ", err)
-		return err
+	code := exitOK
+	if len(args) > 0 {
+		code = run(args)
+	} else if err := runREPL(cfg.DefaultUser); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		code = exitCode(err)
 	}
-	fmt.Printf("added %d item of %s to cart\n", qty, prod.Name)
-	return nil
+	container.Close()
+	os.Exit(code)
 }
 
-func showCartItems() error {
-	userCart, err := cartUsecase.FetchUserCart("yauritux")
-	if err != nil {
-		return err
+func formatOptions(options map[string]string) string {
+	if len(options) == 0 {
+		return ""
 	}
-	cart, ok := userCart.(*cartSvc.Cart)
-	if !ok {
-		return errors.New("failed to show cart items, invalid type of cart usecase model")
+	keys := make([]string, 0, len(options))
+	for k := range options {
+		keys = append(keys, k)
 	}
-	fmt.Println("\nYour cart items:")
-	for _, v := range cart.Items {
-		fmt.Printf("%d pcs of %s (%s)\n", v.Qty, v.ID, v.Name)
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k+": "+options[k])
 	}
-	return nil
+	return "[" + strings.Join(pairs, ", ") + "]"
 }
@@ -0,0 +1,606 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/yauritux/cartsvc/pkg/domain/entity"
+	"github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+	cartSvc "github.com/yauritux/cartsvc/pkg/usecase/carts"
+	loyaltySvc "github.com/yauritux/cartsvc/pkg/usecase/loyalty"
+	productSvc "github.com/yauritux/cartsvc/pkg/usecase/products"
+	subscriptionSvc "github.com/yauritux/cartsvc/pkg/usecase/subscriptions"
+	userSvc "github.com/yauritux/cartsvc/pkg/usecase/users"
+)
+
+type cartView struct {
+	ID       string          `json:"id"`
+	UserID   string          `json:"user_id"`
+	Status   string          `json:"status"`
+	Items    []*cartItemView `json:"items"`
+	Gross    float64         `json:"gross"`
+	Discount float64         `json:"discount"`
+	Total    float64         `json:"total"`
+	// Redeemed is the part of the total paid with the loyalty Points, Due being what is left to pay
+	Points    int             `json:"points_redeemed,omitempty"`
+	Redeemed  float64         `json:"redeemed,omitempty"`
+	Due       float64         `json:"due"`
+	Currency  string          `json:"currency"`
+	Shipments []*shipmentView `json:"shipments,omitempty"`
+}
+
+type shipmentView struct {
+	WarehouseID string           `json:"warehouse_id,omitempty"`
+	Items       []*componentView `json:"items"`
+	Fulfillment string           `json:"fulfillment,omitempty"`
+	AvailableAt *time.Time       `json:"available_at,omitempty"`
+}
+
+type cartItemView struct {
+	ID         string            `json:"id"`
+	Name       string            `json:"name"`
+	SKU        string            `json:"sku"`
+	Options    map[string]string `json:"options,omitempty"`
+	Qty        int               `json:"qty"`
+	Price      float64           `json:"price"`
+	Disc       float64           `json:"disc"`
+	Subtotal   float64           `json:"subtotal"`
+	Components []*componentView  `json:"components,omitempty"`
+	// Fulfillment is only set for a line waiting for the stock
+	Fulfillment string     `json:"fulfillment,omitempty"`
+	AvailableAt *time.Time `json:"available_at,omitempty"`
+}
+
+type componentView struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+	SKU  string `json:"sku,omitempty"`
+	Qty  int    `json:"qty"`
+}
+
+type savedListView struct {
+	UserID string          `json:"user_id"`
+	Name   string          `json:"name"`
+	Items  []*cartItemView `json:"items"`
+}
+
+type cartRefreshView struct {
+	Cart    *cartView         `json:"cart"`
+	Changes []*cartChangeView `json:"changes"`
+}
+
+type cartChangeView struct {
+	SKU       string  `json:"sku"`
+	Kind      string  `json:"kind"`
+	OldPrice  float64 `json:"old_price"`
+	NewPrice  float64 `json:"new_price"`
+	Available int     `json:"available"`
+	Notice    string  `json:"notice"`
+}
+
+type productView struct {
+	ID         string           `json:"id"`
+	Name       string           `json:"name"`
+	Type       string           `json:"type,omitempty"`
+	Stock      int              `json:"stock"`
+	Warehouses map[string]int   `json:"warehouse_stock,omitempty"`
+	Price      float64          `json:"price"`
+	Disc       float64          `json:"disc"`
+	Categories []string         `json:"categories,omitempty"`
+	Variants   []*variantView   `json:"variants,omitempty"`
+	Components []*componentView `json:"components,omitempty"`
+	Limit      *limitView       `json:"purchase_limit,omitempty"`
+	Schedules  []*scheduleView  `json:"price_schedules,omitempty"`
+	Policy     *stockPolicyView `json:"stock_policy,omitempty"`
+}
+
+type stockPolicyView struct {
+	Mode        string     `json:"mode"`
+	AvailableAt *time.Time `json:"available_at,omitempty"`
+	Limit       int        `json:"limit,omitempty"`
+	Committed   int        `json:"committed"`
+}
+
+type scheduleView struct {
+	ID       string    `json:"id"`
+	SKU      string    `json:"sku,omitempty"`
+	Price    float64   `json:"price,omitempty"`
+	Disc     float64   `json:"disc"`
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+	Quantity int       `json:"quantity,omitempty"`
+	Sold     int       `json:"sold,omitempty"`
+}
+
+type limitView struct {
+	MaxPerOrder    int    `json:"max_per_order,omitempty"`
+	MaxPerCustomer int    `json:"max_per_customer,omitempty"`
+	Period         string `json:"period,omitempty"`
+}
+
+type variantView struct {
+	SKU        string            `json:"sku"`
+	Options    map[string]string `json:"options,omitempty"`
+	Stock      int               `json:"stock"`
+	Warehouses map[string]int    `json:"warehouse_stock,omitempty"`
+	Price      float64           `json:"price"`
+	Disc       float64           `json:"disc"`
+}
+
+type warehouseView struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	City     string `json:"city,omitempty"`
+	Province string `json:"province,omitempty"`
+	Region   string `json:"region,omitempty"`
+	Postal   string `json:"postal,omitempty"`
+	Country  string `json:"country,omitempty"`
+}
+
+type subscriptionView struct {
+	ProductID   string    `json:"product_id"`
+	SKU         string    `json:"sku"`
+	ProductName string    `json:"product_name"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type balanceView struct {
+	UserID         string             `json:"user_id"`
+	Points         int                `json:"points"`
+	Value          float64            `json:"value"`
+	Currency       string             `json:"currency"`
+	NextExpiry     *time.Time         `json:"next_expiry,omitempty"`
+	ExpiringPoints int                `json:"expiring_points,omitempty"`
+	Entries        []*ledgerEntryView `json:"entries"`
+}
+
+type ledgerEntryView struct {
+	ID        string     `json:"id"`
+	CartID    string     `json:"cart_id,omitempty"`
+	Kind      string     `json:"kind"`
+	Points    int        `json:"points"`
+	RefID     string     `json:"ref_id,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// userView never carries the password hash
+type userView struct {
+	ID              string       `json:"id"`
+	Name            string       `json:"name,omitempty"`
+	Email           string       `json:"email"`
+	Phone           string       `json:"phone,omitempty"`
+	Role            string       `json:"role"`
+	ShippingAddress *addressView `json:"shipping_address,omitempty"`
+	BillingAddress  *addressView `json:"billing_address,omitempty"`
+}
+
+type addressView struct {
+	Street   string `json:"street"`
+	City     string `json:"city"`
+	Postal   string `json:"postal"`
+	Province string `json:"province"`
+	Region   string `json:"region"`
+	Country  string `json:"country"`
+}
+
+type productPageView struct {
+	Items      []*productView `json:"items"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+func buildCartView(c *cartSvc.Cart) *cartView {
+	totals := c.Totals()
+	view := &cartView{
+		ID:       c.ID,
+		UserID:   c.UserID,
+		Status:   string(c.Status),
+		Items:    make([]*cartItemView, 0),
+		Gross:    totals.Gross,
+		Discount: totals.Discount,
+		Total:    totals.Net,
+		Redeemed: totals.Redeemed,
+		Due:      totals.Due,
+		Currency: container.Config.Currency,
+	}
+	if c.Redemption != nil {
+		view.Points = c.Redemption.Points
+	}
+	for _, v := range c.Items {
+		item := &cartItemView{
+			ID:          v.ID,
+			Name:        v.Name,
+			SKU:         v.SKU,
+			Options:     v.Options,
+			Qty:         v.Qty,
+			Price:       v.Price,
+			Disc:        v.Disc,
+			Subtotal:    (v.Price - v.Disc) * float64(v.Qty),
+			Fulfillment: string(v.Fulfillment),
+			AvailableAt: v.AvailableAt,
+		}
+		for _, comp := range v.Components {
+			item.Components = append(item.Components, &componentView{ID: comp.ID, Name: comp.Name, SKU: comp.SKU, Qty: comp.Qty})
+		}
+		view.Items = append(view.Items, item)
+	}
+	for _, s := range c.Shipments {
+		shipment := &shipmentView{
+			WarehouseID: s.WarehouseID, Items: make([]*componentView, 0), Fulfillment: string(s.Fulfillment), AvailableAt: s.AvailableAt,
+		}
+		for _, v := range s.Items {
+			shipment.Items = append(shipment.Items, &componentView{ID: v.ID, Name: v.Name, SKU: v.SKU, Qty: v.Qty})
+		}
+		view.Shipments = append(view.Shipments, shipment)
+	}
+	return view
+}
+
+func buildSavedListView(l *cartSvc.SavedList) *savedListView {
+	view := &savedListView{UserID: l.UserID, Name: string(l.Name), Items: make([]*cartItemView, 0)}
+	for _, v := range l.Items {
+		view.Items = append(view.Items, &cartItemView{
+			ID:       v.ID,
+			Name:     v.Name,
+			SKU:      v.SKU,
+			Options:  v.Options,
+			Qty:      v.Qty,
+			Price:    v.Price,
+			Disc:     v.Disc,
+			Subtotal: (v.Price - v.Disc) * float64(v.Qty),
+		})
+	}
+	return view
+}
+
+func buildCartRefreshView(r *cartSvc.CartRefresh) *cartRefreshView {
+	view := &cartRefreshView{Cart: buildCartView(r.Cart), Changes: make([]*cartChangeView, 0)}
+	for _, v := range r.Changes {
+		view.Changes = append(view.Changes, &cartChangeView{
+			SKU: v.SKU, Kind: string(v.Kind), OldPrice: v.OldPrice, NewPrice: v.NewPrice, Available: v.Available, Notice: v.Notice,
+		})
+	}
+	return view
+}
+
+func buildProductView(p *productSvc.Product) *productView {
+	view := &productView{
+		ID:         p.ID,
+		Name:       p.Name,
+		Type:       string(p.Type),
+		Stock:      p.Stock,
+		Warehouses: p.WarehouseStock,
+		Price:      p.Price,
+		Disc:       p.Disc,
+		Categories: p.CategoryIDs,
+	}
+	for _, v := range p.Variants {
+		view.Variants = append(view.Variants, &variantView{
+			SKU: v.SKU, Options: v.Options, Stock: v.Stock, Warehouses: v.WarehouseStock, Price: v.Price, Disc: v.Disc,
+		})
+	}
+	for _, c := range p.Components {
+		view.Components = append(view.Components, &componentView{ID: c.ProductID, SKU: c.SKU, Qty: c.Qty})
+	}
+	if l := p.PurchaseLimit; l != nil {
+		view.Limit = &limitView{MaxPerOrder: l.MaxPerOrder, MaxPerCustomer: l.MaxPerCustomer}
+		if l.Period > 0 {
+			view.Limit.Period = l.Period.String()
+		}
+	}
+	for _, v := range p.PriceSchedules {
+		view.Schedules = append(view.Schedules, &scheduleView{
+			ID: v.ID, SKU: v.SKU, Price: v.Price, Disc: v.Disc,
+			StartsAt: v.StartsAt, EndsAt: v.EndsAt, Quantity: v.Quantity, Sold: v.Sold,
+		})
+	}
+	if s := p.StockPolicy; s != nil {
+		view.Policy = &stockPolicyView{Mode: string(s.Mode), AvailableAt: s.AvailableAt, Limit: s.Limit, Committed: s.Committed}
+	}
+	return view
+}
+
+func buildProductPageView(page *productSvc.ProductPage) *productPageView {
+	view := &productPageView{Items: make([]*productView, 0), NextCursor: page.NextCursor}
+	for _, p := range page.Items {
+		view.Items = append(view.Items, buildProductView(p))
+	}
+	return view
+}
+
+func printJSON(v interface{}) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+func printCartTable(c *cartView) {
+	fmt.Printf("cart %s of %s (%s)\n", c.ID, c.UserID, c.Status)
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "SKU\tNAME\tQTY\tPRICE\tDISC\tSUBTOTAL")
+	for _, v := range c.Items {
+		fmt.Fprintf(w, "%s\t%s %s%s\t%d\t%.2f\t%.2f\t%.2f\n",
+			v.SKU, v.Name, formatOptions(v.Options), formatFulfillment(v.Fulfillment, v.AvailableAt), v.Qty, v.Price, v.Disc, v.Subtotal)
+		for _, comp := range v.Components {
+			fmt.Fprintf(w, "\t  - %d x %s (%s)\t\t\t\t\n", comp.Qty, comp.Name, comp.SKU)
+		}
+	}
+	fmt.Fprintf(w, "\tDISCOUNT\t\t\t\t%.2f\n", c.Discount)
+	fmt.Fprintf(w, "\tTOTAL (%s)\t\t\t\t%.2f\n", c.Currency, c.Total)
+	if c.Points > 0 {
+		fmt.Fprintf(w, "\t%d POINTS REDEEMED\t\t\t\t-%.2f\n", c.Points, c.Redeemed)
+		fmt.Fprintf(w, "\tDUE (%s)\t\t\t\t%.2f\n", c.Currency, c.Due)
+	}
+	w.Flush()
+	for _, s := range c.Shipments {
+		switch s.Fulfillment {
+		case "":
+			fmt.Printf("\nshipped from warehouse %s:\n", s.WarehouseID)
+		case string(enum.PreOrder):
+			fmt.Printf("\npre-ordered, shipped upon the release%s:\n", formatExpected(s.AvailableAt))
+		default:
+			fmt.Printf("\nbackordered, shipped once the stock is replenished%s:\n", formatExpected(s.AvailableAt))
+		}
+		for _, v := range s.Items {
+			fmt.Printf("  - %d x %s (%s)\n", v.Qty, v.Name, v.SKU)
+		}
+	}
+}
+
+func printSavedListTable(l *savedListView) {
+	fmt.Printf("%s of %s\n", l.Name, l.UserID)
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "SKU\tNAME\tQTY\tSAVED PRICE\tDISC")
+	for _, v := range l.Items {
+		fmt.Fprintf(w, "%s\t%s %s\t%d\t%.2f\t%.2f\n", v.SKU, v.Name, formatOptions(v.Options), v.Qty, v.Price, v.Disc)
+	}
+	w.Flush()
+}
+
+func printCartChanges(changes []*cartChangeView) {
+	if len(changes) == 0 {
+		fmt.Println("your cart is up to date")
+		return
+	}
+	for _, v := range changes {
+		fmt.Printf("! %s\n", v.Notice)
+	}
+}
+
+func printLimit(p *productView) {
+	l := p.Limit
+	if l == nil {
+		fmt.Printf("%s can be bought without limit\n", p.Name)
+		return
+	}
+	if l.MaxPerOrder > 0 {
+		fmt.Printf("%s: at most %d per order\n", p.Name, l.MaxPerOrder)
+	}
+	if l.MaxPerCustomer > 0 {
+		period := "ever"
+		if l.Period != "" {
+			period = "within " + l.Period
+		}
+		fmt.Printf("%s: at most %d per customer %s\n", p.Name, l.MaxPerCustomer, period)
+	}
+}
+
+func printStockPolicy(p *productView) {
+	s := p.Policy
+	if s == nil {
+		fmt.Printf("%s only sells its stock\n", p.Name)
+		return
+	}
+	limit := "without limit"
+	if s.Limit > 0 {
+		limit = fmt.Sprintf("up to %d units", s.Limit)
+	}
+	if s.Mode == string(enum.PreOrder) {
+		fmt.Printf("%s: pre-order %s, released%s\n", p.Name, limit, formatExpected(s.AvailableAt))
+	} else {
+		fmt.Printf("%s: backorder %s beyond the stock, expected%s\n", p.Name, limit, orLater(formatExpected(s.AvailableAt)))
+	}
+	fmt.Printf("%s: %d unit(s) checked out waiting for the stock\n", p.Name, s.Committed)
+}
+
+// formatFulfillment marks a cart line waiting for the stock
+func formatFulfillment(f string, availableAt *time.Time) string {
+	switch f {
+	case "":
+		return ""
+	case string(enum.PreOrder):
+		return fmt.Sprintf(" [pre-order, released%s]", formatExpected(availableAt))
+	default:
+		return fmt.Sprintf(" [backorder, expected%s]", orLater(formatExpected(availableAt)))
+	}
+}
+
+func formatExpected(at *time.Time) string {
+	if at == nil {
+		return ""
+	}
+	return " on " + at.Format("2006-01-02")
+}
+
+func orLater(expected string) string {
+	if expected == "" {
+		return " later"
+	}
+	return expected
+}
+
+func printScheduleTable(p *productView) {
+	if len(p.Schedules) == 0 {
+		fmt.Printf("%s has no price schedule\n", p.Name)
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSKU\tPRICE\tDISC\tFROM\tUNTIL\tSOLD")
+	for _, s := range p.Schedules {
+		sku, price, sold := s.SKU, "regular", fmt.Sprintf("%d", s.Sold)
+		if sku == "" {
+			sku = p.ID
+		}
+		if s.Price > 0 {
+			price = fmt.Sprintf("%.2f", s.Price)
+		}
+		if s.Quantity > 0 {
+			sold = fmt.Sprintf("%d/%d", s.Sold, s.Quantity)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%.2f\t%s\t%s\t%s\n", s.ID, sku, price, s.Disc,
+			s.StartsAt.Format(time.RFC3339), s.EndsAt.Format(time.RFC3339), sold)
+	}
+	w.Flush()
+}
+
+func printStockTable(p *productView) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "SKU\tWAREHOUSE\tSTOCK")
+	printLevels := func(sku string, stock int, levels map[string]int) {
+		if len(levels) == 0 {
+			fmt.Fprintf(w, "%s\t%s\t%d\n", sku, entity.MainWarehouse, stock)
+			return
+		}
+		ids := make([]string, 0, len(levels))
+		for id := range levels {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			fmt.Fprintf(w, "%s\t%s\t%d\n", sku, id, levels[id])
+		}
+	}
+	if len(p.Variants) == 0 {
+		printLevels(p.ID, p.Stock, p.Warehouses)
+	}
+	for _, v := range p.Variants {
+		printLevels(v.SKU, v.Stock, v.Warehouses)
+	}
+	w.Flush()
+}
+
+func buildWarehouseViews(warehouses []*productSvc.Warehouse) []*warehouseView {
+	views := make([]*warehouseView, 0)
+	for _, w := range warehouses {
+		view := warehouseView(*w)
+		views = append(views, &view)
+	}
+	return views
+}
+
+func printWarehouseTable(warehouses []*warehouseView) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tCITY\tPROVINCE\tCOUNTRY")
+	for _, v := range warehouses {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", v.ID, v.Name, v.City, v.Province, v.Country)
+	}
+	w.Flush()
+}
+
+func buildSubscriptionViews(subscriptions []*subscriptionSvc.Subscription) []*subscriptionView {
+	views := make([]*subscriptionView, 0)
+	for _, s := range subscriptions {
+		views = append(views, &subscriptionView{ProductID: s.ProductID, SKU: s.SKU, ProductName: s.ProductName, CreatedAt: s.CreatedAt})
+	}
+	return views
+}
+
+func printSubscriptionTable(subscriptions []*subscriptionView) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "SKU\tNAME\tSINCE")
+	for _, v := range subscriptions {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", v.SKU, v.ProductName, v.CreatedAt.Format(time.RFC3339))
+	}
+	w.Flush()
+}
+
+func buildBalanceView(b *loyaltySvc.Balance) *balanceView {
+	view := &balanceView{
+		UserID:         b.UserID,
+		Points:         b.Points,
+		Value:          b.Value,
+		Currency:       container.Config.Currency,
+		NextExpiry:     b.NextExpiry,
+		ExpiringPoints: b.ExpiringPoints,
+		Entries:        make([]*ledgerEntryView, 0),
+	}
+	for _, v := range b.Entries {
+		view.Entries = append(view.Entries, &ledgerEntryView{
+			ID: v.ID, CartID: v.CartID, Kind: string(v.Kind), Points: v.Points, RefID: v.RefID, CreatedAt: v.CreatedAt, ExpiresAt: v.ExpiresAt,
+		})
+	}
+	return view
+}
+
+func printBalanceTable(b *balanceView) {
+	fmt.Printf("%s holds %d points, worth %.2f %s\n", b.UserID, b.Points, b.Value, b.Currency)
+	if b.NextExpiry != nil {
+		fmt.Printf("%d points expire on %s\n", b.ExpiringPoints, b.NextExpiry.Format(time.RFC3339))
+	}
+	if len(b.Entries) == 0 {
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tKIND\tPOINTS\tCART\tDATE")
+	for _, v := range b.Entries {
+		kind := v.Kind
+		if v.RefID != "" {
+			kind += " #" + v.RefID
+		}
+		fmt.Fprintf(w, "%s\t%s\t%+d\t%s\t%s\n", v.ID, kind, v.Points, v.CartID, v.CreatedAt.Format(time.RFC3339))
+	}
+	w.Flush()
+}
+
+func printProductTable(products []*productView) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSKU\tNAME\tSTOCK\tPRICE\tDISC")
+	for _, p := range products {
+		if len(p.Variants) == 0 {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%.2f\t%.2f\n", p.ID, p.ID, p.Name, p.Stock, p.Price, p.Disc)
+			continue
+		}
+		for _, v := range p.Variants {
+			fmt.Fprintf(w, "%s\t%s\t%s %s\t%d\t%.2f\t%.2f\n",
+				p.ID, v.SKU, p.Name, formatOptions(v.Options), v.Stock, v.Price, v.Disc)
+		}
+	}
+	w.Flush()
+}
+
+func buildUserView(u *userSvc.User) *userView {
+	return &userView{
+		ID:              u.ID,
+		Name:            u.Username,
+		Email:           u.Email,
+		Phone:           u.Phone,
+		Role:            string(u.Role),
+		ShippingAddress: buildAddressView(u.ShippingAddr),
+		BillingAddress:  buildAddressView(u.BillingAddr),
+	}
+}
+
+func buildAddressView(addr *userSvc.Address) *addressView {
+	if addr == nil {
+		return nil
+	}
+	return &addressView{
+		Street:   addr.Street,
+		City:     addr.City,
+		Postal:   addr.Postal,
+		Province: addr.Province,
+		Region:   addr.Region,
+		Country:  addr.Country,
+	}
+}
+
+func printUser(u *userView) {
+	fmt.Printf("%s registered as %s (%s)\n", u.ID, u.Role, u.Email)
+	if a := u.ShippingAddress; a != nil {
+		fmt.Printf("ships to %s, %s %s, %s, %s, %s\n", a.Street, a.Region, a.Postal, a.City, a.Province, a.Country)
+	}
+}
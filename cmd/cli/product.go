@@ -0,0 +1,227 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/yauritux/cartsvc/pkg/domain/entity"
+	"github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+	productSvc "github.com/yauritux/cartsvc/pkg/usecase/products"
+)
+
+func runProduct(sub string, args []string) int {
+	cmd := newCommand("product " + sub)
+
+	switch sub {
+	case "get":
+		id := cmd.flags.String("id", "", "id of the product")
+		if code := cmd.parse(args); code != exitOK {
+			return code
+		}
+		if code := cmd.require("id"); code != exitOK {
+			return code
+		}
+		p, err := prodUsecase.ShowProduct(*id)
+		if err != nil {
+			return cmd.fail(err)
+		}
+		view := buildProductView(p.(*productSvc.Product))
+		if *cmd.output == outputJSON {
+			printJSON(view)
+		} else {
+			printProductTable([]*productView{view})
+		}
+		return exitOK
+	case "list":
+		query := &productSvc.ProductQuery{}
+		cmd.flags.StringVar(&query.Keyword, "q", "", "keyword to search for")
+		sortBy := cmd.flags.String("sort", "", "name or price")
+		cmd.flags.BoolVar(&query.Descending, "desc", false, "sort descending")
+		cmd.flags.StringVar(&query.Cursor, "cursor", "", "next_cursor of the previous page")
+		cmd.flags.IntVar(&query.Limit, "limit", 0, "page size")
+		if code := cmd.parse(args); code != exitOK {
+			return code
+		}
+		query.SortBy = productSvc.SortField(*sortBy)
+
+		page, err := prodUsecase.SearchProducts(query)
+		if err != nil {
+			return cmd.fail(err)
+		}
+		view := buildProductPageView(page.(*productSvc.ProductPage))
+		if *cmd.output == outputJSON {
+			printJSON(view)
+		} else {
+			printProductTable(view.Items)
+			if view.NextCursor != "" {
+				fmt.Printf("\nnext page: --cursor %s\n", view.NextCursor)
+			}
+		}
+		return exitOK
+	case "limit":
+		id := cmd.flags.String("id", "", "id of the product")
+		limit := &productSvc.PurchaseLimit{}
+		cmd.flags.IntVar(&limit.MaxPerOrder, "max-per-order", 0, "units allowed in a single order, 0 for no limit")
+		cmd.flags.IntVar(&limit.MaxPerCustomer, "max-per-customer", 0, "units a customer can buy over the period, 0 for no limit")
+		cmd.flags.DurationVar(&limit.Period, "period", 0, "period of the customer limit, 0 counts every order")
+		if code := cmd.parse(args); code != exitOK {
+			return code
+		}
+		if code := cmd.require("id"); code != exitOK {
+			return code
+		}
+		if limit.MaxPerOrder == 0 && limit.MaxPerCustomer == 0 {
+			limit = nil
+		}
+
+		p, err := prodUsecase.SetPurchaseLimit(*id, limit)
+		if err != nil {
+			return cmd.fail(err)
+		}
+		view := buildProductView(p.(*productSvc.Product))
+		if *cmd.output == outputJSON {
+			printJSON(view)
+		} else {
+			printLimit(view)
+		}
+		return exitOK
+	case "schedule":
+		id := cmd.flags.String("id", "", "id of the product")
+		schedule := &productSvc.PriceSchedule{}
+		cmd.flags.StringVar(&schedule.SKU, "sku", "", "variant on sale, the whole product by default")
+		cmd.flags.Float64Var(&schedule.Price, "price", 0, "scheduled price, 0 keeps the regular price")
+		cmd.flags.Float64Var(&schedule.Disc, "disc", 0, "scheduled discount per unit")
+		from := cmd.flags.String("from", "", "start time (RFC 3339), now by default")
+		until := cmd.flags.String("until", "", "end time (RFC 3339)")
+		cmd.flags.IntVar(&schedule.Quantity, "qty", 0, "units sold at the scheduled price, 0 for no cap")
+		if code := cmd.parse(args); code != exitOK {
+			return code
+		}
+		if code := cmd.require("id", "until"); code != exitOK {
+			return code
+		}
+		schedule.StartsAt = time.Now().UTC().Truncate(time.Second)
+		if *from != "" {
+			startsAt, err := time.Parse(time.RFC3339, *from)
+			if err != nil {
+				return cmd.fail(e.NewErrInvalidData("invalid --from " + *from + ", should be an RFC 3339 time"))
+			}
+			schedule.StartsAt = startsAt
+		}
+		endsAt, err := time.Parse(time.RFC3339, *until)
+		if err != nil {
+			return cmd.fail(e.NewErrInvalidData("invalid --until " + *until + ", should be an RFC 3339 time"))
+		}
+		schedule.EndsAt = endsAt
+
+		p, err := prodUsecase.SchedulePrice(*id, schedule)
+		if err != nil {
+			return cmd.fail(err)
+		}
+		return printSchedules(cmd, p.(*productSvc.Product))
+	case "unschedule":
+		id := cmd.flags.String("id", "", "id of the product")
+		scheduleID := cmd.flags.String("schedule", "", "id of the price schedule")
+		if code := cmd.parse(args); code != exitOK {
+			return code
+		}
+		if code := cmd.require("id", "schedule"); code != exitOK {
+			return code
+		}
+		p, err := prodUsecase.CancelPriceSchedule(*id, *scheduleID)
+		if err != nil {
+			return cmd.fail(err)
+		}
+		return printSchedules(cmd, p.(*productSvc.Product))
+	case "stock":
+		id := cmd.flags.String("id", "", "id of the product")
+		sku := cmd.flags.String("sku", "", "variant to adjust, the product itself by default")
+		warehouse := cmd.flags.String("warehouse", entity.MainWarehouse, "warehouse holding the stock")
+		delta := cmd.flags.Int("delta", 0, "units added, or taken out when negative")
+		if code := cmd.parse(args); code != exitOK {
+			return code
+		}
+		if code := cmd.require("id"); code != exitOK {
+			return code
+		}
+		//the stock is adjusted even when the restock fails to reach its subscribers
+		p, err := prodUsecase.AdjustWarehouseStock(*id, *sku, *warehouse, *delta)
+		if p == nil {
+			return cmd.fail(err)
+		}
+		view := buildProductView(p.(*productSvc.Product))
+		if *cmd.output == outputJSON {
+			printJSON(view)
+		} else {
+			printStockTable(view)
+		}
+		if err != nil {
+			return cmd.fail(err)
+		}
+		return exitOK
+	case "availability":
+		id := cmd.flags.String("id", "", "id of the product")
+		mode := cmd.flags.String("mode", "", "backorder or preorder, none sells the stock only")
+		availableAt := cmd.flags.String("available-at", "", "when the stock is expected (RFC 3339), the release of a pre-order")
+		limit := cmd.flags.Int("limit", 0, "units waiting for the stock, 0 for no cap")
+		if code := cmd.parse(args); code != exitOK {
+			return code
+		}
+		if code := cmd.require("id"); code != exitOK {
+			return code
+		}
+		var policy *productSvc.StockPolicy
+		if *mode != "" {
+			policy = &productSvc.StockPolicy{Mode: enum.Fulfillment(*mode), Limit: *limit}
+			if *availableAt != "" {
+				at, err := time.Parse(time.RFC3339, *availableAt)
+				if err != nil {
+					return cmd.fail(e.NewErrInvalidData("invalid --available-at " + *availableAt + ", should be an RFC 3339 time"))
+				}
+				policy.AvailableAt = &at
+			}
+		}
+
+		p, err := prodUsecase.SetStockPolicy(*id, policy)
+		if err != nil {
+			return cmd.fail(err)
+		}
+		view := buildProductView(p.(*productSvc.Product))
+		if *cmd.output == outputJSON {
+			printJSON(view)
+		} else {
+			printStockPolicy(view)
+		}
+		return exitOK
+	case "warehouses":
+		if code := cmd.parse(args); code != exitOK {
+			return code
+		}
+		res, err := prodUsecase.ListWarehouses()
+		if err != nil {
+			return cmd.fail(err)
+		}
+		views := buildWarehouseViews(res.([]*productSvc.Warehouse))
+		if *cmd.output == outputJSON {
+			printJSON(views)
+		} else {
+			printWarehouseTable(views)
+		}
+		return exitOK
+	default:
+		fmt.Fprintf(os.Stderr, "unknown subcommand product %s\n\n%s\n", sub, usage)
+		return exitUsage
+	}
+}
+
+func printSchedules(cmd *command, p *productSvc.Product) int {
+	view := buildProductView(p)
+	if *cmd.output == outputJSON {
+		printJSON(view)
+	} else {
+		printScheduleTable(view)
+	}
+	return exitOK
+}
@@ -0,0 +1,526 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/chzyer/readline"
+	"github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+	cartSvc "github.com/yauritux/cartsvc/pkg/usecase/carts"
+	loyaltySvc "github.com/yauritux/cartsvc/pkg/usecase/loyalty"
+	productSvc "github.com/yauritux/cartsvc/pkg/usecase/products"
+	subscriptionSvc "github.com/yauritux/cartsvc/pkg/usecase/subscriptions"
+)
+
+const replHelp = `commands:
+  products [keyword]            browse the catalog, 'more' shows the next page
+  product <id>                  show the product along with its variants
+  add <product_id> <qty> [sku]  add an item (the sku is required for a product having variants)
+  update <sku> <qty>            change the quantity of a cart item
+  remove <sku>                  remove an item from the cart
+  cart                          show the cart items and totals
+  total                         show the cart totals
+  refresh                       reprice the cart and show what has changed
+  checkout [points]             checkout the cart, redeeming the loyalty points as a discount
+  points                        show the loyalty points along with their ledger
+  cancel                        cancel the cart, a checked out one giving back its stock and points
+  save <sku>                    move a cart item to the saved for later list
+  wish <product_id> [sku]       add a product to the wishlist
+  notify <product_id> [sku]     be notified once an out of stock product is restocked
+  unnotify <sku>                stop waiting for the restock
+  lists                         show the wishlist and the saved for later list
+  move <list> <sku>             move a saved item back to the cart, list is wishlist or saved_for_later
+  unsave <list> <sku>           remove an item from the list
+  user <id>                     switch the active user
+  history                       show the commands entered so far
+  help                          show this help
+  exit                          leave`
+
+type repl struct {
+	user       string
+	history    []string
+	lastQuery  *productSvc.ProductQuery
+	nextCursor string
+}
+
+func runREPL(user string) error {
+	r := &repl{user: user}
+	if err := openCart(r.user); err != nil {
+		return err
+	}
+
+	rl, err := readline.NewEx(&readline.Config{
+		Prompt:          r.prompt(),
+		HistoryFile:     historyFile(),
+		AutoComplete:    r.completer(),
+		InterruptPrompt: "^C",
+		EOFPrompt:       "exit",
+	})
+	if err != nil {
+		return err
+	}
+	defer rl.Close()
+
+	fmt.Println("type 'help' to list the commands")
+	for {
+		line, err := rl.Readline()
+		if err == readline.ErrInterrupt {
+			continue
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		r.history = append(r.history, line)
+		if done := r.exec(strings.Fields(line)); done {
+			return nil
+		}
+		rl.SetPrompt(r.prompt())
+	}
+}
+
+// exec runs a single command, it returns true when the user asks to leave
+func (r *repl) exec(args []string) bool {
+	var err error
+	switch args[0] {
+	case "exit", "quit":
+		return true
+	case "help":
+		fmt.Println(replHelp)
+	case "history":
+		for i, h := range r.history {
+			fmt.Printf("%4d  %s\n", i+1, h)
+		}
+	case "user":
+		err = r.switchUser(args[1:])
+	case "products":
+		err = r.browse(&productSvc.ProductQuery{Keyword: strings.Join(args[1:], " ")})
+	case "more":
+		err = r.more()
+	case "product":
+		err = r.showProduct(args[1:])
+	case "add":
+		err = r.add(args[1:])
+	case "update":
+		err = r.update(args[1:])
+	case "remove":
+		err = r.remove(args[1:])
+	case "cart":
+		err = r.showCart()
+	case "total":
+		err = r.showTotal()
+	case "refresh":
+		err = r.refresh()
+	case "checkout":
+		err = r.checkout(args[1:])
+	case "points":
+		err = r.showPoints()
+	case "cancel":
+		err = r.cancel()
+	case "save":
+		err = r.saveForLater(args[1:])
+	case "wish":
+		err = r.wish(args[1:])
+	case "notify":
+		err = r.notify(args[1:])
+	case "unnotify":
+		err = r.unnotify(args[1:])
+	case "lists":
+		err = r.showLists()
+	case "move":
+		err = r.moveToCart(args[1:])
+	case "unsave":
+		err = r.unsave(args[1:])
+	default:
+		err = fmt.Errorf("unknown command %s, type 'help' to list the commands", args[0])
+	}
+	if err != nil {
+		fmt.Printf("error: %v\n", err)
+	}
+	return false
+}
+
+func (r *repl) prompt() string {
+	return r.user + "> "
+}
+
+func (r *repl) switchUser(args []string) error {
+	if len(args) != 1 {
+		return e.NewErrInvalidData("usage: user <id>")
+	}
+	if err := openCart(args[0]); err != nil {
+		return err
+	}
+	r.user = args[0]
+	fmt.Printf("switched to %s\n", r.user)
+	return nil
+}
+
+func (r *repl) browse(query *productSvc.ProductQuery) error {
+	page, err := prodUsecase.SearchProducts(query)
+	if err != nil {
+		return err
+	}
+	view := buildProductPageView(page.(*productSvc.ProductPage))
+	if len(view.Items) == 0 {
+		fmt.Println("no product found")
+		return nil
+	}
+	printProductTable(view.Items)
+
+	r.lastQuery = query
+	r.nextCursor = view.NextCursor
+	if r.nextCursor != "" {
+		fmt.Println("type 'more' for the next page")
+	}
+	return nil
+}
+
+func (r *repl) more() error {
+	if r.lastQuery == nil || r.nextCursor == "" {
+		return e.NewErrNoData("no more products")
+	}
+	query := *r.lastQuery
+	query.Cursor = r.nextCursor
+	return r.browse(&query)
+}
+
+func (r *repl) showProduct(args []string) error {
+	if len(args) != 1 {
+		return e.NewErrInvalidData("usage: product <id>")
+	}
+	p, err := prodUsecase.ShowProduct(args[0])
+	if err != nil {
+		return err
+	}
+	product := p.(*productSvc.Product)
+	printProductTable([]*productView{buildProductView(product)})
+	for _, c := range product.Components {
+		fmt.Printf("  - %d x %s\n", c.Qty, c.ProductID)
+	}
+	return nil
+}
+
+func (r *repl) add(args []string) error {
+	if len(args) < 2 || len(args) > 3 {
+		return e.NewErrInvalidData("usage: add <product_id> <qty> [sku]")
+	}
+	qty, err := parseQty(args[1])
+	if err != nil {
+		return err
+	}
+	item := &cartSvc.CartItem{ID: args[0], Qty: qty}
+	if len(args) == 3 {
+		item.SKU = args[2]
+	}
+
+	if item.SKU == "" {
+		p, err := prodUsecase.FindByProductID(item.ID)
+		if err != nil {
+			return err
+		}
+		if product := p.(*productSvc.Product); len(product.Variants) > 0 {
+			printProductTable([]*productView{buildProductView(product)})
+			return e.NewErrInvalidData(fmt.Sprintf("please choose a variant, e.g. add %s %d %s",
+				product.ID, qty, product.Variants[0].SKU))
+		}
+	}
+
+	if err := cartUsecase.AddToCart(r.user, item); err != nil {
+		if _, ok := err.(*e.ErrOutOfStock); ok {
+			fmt.Printf("type 'notify %s' to be told once it is restocked\n", strings.TrimSpace(item.ID+" "+item.SKU))
+		}
+		return err
+	}
+	return r.showCart()
+}
+
+func (r *repl) update(args []string) error {
+	if len(args) != 2 {
+		return e.NewErrInvalidData("usage: update <sku> <qty>")
+	}
+	qty, err := parseQty(args[1])
+	if err != nil {
+		return err
+	}
+	if err := cartUsecase.UpdateItemQty(r.user, args[0], qty); err != nil {
+		return err
+	}
+	return r.showCart()
+}
+
+func (r *repl) remove(args []string) error {
+	if len(args) != 1 {
+		return e.NewErrInvalidData("usage: remove <sku>")
+	}
+	if err := cartUsecase.RemoveFromCart(r.user, args[0]); err != nil {
+		return err
+	}
+	return r.showCart()
+}
+
+func (r *repl) showCart() error {
+	c, err := r.fetchCart()
+	if err != nil {
+		return err
+	}
+	if len(c.Items) == 0 {
+		fmt.Println("your cart is empty")
+		return nil
+	}
+	printCartTable(buildCartView(c))
+	return nil
+}
+
+func (r *repl) showTotal() error {
+	c, err := r.fetchCart()
+	if err != nil {
+		return err
+	}
+	t := c.Totals()
+	fmt.Printf("%d item(s), gross %.2f, discount %.2f, total %.2f %s\n",
+		t.Units, t.Gross, t.Discount, t.Net, container.Config.Currency)
+	return nil
+}
+
+func (r *repl) refresh() error {
+	res, err := cartUsecase.RefreshCart(r.user)
+	if err != nil {
+		return err
+	}
+	view := buildCartRefreshView(res.(*cartSvc.CartRefresh))
+	if len(view.Cart.Items) > 0 {
+		printCartTable(view.Cart)
+	}
+	printCartChanges(view.Changes)
+	return nil
+}
+
+func (r *repl) checkout(args []string) error {
+	if len(args) > 1 {
+		return e.NewErrInvalidData("usage: checkout [points]")
+	}
+	points := 0
+	if len(args) == 1 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil {
+			return e.NewErrInvalidData("usage: checkout [points], points should be a number")
+		}
+		points = n
+	}
+	c, err := cartUsecase.CheckoutWithPoints(r.user, points)
+	if err != nil {
+		return err
+	}
+	cart := c.(*cartSvc.Cart)
+	totals := cart.Totals()
+	if cart.Redemption != nil {
+		fmt.Printf("%d points redeemed for %.2f %s\n", cart.Redemption.Points, totals.Redeemed, container.Config.Currency)
+	}
+	fmt.Printf("cart %s is now %s, total to pay %.2f %s\n", cart.ID, cart.Status, totals.Due, container.Config.Currency)
+	return openCart(r.user)
+}
+
+func (r *repl) showPoints() error {
+	res, err := loyaltyUsecase.FetchBalance(r.user)
+	if err != nil {
+		return err
+	}
+	printBalanceTable(buildBalanceView(res.(*loyaltySvc.Balance)))
+	return nil
+}
+
+func (r *repl) cancel() error {
+	c, err := cartUsecase.CancelCart(r.user)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("cart %s is %s\n", c.(*cartSvc.Cart).ID, c.(*cartSvc.Cart).Status)
+	return openCart(r.user)
+}
+
+func (r *repl) saveForLater(args []string) error {
+	if len(args) != 1 {
+		return e.NewErrInvalidData("usage: save <sku>")
+	}
+	if err := cartUsecase.MoveToSavedForLater(r.user, args[0]); err != nil {
+		return err
+	}
+	return r.showCart()
+}
+
+func (r *repl) wish(args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return e.NewErrInvalidData("usage: wish <product_id> [sku]")
+	}
+	item := &cartSvc.CartItem{ID: args[0], Qty: 1}
+	if len(args) == 2 {
+		item.SKU = args[1]
+	}
+	if err := cartUsecase.SaveToList(r.user, enum.Wishlist, item); err != nil {
+		return err
+	}
+	return r.showList(enum.Wishlist)
+}
+
+func (r *repl) notify(args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return e.NewErrInvalidData("usage: notify <product_id> [sku]")
+	}
+	sku := ""
+	if len(args) == 2 {
+		sku = args[1]
+	}
+	res, err := subscriptionUsecase.Subscribe(r.user, args[0], sku)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("you will be notified once %s is restocked\n", res.(*subscriptionSvc.Subscription).ProductName)
+	return nil
+}
+
+func (r *repl) unnotify(args []string) error {
+	if len(args) != 1 {
+		return e.NewErrInvalidData("usage: unnotify <sku>")
+	}
+	if err := subscriptionUsecase.Unsubscribe(r.user, args[0]); err != nil {
+		return err
+	}
+	fmt.Printf("no longer waiting for %s\n", args[0])
+	return nil
+}
+
+func (r *repl) showLists() error {
+	if err := r.showList(enum.Wishlist); err != nil {
+		return err
+	}
+	return r.showList(enum.SavedForLater)
+}
+
+func (r *repl) showList(name enum.SavedListName) error {
+	l, err := cartUsecase.FetchSavedList(r.user, name)
+	if err != nil {
+		return err
+	}
+	list := l.(*cartSvc.SavedList)
+	if len(list.Items) == 0 {
+		fmt.Printf("your %s is empty\n", list.Name)
+		return nil
+	}
+	printSavedListTable(buildSavedListView(list))
+	return nil
+}
+
+func (r *repl) moveToCart(args []string) error {
+	if len(args) != 2 {
+		return e.NewErrInvalidData("usage: move <list> <sku>")
+	}
+	res, err := cartUsecase.MoveToCart(r.user, enum.SavedListName(args[0]), args[1])
+	if err != nil {
+		return err
+	}
+	if moved := res.(*cartSvc.MovedItem); moved.PriceChanged {
+		fmt.Printf("the price of %s has changed from %.2f to %.2f since it was saved\n", moved.Item.Name,
+			moved.SavedPrice-moved.SavedDisc, moved.Item.Price-moved.Item.Disc)
+	}
+	return r.showCart()
+}
+
+func (r *repl) unsave(args []string) error {
+	if len(args) != 2 {
+		return e.NewErrInvalidData("usage: unsave <list> <sku>")
+	}
+	name := enum.SavedListName(args[0])
+	if err := cartUsecase.RemoveFromList(r.user, name, args[1]); err != nil {
+		return err
+	}
+	return r.showList(name)
+}
+
+func (r *repl) fetchCart() (*cartSvc.Cart, error) {
+	c, err := cartUsecase.FetchUserCart(r.user)
+	if err != nil {
+		return nil, err
+	}
+	return c.(*cartSvc.Cart), nil
+}
+
+func (r *repl) completer() *readline.PrefixCompleter {
+	productIDs := readline.PcItemDynamic(func(string) []string {
+		ids := make([]string, 0)
+		if res, err := prodUsecase.ExportProducts(); err == nil {
+			for _, p := range res.([]*productSvc.Product) {
+				ids = append(ids, p.ID)
+			}
+		}
+		return ids
+	})
+	cartSKUs := readline.PcItemDynamic(func(string) []string {
+		skus := make([]string, 0)
+		if c, err := r.fetchCart(); err == nil {
+			for _, v := range c.Items {
+				skus = append(skus, v.SKU)
+			}
+		}
+		return skus
+	})
+
+	listNames := []readline.PrefixCompleterInterface{
+		readline.PcItem(string(enum.Wishlist)),
+		readline.PcItem(string(enum.SavedForLater)),
+	}
+
+	return readline.NewPrefixCompleter(
+		readline.PcItem("products"),
+		readline.PcItem("more"),
+		readline.PcItem("product", productIDs),
+		readline.PcItem("add", productIDs),
+		readline.PcItem("update", cartSKUs),
+		readline.PcItem("remove", cartSKUs),
+		readline.PcItem("cart"),
+		readline.PcItem("total"),
+		readline.PcItem("refresh"),
+		readline.PcItem("checkout"),
+		readline.PcItem("points"),
+		readline.PcItem("cancel"),
+		readline.PcItem("save", cartSKUs),
+		readline.PcItem("wish", productIDs),
+		readline.PcItem("notify", productIDs),
+		readline.PcItem("unnotify"),
+		readline.PcItem("lists"),
+		readline.PcItem("move", listNames...),
+		readline.PcItem("unsave", listNames...),
+		readline.PcItem("user"),
+		readline.PcItem("history"),
+		readline.PcItem("help"),
+		readline.PcItem("exit"),
+	)
+}
+
+func historyFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".cartsvc_history")
+}
+
+func parseQty(s string) (int, error) {
+	qty, err := strconv.Atoi(s)
+	if err != nil || qty <= 0 {
+		return 0, e.NewErrInvalidData(fmt.Sprintf("invalid quantity %s, should be greater than zero", s))
+	}
+	return qty, nil
+}
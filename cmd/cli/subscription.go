@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	subscriptionSvc "github.com/yauritux/cartsvc/pkg/usecase/subscriptions"
+)
+
+func runSubscription(sub string, args []string) int {
+	cmd := newCommand("subscription " + sub)
+	user := cmd.flags.String("user", "", "id of the subscriber")
+	product := cmd.flags.String("product", "", "id of the product")
+	sku := cmd.flags.String("sku", "", "sku of the product variant")
+	if code := cmd.parse(args); code != exitOK {
+		return code
+	}
+	if code := cmd.require("user"); code != exitOK {
+		return code
+	}
+
+	switch sub {
+	case "add":
+		if code := cmd.require("product"); code != exitOK {
+			return code
+		}
+		if _, err := subscriptionUsecase.Subscribe(*user, *product, *sku); err != nil {
+			return cmd.fail(err)
+		}
+	case "remove":
+		if *sku == "" {
+			*sku = *product
+		}
+		if *sku == "" {
+			fmt.Fprintf(os.Stderr, "%s: --sku is required\n", cmd.flags.Name())
+			return exitUsage
+		}
+		if err := subscriptionUsecase.Unsubscribe(*user, *sku); err != nil {
+			return cmd.fail(err)
+		}
+	case "list":
+	default:
+		fmt.Fprintf(os.Stderr, "unknown subcommand subscription %s\n\n%s\n", sub, usage)
+		return exitUsage
+	}
+
+	res, err := subscriptionUsecase.FetchSubscriptions(*user)
+	if err != nil {
+		return cmd.fail(err)
+	}
+	views := buildSubscriptionViews(res.([]*subscriptionSvc.Subscription))
+	if *cmd.output == outputJSON {
+		printJSON(views)
+	} else if len(views) == 0 {
+		fmt.Printf("%s is not waiting for any restock\n", *user)
+	} else {
+		printSubscriptionTable(views)
+	}
+	return exitOK
+}
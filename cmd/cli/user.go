@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	userSvc "github.com/yauritux/cartsvc/pkg/usecase/users"
+)
+
+func runUser(sub string, args []string) int {
+	cmd := newCommand("user " + sub)
+	id := cmd.flags.String("id", "", "id of the user")
+	name := cmd.flags.String("name", "", "name of the user")
+	email := cmd.flags.String("email", "", "email of the user")
+	phone := cmd.flags.String("phone", "", "phone number of the user")
+	password := cmd.flags.String("password", "", "password of the user, only its hash is stored")
+	street := cmd.flags.String("street", "", "street of the shipping and billing address")
+	city := cmd.flags.String("city", "", "city of the address")
+	postal := cmd.flags.String("postal", "", "postal code of the address")
+	province := cmd.flags.String("province", "", "province of the address")
+	region := cmd.flags.String("region", "", "region of the address")
+	country := cmd.flags.String("country", "", "country of the address")
+	admin := cmd.flags.Bool("admin", false, "registers an administrator rather than a customer")
+	if code := cmd.parse(args); code != exitOK {
+		return code
+	}
+
+	switch sub {
+	case "register":
+	default:
+		fmt.Fprintf(os.Stderr, "unknown subcommand user %s\n\n%s\n", sub, usage)
+		return exitUsage
+	}
+	if code := cmd.require("id", "email", "password"); code != exitOK {
+		return code
+	}
+
+	user := &userSvc.User{ID: *id, Username: *name, Email: *email, Phone: *phone, Password: *password}
+	if *street != "" {
+		addr := userSvc.Address{Street: *street, City: *city, Postal: *postal, Province: *province, Region: *region, Country: *country}
+		shipping, billing := addr, addr
+		user.ShippingAddr, user.BillingAddr = &shipping, &billing
+	}
+	register := userUsecase.RegisterUser
+	if *admin {
+		register = userUsecase.RegisterAdmin
+	}
+	res, err := register(user)
+	if err != nil {
+		return cmd.fail(err)
+	}
+	view := buildUserView(res.(*userSvc.User))
+	if *cmd.output == outputJSON {
+		printJSON(view)
+	} else {
+		printUser(view)
+	}
+	return exitOK
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/yauritux/cartsvc/pkg/adapter/rest"
+	"github.com/yauritux/cartsvc/pkg/app"
+	"github.com/yauritux/cartsvc/pkg/config"
+	cartSvc "github.com/yauritux/cartsvc/pkg/usecase/carts"
+)
+
+// sweepInterval is how often the idle carts are looked for, to be expired or reminded
+const sweepInterval = time.Minute
+
+func main() {
+	cfg, _, err := config.Load("http", os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+	container, err := app.New(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	handler := rest.NewHandler(container.AuthUsecase, container.UserUsecase, container.CartUsecase, container.ProductUsecase,
+		container.SubscriptionUsecase, container.LoyaltyUsecase)
+	routes := handler.Routes()
+	if cfg.LogLevel.Enables(config.Debug) {
+		routes = logRequests(routes)
+	}
+
+	if cfg.CartTTL > 0 {
+		startExpirySweeper(container, cfg)
+	}
+	if cfg.RemindAfter > 0 && cfg.ReminderLimit > 0 {
+		go container.CartUsecase.RunRecoverySweeper(context.Background(), sweepInterval, func(err error) {
+			if cfg.LogLevel.Enables(config.Error) {
+				log.Printf("cart recovery: %v", err)
+			}
+		})
+	}
+
+	addr := fmt.Sprintf(":%d", cfg.HTTPPort)
+	if cfg.LogLevel.Enables(config.Info) {
+		log.Printf("cart service is listening on %s (backend %s)", addr, cfg.Backend)
+	}
+	log.Fatal(http.ListenAndServe(addr, routes))
+}
+
+func startExpirySweeper(container *app.Container, cfg *config.Config) {
+	if cfg.LogLevel.Enables(config.Info) {
+		container.Events.Subscribe(func(event interface{}) error {
+			if expired, ok := event.(*cartSvc.CartExpired); ok {
+				log.Printf("cart %s of user %s expired after being idle since %s",
+					expired.CartID, expired.UserID, expired.LastActivityAt.Format(time.RFC3339))
+			}
+			return nil
+		})
+	}
+	go container.CartUsecase.RunExpirySweeper(context.Background(), sweepInterval, func(err error) {
+		if cfg.LogLevel.Enables(config.Error) {
+			log.Printf("cart expiry: %v", err)
+		}
+	})
+}
+
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		next.ServeHTTP(w, r)
+		log.Printf("%s %s (%v)", r.Method, r.URL.Path, time.Since(start))
+	})
+}
@@ -3,7 +3,11 @@ module github.com/yauritux/cartsvc
 go 1.13
 
 require (
+	github.com/chzyer/readline v1.5.1
 	github.com/lucsky/cuid v1.0.2
 	github.com/smartystreets/goconvey v1.6.4
 	github.com/stretchr/testify v1.5.1
+	go.etcd.io/bbolt v1.3.5
+	golang.org/x/crypto v0.0.0-20200622213623-75b288015ac9
+	golang.org/x/sys v0.0.0-20220310020820-b874c991c1a5
 )
@@ -0,0 +1,193 @@
+package local
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	vo "github.com/yauritux/cartsvc/pkg/domain/valueobject"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+)
+
+const defaultCountry = "Indonesia"
+
+var countryAliases = []string{"Indonesia", "ID", "IDN", "Republic of Indonesia"}
+
+// AddressValidator is a local implementation of the service.AddressValidator
+// backed by the Indonesian provinces and regions reference dataset
+type AddressValidator struct {
+	provinces []*province
+	regions   []*region
+}
+
+func NewAddressValidator() *AddressValidator {
+	return &AddressValidator{
+		provinces: provinces,
+		regions:   regions,
+	}
+}
+
+func (v *AddressValidator) Validate(addr vo.BuyerAddress) (vo.BuyerAddress, error) {
+	normalized := vo.BuyerAddress{
+		StreetName: collapseSpaces(addr.StreetName),
+		City:       collapseSpaces(addr.City),
+		Postal:     strings.ReplaceAll(collapseSpaces(addr.Postal), " ", ""),
+		Province:   collapseSpaces(addr.Province),
+		Region:     collapseSpaces(addr.Region),
+		Country:    collapseSpaces(addr.Country),
+		Type:       addr.Type,
+	}
+
+	if normalized.StreetName == "" {
+		return addr, e.NewErrInvalidData("invalid address, 'street_name' is missing")
+	}
+	if normalized.Postal == "" {
+		return addr, e.NewErrInvalidData("invalid address, 'postal' is missing")
+	}
+
+	if normalized.Country == "" || matchName(normalized.Country, defaultCountry, countryAliases) {
+		normalized.Country = defaultCountry
+	} else {
+		//we've got no reference data for other countries, hence only normalize the casing
+		normalized.Country = titleCase(normalized.Country)
+		normalized.City = titleCase(normalized.City)
+		normalized.Province = titleCase(normalized.Province)
+		normalized.Region = titleCase(normalized.Region)
+		return normalized, nil
+	}
+
+	postal, err := strconv.Atoi(normalized.Postal)
+	if err != nil || len(normalized.Postal) != 5 {
+		return addr, e.NewErrInvalidData(fmt.Sprintf("invalid postal code %s, should be 5 digits", addr.Postal))
+	}
+
+	prov := v.findProvinceByPostal(postal)
+	if prov == nil {
+		return addr, e.NewErrInvalidData(fmt.Sprintf("unknown postal code %s", normalized.Postal))
+	}
+	if normalized.Province != "" {
+		givenProv := v.findProvinceByName(normalized.Province)
+		if givenProv == nil {
+			return addr, e.NewErrInvalidData(fmt.Sprintf("unknown province %s", normalized.Province))
+		}
+		if givenProv != prov {
+			return addr, e.NewErrInvalidData(fmt.Sprintf(
+				"postal code %s does not belong to province %s", normalized.Postal, givenProv.name,
+			))
+		}
+	}
+	normalized.Province = prov.name
+
+	reg := v.findRegionByPostal(postal)
+	if normalized.Region != "" {
+		givenReg := v.findRegionByName(normalized.Region)
+		switch {
+		case givenReg == nil:
+			normalized.Region = titleCase(normalized.Region)
+		case givenReg.province != prov.name:
+			return addr, e.NewErrInvalidData(fmt.Sprintf(
+				"region %s does not belong to province %s", givenReg.name, prov.name,
+			))
+		case reg != nil && givenReg != reg:
+			return addr, e.NewErrInvalidData(fmt.Sprintf(
+				"postal code %s does not belong to region %s", normalized.Postal, givenReg.name,
+			))
+		default:
+			reg = givenReg
+		}
+	}
+	if reg == nil {
+		normalized.City = titleCase(normalized.City)
+		return normalized, nil
+	}
+	normalized.Region = reg.name
+
+	if normalized.City != "" && !strings.EqualFold(normalized.City, reg.city) &&
+		!matchName(normalized.City, reg.name, reg.aliases) {
+		return addr, e.NewErrInvalidData(fmt.Sprintf(
+			"city %s does not match region %s", normalized.City, reg.name,
+		))
+	}
+	normalized.City = reg.city
+
+	return normalized, nil
+}
+
+func (v *AddressValidator) findProvinceByPostal(postal int) *province {
+	var found *province
+	narrowest := -1
+	for _, p := range v.provinces {
+		for _, r := range p.postal {
+			if postal < r.from || postal > r.to {
+				continue
+			}
+			if narrowest == -1 || r.to-r.from < narrowest {
+				found = p
+				narrowest = r.to - r.from
+			}
+		}
+	}
+	return found
+}
+
+func (v *AddressValidator) findProvinceByName(name string) *province {
+	for _, p := range v.provinces {
+		if matchName(name, p.name, p.aliases) {
+			return p
+		}
+	}
+	return nil
+}
+
+func (v *AddressValidator) findRegionByPostal(postal int) *region {
+	var found *region
+	narrowest := -1
+	for _, reg := range v.regions {
+		for _, r := range reg.postal {
+			if postal < r.from || postal > r.to {
+				continue
+			}
+			if narrowest == -1 || r.to-r.from < narrowest {
+				found = reg
+				narrowest = r.to - r.from
+			}
+		}
+	}
+	return found
+}
+
+func (v *AddressValidator) findRegionByName(name string) *region {
+	for _, reg := range v.regions {
+		if matchName(name, reg.name, reg.aliases) {
+			return reg
+		}
+	}
+	return nil
+}
+
+func matchName(given string, name string, aliases []string) bool {
+	if strings.EqualFold(given, name) {
+		return true
+	}
+	for _, alias := range aliases {
+		if strings.EqualFold(given, alias) {
+			return true
+		}
+	}
+	return false
+}
+
+func collapseSpaces(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func titleCase(s string) string {
+	words := strings.Fields(strings.ToLower(s))
+	for i, w := range words {
+		r := []rune(w)
+		r[0] = unicode.ToUpper(r[0])
+		words[i] = string(r)
+	}
+	return strings.Join(words, " ")
+}
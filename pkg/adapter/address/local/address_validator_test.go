@@ -0,0 +1,64 @@
+package local
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	vo "github.com/yauritux/cartsvc/pkg/domain/valueobject"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+)
+
+func TestAddressValidator(t *testing.T) {
+
+	Convey("1. Given an Indonesian buyer address", t, func() {
+
+		v := NewAddressValidator()
+
+		Convey("-> Negative Scenarios", func() {
+			Convey("-> Should reject a malformed postal code", func() {
+				_, err := v.Validate(vo.BuyerAddress{StreetName: "Kalibata Raya", Postal: "127"})
+				So(err, ShouldHaveSameTypeAs, &e.ErrInvalidData{})
+				So(err.Error(), ShouldEqual, "invalid postal code 127, should be 5 digits")
+			})
+			Convey("-> Should reject a postal code which does not belong to the province", func() {
+				_, err := v.Validate(vo.BuyerAddress{StreetName: "Jl. Legian", Postal: "12750", Province: "bali"})
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "postal code 12750 does not belong to province Bali")
+			})
+			Convey("-> Should reject a region which does not match the postal code", func() {
+				_, err := v.Validate(vo.BuyerAddress{StreetName: "Kalibata Raya", Postal: "12750", Region: "North Jakarta"})
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "postal code 12750 does not belong to region Jakarta Utara")
+			})
+			Convey("-> Should reject a city which does not match the region", func() {
+				_, err := v.Validate(vo.BuyerAddress{StreetName: "Jl. Dago", Postal: "40135", City: "Surabaya"})
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "city Surabaya does not match region Kota Bandung")
+			})
+		})
+
+		Convey("-> Positive Scenarios", func() {
+			Convey("-> Should fill the missing province and region from the postal code", func() {
+				addr, err := v.Validate(vo.BuyerAddress{StreetName: " Kalibata  Raya No.1 ", City: "jakarta", Postal: "12750"})
+				So(err, ShouldBeNil)
+				So(addr.StreetName, ShouldEqual, "Kalibata Raya No.1")
+				So(addr.City, ShouldEqual, "Jakarta")
+				So(addr.Province, ShouldEqual, "DKI Jakarta")
+				So(addr.Region, ShouldEqual, "Jakarta Selatan")
+				So(addr.Country, ShouldEqual, "Indonesia")
+			})
+			Convey("-> Should resolve the narrowest postal range", func() {
+				addr, err := v.Validate(vo.BuyerAddress{StreetName: "Jl. Malioboro", Postal: "55213", Province: "diy"})
+				So(err, ShouldBeNil)
+				So(addr.Province, ShouldEqual, "DI Yogyakarta")
+				So(addr.City, ShouldEqual, "Yogyakarta")
+			})
+			Convey("-> Should only normalize the casing of an address outside the dataset", func() {
+				addr, err := v.Validate(vo.BuyerAddress{StreetName: "Orchard Road", Postal: "238801", City: "SINGAPORE", Country: "singapore"})
+				So(err, ShouldBeNil)
+				So(addr.City, ShouldEqual, "Singapore")
+				So(addr.Country, ShouldEqual, "Singapore")
+			})
+		})
+	})
+}
@@ -0,0 +1,82 @@
+package local
+
+type postalRange struct {
+	from int
+	to   int
+}
+
+type province struct {
+	name    string
+	aliases []string
+	postal  []postalRange
+}
+
+type region struct {
+	name     string
+	aliases  []string
+	city     string
+	province string
+	postal   []postalRange
+}
+
+// provinces holds the Indonesian provinces along with their postal code ranges.
+// Some ranges overlap (e.g. DI Yogyakarta lies within Jawa Tengah), the narrowest
+// range wins when resolving a postal code.
+var provinces = []*province{
+	{name: "Aceh", aliases: []string{"Nanggroe Aceh Darussalam", "NAD"}, postal: []postalRange{{23111, 24794}}},
+	{name: "Sumatera Utara", aliases: []string{"North Sumatra", "Sumut"}, postal: []postalRange{{20111, 22999}}},
+	{name: "Sumatera Barat", aliases: []string{"West Sumatra", "Sumbar"}, postal: []postalRange{{25111, 27779}}},
+	{name: "Riau", postal: []postalRange{{28111, 29569}}},
+	{name: "Kepulauan Riau", aliases: []string{"Riau Islands", "Kepri"}, postal: []postalRange{{29111, 29199}, {29411, 29878}}},
+	{name: "Jambi", postal: []postalRange{{36111, 37574}}},
+	{name: "Sumatera Selatan", aliases: []string{"South Sumatra", "Sumsel"}, postal: []postalRange{{30111, 32388}}},
+	{name: "Bangka Belitung", aliases: []string{"Kepulauan Bangka Belitung", "Babel"}, postal: []postalRange{{33111, 33792}}},
+	{name: "Bengkulu", postal: []postalRange{{38113, 39377}}},
+	{name: "Lampung", postal: []postalRange{{34111, 35686}}},
+	{name: "DKI Jakarta", aliases: []string{"Jakarta", "DKI"}, postal: []postalRange{{10110, 14540}}},
+	{name: "Jawa Barat", aliases: []string{"West Java", "Jabar"}, postal: []postalRange{{16110, 17730}, {40111, 46476}}},
+	{name: "Banten", postal: []postalRange{{15110, 15820}, {42111, 42455}}},
+	{name: "Jawa Tengah", aliases: []string{"Central Java", "Jateng"}, postal: []postalRange{{50111, 59584}}},
+	{name: "DI Yogyakarta", aliases: []string{"Yogyakarta", "DIY", "Special Region of Yogyakarta"}, postal: []postalRange{{55111, 55893}}},
+	{name: "Jawa Timur", aliases: []string{"East Java", "Jatim"}, postal: []postalRange{{60111, 69493}}},
+	{name: "Bali", postal: []postalRange{{80111, 82262}}},
+	{name: "Nusa Tenggara Barat", aliases: []string{"West Nusa Tenggara", "NTB"}, postal: []postalRange{{83115, 84459}}},
+	{name: "Nusa Tenggara Timur", aliases: []string{"East Nusa Tenggara", "NTT"}, postal: []postalRange{{85111, 87284}}},
+	{name: "Kalimantan Barat", aliases: []string{"West Kalimantan", "Kalbar"}, postal: []postalRange{{78111, 79682}}},
+	{name: "Kalimantan Tengah", aliases: []string{"Central Kalimantan", "Kalteng"}, postal: []postalRange{{73111, 74874}}},
+	{name: "Kalimantan Selatan", aliases: []string{"South Kalimantan", "Kalsel"}, postal: []postalRange{{70111, 72276}}},
+	{name: "Kalimantan Timur", aliases: []string{"East Kalimantan", "Kaltim"}, postal: []postalRange{{75111, 77381}}},
+	{name: "Sulawesi Utara", aliases: []string{"North Sulawesi", "Sulut"}, postal: []postalRange{{95111, 95999}}},
+	{name: "Gorontalo", postal: []postalRange{{96111, 96574}}},
+	{name: "Sulawesi Tengah", aliases: []string{"Central Sulawesi", "Sulteng"}, postal: []postalRange{{94111, 94981}}},
+	{name: "Sulawesi Selatan", aliases: []string{"South Sulawesi", "Sulsel"}, postal: []postalRange{{90111, 92985}}},
+	{name: "Sulawesi Tenggara", aliases: []string{"Southeast Sulawesi", "Sultra"}, postal: []postalRange{{93111, 93963}}},
+	{name: "Maluku", postal: []postalRange{{97114, 97669}}},
+	{name: "Maluku Utara", aliases: []string{"North Maluku", "Malut"}, postal: []postalRange{{97711, 97869}}},
+	{name: "Papua Barat", aliases: []string{"West Papua"}, postal: []postalRange{{98011, 98495}}},
+	{name: "Papua", postal: []postalRange{{98511, 99976}}},
+}
+
+// regions holds the major cities/regencies, it is not meant to be exhaustive.
+// Addresses within a region which is not listed here are only being normalized.
+var regions = []*region{
+	{name: "Jakarta Pusat", aliases: []string{"Central Jakarta"}, city: "Jakarta", province: "DKI Jakarta", postal: []postalRange{{10110, 10760}}},
+	{name: "Jakarta Barat", aliases: []string{"West Jakarta"}, city: "Jakarta", province: "DKI Jakarta", postal: []postalRange{{11110, 11850}}},
+	{name: "Jakarta Selatan", aliases: []string{"South Jakarta"}, city: "Jakarta", province: "DKI Jakarta", postal: []postalRange{{12110, 12980}}},
+	{name: "Jakarta Timur", aliases: []string{"East Jakarta"}, city: "Jakarta", province: "DKI Jakarta", postal: []postalRange{{13110, 13960}}},
+	{name: "Jakarta Utara", aliases: []string{"North Jakarta"}, city: "Jakarta", province: "DKI Jakarta", postal: []postalRange{{14110, 14470}}},
+	{name: "Kota Tangerang", aliases: []string{"Tangerang"}, city: "Tangerang", province: "Banten", postal: []postalRange{{15111, 15158}}},
+	{name: "Kota Tangerang Selatan", aliases: []string{"Tangerang Selatan", "South Tangerang"}, city: "Tangerang Selatan", province: "Banten", postal: []postalRange{{15310, 15419}}},
+	{name: "Kota Bogor", aliases: []string{"Bogor"}, city: "Bogor", province: "Jawa Barat", postal: []postalRange{{16111, 16169}}},
+	{name: "Kota Depok", aliases: []string{"Depok"}, city: "Depok", province: "Jawa Barat", postal: []postalRange{{16411, 16518}}},
+	{name: "Kota Bekasi", aliases: []string{"Bekasi"}, city: "Bekasi", province: "Jawa Barat", postal: []postalRange{{17111, 17149}}},
+	{name: "Kota Bandung", aliases: []string{"Bandung"}, city: "Bandung", province: "Jawa Barat", postal: []postalRange{{40111, 40973}}},
+	{name: "Kota Semarang", aliases: []string{"Semarang"}, city: "Semarang", province: "Jawa Tengah", postal: []postalRange{{50111, 50275}}},
+	{name: "Kota Yogyakarta", aliases: []string{"Yogyakarta", "Jogja", "Jogjakarta"}, city: "Yogyakarta", province: "DI Yogyakarta", postal: []postalRange{{55111, 55283}}},
+	{name: "Kota Surabaya", aliases: []string{"Surabaya"}, city: "Surabaya", province: "Jawa Timur", postal: []postalRange{{60111, 60299}}},
+	{name: "Kota Malang", aliases: []string{"Malang"}, city: "Malang", province: "Jawa Timur", postal: []postalRange{{65111, 65149}}},
+	{name: "Kota Denpasar", aliases: []string{"Denpasar"}, city: "Denpasar", province: "Bali", postal: []postalRange{{80111, 80239}}},
+	{name: "Kota Medan", aliases: []string{"Medan"}, city: "Medan", province: "Sumatera Utara", postal: []postalRange{{20111, 20373}}},
+	{name: "Kota Palembang", aliases: []string{"Palembang"}, city: "Palembang", province: "Sumatera Selatan", postal: []postalRange{{30111, 30169}}},
+	{name: "Kota Makassar", aliases: []string{"Makassar"}, city: "Makassar", province: "Sulawesi Selatan", postal: []postalRange{{90111, 90245}}},
+}
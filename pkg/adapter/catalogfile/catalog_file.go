@@ -0,0 +1,56 @@
+package catalogfile
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+	uc "github.com/yauritux/cartsvc/pkg/usecase/products"
+)
+
+// Format is the encoding of a product catalog file
+type Format string
+
+const (
+	CSV  Format = "csv"
+	JSON Format = "json"
+)
+
+// ParseFormat validates the given format, or guesses it from the file extension when the format is empty
+func ParseFormat(format string, path string) (Format, error) {
+	if format == "" {
+		format = strings.TrimPrefix(filepath.Ext(path), ".")
+	}
+	switch f := Format(strings.ToLower(format)); f {
+	case CSV, JSON:
+		return f, nil
+	default:
+		return "", e.NewErrInvalidData(fmt.Sprintf("unsupported catalog file format '%s', should be csv or json", format))
+	}
+}
+
+// Decode reads the products of the catalog file, a malformed row is returned along with its error
+// so that the import can report it, only an unreadable file fails the whole decoding
+func Decode(r io.Reader, format Format) ([]*uc.ImportRow, error) {
+	switch format {
+	case CSV:
+		return decodeCSV(r)
+	case JSON:
+		return decodeJSON(r)
+	default:
+		return nil, e.NewErrInvalidData(fmt.Sprintf("unsupported catalog file format '%s'", format))
+	}
+}
+
+func Encode(w io.Writer, format Format, products []*uc.Product) error {
+	switch format {
+	case CSV:
+		return encodeCSV(w, products)
+	case JSON:
+		return encodeJSON(w, products)
+	default:
+		return e.NewErrInvalidData(fmt.Sprintf("unsupported catalog file format '%s'", format))
+	}
+}
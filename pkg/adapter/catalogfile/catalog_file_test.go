@@ -0,0 +1,116 @@
+package catalogfile
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+	uc "github.com/yauritux/cartsvc/pkg/usecase/products"
+)
+
+func catalog() []*uc.Product {
+	return []*uc.Product{
+		{
+			ID: "001", Name: "Shuriken", Stock: 1500, Price: 250.5, CategoryIDs: []string{"throwing-weapons"},
+			PurchaseLimit:  &uc.PurchaseLimit{MaxPerOrder: 5, MaxPerCustomer: 10, Period: 7 * 24 * time.Hour},
+			WarehouseStock: map[string]int{"main": 1000, "medan": 500},
+		},
+		{
+			ID: "003", Name: "Ninja Gi", Price: 320, CategoryIDs: []string{"apparel"},
+			Variants: []*uc.Variant{
+				{SKU: "003-BLK-M", Options: map[string]string{"color": "black", "size": "M"}, Stock: 40, Price: 320,
+					WarehouseStock: map[string]int{"main": 30, "surabaya": 10}},
+			},
+		},
+		{
+			ID: "004", Name: "Ninja Training Set", Type: enum.BundleProduct,
+			Components: []*uc.BundleComponent{
+				{ProductID: "001", Qty: 2}, {ProductID: "003", SKU: "003-BLK-M", Qty: 1},
+			},
+			BundlePricing: &uc.BundlePricing{Mode: enum.PercentOffBundle, Value: 10},
+		},
+	}
+}
+
+func TestCatalogFile(t *testing.T) {
+
+	Convey("1. Given a catalog file format", t, func() {
+		Convey("-> Should be guessed from the file extension", func() {
+			f, err := ParseFormat("", "/tmp/products.CSV")
+			So(err, ShouldBeNil)
+			So(f, ShouldEqual, CSV)
+		})
+		Convey("-> Should reject an unsupported format", func() {
+			_, err := ParseFormat("", "products.xml")
+			So(err, ShouldHaveSameTypeAs, &e.ErrInvalidData{})
+		})
+	})
+
+	Convey("2. Given a catalog is exported then imported back", t, func() {
+		for _, format := range []Format{CSV, JSON} {
+			Convey("-> The "+string(format)+" file should yield the same products", func() {
+				var buf bytes.Buffer
+				So(Encode(&buf, format, catalog()), ShouldBeNil)
+
+				rows, err := Decode(&buf, format)
+				So(err, ShouldBeNil)
+				So(rows, ShouldHaveLength, 3)
+				for _, r := range rows {
+					So(r.Err, ShouldBeNil)
+				}
+				So(rows[0].Product.PurchaseLimit, ShouldResemble, catalog()[0].PurchaseLimit)
+				So(rows[1].Product.PurchaseLimit, ShouldBeNil)
+				So(rows[1].Product.Variants[0].Options["size"], ShouldEqual, "M")
+				So(rows[0].Product.WarehouseStock, ShouldResemble, catalog()[0].WarehouseStock)
+				So(rows[1].Product.WarehouseStock, ShouldBeEmpty)
+				So(rows[1].Product.Variants[0].WarehouseStock, ShouldResemble, catalog()[1].Variants[0].WarehouseStock)
+				So(rows[2].Product.Type, ShouldEqual, enum.BundleProduct)
+				So(rows[2].Product.Components[1].SKU, ShouldEqual, "003-BLK-M")
+				So(rows[2].Product.BundlePricing.Value, ShouldEqual, 10)
+			})
+		}
+	})
+
+	Convey("3. Given a catalog file with malformed rows", t, func() {
+		Convey("-> A csv row error should be reported along with its line", func() {
+			rows, err := Decode(strings.NewReader(
+				"name,id,price\nShuriken,001,250.5\nSai,002,cheap\nKunai,005\n",
+			), CSV)
+			So(err, ShouldBeNil)
+			So(rows, ShouldHaveLength, 3)
+			So(rows[0].Err, ShouldBeNil)
+			So(rows[0].Product.ID, ShouldEqual, "001")
+			So(rows[1].Line, ShouldEqual, 3)
+			So(rows[1].Err.Error(), ShouldEqual, "invalid price 'cheap', should be a number")
+			So(rows[2].Err, ShouldNotBeNil)
+		})
+		Convey("-> A malformed csv purchase limit should be reported", func() {
+			rows, err := Decode(strings.NewReader("id,name,price,purchase_limit\n001,Shuriken,250.5,5:ten\n"), CSV)
+			So(err, ShouldBeNil)
+			So(rows[0].Err, ShouldHaveSameTypeAs, &e.ErrInvalidData{})
+		})
+		Convey("-> A csv warehouse stock of an unknown variant should be reported", func() {
+			rows, err := Decode(strings.NewReader("id,name,price,warehouse_stock\n001,Shuriken,250.5,001-RED@main:5\n"), CSV)
+			So(err, ShouldBeNil)
+			So(rows[0].Err.Error(), ShouldEqual, "invalid warehouse stock '001-RED@main:5', no variant 001-RED found")
+		})
+		Convey("-> An unknown csv column should fail the whole file", func() {
+			_, err := Decode(strings.NewReader("id,name,colour\n"), CSV)
+			So(err.Error(), ShouldEqual, "invalid csv catalog, unknown column 'colour'")
+		})
+		Convey("-> A json product of the wrong shape should be reported on its own", func() {
+			rows, err := Decode(strings.NewReader(
+				`[{"id":"001","name":"Shuriken"},{"id":"002","name":"Sai","stock":"many"}]`,
+			), JSON)
+			So(err, ShouldBeNil)
+			So(rows, ShouldHaveLength, 2)
+			So(rows[0].Err, ShouldBeNil)
+			So(rows[1].Line, ShouldEqual, 2)
+			So(rows[1].Err, ShouldHaveSameTypeAs, &e.ErrInvalidData{})
+		})
+	})
+}
@@ -0,0 +1,379 @@
+package catalogfile
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+	uc "github.com/yauritux/cartsvc/pkg/usecase/products"
+)
+
+// csvColumns is the header of an exported csv catalog, an imported file may omit any column but id and name
+// and may order them freely. The nested values are packed as follows:
+//
+//	categories      throwing-weapons|melee-weapons
+//	variants        003-BLK-M:40:320:0:color=black;size=M|003-NVY-M:10:335:0:color=navy;size=M
+//	components      001:1|003:003-BLK-M:1  (product_id[:sku]:qty)
+//	bundle_pricing  percent_off:10
+//	purchase_limit  5:10:168h  (max_per_order:max_per_customer[:period], 0 for no maximum)
+//	warehouse_stock main:30|medan:20|003-BLK-M@main:40  ([sku@]warehouse_id:stock)
+var csvColumns = []string{
+	"id", "name", "type", "stock", "price", "disc", "categories", "variants", "components", "bundle_pricing", "purchase_limit",
+	"warehouse_stock",
+}
+
+const listSeparator = "|"
+
+func decodeCSV(r io.Reader) ([]*uc.ImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, e.NewErrInvalidData("invalid csv catalog, the header is missing")
+	}
+	columns, err := parseHeader(header)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]*uc.ImportRow, 0)
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		row := &uc.ImportRow{Line: line}
+		switch err.(type) {
+		case nil:
+			row.Product, row.Err = parseRecord(columns, record)
+		case *csv.ParseError:
+			row.Err = e.NewErrInvalidData(err.Error())
+		default:
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+}
+
+func parseHeader(header []string) (map[string]int, error) {
+	columns := make(map[string]int)
+	for i, h := range header {
+		name := strings.ToLower(strings.TrimSpace(h))
+		known := false
+		for _, c := range csvColumns {
+			if c == name {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, e.NewErrInvalidData(fmt.Sprintf("invalid csv catalog, unknown column '%s'", h))
+		}
+		columns[name] = i
+	}
+	if _, ok := columns["id"]; !ok {
+		return nil, e.NewErrInvalidData("invalid csv catalog, column 'id' is missing")
+	}
+	if _, ok := columns["name"]; !ok {
+		return nil, e.NewErrInvalidData("invalid csv catalog, column 'name' is missing")
+	}
+	return columns, nil
+}
+
+func parseRecord(columns map[string]int, record []string) (*uc.Product, error) {
+	if len(record) != len(columns) {
+		return nil, e.NewErrInvalidData(fmt.Sprintf("expecting %d fields, got %d", len(columns), len(record)))
+	}
+	field := func(name string) string {
+		if i, ok := columns[name]; ok {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	p := &uc.Product{
+		ID:   field("id"),
+		Name: field("name"),
+		Type: enum.ProductType(field("type")),
+	}
+	var err error
+	if p.Stock, err = parseInt("stock", field("stock")); err != nil {
+		return p, err
+	}
+	if p.Price, err = parseFloat("price", field("price")); err != nil {
+		return p, err
+	}
+	if p.Disc, err = parseFloat("disc", field("disc")); err != nil {
+		return p, err
+	}
+	p.CategoryIDs = splitList(field("categories"))
+	if p.Variants, err = parseVariants(field("variants")); err != nil {
+		return p, err
+	}
+	if p.Components, err = parseComponents(field("components")); err != nil {
+		return p, err
+	}
+	if p.BundlePricing, err = parseBundlePricing(field("bundle_pricing")); err != nil {
+		return p, err
+	}
+	if p.PurchaseLimit, err = parsePurchaseLimit(field("purchase_limit")); err != nil {
+		return p, err
+	}
+	if err = parseWarehouseStock(p, field("warehouse_stock")); err != nil {
+		return p, err
+	}
+	return p, nil
+}
+
+func parseVariants(s string) ([]*uc.Variant, error) {
+	variants := make([]*uc.Variant, 0)
+	for _, item := range splitList(s) {
+		parts := strings.SplitN(item, ":", 5)
+		if len(parts) < 4 {
+			return nil, e.NewErrInvalidData(fmt.Sprintf("invalid variant '%s', expecting sku:stock:price:disc[:options]", item))
+		}
+		v := &uc.Variant{SKU: parts[0]}
+		var err error
+		if v.Stock, err = parseInt("variant stock", parts[1]); err != nil {
+			return nil, err
+		}
+		if v.Price, err = parseFloat("variant price", parts[2]); err != nil {
+			return nil, err
+		}
+		if v.Disc, err = parseFloat("variant disc", parts[3]); err != nil {
+			return nil, err
+		}
+		if len(parts) == 5 && parts[4] != "" {
+			v.Options = make(map[string]string)
+			for _, opt := range strings.Split(parts[4], ";") {
+				kv := strings.SplitN(opt, "=", 2)
+				if len(kv) != 2 || kv[0] == "" {
+					return nil, e.NewErrInvalidData(fmt.Sprintf("invalid variant option '%s', expecting key=value", opt))
+				}
+				v.Options[kv[0]] = kv[1]
+			}
+		}
+		variants = append(variants, v)
+	}
+	return variants, nil
+}
+
+func parseComponents(s string) ([]*uc.BundleComponent, error) {
+	components := make([]*uc.BundleComponent, 0)
+	for _, item := range splitList(s) {
+		parts := strings.Split(item, ":")
+		c := &uc.BundleComponent{ProductID: parts[0]}
+		switch len(parts) {
+		case 2:
+		case 3:
+			c.SKU = parts[1]
+		default:
+			return nil, e.NewErrInvalidData(fmt.Sprintf("invalid component '%s', expecting product_id[:sku]:qty", item))
+		}
+		var err error
+		if c.Qty, err = parseInt("component qty", parts[len(parts)-1]); err != nil {
+			return nil, err
+		}
+		components = append(components, c)
+	}
+	return components, nil
+}
+
+func parseBundlePricing(s string) (*uc.BundlePricing, error) {
+	if s == "" {
+		return nil, nil
+	}
+	parts := strings.Split(s, ":")
+	if len(parts) != 2 {
+		return nil, e.NewErrInvalidData(fmt.Sprintf("invalid bundle pricing '%s', expecting mode:value", s))
+	}
+	value, err := parseFloat("bundle pricing value", parts[1])
+	if err != nil {
+		return nil, err
+	}
+	return &uc.BundlePricing{Mode: enum.BundlePricingMode(parts[0]), Value: value}, nil
+}
+
+func parsePurchaseLimit(s string) (*uc.PurchaseLimit, error) {
+	if s == "" {
+		return nil, nil
+	}
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return nil, e.NewErrInvalidData(fmt.Sprintf("invalid purchase limit '%s', expecting max_per_order:max_per_customer[:period]", s))
+	}
+	limit := &uc.PurchaseLimit{}
+	var err error
+	if limit.MaxPerOrder, err = parseInt("max per order", parts[0]); err != nil {
+		return nil, err
+	}
+	if limit.MaxPerCustomer, err = parseInt("max per customer", parts[1]); err != nil {
+		return nil, err
+	}
+	if len(parts) == 3 && parts[2] != "" {
+		if limit.Period, err = time.ParseDuration(parts[2]); err != nil {
+			return nil, e.NewErrInvalidData(fmt.Sprintf("invalid purchase limit period '%s', should be a duration", parts[2]))
+		}
+	}
+	return limit, nil
+}
+
+func parseWarehouseStock(p *uc.Product, s string) error {
+	for _, item := range splitList(s) {
+		levels := &p.WarehouseStock
+		warehouse := item
+		if i := strings.Index(item, "@"); i >= 0 {
+			v := p.FindVariant(item[:i])
+			if v == nil {
+				return e.NewErrInvalidData(fmt.Sprintf("invalid warehouse stock '%s', no variant %s found", item, item[:i]))
+			}
+			levels, warehouse = &v.WarehouseStock, item[i+1:]
+		}
+		parts := strings.Split(warehouse, ":")
+		if len(parts) != 2 {
+			return e.NewErrInvalidData(fmt.Sprintf("invalid warehouse stock '%s', expecting [sku@]warehouse_id:stock", item))
+		}
+		stock, err := parseInt("warehouse stock", parts[1])
+		if err != nil {
+			return err
+		}
+		if *levels == nil {
+			*levels = make(map[string]int)
+		}
+		(*levels)[parts[0]] = stock
+	}
+	return nil
+}
+
+func encodeCSV(w io.Writer, products []*uc.Product) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvColumns); err != nil {
+		return err
+	}
+	for _, p := range products {
+		if err := writer.Write(buildRecord(p)); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func buildRecord(p *uc.Product) []string {
+	variants := make([]string, 0)
+	for _, v := range p.Variants {
+		variant := fmt.Sprintf("%s:%d:%s:%s", v.SKU, v.Stock, formatFloat(v.Price), formatFloat(v.Disc))
+		if len(v.Options) > 0 {
+			variant += ":" + formatOptions(v.Options)
+		}
+		variants = append(variants, variant)
+	}
+	components := make([]string, 0)
+	for _, c := range p.Components {
+		if c.SKU != "" {
+			components = append(components, fmt.Sprintf("%s:%s:%d", c.ProductID, c.SKU, c.Qty))
+		} else {
+			components = append(components, fmt.Sprintf("%s:%d", c.ProductID, c.Qty))
+		}
+	}
+	pricing := ""
+	if p.BundlePricing != nil {
+		pricing = fmt.Sprintf("%s:%s", p.BundlePricing.Mode, formatFloat(p.BundlePricing.Value))
+	}
+	limit := ""
+	if l := p.PurchaseLimit; l != nil {
+		limit = fmt.Sprintf("%d:%d", l.MaxPerOrder, l.MaxPerCustomer)
+		if l.Period > 0 {
+			limit += ":" + l.Period.String()
+		}
+	}
+	warehouseStock := formatStockLevels("", p.WarehouseStock)
+	for _, v := range p.Variants {
+		warehouseStock = append(warehouseStock, formatStockLevels(v.SKU+"@", v.WarehouseStock)...)
+	}
+
+	return []string{
+		p.ID,
+		p.Name,
+		string(p.Type),
+		strconv.Itoa(p.Stock),
+		formatFloat(p.Price),
+		formatFloat(p.Disc),
+		strings.Join(p.CategoryIDs, listSeparator),
+		strings.Join(variants, listSeparator),
+		strings.Join(components, listSeparator),
+		pricing,
+		limit,
+		strings.Join(warehouseStock, listSeparator),
+	}
+}
+
+func formatStockLevels(prefix string, levels map[string]int) []string {
+	ids := make([]string, 0, len(levels))
+	for id := range levels {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	items := make([]string, 0, len(ids))
+	for _, id := range ids {
+		items = append(items, fmt.Sprintf("%s%s:%d", prefix, id, levels[id]))
+	}
+	return items
+}
+
+func splitList(s string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(s, listSeparator) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func formatOptions(options map[string]string) string {
+	keys := make([]string, 0, len(options))
+	for k := range options {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k+"="+options[k])
+	}
+	return strings.Join(pairs, ";")
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func parseInt(name string, s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, e.NewErrInvalidData(fmt.Sprintf("invalid %s '%s', should be a number", name, s))
+	}
+	return i, nil
+}
+
+func parseFloat(name string, s string) (float64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, e.NewErrInvalidData(fmt.Sprintf("invalid %s '%s', should be a number", name, s))
+	}
+	return f, nil
+}
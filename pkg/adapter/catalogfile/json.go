@@ -0,0 +1,157 @@
+package catalogfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+	uc "github.com/yauritux/cartsvc/pkg/usecase/products"
+)
+
+type productRecord struct {
+	ID            string             `json:"id"`
+	Name          string             `json:"name"`
+	Type          string             `json:"type,omitempty"`
+	Stock         int                `json:"stock"`
+	Warehouses    map[string]int     `json:"warehouse_stock,omitempty"`
+	Price         float64            `json:"price"`
+	Disc          float64            `json:"disc"`
+	Categories    []string           `json:"categories,omitempty"`
+	Variants      []*variantRecord   `json:"variants,omitempty"`
+	Components    []*componentRecord `json:"components,omitempty"`
+	BundlePricing *pricingRecord     `json:"bundle_pricing,omitempty"`
+	PurchaseLimit *limitRecord       `json:"purchase_limit,omitempty"`
+}
+
+type variantRecord struct {
+	SKU        string            `json:"sku"`
+	Options    map[string]string `json:"options,omitempty"`
+	Stock      int               `json:"stock"`
+	Warehouses map[string]int    `json:"warehouse_stock,omitempty"`
+	Price      float64           `json:"price"`
+	Disc       float64           `json:"disc"`
+}
+
+type componentRecord struct {
+	ProductID string `json:"product_id"`
+	SKU       string `json:"sku,omitempty"`
+	Qty       int    `json:"qty"`
+}
+
+type pricingRecord struct {
+	Mode  string  `json:"mode"`
+	Value float64 `json:"value"`
+}
+
+// limitRecord holds the period as a duration string, e.g. 168h
+type limitRecord struct {
+	MaxPerOrder    int    `json:"max_per_order,omitempty"`
+	MaxPerCustomer int    `json:"max_per_customer,omitempty"`
+	Period         string `json:"period,omitempty"`
+}
+
+// decodeJSON expects an array of products, the line of each row is its position within the array
+func decodeJSON(r io.Reader) ([]*uc.ImportRow, error) {
+	dec := json.NewDecoder(r)
+	if t, err := dec.Token(); err != nil || t != json.Delim('[') {
+		return nil, e.NewErrInvalidData("invalid json catalog, expecting an array of products")
+	}
+
+	rows := make([]*uc.ImportRow, 0)
+	for dec.More() {
+		row := &uc.ImportRow{Line: len(rows) + 1}
+		var rec productRecord
+		if err := dec.Decode(&rec); err != nil {
+			if _, ok := err.(*json.UnmarshalTypeError); !ok {
+				return nil, e.NewErrInvalidData(fmt.Sprintf("invalid json catalog at product #%d: %v", row.Line, err))
+			}
+			row.Err = e.NewErrInvalidData(err.Error())
+		} else {
+			row.Product, row.Err = rec.toProduct()
+		}
+		rows = append(rows, row)
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, e.NewErrInvalidData("invalid json catalog, the array of products is not terminated")
+	}
+	return rows, nil
+}
+
+func encodeJSON(w io.Writer, products []*uc.Product) error {
+	records := make([]*productRecord, 0)
+	for _, p := range products {
+		records = append(records, buildProductRecord(p))
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(records)
+}
+
+func (rec *productRecord) toProduct() (*uc.Product, error) {
+	p := &uc.Product{
+		ID:             rec.ID,
+		Name:           rec.Name,
+		Type:           enum.ProductType(rec.Type),
+		Stock:          rec.Stock,
+		WarehouseStock: rec.Warehouses,
+		Price:          rec.Price,
+		Disc:           rec.Disc,
+		CategoryIDs:    rec.Categories,
+	}
+	for _, v := range rec.Variants {
+		p.Variants = append(p.Variants, &uc.Variant{
+			SKU: v.SKU, Options: v.Options, Stock: v.Stock, WarehouseStock: v.Warehouses, Price: v.Price, Disc: v.Disc,
+		})
+	}
+	for _, c := range rec.Components {
+		p.Components = append(p.Components, &uc.BundleComponent{ProductID: c.ProductID, SKU: c.SKU, Qty: c.Qty})
+	}
+	if rec.BundlePricing != nil {
+		p.BundlePricing = &uc.BundlePricing{Mode: enum.BundlePricingMode(rec.BundlePricing.Mode), Value: rec.BundlePricing.Value}
+	}
+	if l := rec.PurchaseLimit; l != nil {
+		p.PurchaseLimit = &uc.PurchaseLimit{MaxPerOrder: l.MaxPerOrder, MaxPerCustomer: l.MaxPerCustomer}
+		if l.Period != "" {
+			period, err := time.ParseDuration(l.Period)
+			if err != nil {
+				return p, e.NewErrInvalidData(fmt.Sprintf("invalid purchase limit period '%s', should be a duration", l.Period))
+			}
+			p.PurchaseLimit.Period = period
+		}
+	}
+	return p, nil
+}
+
+func buildProductRecord(p *uc.Product) *productRecord {
+	rec := &productRecord{
+		ID:         p.ID,
+		Name:       p.Name,
+		Type:       string(p.Type),
+		Stock:      p.Stock,
+		Warehouses: p.WarehouseStock,
+		Price:      p.Price,
+		Disc:       p.Disc,
+		Categories: p.CategoryIDs,
+	}
+	for _, v := range p.Variants {
+		rec.Variants = append(rec.Variants, &variantRecord{
+			SKU: v.SKU, Options: v.Options, Stock: v.Stock, Warehouses: v.WarehouseStock, Price: v.Price, Disc: v.Disc,
+		})
+	}
+	for _, c := range p.Components {
+		rec.Components = append(rec.Components, &componentRecord{ProductID: c.ProductID, SKU: c.SKU, Qty: c.Qty})
+	}
+	if p.BundlePricing != nil {
+		rec.BundlePricing = &pricingRecord{Mode: string(p.BundlePricing.Mode), Value: p.BundlePricing.Value}
+	}
+	if l := p.PurchaseLimit; l != nil {
+		rec.PurchaseLimit = &limitRecord{MaxPerOrder: l.MaxPerOrder, MaxPerCustomer: l.MaxPerCustomer}
+		if l.Period > 0 {
+			rec.PurchaseLimit.Period = l.Period.String()
+		}
+	}
+	return rec
+}
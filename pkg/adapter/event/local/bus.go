@@ -0,0 +1,42 @@
+package local
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Bus is an in-process implementation of the service.EventPublisher, the events are handed
+// synchronously to every subscriber in the order they subscribed
+type Bus struct {
+	mu          sync.RWMutex
+	subscribers []func(event interface{}) error
+}
+
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+func (b *Bus) Subscribe(fn func(event interface{}) error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers = append(b.subscribers, fn)
+}
+
+// Publish delivers the event to all of the subscribers even when some of them fail
+func (b *Bus) Publish(event interface{}) error {
+	b.mu.RLock()
+	subscribers := b.subscribers
+	b.mu.RUnlock()
+
+	failures := make([]string, 0)
+	for _, fn := range subscribers {
+		if err := fn(event); err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("failed to deliver %T: %s", event, strings.Join(failures, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package local
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBus(t *testing.T) {
+
+	Convey("1. Given a bus with several subscribers", t, func() {
+
+		bus := NewBus()
+		received := make([]string, 0)
+		bus.Subscribe(func(event interface{}) error {
+			received = append(received, "first:"+event.(string))
+			return errors.New("mailbox is full")
+		})
+		bus.Subscribe(func(event interface{}) error {
+			received = append(received, "second:"+event.(string))
+			return nil
+		})
+
+		Convey("-> Every subscriber should receive the event even when one of them fails", func() {
+			err := bus.Publish("cart expired")
+			So(err.Error(), ShouldEqual, "failed to deliver string: mailbox is full")
+			So(received, ShouldResemble, []string{"first:cart expired", "second:cart expired"})
+		})
+	})
+
+	Convey("2. Given a bus without any subscriber", t, func() {
+		Convey("-> Publishing should be a no-op", func() {
+			So(NewBus().Publish("cart expired"), ShouldBeNil)
+		})
+	})
+}
@@ -0,0 +1,52 @@
+package local
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	vo "github.com/yauritux/cartsvc/pkg/domain/valueobject"
+)
+
+// Notifier is a local implementation of the service.Notifier, it writes the notifications
+// to a file or to the standard output instead of delivering them, which lets them be
+// reviewed before a real email or SMS gateway gets plugged in
+type Notifier struct {
+	mu    sync.Mutex
+	w     io.Writer
+	close func() error
+}
+
+func NewNotifier(w io.Writer) *Notifier {
+	return &Notifier{w: w, close: func() error { return nil }}
+}
+
+// OpenFile appends the notifications to the file found at path, which gets created when missing
+func OpenFile(path string) (*Notifier, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open notification file: %v", err)
+	}
+	return &Notifier{w: f, close: f.Close}, nil
+}
+
+func (n *Notifier) Send(notification vo.Notification) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if _, err := fmt.Fprintf(n.w, "--- %s to %s ---\n", notification.Channel, notification.Recipient); err != nil {
+		return err
+	}
+	if notification.Subject != "" {
+		if _, err := fmt.Fprintf(n.w, "Subject: %s\n\n", notification.Subject); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(n.w, "%s\n\n", notification.Body)
+	return err
+}
+
+func (n *Notifier) Close() error {
+	return n.close()
+}
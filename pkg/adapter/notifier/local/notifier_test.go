@@ -0,0 +1,58 @@
+package local
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	vo "github.com/yauritux/cartsvc/pkg/domain/valueobject"
+	"github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+)
+
+func TestNotifier(t *testing.T) {
+
+	email := vo.Notification{Channel: enum.EmailChannel, Recipient: "yauritux@gmail.com", Subject: "Your cart", Body: "Hi yauritux"}
+	sms := vo.Notification{Channel: enum.SMSChannel, Recipient: "+62822", Body: "Hi hanzo"}
+
+	Convey("1. Given a notifier writing to the standard output", t, func() {
+		var out bytes.Buffer
+		n := NewNotifier(&out)
+
+		Convey("-> The subject should only be written for an email", func() {
+			So(n.Send(email), ShouldBeNil)
+			So(n.Send(sms), ShouldBeNil)
+			So(out.String(), ShouldEqual, "--- email to yauritux@gmail.com ---\nSubject: Your cart\n\nHi yauritux\n\n"+
+				"--- sms to +62822 ---\nHi hanzo\n\n")
+		})
+	})
+
+	Convey("2. Given a notifier writing to a file", t, func() {
+		dir, err := ioutil.TempDir("", "cartsvc-notifier")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "notifications.log")
+
+		Convey("-> The notifications should be appended across the reopenings", func() {
+			n, err := OpenFile(path)
+			So(err, ShouldBeNil)
+			So(n.Send(sms), ShouldBeNil)
+			So(n.Close(), ShouldBeNil)
+
+			n, err = OpenFile(path)
+			So(err, ShouldBeNil)
+			So(n.Send(sms), ShouldBeNil)
+			So(n.Close(), ShouldBeNil)
+
+			data, err := ioutil.ReadFile(path)
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, "--- sms to +62822 ---\nHi hanzo\n\n--- sms to +62822 ---\nHi hanzo\n\n")
+		})
+		Convey("-> Should fail when the file cannot be created", func() {
+			_, err := OpenFile(filepath.Join(dir, "missing", "notifications.log"))
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
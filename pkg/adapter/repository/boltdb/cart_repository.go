@@ -0,0 +1,222 @@
+package boltdb
+
+import (
+	"encoding/json"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/yauritux/cartsvc/pkg/adapter/repository/inmem"
+	"github.com/yauritux/cartsvc/pkg/adapter/repository/inmem/model"
+	"github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+)
+
+// CartRepository keeps the user_open_cart and user_last_cart indexes up to date within the
+// same transaction as the cart itself
+type CartRepository struct {
+	db *DB
+}
+
+func NewCartRepository(db *DB) *CartRepository {
+	return &CartRepository{db: db}
+}
+
+// Open makes sure the user owns an open cart, a new one is created when he has got none
+func (r *CartRepository) Open(uid string, openedAt time.Time) error {
+	return r.db.bolt.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket(userOpenCartBucket).Get([]byte(uid)) != nil {
+			return nil
+		}
+		repo := inmem.NewCartRepositoryWith(nil)
+		if err := repo.Open(uid, openedAt); err != nil {
+			return err
+		}
+		cart := repo.Records()[0]
+		if err := putCart(tx, cart); err != nil {
+			return err
+		}
+		return tx.Bucket(userLastCartBucket).Put([]byte(uid), []byte(cart.ID))
+	})
+}
+
+// FetchUserCart looks the user's open cart up through the user_open_cart index
+func (r *CartRepository) FetchUserCart(userID string) (interface{}, error) {
+	return r.fetchIndexed(userOpenCartBucket, userID, func(repo *inmem.CartRepository) (interface{}, error) {
+		return repo.FetchUserCart(userID)
+	})
+}
+
+// FetchLatestCart looks the cart the user opened last up through the user_last_cart index
+func (r *CartRepository) FetchLatestCart(userID string) (interface{}, error) {
+	return r.fetchIndexed(userLastCartBucket, userID, func(repo *inmem.CartRepository) (interface{}, error) {
+		return repo.FetchLatestCart(userID)
+	})
+}
+
+// fetchIndexed runs fn upon the cart the index holds for the user, if any
+func (r *CartRepository) fetchIndexed(index []byte, userID string, fn func(*inmem.CartRepository) (interface{}, error)) (interface{}, error) {
+	var res interface{}
+	err := r.db.bolt.View(func(tx *bolt.Tx) error {
+		cartID := tx.Bucket(index).Get([]byte(userID))
+		records, err := cartRecords(tx, string(cartID))
+		if err != nil {
+			return err
+		}
+		res, err = fn(inmem.NewCartRepositoryWith(records))
+		return err
+	})
+	return res, err
+}
+
+func (r *CartRepository) AddToCart(cartID string, item interface{}, at time.Time) error {
+	return r.update(cartID, func(repo *inmem.CartRepository) error {
+		return repo.AddToCart(cartID, item, at)
+	})
+}
+
+func (r *CartRepository) RemoveItem(cartID string, itemID string, at time.Time) error {
+	return r.update(cartID, func(repo *inmem.CartRepository) error {
+		return repo.RemoveItem(cartID, itemID, at)
+	})
+}
+
+func (r *CartRepository) UpdateItem(cartID string, item interface{}, at time.Time) error {
+	return r.update(cartID, func(repo *inmem.CartRepository) error {
+		return repo.UpdateItem(cartID, item, at)
+	})
+}
+
+func (r *CartRepository) Checkout(cartID string, checkedOutAt time.Time) interface{} {
+	var res interface{}
+	err := r.update(cartID, func(repo *inmem.CartRepository) error {
+		res = repo.Checkout(cartID, checkedOutAt)
+		if err, ok := res.(error); ok {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return res
+}
+
+func (r *CartRepository) Canceled(cartID string, canceledAt time.Time) error {
+	return r.update(cartID, func(repo *inmem.CartRepository) error {
+		return repo.Canceled(cartID, canceledAt)
+	})
+}
+
+func (r *CartRepository) Close(cartID string, closedAt time.Time) error {
+	return r.update(cartID, func(repo *inmem.CartRepository) error {
+		return repo.Close(cartID, closedAt)
+	})
+}
+
+func (r *CartRepository) Refunded(cartID string) error {
+	return r.update(cartID, func(repo *inmem.CartRepository) error {
+		return repo.Refunded(cartID)
+	})
+}
+
+func (r *CartRepository) RecordReminder(cartID string, sentAt time.Time) error {
+	return r.update(cartID, func(repo *inmem.CartRepository) error {
+		return repo.RecordReminder(cartID, sentAt)
+	})
+}
+
+func (r *CartRepository) RecordShipments(cartID string, shipments interface{}) error {
+	return r.update(cartID, func(repo *inmem.CartRepository) error {
+		return repo.RecordShipments(cartID, shipments)
+	})
+}
+
+func (r *CartRepository) RecordRedemption(cartID string, redemption interface{}) error {
+	return r.update(cartID, func(repo *inmem.CartRepository) error {
+		return repo.RecordRedemption(cartID, redemption)
+	})
+}
+
+// FetchIdleCarts scans the whole carts bucket
+func (r *CartRepository) FetchIdleCarts(idleSince time.Time) (interface{}, error) {
+	return r.scan(func(repo *inmem.CartRepository) (interface{}, error) {
+		return repo.FetchIdleCarts(idleSince)
+	})
+}
+
+// FetchOrders scans the whole carts bucket, only the open carts are indexed by their owner
+func (r *CartRepository) FetchOrders(userID string, since time.Time) (interface{}, error) {
+	return r.scan(func(repo *inmem.CartRepository) (interface{}, error) {
+		return repo.FetchOrders(userID, since)
+	})
+}
+
+// scan runs fn upon every cart within a read only transaction
+func (r *CartRepository) scan(fn func(*inmem.CartRepository) (interface{}, error)) (interface{}, error) {
+	var res interface{}
+	err := r.db.bolt.View(func(tx *bolt.Tx) error {
+		records := make([]*model.Cart, 0)
+		err := tx.Bucket(cartsBucket).ForEach(func(k, v []byte) error {
+			var c model.Cart
+			if err := json.Unmarshal(v, &c); err != nil {
+				return err
+			}
+			records = append(records, &c)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		res, err = fn(inmem.NewCartRepositoryWith(records))
+		return err
+	})
+	return res, err
+}
+
+// update runs fn upon the cart and saves the outcome within a single transaction, nothing is
+// saved when fn fails
+func (r *CartRepository) update(cartID string, fn func(*inmem.CartRepository) error) error {
+	return r.db.bolt.Update(func(tx *bolt.Tx) error {
+		records, err := cartRecords(tx, cartID)
+		if err != nil {
+			return err
+		}
+		repo := inmem.NewCartRepositoryWith(records)
+		if err := fn(repo); err != nil {
+			return err
+		}
+		for _, c := range repo.Records() {
+			if err := putCart(tx, c); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func putCart(tx *bolt.Tx, cart *model.Cart) error {
+	if err := put(tx.Bucket(cartsBucket), cart.ID, cart); err != nil {
+		return err
+	}
+	index := tx.Bucket(userOpenCartBucket)
+	if cart.Status == enum.Open {
+		return index.Put([]byte(cart.UserID), []byte(cart.ID))
+	}
+	if string(index.Get([]byte(cart.UserID))) == cart.ID {
+		return index.Delete([]byte(cart.UserID))
+	}
+	return nil
+}
+
+func cartRecords(tx *bolt.Tx, cartID string) ([]*model.Cart, error) {
+	records := make([]*model.Cart, 0)
+	if cartID == "" {
+		return records, nil
+	}
+	var c model.Cart
+	found, err := get(tx.Bucket(cartsBucket), cartID, &c)
+	if found {
+		records = append(records, &c)
+	}
+	return records, err
+}
@@ -0,0 +1,89 @@
+package boltdb
+
+import (
+	"encoding/json"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/yauritux/cartsvc/pkg/adapter/repository/inmem"
+	"github.com/yauritux/cartsvc/pkg/adapter/repository/inmem/model"
+	uc "github.com/yauritux/cartsvc/pkg/usecase/categories"
+)
+
+type CategoryRepository struct {
+	db *DB
+}
+
+func NewCategoryRepository(db *DB) *CategoryRepository {
+	return &CategoryRepository{db: db}
+}
+
+func (r *CategoryRepository) FindByCategoryID(id string) (interface{}, error) {
+	var res interface{}
+	err := r.db.bolt.View(func(tx *bolt.Tx) error {
+		records, err := categoryRecords(tx, id)
+		if err != nil {
+			return err
+		}
+		res, err = inmem.NewCategoryRepositoryWith(records).FindByCategoryID(id)
+		return err
+	})
+	return res, err
+}
+
+func (r *CategoryRepository) FetchAll() (interface{}, error) {
+	var res interface{}
+	err := r.db.bolt.View(func(tx *bolt.Tx) error {
+		records := make([]*model.Category, 0)
+		err := tx.Bucket(categoriesBucket).ForEach(func(k, v []byte) error {
+			var c model.Category
+			if err := json.Unmarshal(v, &c); err != nil {
+				return err
+			}
+			records = append(records, &c)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		res, err = inmem.NewCategoryRepositoryWith(records).FetchAll()
+		return err
+	})
+	return res, err
+}
+
+func (r *CategoryRepository) Create(category interface{}) error {
+	id := ""
+	if c, ok := category.(*uc.Category); ok {
+		id = c.ID
+	}
+	return r.db.bolt.Update(func(tx *bolt.Tx) error {
+		records, err := categoryRecords(tx, id)
+		if err != nil {
+			return err
+		}
+		repo := inmem.NewCategoryRepositoryWith(records)
+		if err := repo.Create(category); err != nil {
+			return err
+		}
+		for _, c := range repo.Records() {
+			if err := put(tx.Bucket(categoriesBucket), c.ID, c); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func categoryRecords(tx *bolt.Tx, id string) ([]*model.Category, error) {
+	records := make([]*model.Category, 0)
+	if id == "" {
+		return records, nil
+	}
+	var c model.Category
+	found, err := get(tx.Bucket(categoriesBucket), id, &c)
+	if found {
+		records = append(records, &c)
+	}
+	return records, err
+}
@@ -0,0 +1,56 @@
+package boltdb
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/yauritux/cartsvc/pkg/adapter/repository/contract"
+	"github.com/yauritux/cartsvc/pkg/domain/repository"
+)
+
+// contractDB opens a new database which is removed at the end of the test
+func contractDB(t *testing.T) *DB {
+	db, path := openDB(t)
+	t.Cleanup(func() {
+		db.Close()
+		os.RemoveAll(filepath.Dir(path))
+	})
+	return db
+}
+
+func TestCartRepositoryContract(t *testing.T) {
+	contract.TestCartRepository(t, func(t *testing.T) repository.CartRepository {
+		return NewCartRepository(contractDB(t))
+	})
+}
+
+func TestProductRepositoryContract(t *testing.T) {
+	contract.TestProductRepository(t, func(t *testing.T) repository.ProductRepository {
+		return NewProductRepository(contractDB(t))
+	})
+}
+
+func TestUserRepositoryContract(t *testing.T) {
+	contract.TestUserRepository(t, func(t *testing.T) repository.UserRepository {
+		return NewUserRepository(contractDB(t))
+	})
+}
+
+func TestSubscriptionRepositoryContract(t *testing.T) {
+	contract.TestSubscriptionRepository(t, func(t *testing.T) repository.SubscriptionRepository {
+		return NewSubscriptionRepository(contractDB(t))
+	})
+}
+
+func TestLoyaltyRepositoryContract(t *testing.T) {
+	contract.TestLoyaltyRepository(t, func(t *testing.T) repository.LoyaltyRepository {
+		return NewLoyaltyRepository(contractDB(t))
+	})
+}
+
+func TestWarehouseRepositoryContract(t *testing.T) {
+	contract.TestWarehouseRepository(t, func(t *testing.T) repository.WarehouseRepository {
+		return NewWarehouseRepository(contractDB(t))
+	})
+}
@@ -0,0 +1,136 @@
+package boltdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/yauritux/cartsvc/pkg/adapter/repository/inmem"
+)
+
+// a bucket per aggregate, keyed by ID, the documents are the inmem models encoded as JSON
+var (
+	productsBucket   = []byte("products")
+	usersBucket      = []byte("users")
+	cartsBucket      = []byte("carts")
+	sessionsBucket   = []byte("sessions")
+	categoriesBucket = []byte("categories")
+	// keyed by user ID and SKU
+	subscriptionsBucket = []byte("subscriptions")
+	// the loyalty ledgers, keyed by user ID
+	loyaltyBucket = []byte("loyalty")
+	// keyed by their position so that they are read in the order they were seeded
+	warehousesBucket = []byte("warehouses")
+
+	// secondary indexes keyed by user ID
+	userOpenCartBucket = []byte("user_open_cart")
+	userLastCartBucket = []byte("user_last_cart")
+)
+
+// DB is a single-file store, every repository call runs within its own transaction
+type DB struct {
+	bolt *bolt.DB
+}
+
+// Open opens the database file, creating it along with the buckets when needed. The catalog,
+// users and categories of the inmem repositories are seeded into a new database, so are the
+// warehouses into a database having none yet.
+func Open(path string) (*DB, error) {
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket(productsBucket) == nil {
+			if err := seed(tx); err != nil {
+				return err
+			}
+		}
+		if tx.Bucket(warehousesBucket) == nil {
+			if err := seedWarehouses(tx); err != nil {
+				return err
+			}
+		}
+		for _, name := range [][]byte{cartsBucket, sessionsBucket, subscriptionsBucket, loyaltyBucket, userOpenCartBucket, userLastCartBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &DB{bolt: db}, nil
+}
+
+func (db *DB) Close() error {
+	return db.bolt.Close()
+}
+
+func seed(tx *bolt.Tx) error {
+	products, err := tx.CreateBucket(productsBucket)
+	if err != nil {
+		return err
+	}
+	for _, p := range inmem.NewProductRepository().Records() {
+		if err := put(products, p.ID, p); err != nil {
+			return err
+		}
+	}
+
+	users, err := tx.CreateBucketIfNotExists(usersBucket)
+	if err != nil {
+		return err
+	}
+	for _, u := range inmem.NewUserRepository().Records() {
+		if err := put(users, u.ID, u); err != nil {
+			return err
+		}
+	}
+
+	categories, err := tx.CreateBucketIfNotExists(categoriesBucket)
+	if err != nil {
+		return err
+	}
+	for _, c := range inmem.NewCategoryRepository().Records() {
+		if err := put(categories, c.ID, c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func seedWarehouses(tx *bolt.Tx) error {
+	warehouses, err := tx.CreateBucket(warehousesBucket)
+	if err != nil {
+		return err
+	}
+	for i, w := range inmem.NewWarehouseRepository().Records() {
+		if err := put(warehouses, fmt.Sprintf("%06d", i), w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func put(b *bolt.Bucket, key string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return b.Put([]byte(key), data)
+}
+
+// get decodes the document into v, it returns false when the key does not exist
+func get(b *bolt.Bucket, key string, v interface{}) (bool, error) {
+	data := b.Get([]byte(key))
+	if data == nil {
+		return false, nil
+	}
+	return true, json.Unmarshal(data, v)
+}
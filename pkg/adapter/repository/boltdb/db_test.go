@@ -0,0 +1,159 @@
+package boltdb
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+	authUsecase "github.com/yauritux/cartsvc/pkg/usecase/auth"
+	cartUsecase "github.com/yauritux/cartsvc/pkg/usecase/carts"
+	catUsecase "github.com/yauritux/cartsvc/pkg/usecase/categories"
+	prodUsecase "github.com/yauritux/cartsvc/pkg/usecase/products"
+)
+
+func openDB(t *testing.T) (*DB, string) {
+	dir, err := ioutil.TempDir("", "cartsvc-bolt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "cartsvc.db")
+	db, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, path
+}
+
+func openCartID(db *DB, uid string) string {
+	var id string
+	db.bolt.View(func(tx *bolt.Tx) error {
+		id = string(tx.Bucket(userOpenCartBucket).Get([]byte(uid)))
+		return nil
+	})
+	return id
+}
+
+func TestBoltRepositories(t *testing.T) {
+
+	Convey("1. Given the database is reopened", t, func() {
+		db, path := openDB(t)
+		defer os.RemoveAll(filepath.Dir(path))
+
+		products := NewProductRepository(db)
+		So(products.Create(&prodUsecase.Product{ID: "005", Name: "Kunai", Stock: 10, Price: 99}), ShouldBeNil)
+		So(products.Delete("002"), ShouldBeNil)
+		So(db.Close(), ShouldBeNil)
+
+		db, err := Open(path)
+		So(err, ShouldBeNil)
+		defer db.Close()
+		products = NewProductRepository(db)
+
+		Convey("-> The changes should be kept and the seed should not be applied again", func() {
+			p, err := products.FindByProductID("005")
+			So(err, ShouldBeNil)
+			So(p.(*prodUsecase.Product).Name, ShouldEqual, "Kunai")
+			_, err = products.FindByProductID("002")
+			So(err, ShouldHaveSameTypeAs, &e.ErrNoData{})
+
+			page, err := products.Search(&prodUsecase.ProductQuery{SortBy: prodUsecase.SortByName, Limit: 10})
+			So(err, ShouldBeNil)
+			names := make([]string, 0)
+			for _, p := range page.(*prodUsecase.ProductPage).Items {
+				names = append(names, p.Name)
+			}
+			So(names, ShouldResemble, []string{"Kunai", "Ninja Gi", "Ninja Training Set", "Shuriken"})
+		})
+		Convey("-> Creating a deleted product again should be rejected", func() {
+			err := products.Create(&prodUsecase.Product{ID: "002", Name: "Sai", Stock: 1, Price: 1})
+			So(err, ShouldHaveSameTypeAs, &e.ErrDuplicateData{})
+		})
+	})
+
+	Convey("2. Given the carts of a user", t, func() {
+		db, path := openDB(t)
+		defer os.RemoveAll(filepath.Dir(path))
+		defer db.Close()
+		carts := NewCartRepository(db)
+
+		So(carts.Open("yauritux", time.Now()), ShouldBeNil)
+		cartID := openCartID(db, "yauritux")
+		So(cartID, ShouldNotBeEmpty)
+
+		Convey("-> Opening the cart again should keep the same one", func() {
+			So(carts.Open("yauritux", time.Now()), ShouldBeNil)
+			So(openCartID(db, "yauritux"), ShouldEqual, cartID)
+		})
+		Convey("-> The open cart index should follow the status of the cart", func() {
+			So(carts.AddToCart(cartID, &cartUsecase.CartItem{ID: "001", Name: "Shuriken", Qty: 1, Price: 250.5}, time.Now()), ShouldBeNil)
+			res := carts.Checkout(cartID, time.Now())
+			So(res, ShouldHaveSameTypeAs, &cartUsecase.Cart{})
+			So(openCartID(db, "yauritux"), ShouldBeEmpty)
+
+			_, err := carts.FetchUserCart("yauritux")
+			So(err, ShouldHaveSameTypeAs, &e.ErrNoData{})
+			c, err := carts.FetchLatestCart("yauritux")
+			So(err, ShouldBeNil)
+			So(c.(*cartUsecase.Cart).ID, ShouldEqual, cartID)
+			So(c.(*cartUsecase.Cart).Status, ShouldEqual, enum.PaymentProcessing)
+
+			So(carts.Open("yauritux", time.Now()), ShouldBeNil)
+			So(openCartID(db, "yauritux"), ShouldNotEqual, cartID)
+			c, _ = carts.FetchUserCart("yauritux")
+			So(c.(*cartUsecase.Cart).Status, ShouldEqual, enum.Open)
+			So(c.(*cartUsecase.Cart).Items, ShouldBeEmpty)
+			c, _ = carts.FetchLatestCart("yauritux")
+			So(c.(*cartUsecase.Cart).Status, ShouldEqual, enum.Open)
+		})
+		Convey("-> A failed update should roll the transaction back", func() {
+			So(carts.Canceled(cartID, time.Now()), ShouldBeNil)
+			So(carts.AddToCart(cartID, &cartUsecase.CartItem{ID: "001", Name: "Shuriken", Qty: 1}, time.Now()), ShouldNotBeNil)
+			So(carts.Close(cartID, time.Now()), ShouldNotBeNil)
+
+			c, _ := carts.FetchLatestCart("yauritux")
+			So(c.(*cartUsecase.Cart).Status, ShouldEqual, enum.Canceled)
+			So(c.(*cartUsecase.Cart).Items, ShouldBeEmpty)
+			So(c.(*cartUsecase.Cart).CanceledAt, ShouldNotBeNil)
+		})
+	})
+
+	Convey("3. Given the sessions and categories", t, func() {
+		db, path := openDB(t)
+		defer os.RemoveAll(filepath.Dir(path))
+		defer db.Close()
+
+		Convey("-> A session should be found until it is deleted", func() {
+			sessions := NewSessionRepository(db)
+			expiresAt := time.Now().Add(time.Hour).Round(0)
+			So(sessions.Save(&authUsecase.Session{Token: "abc", UserID: "yauritux", Role: enum.Customer, ExpiresAt: expiresAt}), ShouldBeNil)
+
+			s, err := sessions.FindByToken("abc")
+			So(err, ShouldBeNil)
+			So(s.(*authUsecase.Session).UserID, ShouldEqual, "yauritux")
+			So(s.(*authUsecase.Session).ExpiresAt.Equal(expiresAt), ShouldBeTrue)
+
+			So(sessions.Delete("abc"), ShouldBeNil)
+			_, err = sessions.FindByToken("abc")
+			So(err, ShouldHaveSameTypeAs, &e.ErrNoData{})
+		})
+		Convey("-> The seeded categories should be found along with the new ones", func() {
+			categories := NewCategoryRepository(db)
+			So(categories.Create(&catUsecase.Category{ID: "armor", Name: "Armor"}), ShouldBeNil)
+			So(categories.Create(&catUsecase.Category{ID: "armor", Name: "Armor"}), ShouldHaveSameTypeAs, &e.ErrDuplicateData{})
+
+			all, err := categories.FetchAll()
+			So(err, ShouldBeNil)
+			So(all.([]*catUsecase.Category), ShouldHaveLength, 5)
+			c, err := categories.FindByCategoryID("melee-weapons")
+			So(err, ShouldBeNil)
+			So(c.(*catUsecase.Category).ParentID, ShouldEqual, "weapons")
+		})
+	})
+}
@@ -0,0 +1,49 @@
+package boltdb
+
+import (
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/yauritux/cartsvc/pkg/adapter/repository/inmem"
+	"github.com/yauritux/cartsvc/pkg/adapter/repository/inmem/model"
+)
+
+type LoyaltyRepository struct {
+	db *DB
+}
+
+func NewLoyaltyRepository(db *DB) *LoyaltyRepository {
+	return &LoyaltyRepository{db: db}
+}
+
+func (r *LoyaltyRepository) FetchLedger(userID string) (interface{}, error) {
+	var res interface{}
+	err := r.db.bolt.View(func(tx *bolt.Tx) error {
+		records := make([]*model.Ledger, 0)
+		var l model.Ledger
+		found, err := get(tx.Bucket(loyaltyBucket), userID, &l)
+		if err != nil {
+			return err
+		}
+		if found {
+			records = append(records, &l)
+		}
+		res, err = inmem.NewLoyaltyRepositoryWith(records).FetchLedger(userID)
+		return err
+	})
+	return res, err
+}
+
+func (r *LoyaltyRepository) SaveLedger(ledger interface{}) error {
+	return r.db.bolt.Update(func(tx *bolt.Tx) error {
+		repo := inmem.NewLoyaltyRepository()
+		if err := repo.SaveLedger(ledger); err != nil {
+			return err
+		}
+		for _, l := range repo.Records() {
+			if err := put(tx.Bucket(loyaltyBucket), l.UserID, l); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,127 @@
+package boltdb
+
+import (
+	"encoding/json"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/yauritux/cartsvc/pkg/adapter/repository/inmem"
+	"github.com/yauritux/cartsvc/pkg/adapter/repository/inmem/model"
+	uc "github.com/yauritux/cartsvc/pkg/usecase/products"
+)
+
+// ProductRepository runs the rules of the inmem repository upon the documents read within
+// the transaction, so both adapters behave the same way
+type ProductRepository struct {
+	db *DB
+}
+
+func NewProductRepository(db *DB) *ProductRepository {
+	return &ProductRepository{db: db}
+}
+
+func (r *ProductRepository) FindByProductID(id string) (interface{}, error) {
+	var res interface{}
+	err := r.db.bolt.View(func(tx *bolt.Tx) error {
+		records, err := productRecords(tx, id)
+		if err != nil {
+			return err
+		}
+		res, err = inmem.NewProductRepositoryWith(records).FindByProductID(id)
+		return err
+	})
+	return res, err
+}
+
+func (r *ProductRepository) FindAnyByProductID(id string) (interface{}, error) {
+	var res interface{}
+	err := r.db.bolt.View(func(tx *bolt.Tx) error {
+		records, err := productRecords(tx, id)
+		if err != nil {
+			return err
+		}
+		res, err = inmem.NewProductRepositoryWith(records).FindAnyByProductID(id)
+		return err
+	})
+	return res, err
+}
+
+func (r *ProductRepository) Create(product interface{}) error {
+	return r.update(productID(product), func(repo *inmem.ProductRepository) error {
+		return repo.Create(product)
+	})
+}
+
+func (r *ProductRepository) Update(product interface{}) error {
+	return r.update(productID(product), func(repo *inmem.ProductRepository) error {
+		return repo.Update(product)
+	})
+}
+
+func (r *ProductRepository) Delete(id string) error {
+	return r.update(id, func(repo *inmem.ProductRepository) error {
+		return repo.Delete(id)
+	})
+}
+
+// Search scans the whole bucket, it is meant for catalogs fitting in memory
+func (r *ProductRepository) Search(query interface{}) (interface{}, error) {
+	var res interface{}
+	err := r.db.bolt.View(func(tx *bolt.Tx) error {
+		records := make([]*model.Product, 0)
+		err := tx.Bucket(productsBucket).ForEach(func(k, v []byte) error {
+			var p model.Product
+			if err := json.Unmarshal(v, &p); err != nil {
+				return err
+			}
+			records = append(records, &p)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		res, err = inmem.NewProductRepositoryWith(records).Search(query)
+		return err
+	})
+	return res, err
+}
+
+// update runs fn upon the product having the given ID, if any, and saves the outcome
+func (r *ProductRepository) update(id string, fn func(*inmem.ProductRepository) error) error {
+	return r.db.bolt.Update(func(tx *bolt.Tx) error {
+		records, err := productRecords(tx, id)
+		if err != nil {
+			return err
+		}
+		repo := inmem.NewProductRepositoryWith(records)
+		if err := fn(repo); err != nil {
+			return err
+		}
+		for _, p := range repo.Records() {
+			if err := put(tx.Bucket(productsBucket), p.ID, p); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func productRecords(tx *bolt.Tx, id string) ([]*model.Product, error) {
+	records := make([]*model.Product, 0)
+	if id == "" {
+		return records, nil
+	}
+	var p model.Product
+	found, err := get(tx.Bucket(productsBucket), id, &p)
+	if found {
+		records = append(records, &p)
+	}
+	return records, err
+}
+
+func productID(product interface{}) string {
+	if p, ok := product.(*uc.Product); ok {
+		return p.ID
+	}
+	return ""
+}
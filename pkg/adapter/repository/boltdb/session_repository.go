@@ -0,0 +1,62 @@
+package boltdb
+
+import (
+	"errors"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/yauritux/cartsvc/pkg/adapter/repository/inmem/model"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+	uc "github.com/yauritux/cartsvc/pkg/usecase/auth"
+)
+
+type SessionRepository struct {
+	db *DB
+}
+
+func NewSessionRepository(db *DB) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+func (r *SessionRepository) Save(session interface{}) error {
+	s, ok := session.(*uc.Session)
+	if !ok {
+		return errors.New("failed to save session, invalid type of session")
+	}
+
+	return r.db.bolt.Update(func(tx *bolt.Tx) error {
+		return put(tx.Bucket(sessionsBucket), s.Token, &model.Session{
+			Token:     s.Token,
+			UserID:    s.UserID,
+			Role:      s.Role,
+			ExpiresAt: s.ExpiresAt,
+		})
+	})
+}
+
+func (r *SessionRepository) FindByToken(token string) (interface{}, error) {
+	var s model.Session
+	var found bool
+	err := r.db.bolt.View(func(tx *bolt.Tx) (err error) {
+		found, err = get(tx.Bucket(sessionsBucket), token, &s)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, e.NewErrNoData("no session found for the given token")
+	}
+	return &uc.Session{
+		Token:     s.Token,
+		UserID:    s.UserID,
+		Role:      s.Role,
+		ExpiresAt: s.ExpiresAt,
+	}, nil
+}
+
+func (r *SessionRepository) Delete(token string) error {
+	return r.db.bolt.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(sessionsBucket).Delete([]byte(token))
+	})
+}
@@ -0,0 +1,89 @@
+package boltdb
+
+import (
+	"encoding/json"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/yauritux/cartsvc/pkg/adapter/repository/inmem"
+	"github.com/yauritux/cartsvc/pkg/adapter/repository/inmem/model"
+)
+
+type SubscriptionRepository struct {
+	db *DB
+}
+
+func NewSubscriptionRepository(db *DB) *SubscriptionRepository {
+	return &SubscriptionRepository{db: db}
+}
+
+func (r *SubscriptionRepository) FetchUserSubscriptions(userID string) (interface{}, error) {
+	return r.scan(func(repo *inmem.SubscriptionRepository) (interface{}, error) {
+		return repo.FetchUserSubscriptions(userID)
+	})
+}
+
+func (r *SubscriptionRepository) FetchSKUSubscriptions(sku string) (interface{}, error) {
+	return r.scan(func(repo *inmem.SubscriptionRepository) (interface{}, error) {
+		return repo.FetchSKUSubscriptions(sku)
+	})
+}
+
+func (r *SubscriptionRepository) Save(subscription interface{}) error {
+	return r.db.bolt.Update(func(tx *bolt.Tx) error {
+		repo := inmem.NewSubscriptionRepository()
+		if err := repo.Save(subscription); err != nil {
+			return err
+		}
+		for _, s := range repo.Records() {
+			if err := put(tx.Bucket(subscriptionsBucket), subscriptionKey(s.UserID, s.SKU), s); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *SubscriptionRepository) Remove(userID string, sku string) error {
+	return r.db.bolt.Update(func(tx *bolt.Tx) error {
+		records := make([]*model.Subscription, 0)
+		var s model.Subscription
+		found, err := get(tx.Bucket(subscriptionsBucket), subscriptionKey(userID, sku), &s)
+		if err != nil {
+			return err
+		}
+		if found {
+			records = append(records, &s)
+		}
+		if err := inmem.NewSubscriptionRepositoryWith(records).Remove(userID, sku); err != nil {
+			return err
+		}
+		return tx.Bucket(subscriptionsBucket).Delete([]byte(subscriptionKey(userID, sku)))
+	})
+}
+
+// scan runs fn upon every subscription within a read only transaction
+func (r *SubscriptionRepository) scan(fn func(*inmem.SubscriptionRepository) (interface{}, error)) (interface{}, error) {
+	var res interface{}
+	err := r.db.bolt.View(func(tx *bolt.Tx) error {
+		records := make([]*model.Subscription, 0)
+		err := tx.Bucket(subscriptionsBucket).ForEach(func(k, v []byte) error {
+			var s model.Subscription
+			if err := json.Unmarshal(v, &s); err != nil {
+				return err
+			}
+			records = append(records, &s)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		res, err = fn(inmem.NewSubscriptionRepositoryWith(records))
+		return err
+	})
+	return res, err
+}
+
+func subscriptionKey(userID string, sku string) string {
+	return userID + "/" + sku
+}
@@ -0,0 +1,66 @@
+package boltdb
+
+import (
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/yauritux/cartsvc/pkg/adapter/repository/inmem"
+	"github.com/yauritux/cartsvc/pkg/adapter/repository/inmem/model"
+	uc "github.com/yauritux/cartsvc/pkg/usecase/users"
+)
+
+type UserRepository struct {
+	db *DB
+}
+
+func NewUserRepository(db *DB) *UserRepository {
+	return &UserRepository{db: db}
+}
+
+func (r *UserRepository) FindByUserID(uid string) (interface{}, error) {
+	var res interface{}
+	err := r.db.bolt.View(func(tx *bolt.Tx) error {
+		records, err := userRecords(tx, uid)
+		if err != nil {
+			return err
+		}
+		res, err = inmem.NewUserRepositoryWith(records).FindByUserID(uid)
+		return err
+	})
+	return res, err
+}
+
+func (r *UserRepository) Create(user interface{}) error {
+	uid := ""
+	if u, ok := user.(*uc.User); ok {
+		uid = u.ID
+	}
+	return r.db.bolt.Update(func(tx *bolt.Tx) error {
+		records, err := userRecords(tx, uid)
+		if err != nil {
+			return err
+		}
+		repo := inmem.NewUserRepositoryWith(records)
+		if err := repo.Create(user); err != nil {
+			return err
+		}
+		for _, u := range repo.Records() {
+			if err := put(tx.Bucket(usersBucket), u.ID, u); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func userRecords(tx *bolt.Tx, uid string) ([]*model.User, error) {
+	records := make([]*model.User, 0)
+	if uid == "" {
+		return records, nil
+	}
+	var u model.User
+	found, err := get(tx.Bucket(usersBucket), uid, &u)
+	if found {
+		records = append(records, &u)
+	}
+	return records, err
+}
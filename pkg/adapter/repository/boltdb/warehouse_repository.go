@@ -0,0 +1,59 @@
+package boltdb
+
+import (
+	"encoding/json"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/yauritux/cartsvc/pkg/adapter/repository/inmem"
+	"github.com/yauritux/cartsvc/pkg/adapter/repository/inmem/model"
+)
+
+type WarehouseRepository struct {
+	db *DB
+}
+
+func NewWarehouseRepository(db *DB) *WarehouseRepository {
+	return &WarehouseRepository{db: db}
+}
+
+func (r *WarehouseRepository) FindByWarehouseID(id string) (interface{}, error) {
+	var res interface{}
+	err := r.db.bolt.View(func(tx *bolt.Tx) error {
+		records, err := warehouseRecords(tx)
+		if err != nil {
+			return err
+		}
+		res, err = inmem.NewWarehouseRepositoryWith(records).FindByWarehouseID(id)
+		return err
+	})
+	return res, err
+}
+
+func (r *WarehouseRepository) FetchAll() (interface{}, error) {
+	var res interface{}
+	err := r.db.bolt.View(func(tx *bolt.Tx) error {
+		records, err := warehouseRecords(tx)
+		if err != nil {
+			return err
+		}
+		res, err = inmem.NewWarehouseRepositoryWith(records).FetchAll()
+		return err
+	})
+	return res, err
+}
+
+// warehouseRecords reads the warehouses in the order they were seeded, which breaks the ties
+// among the warehouses as near of the shipping address
+func warehouseRecords(tx *bolt.Tx) ([]*model.Warehouse, error) {
+	records := make([]*model.Warehouse, 0)
+	err := tx.Bucket(warehousesBucket).ForEach(func(k, v []byte) error {
+		var w model.Warehouse
+		if err := json.Unmarshal(v, &w); err != nil {
+			return err
+		}
+		records = append(records, &w)
+		return nil
+	})
+	return records, err
+}
@@ -0,0 +1,372 @@
+// Package contract holds the behaviour every repository adapter should comply with, each
+// adapter runs these suites from its own tests upon a fresh repository.
+package contract
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/yauritux/cartsvc/pkg/domain/repository"
+	"github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+	uc "github.com/yauritux/cartsvc/pkg/usecase/carts"
+)
+
+// openedAt is the time the carts of the suite are opened at, the repositories stamp the times they are given
+var openedAt = time.Date(2020, time.May, 1, 12, 0, 0, 0, time.UTC)
+
+func fetchCart(repo repository.CartRepository, userID string) *uc.Cart {
+	c, err := repo.FetchUserCart(userID)
+	So(err, ShouldBeNil)
+	So(c, ShouldHaveSameTypeAs, &uc.Cart{})
+	return c.(*uc.Cart)
+}
+
+func latestCart(repo repository.CartRepository, userID string) *uc.Cart {
+	c, err := repo.FetchLatestCart(userID)
+	So(err, ShouldBeNil)
+	So(c, ShouldHaveSameTypeAs, &uc.Cart{})
+	return c.(*uc.Cart)
+}
+
+func openCart(repo repository.CartRepository, userID string) *uc.Cart {
+	So(repo.Open(userID, openedAt), ShouldBeNil)
+	return fetchCart(repo, userID)
+}
+
+func shuriken(qty int) *uc.CartItem {
+	return &uc.CartItem{ID: "001", Name: "Shuriken", SKU: "001", Qty: qty, Price: 250.5}
+}
+
+// TestCartRepository runs the cart contract, newRepo should return an empty repository
+func TestCartRepository(t *testing.T, newRepo func(t *testing.T) repository.CartRepository) {
+
+	Convey("Cart contract: fetching the cart of a user", t, func() {
+		repo := newRepo(t)
+
+		Convey("-> Should return ErrNoData for a user without any cart", func() {
+			c, err := repo.FetchUserCart("nobody")
+			So(c, ShouldBeNil)
+			So(err, ShouldHaveSameTypeAs, &e.ErrNoData{})
+		})
+		Convey("-> Should return the empty open cart of the user", func() {
+			c := openCart(repo, "yauritux")
+			So(c.ID, ShouldNotBeEmpty)
+			So(c.UserID, ShouldEqual, "yauritux")
+			So(c.Status, ShouldEqual, enum.Open)
+			So(c.Items, ShouldBeEmpty)
+			So(c.CreatedAt.Equal(openedAt), ShouldBeTrue)
+			So(c.CanceledAt, ShouldBeNil)
+		})
+		Convey("-> Opening a cart twice should keep the same open cart", func() {
+			first := openCart(repo, "yauritux")
+			So(openCart(repo, "yauritux").ID, ShouldEqual, first.ID)
+		})
+	})
+
+	Convey("Cart contract: managing the items of an open cart", t, func() {
+		repo := newRepo(t)
+		cart := openCart(repo, "yauritux")
+
+		Convey("-> An added item should be kept as is", func() {
+			gi := &uc.CartItem{
+				ID: "003", Name: "Ninja Gi", SKU: "003-BLK-M", Qty: 2, Price: 320, Disc: 20,
+				Options: map[string]string{"color": "black", "size": "M"},
+			}
+			set := &uc.CartItem{
+				ID: "004", Name: "Ninja Training Set", SKU: "004", Qty: 1, Price: 425.75, Disc: 42.58,
+				Components: []*uc.CartItemComponent{
+					{ID: "001", Name: "Shuriken", SKU: "001", Qty: 1},
+					{ID: "002", Name: "Sai", SKU: "002", Qty: 1},
+				},
+			}
+			So(repo.AddToCart(cart.ID, gi, openedAt), ShouldBeNil)
+			So(repo.AddToCart(cart.ID, set, openedAt), ShouldBeNil)
+
+			items := fetchCart(repo, "yauritux").Items
+			So(items, ShouldHaveLength, 2)
+			So(items[0], ShouldResemble, gi)
+			So(items[1], ShouldResemble, set)
+		})
+		Convey("-> Adding an item into an unknown cart should fail", func() {
+			So(repo.AddToCart("unknown", shuriken(1), openedAt), ShouldNotBeNil)
+		})
+		Convey("-> Updating an item should replace it", func() {
+			So(repo.AddToCart(cart.ID, shuriken(1), openedAt), ShouldBeNil)
+			expectedAt := time.Date(2020, time.June, 1, 0, 0, 0, 0, time.UTC)
+			backordered := shuriken(5)
+			backordered.Fulfillment, backordered.AvailableAt = enum.Backorder, &expectedAt
+			So(repo.UpdateItem(cart.ID, backordered, openedAt), ShouldBeNil)
+
+			items := fetchCart(repo, "yauritux").Items
+			So(items, ShouldHaveLength, 1)
+			So(items[0], ShouldResemble, backordered)
+		})
+		Convey("-> Updating a missing item should return ErrNoData", func() {
+			So(repo.UpdateItem(cart.ID, shuriken(5), openedAt), ShouldHaveSameTypeAs, &e.ErrNoData{})
+			So(fetchCart(repo, "yauritux").Items, ShouldBeEmpty)
+		})
+		Convey("-> Removing an item should keep the other ones in order", func() {
+			for _, sku := range []string{"001", "002", "003"} {
+				So(repo.AddToCart(cart.ID, &uc.CartItem{ID: sku, Name: sku, SKU: sku, Qty: 1, Price: 1}, openedAt), ShouldBeNil)
+			}
+			So(repo.RemoveItem(cart.ID, "002", openedAt), ShouldBeNil)
+
+			items := fetchCart(repo, "yauritux").Items
+			So(items, ShouldHaveLength, 2)
+			So(items[0].SKU, ShouldEqual, "001")
+			So(items[1].SKU, ShouldEqual, "003")
+		})
+		Convey("-> Removing a missing item should return ErrNoData", func() {
+			So(repo.AddToCart(cart.ID, shuriken(1), openedAt), ShouldBeNil)
+			So(repo.RemoveItem(cart.ID, "002", openedAt), ShouldHaveSameTypeAs, &e.ErrNoData{})
+			So(fetchCart(repo, "yauritux").Items, ShouldHaveLength, 1)
+		})
+	})
+
+	Convey("Cart contract: the carts are isolated from each other", t, func() {
+		repo := newRepo(t)
+		mine := openCart(repo, "yauritux")
+		theirs := openCart(repo, "admin")
+		So(mine.ID, ShouldNotEqual, theirs.ID)
+
+		So(repo.AddToCart(mine.ID, shuriken(1), openedAt), ShouldBeNil)
+		So(repo.AddToCart(theirs.ID, &uc.CartItem{ID: "002", Name: "Sai", SKU: "002", Qty: 3, Price: 175.25}, openedAt), ShouldBeNil)
+		So(repo.UpdateItem(mine.ID, shuriken(2), openedAt), ShouldBeNil)
+		So(repo.Checkout(theirs.ID, openedAt), ShouldHaveSameTypeAs, &uc.Cart{})
+
+		Convey("-> The changes of a cart should not leak into another one", func() {
+			c := fetchCart(repo, "yauritux")
+			So(c.Status, ShouldEqual, enum.Open)
+			So(c.Items, ShouldHaveLength, 1)
+			So(c.Items[0].SKU, ShouldEqual, "001")
+			So(c.Items[0].Qty, ShouldEqual, 2)
+
+			c = latestCart(repo, "admin")
+			So(c.Status, ShouldEqual, enum.PaymentProcessing)
+			So(c.Items, ShouldHaveLength, 1)
+			So(c.Items[0].SKU, ShouldEqual, "002")
+		})
+	})
+
+	Convey("Cart contract: the status rules", t, func() {
+		repo := newRepo(t)
+		cart := openCart(repo, "yauritux")
+		So(repo.AddToCart(cart.ID, shuriken(1), openedAt), ShouldBeNil)
+
+		Convey("-> An open cart can neither be closed nor checked out when unknown", func() {
+			So(repo.Close(cart.ID, openedAt), ShouldNotBeNil)
+			_, failed := repo.Checkout("unknown", openedAt).(error)
+			So(failed, ShouldBeTrue)
+		})
+		Convey("-> A checked out cart should be frozen until it is closed", func() {
+			res := repo.Checkout(cart.ID, openedAt)
+			So(res, ShouldHaveSameTypeAs, &uc.Cart{})
+			So(res.(*uc.Cart).Status, ShouldEqual, enum.PaymentProcessing)
+			So(res.(*uc.Cart).Items, ShouldHaveLength, 1)
+
+			So(repo.AddToCart(cart.ID, shuriken(1), openedAt), ShouldNotBeNil)
+			So(repo.UpdateItem(cart.ID, shuriken(2), openedAt), ShouldNotBeNil)
+			So(repo.RemoveItem(cart.ID, "001", openedAt), ShouldNotBeNil)
+			_, failed := repo.Checkout(cart.ID, openedAt).(error)
+			So(failed, ShouldBeTrue)
+
+			closedAt := openedAt.Add(time.Hour)
+			So(repo.Close(cart.ID, closedAt), ShouldBeNil)
+			c := latestCart(repo, "yauritux")
+			So(c.Status, ShouldEqual, enum.Closed)
+			So(c.ClosedAt, ShouldNotBeNil)
+			So(c.ClosedAt.Equal(closedAt), ShouldBeTrue)
+			So(repo.Close(cart.ID, closedAt.Add(time.Hour)), ShouldNotBeNil)
+			So(latestCart(repo, "yauritux").ClosedAt.Equal(closedAt), ShouldBeTrue)
+		})
+		Convey("-> A checked out cart can still be canceled when its payment is abandoned", func() {
+			So(repo.Checkout(cart.ID, openedAt), ShouldHaveSameTypeAs, &uc.Cart{})
+			So(repo.Canceled(cart.ID, openedAt), ShouldBeNil)
+			So(latestCart(repo, "yauritux").Status, ShouldEqual, enum.Canceled)
+			So(repo.Close(cart.ID, openedAt), ShouldNotBeNil)
+		})
+		Convey("-> A closed cart cannot be canceled", func() {
+			So(repo.Checkout(cart.ID, openedAt), ShouldHaveSameTypeAs, &uc.Cart{})
+			So(repo.Close(cart.ID, openedAt), ShouldBeNil)
+			So(repo.Canceled(cart.ID, openedAt), ShouldNotBeNil)
+		})
+		Convey("-> Only a closed cart can be refunded, once", func() {
+			So(repo.Refunded(cart.ID), ShouldNotBeNil)
+			So(repo.Checkout(cart.ID, openedAt), ShouldHaveSameTypeAs, &uc.Cart{})
+			So(repo.Refunded(cart.ID), ShouldNotBeNil)
+			So(repo.Close(cart.ID, openedAt), ShouldBeNil)
+			So(repo.Refunded(cart.ID), ShouldBeNil)
+			So(latestCart(repo, "yauritux").Status, ShouldEqual, enum.Refunded)
+			So(repo.Refunded(cart.ID), ShouldNotBeNil)
+			So(repo.Canceled(cart.ID, openedAt), ShouldNotBeNil)
+		})
+		Convey("-> A canceled cart should be stamped and frozen", func() {
+			canceledAt := openedAt.Add(time.Hour)
+			So(repo.Canceled(cart.ID, canceledAt), ShouldBeNil)
+			c := latestCart(repo, "yauritux")
+			So(c.Status, ShouldEqual, enum.Canceled)
+			So(c.CanceledAt, ShouldNotBeNil)
+			So(c.CanceledAt.Equal(canceledAt), ShouldBeTrue)
+
+			So(repo.AddToCart(cart.ID, shuriken(1), openedAt), ShouldNotBeNil)
+			So(repo.Canceled(cart.ID, openedAt), ShouldNotBeNil)
+			So(repo.Close(cart.ID, openedAt), ShouldNotBeNil)
+		})
+		Convey("-> A checked out cart should no longer be fetched as the open cart of the user", func() {
+			So(repo.Checkout(cart.ID, openedAt), ShouldHaveSameTypeAs, &uc.Cart{})
+			c, err := repo.FetchUserCart("yauritux")
+			So(c, ShouldBeNil)
+			So(err, ShouldHaveSameTypeAs, &e.ErrNoData{})
+			So(latestCart(repo, "yauritux").ID, ShouldEqual, cart.ID)
+
+			next := openCart(repo, "yauritux")
+			So(next.ID, ShouldNotEqual, cart.ID)
+			So(next.Status, ShouldEqual, enum.Open)
+			So(next.Items, ShouldBeEmpty)
+			So(latestCart(repo, "yauritux").ID, ShouldEqual, next.ID)
+		})
+		Convey("-> Should return ErrNoData for the latest cart of a user without any cart", func() {
+			c, err := repo.FetchLatestCart("nobody")
+			So(c, ShouldBeNil)
+			So(err, ShouldHaveSameTypeAs, &e.ErrNoData{})
+		})
+	})
+
+	Convey("Cart contract: tracking the idle carts", t, func() {
+		repo := newRepo(t)
+		cart := openCart(repo, "yauritux")
+		So(cart.LastActivityAt.Equal(openedAt), ShouldBeTrue)
+
+		Convey("-> Changing the items should move the last activity to the given time", func() {
+			addedAt, updatedAt, removedAt := openedAt.Add(time.Minute), openedAt.Add(2*time.Minute), openedAt.Add(3*time.Minute)
+			So(repo.AddToCart(cart.ID, shuriken(1), addedAt), ShouldBeNil)
+			So(fetchCart(repo, "yauritux").LastActivityAt.Equal(addedAt), ShouldBeTrue)
+			So(repo.UpdateItem(cart.ID, shuriken(2), updatedAt), ShouldBeNil)
+			So(fetchCart(repo, "yauritux").LastActivityAt.Equal(updatedAt), ShouldBeTrue)
+			So(repo.RemoveItem(cart.ID, "001", removedAt), ShouldBeNil)
+			So(fetchCart(repo, "yauritux").LastActivityAt.Equal(removedAt), ShouldBeTrue)
+		})
+		Convey("-> Recording a reminder should count it without being an activity", func() {
+			sentAt := openedAt.Add(time.Hour)
+			So(repo.RecordReminder(cart.ID, sentAt), ShouldBeNil)
+			So(repo.RecordReminder(cart.ID, sentAt), ShouldBeNil)
+			c := fetchCart(repo, "yauritux")
+			So(c.RemindersSent, ShouldEqual, 2)
+			So(c.LastRemindedAt.Equal(sentAt), ShouldBeTrue)
+			So(c.LastActivityAt.Equal(cart.LastActivityAt), ShouldBeTrue)
+
+			So(repo.Checkout(cart.ID, openedAt), ShouldHaveSameTypeAs, &uc.Cart{})
+			So(repo.RecordReminder(cart.ID, sentAt), ShouldNotBeNil)
+			So(repo.RecordReminder("unknown", sentAt), ShouldNotBeNil)
+		})
+		Convey("-> Only the open and checked out carts idle since before the given time should be fetched", func() {
+			checkedOut := openCart(repo, "admin")
+			So(repo.Checkout(checkedOut.ID, openedAt), ShouldHaveSameTypeAs, &uc.Cart{})
+			canceled := openCart(repo, "guest")
+			So(repo.Canceled(canceled.ID, openedAt), ShouldBeNil)
+
+			res, err := repo.FetchIdleCarts(cart.LastActivityAt)
+			So(err, ShouldBeNil)
+			So(res.([]*uc.Cart), ShouldBeEmpty)
+
+			res, err = repo.FetchIdleCarts(openedAt.Add(time.Minute))
+			So(err, ShouldBeNil)
+			ids := make([]string, 0)
+			for _, c := range res.([]*uc.Cart) {
+				ids = append(ids, c.ID)
+			}
+			So(ids, ShouldHaveLength, 2)
+			So(ids, ShouldContain, cart.ID)
+			So(ids, ShouldContain, checkedOut.ID)
+		})
+	})
+
+	Convey("Cart contract: fetching the orders of a user", t, func() {
+		repo := newRepo(t)
+		since := openedAt.Add(-time.Minute)
+		first := openCart(repo, "yauritux")
+		So(repo.AddToCart(first.ID, shuriken(2), openedAt), ShouldBeNil)
+		So(repo.Checkout(first.ID, openedAt), ShouldHaveSameTypeAs, &uc.Cart{})
+		So(repo.Close(first.ID, openedAt), ShouldBeNil)
+		second := openCart(repo, "yauritux")
+		So(repo.AddToCart(second.ID, shuriken(1), openedAt), ShouldBeNil)
+		So(repo.Checkout(second.ID, openedAt), ShouldHaveSameTypeAs, &uc.Cart{})
+		canceled := openCart(repo, "yauritux")
+		So(repo.Canceled(canceled.ID, openedAt), ShouldBeNil)
+		openCart(repo, "yauritux")
+		other := openCart(repo, "admin")
+		So(repo.Checkout(other.ID, openedAt), ShouldHaveSameTypeAs, &uc.Cart{})
+
+		Convey("-> A checked out cart should be stamped", func() {
+			c := latestCart(repo, "admin")
+			So(c.CheckedOutAt, ShouldNotBeNil)
+			So(c.CheckedOutAt.Equal(openedAt), ShouldBeTrue)
+			So(c.LastActivityAt.Equal(openedAt), ShouldBeTrue)
+		})
+		Convey("-> Only the carts of the user checked out since the given time should be fetched", func() {
+			res, err := repo.FetchOrders("yauritux", since)
+			So(err, ShouldBeNil)
+			ids := make([]string, 0)
+			for _, c := range res.([]*uc.Cart) {
+				ids = append(ids, c.ID)
+				So(c.Items, ShouldHaveLength, 1)
+			}
+			So(ids, ShouldHaveLength, 2)
+			So(ids, ShouldContain, first.ID)
+			So(ids, ShouldContain, second.ID)
+
+			res, err = repo.FetchOrders("yauritux", openedAt.Add(time.Minute))
+			So(err, ShouldBeNil)
+			So(res.([]*uc.Cart), ShouldBeEmpty)
+		})
+	})
+
+	Convey("Cart contract: recording the shipments of a cart", t, func() {
+		repo := newRepo(t)
+		c := openCart(repo, "yauritux")
+		So(repo.AddToCart(c.ID, shuriken(3), openedAt), ShouldBeNil)
+		shipments := []*uc.Shipment{
+			{WarehouseID: "main", Items: []*uc.CartItemComponent{{ID: "001", Name: "Shuriken", SKU: "001", Qty: 2}}},
+			{WarehouseID: "medan", Items: []*uc.CartItemComponent{{ID: "001", Name: "Shuriken", SKU: "001", Qty: 1}}},
+			{Fulfillment: enum.Backorder, Items: []*uc.CartItemComponent{{ID: "001", Name: "Shuriken", SKU: "001", Qty: 4}}},
+		}
+
+		Convey("-> The shipments should be kept along the checked out cart", func() {
+			So(repo.RecordShipments(c.ID, shipments), ShouldBeNil)
+			So(repo.Checkout(c.ID, openedAt), ShouldHaveSameTypeAs, &uc.Cart{})
+			So(latestCart(repo, "yauritux").Shipments, ShouldResemble, shipments)
+		})
+		Convey("-> Recording no shipment should clear them", func() {
+			So(repo.RecordShipments(c.ID, shipments), ShouldBeNil)
+			So(repo.RecordShipments(c.ID, nil), ShouldBeNil)
+			So(fetchCart(repo, "yauritux").Shipments, ShouldBeEmpty)
+		})
+		Convey("-> Recording the shipments of an unknown cart should fail", func() {
+			So(repo.RecordShipments("unknown", shipments), ShouldNotBeNil)
+		})
+	})
+
+	Convey("Cart contract: recording the loyalty points redeemed for a cart", t, func() {
+		repo := newRepo(t)
+		c := openCart(repo, "yauritux")
+		So(repo.AddToCart(c.ID, shuriken(3), openedAt), ShouldBeNil)
+		redemption := &uc.PointsRedemption{Points: 150, Amount: 150}
+
+		Convey("-> The redemption should be kept along the checked out cart", func() {
+			So(repo.RecordRedemption(c.ID, redemption), ShouldBeNil)
+			So(repo.Checkout(c.ID, openedAt), ShouldHaveSameTypeAs, &uc.Cart{})
+			So(latestCart(repo, "yauritux").Redemption, ShouldResemble, redemption)
+		})
+		Convey("-> Recording no redemption should clear it", func() {
+			So(repo.RecordRedemption(c.ID, redemption), ShouldBeNil)
+			So(repo.RecordRedemption(c.ID, nil), ShouldBeNil)
+			So(fetchCart(repo, "yauritux").Redemption, ShouldBeNil)
+		})
+		Convey("-> Recording the redemption of an unknown cart should fail", func() {
+			So(repo.RecordRedemption("unknown", redemption), ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,67 @@
+package contract
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/yauritux/cartsvc/pkg/domain/repository"
+	"github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+	uc "github.com/yauritux/cartsvc/pkg/usecase/loyalty"
+)
+
+func ledger(userID string, entries ...*uc.LedgerEntry) *uc.Ledger {
+	return &uc.Ledger{UserID: userID, Entries: append(make([]*uc.LedgerEntry, 0), entries...)}
+}
+
+// TestLoyaltyRepository runs the loyalty contract upon a fresh repository
+func TestLoyaltyRepository(t *testing.T, newRepo func(t *testing.T) repository.LoyaltyRepository) {
+
+	Convey("Loyalty contract", t, func() {
+		repo := newRepo(t)
+		createdAt := time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC)
+		expiresAt := createdAt.AddDate(1, 0, 0)
+		earned := &uc.LedgerEntry{
+			ID: "1", CartID: "contract-cart-1", Kind: enum.Earned, Points: 50, Remaining: 20,
+			CreatedAt: createdAt, ExpiresAt: &expiresAt,
+		}
+		redeemed := &uc.LedgerEntry{
+			ID: "2", CartID: "contract-cart-2", Kind: enum.Redeemed, Points: -30, CreatedAt: createdAt.Add(time.Hour),
+		}
+
+		Convey("-> Should return an empty ledger for a user having no entry yet", func() {
+			res, err := repo.FetchLedger("contract-404")
+			So(err, ShouldBeNil)
+			So(res, ShouldResemble, ledger("contract-404"))
+		})
+		Convey("-> The saved ledger should be fetched back, the other users keeping theirs", func() {
+			So(repo.SaveLedger(ledger("contract-hanzo", earned, redeemed)), ShouldBeNil)
+			So(repo.SaveLedger(ledger("contract-kotaro", earned)), ShouldBeNil)
+
+			res, err := repo.FetchLedger("contract-hanzo")
+			So(err, ShouldBeNil)
+			So(res, ShouldResemble, ledger("contract-hanzo", earned, redeemed))
+			res, err = repo.FetchLedger("contract-kotaro")
+			So(err, ShouldBeNil)
+			So(res, ShouldResemble, ledger("contract-kotaro", earned))
+		})
+		Convey("-> Saving the ledger again should replace it", func() {
+			So(repo.SaveLedger(ledger("contract-hanzo", earned, redeemed)), ShouldBeNil)
+			So(repo.SaveLedger(ledger("contract-hanzo", earned)), ShouldBeNil)
+			res, err := repo.FetchLedger("contract-hanzo")
+			So(err, ShouldBeNil)
+			So(res, ShouldResemble, ledger("contract-hanzo", earned))
+		})
+		Convey("-> The fetched ledger should not alias the stored one", func() {
+			So(repo.SaveLedger(ledger("contract-hanzo", earned)), ShouldBeNil)
+			res, _ := repo.FetchLedger("contract-hanzo")
+			res.(*uc.Ledger).Entries[0].Remaining = 0
+			res, _ = repo.FetchLedger("contract-hanzo")
+			So(res.(*uc.Ledger).Entries[0].Remaining, ShouldEqual, 20)
+		})
+		Convey("-> Saving something else than a ledger should fail", func() {
+			So(repo.SaveLedger("hanzo"), ShouldNotBeNil)
+		})
+	})
+}
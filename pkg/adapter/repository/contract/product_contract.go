@@ -0,0 +1,119 @@
+package contract
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/yauritux/cartsvc/pkg/domain/repository"
+	"github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+	uc "github.com/yauritux/cartsvc/pkg/usecase/products"
+)
+
+// the contract products use their own IDs and names, the adapters may come with a seeded catalog
+func katana() *uc.Product {
+	return &uc.Product{
+		ID: "contract-001", Name: "Zanbato Katana", Price: 990, CategoryIDs: []string{"melee-weapons"},
+		Variants: []*uc.Variant{
+			{SKU: "contract-001-S", Options: map[string]string{"length": "short"}, Stock: 3, Price: 990},
+			{SKU: "contract-001-L", Options: map[string]string{"length": "long"}, Stock: 1, Price: 1090, Disc: 90},
+		},
+		Components: []*uc.BundleComponent{},
+	}
+}
+
+func katanaSet() *uc.Product {
+	return &uc.Product{
+		ID: "contract-002", Name: "Zanbato Dojo Set", Type: enum.BundleProduct,
+		Components: []*uc.BundleComponent{
+			{ProductID: "contract-001", SKU: "contract-001-S", Qty: 2},
+		},
+		BundlePricing: &uc.BundlePricing{Mode: enum.FixedBundlePrice, Value: 1800},
+		Variants:      []*uc.Variant{},
+	}
+}
+
+func searchZanbato(repo repository.ProductRepository) []*uc.Product {
+	page, err := repo.Search(&uc.ProductQuery{Keyword: "zanbato", SortBy: uc.SortByName, Limit: 10})
+	So(err, ShouldBeNil)
+	return page.(*uc.ProductPage).Items
+}
+
+// TestProductRepository runs the product contract upon a fresh repository
+func TestProductRepository(t *testing.T, newRepo func(t *testing.T) repository.ProductRepository) {
+
+	Convey("Product contract: finding a product", t, func() {
+		repo := newRepo(t)
+
+		Convey("-> Should return ErrNoData for an empty or unknown ID", func() {
+			_, err := repo.FindByProductID("")
+			So(err, ShouldHaveSameTypeAs, &e.ErrNoData{})
+			_, err = repo.FindByProductID("contract-404")
+			So(err, ShouldHaveSameTypeAs, &e.ErrNoData{})
+		})
+		Convey("-> A created product should be kept as is", func() {
+			So(repo.Create(katana()), ShouldBeNil)
+			So(repo.Create(katanaSet()), ShouldBeNil)
+
+			p, err := repo.FindByProductID("contract-001")
+			So(err, ShouldBeNil)
+			So(p, ShouldResemble, katana())
+			p, err = repo.FindByProductID("contract-002")
+			So(err, ShouldBeNil)
+			So(p, ShouldResemble, katanaSet())
+		})
+	})
+
+	Convey("Product contract: changing the catalog", t, func() {
+		repo := newRepo(t)
+		So(repo.Create(katana()), ShouldBeNil)
+
+		Convey("-> Creating the same product twice should return ErrDuplicateData", func() {
+			So(repo.Create(katana()), ShouldHaveSameTypeAs, &e.ErrDuplicateData{})
+		})
+		Convey("-> Creating or updating something else than a product should fail", func() {
+			So(repo.Create("katana"), ShouldNotBeNil)
+			So(repo.Update("katana"), ShouldNotBeNil)
+		})
+		Convey("-> Updating an unknown product should return ErrNoData", func() {
+			So(repo.Update(katanaSet()), ShouldHaveSameTypeAs, &e.ErrNoData{})
+		})
+		Convey("-> An updated product should be found under its new name only", func() {
+			p := katana()
+			p.Name = "Nodachi"
+			p.Variants[0].Stock = 0
+			p.Variants[1].WarehouseStock = map[string]int{"medan": 1}
+			p.PriceSchedules = []*uc.PriceSchedule{{
+				ID: "1", SKU: "contract-001-L", Price: 900, Disc: 50, Quantity: 5, Sold: 2,
+				StartsAt: time.Date(2020, time.May, 1, 0, 0, 0, 0, time.UTC),
+				EndsAt:   time.Date(2020, time.May, 2, 0, 0, 0, 0, time.UTC),
+			}}
+			releasedAt := time.Date(2020, time.June, 1, 0, 0, 0, 0, time.UTC)
+			p.StockPolicy = &uc.StockPolicy{Mode: enum.PreOrder, AvailableAt: &releasedAt, Limit: 10, Committed: 3}
+			So(repo.Update(p), ShouldBeNil)
+
+			found, err := repo.FindByProductID("contract-001")
+			So(err, ShouldBeNil)
+			So(found, ShouldResemble, p)
+			So(searchZanbato(repo), ShouldBeEmpty)
+		})
+		Convey("-> A deleted product should be gone", func() {
+			So(repo.Create(katanaSet()), ShouldBeNil)
+			So(repo.Delete("contract-001"), ShouldBeNil)
+
+			_, err := repo.FindByProductID("contract-001")
+			So(err, ShouldHaveSameTypeAs, &e.ErrNoData{})
+			found, err := repo.FindAnyByProductID("contract-001")
+			So(err, ShouldBeNil)
+			So(found.(*uc.Product).Name, ShouldEqual, katana().Name)
+			So(repo.Delete("contract-001"), ShouldHaveSameTypeAs, &e.ErrNoData{})
+			So(repo.Update(katana()), ShouldHaveSameTypeAs, &e.ErrNoData{})
+
+			items := searchZanbato(repo)
+			So(items, ShouldHaveLength, 1)
+			So(items[0].ID, ShouldEqual, "contract-002")
+		})
+	})
+}
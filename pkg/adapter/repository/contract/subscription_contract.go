@@ -0,0 +1,73 @@
+package contract
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/yauritux/cartsvc/pkg/domain/repository"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+	uc "github.com/yauritux/cartsvc/pkg/usecase/subscriptions"
+)
+
+func subscription(userID string, sku string, minute int) *uc.Subscription {
+	return &uc.Subscription{
+		UserID: userID, ProductID: "contract-003", SKU: sku, ProductName: "Ninja Gi (color: navy)",
+		CreatedAt: time.Date(2020, 5, 1, 10, minute, 0, 0, time.UTC),
+	}
+}
+
+// TestSubscriptionRepository runs the subscription contract upon a fresh repository
+func TestSubscriptionRepository(t *testing.T, newRepo func(t *testing.T) repository.SubscriptionRepository) {
+
+	Convey("Subscription contract", t, func() {
+		repo := newRepo(t)
+
+		Convey("-> Should return no subscription for an unknown user or SKU", func() {
+			res, err := repo.FetchUserSubscriptions("contract-404")
+			So(err, ShouldBeNil)
+			So(res, ShouldResemble, []*uc.Subscription{})
+			res, err = repo.FetchSKUSubscriptions("contract-404")
+			So(err, ShouldBeNil)
+			So(res, ShouldResemble, []*uc.Subscription{})
+		})
+		Convey("-> The saved subscriptions should be fetched by user and by SKU, the oldest first", func() {
+			So(repo.Save(subscription("contract-hanzo", "contract-003-NVY", 5)), ShouldBeNil)
+			So(repo.Save(subscription("contract-hanzo", "contract-003-BLK", 1)), ShouldBeNil)
+			So(repo.Save(subscription("contract-kotaro", "contract-003-NVY", 3)), ShouldBeNil)
+
+			res, err := repo.FetchUserSubscriptions("contract-hanzo")
+			So(err, ShouldBeNil)
+			So(res, ShouldResemble, []*uc.Subscription{
+				subscription("contract-hanzo", "contract-003-BLK", 1),
+				subscription("contract-hanzo", "contract-003-NVY", 5),
+			})
+			res, err = repo.FetchSKUSubscriptions("contract-003-NVY")
+			So(err, ShouldBeNil)
+			So(res, ShouldResemble, []*uc.Subscription{
+				subscription("contract-kotaro", "contract-003-NVY", 3),
+				subscription("contract-hanzo", "contract-003-NVY", 5),
+			})
+		})
+		Convey("-> Saving the same SKU again should replace the subscription of the user", func() {
+			So(repo.Save(subscription("contract-hanzo", "contract-003-NVY", 1)), ShouldBeNil)
+			So(repo.Save(subscription("contract-hanzo", "contract-003-NVY", 2)), ShouldBeNil)
+			res, err := repo.FetchUserSubscriptions("contract-hanzo")
+			So(err, ShouldBeNil)
+			So(res, ShouldResemble, []*uc.Subscription{subscription("contract-hanzo", "contract-003-NVY", 2)})
+		})
+		Convey("-> A removed subscription should be gone, removing it again should return ErrNoData", func() {
+			So(repo.Save(subscription("contract-hanzo", "contract-003-NVY", 1)), ShouldBeNil)
+			So(repo.Save(subscription("contract-kotaro", "contract-003-NVY", 2)), ShouldBeNil)
+			So(repo.Remove("contract-hanzo", "contract-003-NVY"), ShouldBeNil)
+			res, err := repo.FetchSKUSubscriptions("contract-003-NVY")
+			So(err, ShouldBeNil)
+			So(res, ShouldResemble, []*uc.Subscription{subscription("contract-kotaro", "contract-003-NVY", 2)})
+			So(repo.Remove("contract-hanzo", "contract-003-NVY"), ShouldHaveSameTypeAs, &e.ErrNoData{})
+		})
+		Convey("-> Saving something else than a subscription should fail", func() {
+			So(repo.Save("hanzo"), ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,53 @@
+package contract
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/yauritux/cartsvc/pkg/domain/repository"
+	"github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+	uc "github.com/yauritux/cartsvc/pkg/usecase/users"
+)
+
+func hanzo() *uc.User {
+	return &uc.User{
+		ID: "contract-hanzo", Username: "Hattori Hanzo", Email: "hanzo@iga.jp", Phone: "+81000000",
+		Role: enum.Customer, PasswordHash: "hash",
+		ShippingAddr: &uc.Address{
+			Street: "Iga 1", City: "Iga", Postal: "518-0000", Province: "Mie", Region: "Kansai",
+			Country: "Japan", AddressType: enum.ShippingAddress,
+		},
+	}
+}
+
+// TestUserRepository runs the user contract upon a fresh repository
+func TestUserRepository(t *testing.T, newRepo func(t *testing.T) repository.UserRepository) {
+
+	Convey("User contract", t, func() {
+		repo := newRepo(t)
+
+		Convey("-> Should return ErrNoData for an empty or unknown ID", func() {
+			u, err := repo.FindByUserID("")
+			So(u, ShouldBeNil)
+			So(err, ShouldHaveSameTypeAs, &e.ErrNoData{})
+			u, err = repo.FindByUserID("contract-404")
+			So(u, ShouldBeNil)
+			So(err, ShouldHaveSameTypeAs, &e.ErrNoData{})
+		})
+		Convey("-> A created user should be kept as is", func() {
+			So(repo.Create(hanzo()), ShouldBeNil)
+			u, err := repo.FindByUserID("contract-hanzo")
+			So(err, ShouldBeNil)
+			So(u, ShouldResemble, hanzo())
+		})
+		Convey("-> Creating the same user twice should return ErrDuplicateData", func() {
+			So(repo.Create(hanzo()), ShouldBeNil)
+			So(repo.Create(hanzo()), ShouldHaveSameTypeAs, &e.ErrDuplicateData{})
+		})
+		Convey("-> Creating something else than a user should fail", func() {
+			So(repo.Create("hanzo"), ShouldNotBeNil)
+		})
+	})
+}
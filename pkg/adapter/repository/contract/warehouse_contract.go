@@ -0,0 +1,39 @@
+package contract
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/yauritux/cartsvc/pkg/domain/repository"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+	uc "github.com/yauritux/cartsvc/pkg/usecase/products"
+)
+
+// TestWarehouseRepository runs the warehouse contract upon a repository holding the seeded warehouses
+func TestWarehouseRepository(t *testing.T, newRepo func(t *testing.T) repository.WarehouseRepository) {
+
+	Convey("Warehouse contract", t, func() {
+		repo := newRepo(t)
+
+		Convey("-> Should list the seeded warehouses in their seeding order", func() {
+			res, err := repo.FetchAll()
+			So(err, ShouldBeNil)
+			ids := make([]string, 0)
+			for _, w := range res.([]*uc.Warehouse) {
+				ids = append(ids, w.ID)
+			}
+			So(ids, ShouldResemble, []string{"main", "surabaya", "medan"})
+		})
+		Convey("-> Should find a warehouse by its ID", func() {
+			res, err := repo.FindByWarehouseID("surabaya")
+			So(err, ShouldBeNil)
+			So(res.(*uc.Warehouse).City, ShouldEqual, "Surabaya")
+		})
+		Convey("-> Should return ErrNoData for an unknown warehouse", func() {
+			res, err := repo.FindByWarehouseID("contract-404")
+			So(res, ShouldBeNil)
+			So(err, ShouldHaveSameTypeAs, &e.ErrNoData{})
+		})
+	})
+}
@@ -0,0 +1,138 @@
+package file
+
+import (
+	"time"
+
+	"github.com/yauritux/cartsvc/pkg/adapter/repository/inmem"
+	"github.com/yauritux/cartsvc/pkg/adapter/repository/inmem/model"
+)
+
+const cartsFile = "carts"
+
+type CartRepository struct {
+	store *Store
+}
+
+func NewCartRepository(s *Store) *CartRepository {
+	return &CartRepository{store: s}
+}
+
+// Open makes sure the user owns an open cart, a new one is created when he has got none
+func (r *CartRepository) Open(uid string, openedAt time.Time) error {
+	return r.update(func(repo *inmem.CartRepository) error {
+		return repo.Open(uid, openedAt)
+	})
+}
+
+func (r *CartRepository) FetchUserCart(userID string) (interface{}, error) {
+	var records []*model.Cart
+	if err := r.store.view(cartsFile, &records); err != nil {
+		return nil, err
+	}
+	return inmem.NewCartRepositoryWith(records).FetchUserCart(userID)
+}
+
+func (r *CartRepository) FetchLatestCart(userID string) (interface{}, error) {
+	var records []*model.Cart
+	if err := r.store.view(cartsFile, &records); err != nil {
+		return nil, err
+	}
+	return inmem.NewCartRepositoryWith(records).FetchLatestCart(userID)
+}
+
+func (r *CartRepository) AddToCart(cartID string, item interface{}, at time.Time) error {
+	return r.update(func(repo *inmem.CartRepository) error {
+		return repo.AddToCart(cartID, item, at)
+	})
+}
+
+func (r *CartRepository) RemoveItem(cartID string, itemID string, at time.Time) error {
+	return r.update(func(repo *inmem.CartRepository) error {
+		return repo.RemoveItem(cartID, itemID, at)
+	})
+}
+
+func (r *CartRepository) UpdateItem(cartID string, item interface{}, at time.Time) error {
+	return r.update(func(repo *inmem.CartRepository) error {
+		return repo.UpdateItem(cartID, item, at)
+	})
+}
+
+func (r *CartRepository) Checkout(cartID string, checkedOutAt time.Time) interface{} {
+	var res interface{}
+	err := r.update(func(repo *inmem.CartRepository) error {
+		res = repo.Checkout(cartID, checkedOutAt)
+		if err, ok := res.(error); ok {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return res
+}
+
+func (r *CartRepository) Canceled(cartID string, canceledAt time.Time) error {
+	return r.update(func(repo *inmem.CartRepository) error {
+		return repo.Canceled(cartID, canceledAt)
+	})
+}
+
+func (r *CartRepository) Close(cartID string, closedAt time.Time) error {
+	return r.update(func(repo *inmem.CartRepository) error {
+		return repo.Close(cartID, closedAt)
+	})
+}
+
+func (r *CartRepository) Refunded(cartID string) error {
+	return r.update(func(repo *inmem.CartRepository) error {
+		return repo.Refunded(cartID)
+	})
+}
+
+func (r *CartRepository) RecordReminder(cartID string, sentAt time.Time) error {
+	return r.update(func(repo *inmem.CartRepository) error {
+		return repo.RecordReminder(cartID, sentAt)
+	})
+}
+
+func (r *CartRepository) RecordShipments(cartID string, shipments interface{}) error {
+	return r.update(func(repo *inmem.CartRepository) error {
+		return repo.RecordShipments(cartID, shipments)
+	})
+}
+
+func (r *CartRepository) RecordRedemption(cartID string, redemption interface{}) error {
+	return r.update(func(repo *inmem.CartRepository) error {
+		return repo.RecordRedemption(cartID, redemption)
+	})
+}
+
+func (r *CartRepository) FetchIdleCarts(idleSince time.Time) (interface{}, error) {
+	var records []*model.Cart
+	if err := r.store.view(cartsFile, &records); err != nil {
+		return nil, err
+	}
+	return inmem.NewCartRepositoryWith(records).FetchIdleCarts(idleSince)
+}
+
+func (r *CartRepository) FetchOrders(userID string, since time.Time) (interface{}, error) {
+	var records []*model.Cart
+	if err := r.store.view(cartsFile, &records); err != nil {
+		return nil, err
+	}
+	return inmem.NewCartRepositoryWith(records).FetchOrders(userID, since)
+}
+
+func (r *CartRepository) update(fn func(*inmem.CartRepository) error) error {
+	var records []*model.Cart
+	return r.store.update(cartsFile, &records, func() error {
+		repo := inmem.NewCartRepositoryWith(records)
+		if err := fn(repo); err != nil {
+			return err
+		}
+		records = repo.Records()
+		return nil
+	})
+}
@@ -0,0 +1,68 @@
+package file
+
+import (
+	"os"
+	"testing"
+
+	"github.com/yauritux/cartsvc/pkg/adapter/repository/contract"
+	"github.com/yauritux/cartsvc/pkg/domain/repository"
+)
+
+// contractStore opens a store upon a new directory which is removed at the end of the test
+func contractStore(t *testing.T) *Store {
+	dir := tempDir(t)
+	s := openStore(t, dir)
+	t.Cleanup(func() {
+		s.Close()
+		os.RemoveAll(dir)
+	})
+	return s
+}
+
+func TestCartRepositoryContract(t *testing.T) {
+	contract.TestCartRepository(t, func(t *testing.T) repository.CartRepository {
+		return NewCartRepository(contractStore(t))
+	})
+}
+
+func TestProductRepositoryContract(t *testing.T) {
+	contract.TestProductRepository(t, func(t *testing.T) repository.ProductRepository {
+		r, err := NewProductRepository(contractStore(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	})
+}
+
+func TestUserRepositoryContract(t *testing.T) {
+	contract.TestUserRepository(t, func(t *testing.T) repository.UserRepository {
+		r, err := NewUserRepository(contractStore(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	})
+}
+
+func TestSubscriptionRepositoryContract(t *testing.T) {
+	contract.TestSubscriptionRepository(t, func(t *testing.T) repository.SubscriptionRepository {
+		return NewSubscriptionRepository(contractStore(t))
+	})
+}
+
+func TestLoyaltyRepositoryContract(t *testing.T) {
+	contract.TestLoyaltyRepository(t, func(t *testing.T) repository.LoyaltyRepository {
+		return NewLoyaltyRepository(contractStore(t))
+	})
+}
+
+func TestWarehouseRepositoryContract(t *testing.T) {
+	contract.TestWarehouseRepository(t, func(t *testing.T) repository.WarehouseRepository {
+		r, err := NewWarehouseRepository(contractStore(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	})
+}
@@ -0,0 +1,36 @@
+package file
+
+import (
+	"github.com/yauritux/cartsvc/pkg/adapter/repository/inmem"
+	"github.com/yauritux/cartsvc/pkg/adapter/repository/inmem/model"
+)
+
+const loyaltyFile = "loyalty"
+
+type LoyaltyRepository struct {
+	store *Store
+}
+
+func NewLoyaltyRepository(s *Store) *LoyaltyRepository {
+	return &LoyaltyRepository{store: s}
+}
+
+func (r *LoyaltyRepository) FetchLedger(userID string) (interface{}, error) {
+	var records []*model.Ledger
+	if err := r.store.view(loyaltyFile, &records); err != nil {
+		return nil, err
+	}
+	return inmem.NewLoyaltyRepositoryWith(records).FetchLedger(userID)
+}
+
+func (r *LoyaltyRepository) SaveLedger(ledger interface{}) error {
+	var records []*model.Ledger
+	return r.store.update(loyaltyFile, &records, func() error {
+		repo := inmem.NewLoyaltyRepositoryWith(records)
+		if err := repo.SaveLedger(ledger); err != nil {
+			return err
+		}
+		records = repo.Records()
+		return nil
+	})
+}
@@ -0,0 +1,90 @@
+package file
+
+import (
+	"github.com/yauritux/cartsvc/pkg/adapter/repository/inmem"
+	"github.com/yauritux/cartsvc/pkg/adapter/repository/inmem/model"
+)
+
+const productsFile = "products"
+
+// ProductRepository persists the products into the store, the queries themselves are run
+// by the inmem repository upon the records read from the file
+type ProductRepository struct {
+	store *Store
+}
+
+// NewProductRepository seeds the store with the inmem catalog when it has got no product yet
+func NewProductRepository(s *Store) (*ProductRepository, error) {
+	err := s.seed(productsFile, func() interface{} {
+		return inmem.NewProductRepository().Records()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ProductRepository{store: s}, nil
+}
+
+func (r *ProductRepository) FindByProductID(id string) (interface{}, error) {
+	var res interface{}
+	err := r.view(func(repo *inmem.ProductRepository) (err error) {
+		res, err = repo.FindByProductID(id)
+		return err
+	})
+	return res, err
+}
+
+func (r *ProductRepository) FindAnyByProductID(id string) (interface{}, error) {
+	var res interface{}
+	err := r.view(func(repo *inmem.ProductRepository) (err error) {
+		res, err = repo.FindAnyByProductID(id)
+		return err
+	})
+	return res, err
+}
+
+func (r *ProductRepository) Create(product interface{}) error {
+	return r.update(func(repo *inmem.ProductRepository) error {
+		return repo.Create(product)
+	})
+}
+
+func (r *ProductRepository) Update(product interface{}) error {
+	return r.update(func(repo *inmem.ProductRepository) error {
+		return repo.Update(product)
+	})
+}
+
+func (r *ProductRepository) Delete(id string) error {
+	return r.update(func(repo *inmem.ProductRepository) error {
+		return repo.Delete(id)
+	})
+}
+
+func (r *ProductRepository) Search(query interface{}) (interface{}, error) {
+	var res interface{}
+	err := r.view(func(repo *inmem.ProductRepository) (err error) {
+		res, err = repo.Search(query)
+		return err
+	})
+	return res, err
+}
+
+func (r *ProductRepository) view(fn func(*inmem.ProductRepository) error) error {
+	var records []*model.Product
+	if err := r.store.view(productsFile, &records); err != nil {
+		return err
+	}
+	return fn(inmem.NewProductRepositoryWith(records))
+}
+
+func (r *ProductRepository) update(fn func(*inmem.ProductRepository) error) error {
+	var records []*model.Product
+	return r.store.update(productsFile, &records, func() error {
+		repo := inmem.NewProductRepositoryWith(records)
+		if err := fn(repo); err != nil {
+			return err
+		}
+		records = repo.Records()
+		return nil
+	})
+}
@@ -0,0 +1,212 @@
+package file
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	lockName   = ".lock"
+	tempSuffix = ".tmp-"
+)
+
+// rename is swapped by the tests to simulate a crash right before the data file is replaced
+var rename = os.Rename
+
+// Store keeps every collection of documents as a JSON file inside a directory. Each read
+// takes a shared lock and each write an exclusive one on the lock file of the directory,
+// so several processes can safely share the same data.
+type Store struct {
+	dir         string
+	lockFile    *os.File
+	lockTimeout time.Duration
+	mu          sync.Mutex
+}
+
+type Option func(*Store)
+
+// WithLockTimeout sets how long to wait for another process to release the data directory
+func WithLockTimeout(d time.Duration) Option {
+	return func(s *Store) {
+		s.lockTimeout = d
+	}
+}
+
+// Open opens the data directory, creating it when needed, and cleans up the temporary
+// files left behind by a process which crashed while writing
+func Open(dir string, opts ...Option) (*Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	lockFile, err := os.OpenFile(filepath.Join(dir, lockName), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Store{dir: dir, lockFile: lockFile, lockTimeout: 5 * time.Second}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if err := s.recover(); err != nil {
+		lockFile.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Store) Close() error {
+	return s.lockFile.Close()
+}
+
+// view decodes the collection into v, v is left untouched when the collection does not exist yet
+func (s *Store) view(name string, v interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.lock(false); err != nil {
+		return err
+	}
+	defer unlockFile(s.lockFile)
+
+	return s.read(name, v)
+}
+
+// update decodes the collection into v, runs fn and then writes v back, nothing is written
+// when fn fails
+func (s *Store) update(name string, v interface{}, fn func() error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.lock(true); err != nil {
+		return err
+	}
+	defer unlockFile(s.lockFile)
+
+	if err := s.read(name, v); err != nil {
+		return err
+	}
+	if err := fn(); err != nil {
+		return err
+	}
+	return s.write(name, v)
+}
+
+// seed writes the collection out of fn when it does not exist yet
+func (s *Store) seed(name string, fn func() interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.lock(true); err != nil {
+		return err
+	}
+	defer unlockFile(s.lockFile)
+
+	if _, err := os.Stat(s.path(name)); !os.IsNotExist(err) {
+		return err
+	}
+	return s.write(name, fn())
+}
+
+func (s *Store) read(name string, v interface{}) error {
+	data, err := ioutil.ReadFile(s.path(name))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("data file %s is corrupted: %v", s.path(name), err)
+	}
+	return nil
+}
+
+// write replaces the collection atomically, the data is first written into a temporary file
+// which is then renamed over the previous one
+func (s *Store) write(name string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(s.dir, name+tempSuffix)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := rename(tmp.Name(), s.path(name)); err != nil {
+		return err
+	}
+	return s.syncDir()
+}
+
+func (s *Store) syncDir() error {
+	dir, err := os.Open(s.dir)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	// some platforms cannot sync a directory, the rename is already done anyway
+	dir.Sync()
+	return nil
+}
+
+func (s *Store) recover() error {
+	if err := s.lock(true); err != nil {
+		return err
+	}
+	defer unlockFile(s.lockFile)
+
+	files, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if strings.Contains(f.Name(), tempSuffix) {
+			if err := os.Remove(filepath.Join(s.dir, f.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Store) lock(exclusive bool) error {
+	deadline := time.Now().Add(s.lockTimeout)
+	for {
+		locked, err := tryLockFile(s.lockFile, exclusive)
+		if err != nil {
+			return err
+		}
+		if locked {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("data directory %s is locked by another process", s.dir)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func (s *Store) path(name string) string {
+	return filepath.Join(s.dir, name+".json")
+}
@@ -0,0 +1,175 @@
+package file
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+	cartUsecase "github.com/yauritux/cartsvc/pkg/usecase/carts"
+	prodUsecase "github.com/yauritux/cartsvc/pkg/usecase/products"
+)
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "cartsvc-file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func openStore(t *testing.T, dir string) *Store {
+	s, err := Open(dir, WithLockTimeout(100*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestFileRepositories(t *testing.T) {
+
+	Convey("1. Given the repositories are reopened upon the same directory", t, func() {
+		dir := tempDir(t)
+		defer os.RemoveAll(dir)
+
+		s := openStore(t, dir)
+		products, err := NewProductRepository(s)
+		So(err, ShouldBeNil)
+		users, err := NewUserRepository(s)
+		So(err, ShouldBeNil)
+		carts := NewCartRepository(s)
+
+		So(products.Create(&prodUsecase.Product{ID: "005", Name: "Kunai", Stock: 10, Price: 99}), ShouldBeNil)
+		So(products.Delete("002"), ShouldBeNil)
+		So(carts.Open("yauritux", time.Now()), ShouldBeNil)
+		c, _ := carts.FetchUserCart("yauritux")
+		cartID := c.(*cartUsecase.Cart).ID
+		So(carts.AddToCart(cartID, &cartUsecase.CartItem{ID: "005", Name: "Kunai", Qty: 2, Price: 99}, time.Now()), ShouldBeNil)
+		So(s.Close(), ShouldBeNil)
+
+		s = openStore(t, dir)
+		defer s.Close()
+		products, _ = NewProductRepository(s)
+		users, _ = NewUserRepository(s)
+		carts = NewCartRepository(s)
+
+		Convey("-> The seeded data should not be seeded again", func() {
+			_, err := products.FindByProductID("002")
+			So(err, ShouldNotBeNil)
+			u, err := users.FindByUserID("yauritux")
+			So(err, ShouldBeNil)
+			So(u, ShouldNotBeNil)
+		})
+		Convey("-> The changes should be found again", func() {
+			p, err := products.FindByProductID("005")
+			So(err, ShouldBeNil)
+			So(p.(*prodUsecase.Product).Name, ShouldEqual, "Kunai")
+
+			page, err := products.Search(&prodUsecase.ProductQuery{Keyword: "kunai", Limit: 10})
+			So(err, ShouldBeNil)
+			So(page.(*prodUsecase.ProductPage).Items, ShouldHaveLength, 1)
+
+			c, err := carts.FetchUserCart("yauritux")
+			So(err, ShouldBeNil)
+			So(c.(*cartUsecase.Cart).ID, ShouldEqual, cartID)
+			So(c.(*cartUsecase.Cart).Items, ShouldHaveLength, 1)
+			So(c.(*cartUsecase.Cart).Items[0].Qty, ShouldEqual, 2)
+		})
+		Convey("-> A failed change should not be saved", func() {
+			So(carts.Canceled(cartID, time.Now()), ShouldBeNil)
+			So(carts.Canceled(cartID, time.Now()), ShouldNotBeNil)
+			res := carts.Checkout(cartID, time.Now())
+			_, failed := res.(error)
+			So(failed, ShouldBeTrue)
+
+			c, _ := carts.FetchLatestCart("yauritux")
+			So(c.(*cartUsecase.Cart).Status, ShouldEqual, enum.Canceled)
+		})
+	})
+
+	Convey("2. Given two stores upon the same directory", t, func() {
+		dir := tempDir(t)
+		defer os.RemoveAll(dir)
+		s1 := openStore(t, dir)
+		defer s1.Close()
+		s2 := openStore(t, dir)
+		defer s2.Close()
+
+		Convey("-> Neither store should lose the changes of the other one", func() {
+			p1, _ := NewProductRepository(s1)
+			p2, _ := NewProductRepository(s2)
+			So(p1.Create(&prodUsecase.Product{ID: "005", Name: "Kunai", Stock: 1, Price: 1}), ShouldBeNil)
+			So(p2.Create(&prodUsecase.Product{ID: "006", Name: "Kusarigama", Stock: 1, Price: 1}), ShouldBeNil)
+
+			_, err := p1.FindByProductID("006")
+			So(err, ShouldBeNil)
+			_, err = p2.FindByProductID("005")
+			So(err, ShouldBeNil)
+		})
+		Convey("-> A store should wait for the lock held by the other one", func() {
+			So(s1.lock(true), ShouldBeNil)
+			err := s2.view(productsFile, &[]interface{}{})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "data directory "+dir+" is locked by another process")
+
+			So(unlockFile(s1.lockFile), ShouldBeNil)
+			So(s2.view(productsFile, &[]interface{}{}), ShouldBeNil)
+		})
+	})
+}
+
+func TestCrashRecovery(t *testing.T) {
+
+	Convey("Given a process crashing while writing", t, func() {
+		dir := tempDir(t)
+		defer os.RemoveAll(dir)
+		s := openStore(t, dir)
+		products, err := NewProductRepository(s)
+		So(err, ShouldBeNil)
+
+		Convey("-> The previous data should be kept when the data file cannot be replaced", func() {
+			rename = func(string, string) error { return errors.New("disk unplugged") }
+			defer func() { rename = os.Rename }()
+
+			err := products.Create(&prodUsecase.Product{ID: "005", Name: "Kunai", Stock: 1, Price: 1})
+			So(err.Error(), ShouldEqual, "disk unplugged")
+
+			rename = os.Rename
+			_, err = products.FindByProductID("005")
+			So(err, ShouldNotBeNil)
+			_, err = products.FindByProductID("001")
+			So(err, ShouldBeNil)
+
+			files, _ := filepath.Glob(filepath.Join(dir, "*"+tempSuffix+"*"))
+			So(files, ShouldBeEmpty)
+		})
+		Convey("-> The partially written files should be removed when reopening the directory", func() {
+			So(s.Close(), ShouldBeNil)
+			partial := filepath.Join(dir, productsFile+tempSuffix+"123")
+			So(ioutil.WriteFile(partial, []byte(`[{"ID":"00`), 0644), ShouldBeNil)
+
+			s = openStore(t, dir)
+			defer s.Close()
+			_, err := os.Stat(partial)
+			So(os.IsNotExist(err), ShouldBeTrue)
+
+			products, err := NewProductRepository(s)
+			So(err, ShouldBeNil)
+			_, err = products.FindByProductID("001")
+			So(err, ShouldBeNil)
+		})
+		Convey("-> A corrupted data file should be reported rather than overwritten", func() {
+			path := filepath.Join(dir, productsFile+".json")
+			So(ioutil.WriteFile(path, []byte(`[{"ID":"00`), 0644), ShouldBeNil)
+
+			err := products.Create(&prodUsecase.Product{ID: "005", Name: "Kunai", Stock: 1, Price: 1})
+			So(err, ShouldNotBeNil)
+			data, _ := ioutil.ReadFile(path)
+			So(string(data), ShouldEqual, `[{"ID":"00`)
+		})
+	})
+}
@@ -0,0 +1,56 @@
+package file
+
+import (
+	"github.com/yauritux/cartsvc/pkg/adapter/repository/inmem"
+	"github.com/yauritux/cartsvc/pkg/adapter/repository/inmem/model"
+)
+
+const subscriptionsFile = "subscriptions"
+
+type SubscriptionRepository struct {
+	store *Store
+}
+
+func NewSubscriptionRepository(s *Store) *SubscriptionRepository {
+	return &SubscriptionRepository{store: s}
+}
+
+func (r *SubscriptionRepository) FetchUserSubscriptions(userID string) (interface{}, error) {
+	var records []*model.Subscription
+	if err := r.store.view(subscriptionsFile, &records); err != nil {
+		return nil, err
+	}
+	return inmem.NewSubscriptionRepositoryWith(records).FetchUserSubscriptions(userID)
+}
+
+func (r *SubscriptionRepository) FetchSKUSubscriptions(sku string) (interface{}, error) {
+	var records []*model.Subscription
+	if err := r.store.view(subscriptionsFile, &records); err != nil {
+		return nil, err
+	}
+	return inmem.NewSubscriptionRepositoryWith(records).FetchSKUSubscriptions(sku)
+}
+
+func (r *SubscriptionRepository) Save(subscription interface{}) error {
+	return r.update(func(repo *inmem.SubscriptionRepository) error {
+		return repo.Save(subscription)
+	})
+}
+
+func (r *SubscriptionRepository) Remove(userID string, sku string) error {
+	return r.update(func(repo *inmem.SubscriptionRepository) error {
+		return repo.Remove(userID, sku)
+	})
+}
+
+func (r *SubscriptionRepository) update(fn func(*inmem.SubscriptionRepository) error) error {
+	var records []*model.Subscription
+	return r.store.update(subscriptionsFile, &records, func() error {
+		repo := inmem.NewSubscriptionRepositoryWith(records)
+		if err := fn(repo); err != nil {
+			return err
+		}
+		records = repo.Records()
+		return nil
+	})
+}
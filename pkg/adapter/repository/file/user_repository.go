@@ -0,0 +1,43 @@
+package file
+
+import (
+	"github.com/yauritux/cartsvc/pkg/adapter/repository/inmem"
+	"github.com/yauritux/cartsvc/pkg/adapter/repository/inmem/model"
+)
+
+const usersFile = "users"
+
+type UserRepository struct {
+	store *Store
+}
+
+// NewUserRepository seeds the store with the inmem users when it has got no user yet
+func NewUserRepository(s *Store) (*UserRepository, error) {
+	err := s.seed(usersFile, func() interface{} {
+		return inmem.NewUserRepository().Records()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &UserRepository{store: s}, nil
+}
+
+func (r *UserRepository) FindByUserID(uid string) (interface{}, error) {
+	var records []*model.User
+	if err := r.store.view(usersFile, &records); err != nil {
+		return nil, err
+	}
+	return inmem.NewUserRepositoryWith(records).FindByUserID(uid)
+}
+
+func (r *UserRepository) Create(user interface{}) error {
+	var records []*model.User
+	return r.store.update(usersFile, &records, func() error {
+		repo := inmem.NewUserRepositoryWith(records)
+		if err := repo.Create(user); err != nil {
+			return err
+		}
+		records = repo.Records()
+		return nil
+	})
+}
@@ -0,0 +1,39 @@
+package file
+
+import (
+	"github.com/yauritux/cartsvc/pkg/adapter/repository/inmem"
+	"github.com/yauritux/cartsvc/pkg/adapter/repository/inmem/model"
+)
+
+const warehousesFile = "warehouses"
+
+type WarehouseRepository struct {
+	store *Store
+}
+
+// NewWarehouseRepository seeds the store with the inmem warehouses when it has got no warehouse yet
+func NewWarehouseRepository(s *Store) (*WarehouseRepository, error) {
+	err := s.seed(warehousesFile, func() interface{} {
+		return inmem.NewWarehouseRepository().Records()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &WarehouseRepository{store: s}, nil
+}
+
+func (r *WarehouseRepository) FindByWarehouseID(id string) (interface{}, error) {
+	var records []*model.Warehouse
+	if err := r.store.view(warehousesFile, &records); err != nil {
+		return nil, err
+	}
+	return inmem.NewWarehouseRepositoryWith(records).FindByWarehouseID(id)
+}
+
+func (r *WarehouseRepository) FetchAll() (interface{}, error) {
+	var records []*model.Warehouse
+	if err := r.store.view(warehousesFile, &records); err != nil {
+		return nil, err
+	}
+	return inmem.NewWarehouseRepositoryWith(records).FetchAll()
+}
@@ -3,48 +3,84 @@ package inmem
 import (
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/lucsky/cuid"
 
 	"github.com/yauritux/cartsvc/pkg/adapter/repository/inmem/model"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
 	uc "github.com/yauritux/cartsvc/pkg/usecase/carts"
 )
 
-var carts []*model.Cart
+// carts is shared by every repository created through NewCartRepository
+var carts = &cartStore{}
+
+type cartStore struct {
+	mu      sync.RWMutex
+	records []*model.Cart
+}
 
 type CartRepository struct {
-	currentCart *model.Cart
+	store *cartStore
 }
 
-func NewCartRepository(uid string) *CartRepository {
-	if carts == nil {
-		carts = []*model.Cart{
-			newCart(uid),
-		}
-		return &CartRepository{carts[0]}
-	}
+func NewCartRepository() *CartRepository {
+	return &CartRepository{store: carts}
+}
+
+// NewCartRepositoryWith creates the repository upon its own cart records, apart from the shared ones
+func NewCartRepositoryWith(records []*model.Cart) *CartRepository {
+	return &CartRepository{store: &cartStore{records: records}}
+}
 
-	for i, v := range carts {
+// Open makes sure the user owns an open cart, a new one is created when he has got none
+func (r *CartRepository) Open(uid string, openedAt time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	for _, v := range r.store.records {
 		if v.UserID == uid && v.Status == "open" {
-			return &CartRepository{carts[i]}
+			return nil
 		}
 	}
-	carts = append(carts, newCart(uid))
-	return &CartRepository{carts[len(carts)-1]}
+	r.store.records = append(r.store.records, newCart(uid, openedAt))
+	return nil
+}
+
+func (r *CartRepository) Records() []*model.Cart {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	return r.store.records
 }
 
 func (r *CartRepository) FetchUserCart(userID string) (interface{}, error) {
-	for i, v := range carts {
-		if v.UserID == userID {
-			return buildCartUsecaseModel(carts[i]), nil
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	for _, v := range r.store.records {
+		if v.UserID == userID && v.Status == "open" {
+			return buildCartUsecaseModel(v), nil
+		}
+	}
+
+	return nil, e.NewErrNoData("no open cart found for user " + userID)
+}
+
+// FetchLatestCart returns the cart the user opened last, the carts being kept in the order they were opened
+func (r *CartRepository) FetchLatestCart(userID string) (interface{}, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	for i := len(r.store.records) - 1; i >= 0; i-- {
+		if v := r.store.records[i]; v.UserID == userID {
+			return buildCartUsecaseModel(v), nil
 		}
 	}
 
-	return nil, nil
+	return nil, e.NewErrNoData("no cart found for user " + userID)
 }
 
-func (r *CartRepository) AddToCart(cartID string, item interface{}) error {
+func (r *CartRepository) AddToCart(cartID string, item interface{}, at time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
 	currUserCart, err := r.getCurrentUserCart(cartID)
 	if err != nil {
 		return err
@@ -61,11 +97,13 @@ func (r *CartRepository) AddToCart(cartID string, item interface{}) error {
 	}
 
 	currUserCart.Items = append(currUserCart.Items, r.BuildCartItemRepositoryModel(cartItem).(*model.CartItem))
-	r.currentCart.Items = currUserCart.Items
+	currUserCart.LastActivityAt = at
 	return nil
 }
 
-func (r *CartRepository) RemoveItem(id string, itemID string) error {
+func (r *CartRepository) RemoveItem(id string, itemID string, at time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
 	currUserCart, err := r.getCurrentUserCart(id)
 	if err != nil {
 		return err
@@ -76,20 +114,20 @@ func (r *CartRepository) RemoveItem(id string, itemID string) error {
 			id, currUserCart.Status)
 	}
 
-	var newCartItems []*model.CartItem
-
 	for i, v := range currUserCart.Items {
-		if v.ID != itemID {
+		if cartItemSKU(v) != itemID {
 			continue
 		}
-		newCartItems = make([]*model.CartItem, 0)
-		newCartItems = append(newCartItems[:i], newCartItems[i+1:]...)
-		currUserCart.Items = newCartItems
+		currUserCart.Items = append(currUserCart.Items[:i], currUserCart.Items[i+1:]...)
+		currUserCart.LastActivityAt = at
+		return nil
 	}
-	return nil
+	return e.NewErrNoData(fmt.Sprintf("cannot find cart item with ID %s", itemID))
 }
 
-func (r *CartRepository) UpdateItem(id string, item interface{}) error {
+func (r *CartRepository) UpdateItem(id string, item interface{}, at time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
 	currUserCart, err := r.getCurrentUserCart(id)
 	if err != nil {
 		return err
@@ -107,16 +145,18 @@ func (r *CartRepository) UpdateItem(id string, item interface{}) error {
 
 	updatedCartItem := r.BuildCartItemRepositoryModel(cartItem).(*model.CartItem)
 	for i, v := range currUserCart.Items {
-		if v.ID == updatedCartItem.ID {
+		if cartItemSKU(v) == cartItemSKU(updatedCartItem) {
 			currUserCart.Items[i] = updatedCartItem
-			break
+			currUserCart.LastActivityAt = at
+			return nil
 		}
 	}
-
-	return nil
+	return e.NewErrNoData(fmt.Sprintf("cannot find cart item with ID %s", cartItemSKU(updatedCartItem)))
 }
 
-func (r *CartRepository) Checkout(id string) interface{} {
+func (r *CartRepository) Checkout(id string, checkedOutAt time.Time) interface{} {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
 	currUserCart, err := r.getCurrentUserCart(id)
 	if err != nil {
 		return err
@@ -127,24 +167,90 @@ func (r *CartRepository) Checkout(id string) interface{} {
 			id, currUserCart.Status)
 	}
 
-	return currUserCart
+	currUserCart.Status = "payment_processing"
+	currUserCart.LastActivityAt = checkedOutAt
+	currUserCart.CheckedOutAt = &checkedOutAt
+	return buildCartUsecaseModel(currUserCart)
 }
 
-func (r *CartRepository) Canceled(id string) error {
+func (r *CartRepository) Canceled(id string, canceledAt time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
 	currUserCart, err := r.getCurrentUserCart(id)
 	if err != nil {
 		return err
 	}
 
-	if currUserCart.Status != "open" {
+	// the payment of a checked out cart may be abandoned as well
+	if currUserCart.Status != "open" && currUserCart.Status != "payment_processing" {
 		return fmt.Errorf("cannot cancel the cart with status of %s", currUserCart.Status)
 	}
 
 	currUserCart.Status = "canceled"
+	currUserCart.CanceledAt = &canceledAt
 	return nil
 }
 
-func (r *CartRepository) Close(id string) error {
+func (r *CartRepository) RecordReminder(id string, sentAt time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	currUserCart, err := r.getCurrentUserCart(id)
+	if err != nil {
+		return err
+	}
+
+	if currUserCart.Status != "open" {
+		return fmt.Errorf("cannot remind the cart with status of %s", currUserCart.Status)
+	}
+
+	currUserCart.RemindersSent++
+	currUserCart.LastRemindedAt = &sentAt
+	return nil
+}
+
+func (r *CartRepository) RecordShipments(id string, shipments interface{}) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	currUserCart, err := r.getCurrentUserCart(id)
+	if err != nil {
+		return err
+	}
+
+	if shipments == nil {
+		currUserCart.Shipments = nil
+		return nil
+	}
+	ucShipments, ok := shipments.([]*uc.Shipment)
+	if !ok {
+		return e.NewErrConversion("cannot record shipments, invalid type of shipment usecase model")
+	}
+	currUserCart.Shipments = buildShipmentRepositoryModels(ucShipments)
+	return nil
+}
+
+func (r *CartRepository) RecordRedemption(id string, redemption interface{}) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	currUserCart, err := r.getCurrentUserCart(id)
+	if err != nil {
+		return err
+	}
+
+	if redemption == nil {
+		currUserCart.Redemption = nil
+		return nil
+	}
+	ucRedemption, ok := redemption.(*uc.PointsRedemption)
+	if !ok {
+		return e.NewErrConversion("cannot record redemption, invalid type of points redemption usecase model")
+	}
+	currUserCart.Redemption = &model.PointsRedemption{Points: ucRedemption.Points, Amount: ucRedemption.Amount}
+	return nil
+}
+
+func (r *CartRepository) Close(id string, closedAt time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
 	currUserCart, err := r.getCurrentUserCart(id)
 	if err != nil {
 		return err
@@ -156,34 +262,109 @@ func (r *CartRepository) Close(id string) error {
 	}
 
 	currUserCart.Status = "closed"
+	currUserCart.ClosedAt = &closedAt
 
 	return nil
 }
 
+func (r *CartRepository) Refunded(id string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	currUserCart, err := r.getCurrentUserCart(id)
+	if err != nil {
+		return err
+	}
+
+	if currUserCart.Status != "closed" {
+		return fmt.Errorf("failed to refund cart with ID of %s. It is %s, only a closed cart can be refunded",
+			id, currUserCart.Status)
+	}
+
+	currUserCart.Status = "refunded"
+
+	return nil
+}
+
+func (r *CartRepository) FetchIdleCarts(idleSince time.Time) (interface{}, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	idleCarts := make([]*uc.Cart, 0)
+	for _, v := range r.store.records {
+		if v.Status != "open" && v.Status != "payment_processing" {
+			continue
+		}
+		if lastActivity(v).Before(idleSince) {
+			idleCarts = append(idleCarts, buildCartUsecaseModel(v))
+		}
+	}
+	return idleCarts, nil
+}
+
+func (r *CartRepository) FetchOrders(userID string, since time.Time) (interface{}, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	orders := make([]*uc.Cart, 0)
+	for _, v := range r.store.records {
+		if v.UserID != userID || (v.Status != "payment_processing" && v.Status != "closed") {
+			continue
+		}
+		if !checkedOutAt(v).Before(since) {
+			orders = append(orders, buildCartUsecaseModel(v))
+		}
+	}
+	return orders, nil
+}
+
 func (r *CartRepository) BuildCartItemRepositoryModel(item *uc.CartItem) interface{} {
+	return buildCartItemRepositoryModel(item)
+}
+
+func buildCartItemRepositoryModel(item *uc.CartItem) *model.CartItem {
 	return &model.CartItem{
-		ID:    item.ID,
-		Name:  item.Name,
-		Qty:   item.Qty,
-		Price: item.Price,
-		Disc:  item.Disc,
+		ID:          item.ID,
+		Name:        item.Name,
+		SKU:         item.SKU,
+		Options:     copyOptions(item.Options),
+		Qty:         item.Qty,
+		Price:       item.Price,
+		Disc:        item.Disc,
+		Components:  buildCartItemComponentRepositoryModels(item.Components),
+		Fulfillment: item.Fulfillment,
+		AvailableAt: item.AvailableAt,
 	}
 }
 
-func newCart(uid string) *model.Cart {
+func newCart(uid string, openedAt time.Time) *model.Cart {
 	return &model.Cart{
-		ID:        cuid.New(),
-		UserID:    uid,
-		Status:    "open",
-		Items:     make([]*model.CartItem, 0),
-		CreatedAt: time.Now(),
+		ID:             cuid.New(),
+		UserID:         uid,
+		Status:         "open",
+		Items:          make([]*model.CartItem, 0),
+		CreatedAt:      openedAt,
+		LastActivityAt: openedAt,
+	}
+}
+
+// lastActivity falls back to the creation time for the carts saved before the activity was tracked
+func lastActivity(cart *model.Cart) time.Time {
+	if cart.LastActivityAt.IsZero() {
+		return cart.CreatedAt
+	}
+	return cart.LastActivityAt
+}
+
+// checkedOutAt falls back to the last activity for the carts checked out before the checkout got stamped
+func checkedOutAt(cart *model.Cart) time.Time {
+	if cart.CheckedOutAt != nil {
+		return *cart.CheckedOutAt
 	}
+	return lastActivity(cart)
 }
 
 func (r *CartRepository) getCurrentUserCart(cartID string) (*model.Cart, error) {
-	for i, v := range carts {
+	for _, v := range r.store.records {
 		if v.ID == cartID {
-			return carts[i], nil
+			return v, nil
 		}
 	}
 	return nil, fmt.Errorf("cannot find cart with id %s", cartID)
@@ -191,21 +372,111 @@ func (r *CartRepository) getCurrentUserCart(cartID string) (*model.Cart, error)
 
 func buildCartUsecaseModel(cart *model.Cart) *uc.Cart {
 	ucCart := &uc.Cart{
-		ID:        cart.ID,
-		UserID:    cart.UserID,
-		Status:    cart.Status,
-		CreatedAt: cart.CreatedAt,
+		ID:             cart.ID,
+		UserID:         cart.UserID,
+		Status:         cart.Status,
+		CreatedAt:      cart.CreatedAt,
+		LastActivityAt: lastActivity(cart),
+		CanceledAt:     cart.CanceledAt,
+		CheckedOutAt:   cart.CheckedOutAt,
+		ClosedAt:       cart.ClosedAt,
+		RemindersSent:  cart.RemindersSent,
+		LastRemindedAt: cart.LastRemindedAt,
+		Shipments:      buildShipmentUsecaseModels(cart.Shipments),
+	}
+	if cart.Redemption != nil {
+		ucCart.Redemption = &uc.PointsRedemption{Points: cart.Redemption.Points, Amount: cart.Redemption.Amount}
 	}
 	ucCartItems := make([]*uc.CartItem, 0)
 	for _, v := range cart.Items {
-		ucCartItems = append(ucCartItems, &uc.CartItem{
-			ID:    v.ID,
-			Name:  v.Name,
-			Qty:   v.Qty,
-			Price: v.Price,
-			Disc:  v.Disc,
-		})
+		ucCartItems = append(ucCartItems, buildCartItemUsecaseModel(v))
 	}
 	ucCart.Items = ucCartItems
 	return ucCart
 }
+
+func buildCartItemUsecaseModel(item *model.CartItem) *uc.CartItem {
+	return &uc.CartItem{
+		ID:          item.ID,
+		Name:        item.Name,
+		SKU:         item.SKU,
+		Options:     copyOptions(item.Options),
+		Qty:         item.Qty,
+		Price:       item.Price,
+		Disc:        item.Disc,
+		Components:  buildCartItemComponentUsecaseModels(item.Components),
+		Fulfillment: item.Fulfillment,
+		AvailableAt: item.AvailableAt,
+	}
+}
+
+func cartItemSKU(item *model.CartItem) string {
+	if item.SKU != "" {
+		return item.SKU
+	}
+	return item.ID
+}
+
+func buildCartItemComponentRepositoryModels(components []*uc.CartItemComponent) []*model.CartItemComponent {
+	if len(components) == 0 {
+		return nil
+	}
+	modelComponents := make([]*model.CartItemComponent, 0)
+	for _, c := range components {
+		modelComponents = append(modelComponents, &model.CartItemComponent{
+			ID:   c.ID,
+			Name: c.Name,
+			SKU:  c.SKU,
+			Qty:  c.Qty,
+		})
+	}
+	return modelComponents
+}
+
+func buildCartItemComponentUsecaseModels(components []*model.CartItemComponent) []*uc.CartItemComponent {
+	if len(components) == 0 {
+		return nil
+	}
+	ucComponents := make([]*uc.CartItemComponent, 0)
+	for _, c := range components {
+		ucComponents = append(ucComponents, &uc.CartItemComponent{
+			ID:   c.ID,
+			Name: c.Name,
+			SKU:  c.SKU,
+			Qty:  c.Qty,
+		})
+	}
+	return ucComponents
+}
+
+func buildShipmentRepositoryModels(shipments []*uc.Shipment) []*model.Shipment {
+	if len(shipments) == 0 {
+		return nil
+	}
+	modelShipments := make([]*model.Shipment, 0)
+	for _, s := range shipments {
+		modelShipments = append(modelShipments, &model.Shipment{
+			WarehouseID: s.WarehouseID,
+			Items:       buildCartItemComponentRepositoryModels(s.Items),
+			Fulfillment: s.Fulfillment,
+			AvailableAt: s.AvailableAt,
+		})
+	}
+	return modelShipments
+}
+
+func buildShipmentUsecaseModels(shipments []*model.Shipment) []*uc.Shipment {
+	if len(shipments) == 0 {
+		return nil
+	}
+	ucShipments := make([]*uc.Shipment, 0)
+	for _, s := range shipments {
+		ucShipments = append(ucShipments, &uc.Shipment{
+			WarehouseID: s.WarehouseID,
+			Items:       buildCartItemComponentUsecaseModels(s.Items),
+			Fulfillment: s.Fulfillment,
+			AvailableAt: s.AvailableAt,
+		})
+	}
+	return ucShipments
+}
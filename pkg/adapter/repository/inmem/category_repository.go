@@ -0,0 +1,86 @@
+package inmem
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/yauritux/cartsvc/pkg/adapter/repository/inmem/model"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+	uc "github.com/yauritux/cartsvc/pkg/usecase/categories"
+)
+
+type CategoryRepository struct {
+	mu   sync.RWMutex
+	data []*model.Category
+}
+
+func NewCategoryRepository() *CategoryRepository {
+	return NewCategoryRepositoryWith([]*model.Category{
+		{ID: "weapons", Name: "Weapons"},
+		{ID: "throwing-weapons", Name: "Throwing Weapons", ParentID: "weapons"},
+		{ID: "melee-weapons", Name: "Melee Weapons", ParentID: "weapons"},
+		{ID: "apparel", Name: "Apparel"},
+	})
+}
+
+// NewCategoryRepositoryWith creates the repository upon the given category records
+func NewCategoryRepositoryWith(records []*model.Category) *CategoryRepository {
+	return &CategoryRepository{data: records}
+}
+
+func (r *CategoryRepository) Records() []*model.Category {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.data
+}
+
+func (r *CategoryRepository) FindByCategoryID(id string) (interface{}, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, c := range r.data {
+		if c.ID == id {
+			return buildCategoryUsecaseModel(c), nil
+		}
+	}
+	return nil, e.NewErrNoData("no category found for id " + id)
+}
+
+func (r *CategoryRepository) FetchAll() (interface{}, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	categories := make([]*uc.Category, 0)
+	for _, c := range r.data {
+		categories = append(categories, buildCategoryUsecaseModel(c))
+	}
+	return categories, nil
+}
+
+func (r *CategoryRepository) Create(category interface{}) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := category.(*uc.Category)
+	if !ok {
+		return errors.New("failed to create category, invalid type of category")
+	}
+
+	for _, v := range r.data {
+		if v.ID == c.ID {
+			return e.NewErrDuplicateData("category " + c.ID + " already exists")
+		}
+	}
+
+	r.data = append(r.data, &model.Category{
+		ID:       c.ID,
+		Name:     c.Name,
+		ParentID: c.ParentID,
+	})
+	return nil
+}
+
+func buildCategoryUsecaseModel(c *model.Category) *uc.Category {
+	return &uc.Category{
+		ID:       c.ID,
+		Name:     c.Name,
+		ParentID: c.ParentID,
+	}
+}
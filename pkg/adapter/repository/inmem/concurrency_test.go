@@ -0,0 +1,88 @@
+package inmem
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/yauritux/cartsvc/pkg/adapter/repository/inmem/model"
+	cartUsecase "github.com/yauritux/cartsvc/pkg/usecase/carts"
+	prodUsecase "github.com/yauritux/cartsvc/pkg/usecase/products"
+)
+
+// run it with -race, the handlers and the sweepers share the same repositories
+func TestConcurrentUsecaseCalls(t *testing.T) {
+
+	Convey("1. Given the handlers and the sweepers sharing the in-memory repositories", t, func() {
+
+		prodRepo := NewProductRepositoryWith([]*model.Product{
+			{ID: "001", Name: "Shuriken", Stock: 100000, Price: 250.50},
+			{ID: "002", Name: "Sai", Stock: 100000, Price: 175.25},
+		})
+		cartRepo := NewCartRepositoryWith(make([]*model.Cart, 0))
+		users := []string{"hanzo", "kotaro", "sasuke", "goemon"}
+		for _, u := range users {
+			So(cartRepo.Open(u, time.Now()), ShouldBeNil)
+		}
+
+		carts := cartUsecase.NewCartUsecase(cartRepo, prodRepo, cartUsecase.WithCartTTL(time.Hour))
+		products := prodUsecase.NewProductUsecase(prodRepo)
+
+		Convey("-> Positive Scenarios", func() {
+			Convey("-> Should serve concurrent use case calls without racing", func() {
+				var wg sync.WaitGroup
+				errs := make(chan error, 1000)
+				for _, u := range users {
+					wg.Add(1)
+					go func(userID string) {
+						defer wg.Done()
+						for i := 0; i < 20; i++ {
+							if err := carts.AddToCart(userID, &cartUsecase.CartItem{ID: "001", Qty: 1}); err != nil {
+								errs <- fmt.Errorf("add to cart of %s: %v", userID, err)
+							}
+							if _, err := carts.FetchUserCart(userID); err != nil {
+								errs <- err
+							}
+						}
+					}(u)
+				}
+				wg.Add(2)
+				go func() {
+					defer wg.Done()
+					for i := 0; i < 20; i++ {
+						if _, err := products.AdjustStock("002", 1); err != nil {
+							errs <- err
+						}
+						if _, err := products.SearchProducts(&prodUsecase.ProductQuery{Keyword: "sai", Limit: 10}); err != nil {
+							errs <- err
+						}
+					}
+				}()
+				go func() {
+					defer wg.Done()
+					for i := 0; i < 20; i++ {
+						if _, err := carts.ExpireIdleCarts(); err != nil {
+							errs <- err
+						}
+					}
+				}()
+				wg.Wait()
+				close(errs)
+
+				for err := range errs {
+					So(err, ShouldBeNil)
+				}
+				for _, u := range users {
+					res, err := carts.FetchUserCart(u)
+					So(err, ShouldBeNil)
+					So(res.(*cartUsecase.Cart).Status, ShouldEqual, "open")
+				}
+				res, err := products.FindByProductID("002")
+				So(err, ShouldBeNil)
+				So(res.(*prodUsecase.Product).Stock, ShouldEqual, 100020)
+			})
+		})
+	})
+}
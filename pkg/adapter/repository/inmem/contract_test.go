@@ -0,0 +1,44 @@
+package inmem
+
+import (
+	"testing"
+
+	"github.com/yauritux/cartsvc/pkg/adapter/repository/contract"
+	"github.com/yauritux/cartsvc/pkg/domain/repository"
+)
+
+func TestCartRepositoryContract(t *testing.T) {
+	contract.TestCartRepository(t, func(*testing.T) repository.CartRepository {
+		return NewCartRepositoryWith(nil)
+	})
+}
+
+func TestProductRepositoryContract(t *testing.T) {
+	contract.TestProductRepository(t, func(*testing.T) repository.ProductRepository {
+		return NewProductRepository()
+	})
+}
+
+func TestUserRepositoryContract(t *testing.T) {
+	contract.TestUserRepository(t, func(*testing.T) repository.UserRepository {
+		return NewUserRepository()
+	})
+}
+
+func TestSubscriptionRepositoryContract(t *testing.T) {
+	contract.TestSubscriptionRepository(t, func(*testing.T) repository.SubscriptionRepository {
+		return NewSubscriptionRepository()
+	})
+}
+
+func TestLoyaltyRepositoryContract(t *testing.T) {
+	contract.TestLoyaltyRepository(t, func(*testing.T) repository.LoyaltyRepository {
+		return NewLoyaltyRepository()
+	})
+}
+
+func TestWarehouseRepositoryContract(t *testing.T) {
+	contract.TestWarehouseRepository(t, func(t *testing.T) repository.WarehouseRepository {
+		return NewWarehouseRepository()
+	})
+}
@@ -0,0 +1,82 @@
+package inmem
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/yauritux/cartsvc/pkg/adapter/event/local"
+	"github.com/yauritux/cartsvc/pkg/adapter/repository/inmem/model"
+	"github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+	cartUsecase "github.com/yauritux/cartsvc/pkg/usecase/carts"
+	categoryUsecase "github.com/yauritux/cartsvc/pkg/usecase/categories"
+	loyaltyUsecase "github.com/yauritux/cartsvc/pkg/usecase/loyalty"
+	prodUsecase "github.com/yauritux/cartsvc/pkg/usecase/products"
+)
+
+func TestCartHoldingDeletedProduct(t *testing.T) {
+
+	Convey("1. Given a cart holding a product deleted from the catalog", t, func() {
+
+		prodRepo := NewProductRepositoryWith([]*model.Product{
+			{ID: "001", Name: "Shuriken", Stock: 10, Price: 1000, CategoryIDs: []string{"throwing-weapons"}},
+			{ID: "002", Name: "Sai", Stock: 10, Price: 500},
+		})
+		cartRepo := NewCartRepositoryWith(make([]*model.Cart, 0))
+		So(cartRepo.Open("hanzo", time.Now()), ShouldBeNil)
+
+		loyalty := loyaltyUsecase.NewLoyaltyUsecase(NewLoyaltyRepository(),
+			loyaltyUsecase.WithEarningRules(&loyaltyUsecase.EarningRule{CategoryID: "weapons", Per: 100, Points: 1}),
+			loyaltyUsecase.WithCategoryMatcher(categoryUsecase.NewCategoryUsecase(NewCategoryRepository(), prodRepo)),
+		)
+		events := local.NewBus()
+		events.Subscribe(loyalty.HandleCartEvent)
+
+		carts := cartUsecase.NewCartUsecase(cartRepo, prodRepo, cartUsecase.WithEventPublisher(events))
+		products := prodUsecase.NewProductUsecase(prodRepo)
+		So(carts.AddToCart("hanzo", &cartUsecase.CartItem{ID: "001", Qty: 1}), ShouldBeNil)
+		So(carts.AddToCart("hanzo", &cartUsecase.CartItem{ID: "002", Qty: 1}), ShouldBeNil)
+
+		Convey("-> Negative Scenarios", func() {
+			Convey("-> Should not check the cart out until the deleted product is removed", func() {
+				So(products.DeleteProduct("001"), ShouldBeNil)
+
+				res, err := carts.Checkout("hanzo")
+				So(res, ShouldBeNil)
+				So(err, ShouldHaveSameTypeAs, &e.ErrConflict{})
+			})
+		})
+
+		Convey("-> Positive Scenarios", func() {
+			Convey("-> Refreshing the cart should flag the deleted product as no longer available", func() {
+				So(products.DeleteProduct("001"), ShouldBeNil)
+
+				res, err := carts.RefreshCart("hanzo")
+				So(err, ShouldBeNil)
+				changes := res.(*cartUsecase.CartRefresh).Changes
+				So(changes, ShouldHaveLength, 1)
+				So(changes[0].Kind, ShouldEqual, enum.Unavailable)
+				So(changes[0].Notice, ShouldEqual, "Shuriken is no longer available, please remove it from the cart")
+
+				So(carts.RemoveFromCart("hanzo", "001"), ShouldBeNil)
+				order, err := carts.Checkout("hanzo")
+				So(err, ShouldBeNil)
+				So(order.(*cartUsecase.Cart).Status, ShouldEqual, enum.PaymentProcessing)
+			})
+			Convey("-> An order whose product got deleted should still be closed and earn its points", func() {
+				order, err := carts.Checkout("hanzo")
+				So(err, ShouldBeNil)
+				So(products.DeleteProduct("001"), ShouldBeNil)
+
+				closed, err := carts.CloseCart("hanzo", order.(*cartUsecase.Cart).ID)
+				So(err, ShouldBeNil)
+				So(closed.(*cartUsecase.Cart).Status, ShouldEqual, enum.Closed)
+
+				balance, err := loyalty.FetchBalance("hanzo")
+				So(err, ShouldBeNil)
+				So(balance.(*loyaltyUsecase.Balance).Points, ShouldEqual, 10)
+			})
+		})
+	})
+}
@@ -0,0 +1,69 @@
+package inmem
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/yauritux/cartsvc/pkg/adapter/repository/inmem/model"
+	uc "github.com/yauritux/cartsvc/pkg/usecase/loyalty"
+)
+
+type LoyaltyRepository struct {
+	mu   sync.RWMutex
+	data []*model.Ledger
+}
+
+func NewLoyaltyRepository() *LoyaltyRepository {
+	return NewLoyaltyRepositoryWith(make([]*model.Ledger, 0))
+}
+
+// NewLoyaltyRepositoryWith creates the repository upon the given ledger records
+func NewLoyaltyRepositoryWith(records []*model.Ledger) *LoyaltyRepository {
+	return &LoyaltyRepository{data: records}
+}
+
+// Records returns the ledger records, which the persistent backends store
+func (r *LoyaltyRepository) Records() []*model.Ledger {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.data
+}
+
+func (r *LoyaltyRepository) FetchLedger(userID string) (interface{}, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	ledger := &uc.Ledger{UserID: userID, Entries: make([]*uc.LedgerEntry, 0)}
+	for _, v := range r.data {
+		if v.UserID != userID {
+			continue
+		}
+		for _, entry := range v.Entries {
+			e := uc.LedgerEntry(*entry)
+			ledger.Entries = append(ledger.Entries, &e)
+		}
+	}
+	return ledger, nil
+}
+
+func (r *LoyaltyRepository) SaveLedger(ledger interface{}) error {
+	l, ok := ledger.(*uc.Ledger)
+	if !ok {
+		return errors.New("failed to save ledger, invalid type of ledger")
+	}
+
+	record := &model.Ledger{UserID: l.UserID, Entries: make([]*model.LedgerEntry, 0, len(l.Entries))}
+	for _, entry := range l.Entries {
+		e := model.LedgerEntry(*entry)
+		record.Entries = append(record.Entries, &e)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, v := range r.data {
+		if v.UserID == l.UserID {
+			r.data[i] = record
+			return nil
+		}
+	}
+	r.data = append(r.data, record)
+	return nil
+}
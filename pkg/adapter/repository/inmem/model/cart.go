@@ -7,18 +7,49 @@ import (
 )
 
 type Cart struct {
-	ID         string
-	UserID     string
-	Status     CartStatus
-	Items      []*CartItem
-	CreatedAt  time.Time
-	CanceledAt *time.Time
+	ID             string
+	UserID         string
+	Status         CartStatus
+	Items          []*CartItem
+	CreatedAt      time.Time
+	LastActivityAt time.Time
+	CanceledAt     *time.Time
+	CheckedOutAt   *time.Time
+	ClosedAt       *time.Time
+	RemindersSent  int
+	LastRemindedAt *time.Time
+	Shipments      []*Shipment
+	Redemption     *PointsRedemption
 }
 
 type CartItem struct {
-	ID    string
-	Name  string
-	Qty   int
-	Price float64
-	Disc  float64
+	ID          string
+	Name        string
+	SKU         string
+	Options     map[string]string
+	Qty         int
+	Price       float64
+	Disc        float64
+	Components  []*CartItemComponent
+	Fulfillment Fulfillment
+	AvailableAt *time.Time
+}
+
+type CartItemComponent struct {
+	ID   string
+	Name string
+	SKU  string
+	Qty  int
+}
+
+type Shipment struct {
+	WarehouseID string
+	Items       []*CartItemComponent
+	Fulfillment Fulfillment
+	AvailableAt *time.Time
+}
+
+type PointsRedemption struct {
+	Points int
+	Amount float64
 }
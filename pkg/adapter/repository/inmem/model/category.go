@@ -0,0 +1,7 @@
+package model
+
+type Category struct {
+	ID       string
+	Name     string
+	ParentID string
+}
@@ -0,0 +1,23 @@
+package model
+
+import (
+	"time"
+
+	"github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+)
+
+type Ledger struct {
+	UserID  string
+	Entries []*LedgerEntry
+}
+
+type LedgerEntry struct {
+	ID        string
+	CartID    string
+	Kind      enum.LedgerEntryKind
+	Points    int
+	Remaining int
+	RefID     string
+	CreatedAt time.Time
+	ExpiresAt *time.Time
+}
@@ -1,9 +1,69 @@
 package model
 
+import (
+	. "github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+
+	"time"
+)
+
 type Product struct {
-	ID    string
-	Name  string
-	Stock int
-	Price float64
-	Disc  float64
+	ID             string
+	Name           string
+	Type           ProductType
+	Stock          int
+	WarehouseStock map[string]int
+	Price          float64
+	Disc           float64
+	CategoryIDs    []string
+	Variants       []*Variant
+	Components     []*BundleComponent
+	BundlePricing  *BundlePricing
+	PurchaseLimit  *PurchaseLimit
+	PriceSchedules []*PriceSchedule
+	StockPolicy    *StockPolicy
+	DeletedAt      *time.Time
+}
+
+type Variant struct {
+	SKU            string
+	Options        map[string]string
+	Stock          int
+	WarehouseStock map[string]int
+	Price          float64
+	Disc           float64
+}
+
+type BundleComponent struct {
+	ProductID string
+	SKU       string
+	Qty       int
+}
+
+type BundlePricing struct {
+	Mode  BundlePricingMode
+	Value float64
+}
+
+type PurchaseLimit struct {
+	MaxPerOrder    int
+	MaxPerCustomer int
+	Period         time.Duration
+}
+
+type PriceSchedule struct {
+	ID       string
+	SKU      string
+	Price    float64
+	Disc     float64
+	StartsAt time.Time
+	EndsAt   time.Time
+	Quantity int
+	Sold     int
+}
+
+type StockPolicy struct {
+	Mode        Fulfillment
+	AvailableAt *time.Time
+	Limit       int
+	Committed   int
 }
@@ -0,0 +1,14 @@
+package model
+
+import (
+	. "github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+
+	"time"
+)
+
+type SavedList struct {
+	UserID    string
+	Name      SavedListName
+	Items     []*CartItem
+	UpdatedAt time.Time
+}
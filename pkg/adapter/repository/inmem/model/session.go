@@ -0,0 +1,14 @@
+package model
+
+import (
+	. "github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+
+	"time"
+)
+
+type Session struct {
+	Token     string
+	UserID    string
+	Role      UserRole
+	ExpiresAt time.Time
+}
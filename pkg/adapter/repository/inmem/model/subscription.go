@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+type Subscription struct {
+	UserID      string
+	ProductID   string
+	SKU         string
+	ProductName string
+	CreatedAt   time.Time
+}
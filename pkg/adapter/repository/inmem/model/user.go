@@ -9,6 +9,8 @@ type User struct {
 	Email           string
 	BillingAddress  *Address
 	ShippingAddress *Address
+	Role            UserRole
+	PasswordHash    string
 }
 
 type Address struct {
@@ -0,0 +1,11 @@
+package model
+
+type Warehouse struct {
+	ID       string
+	Name     string
+	City     string
+	Province string
+	Region   string
+	Postal   string
+	Country  string
+}
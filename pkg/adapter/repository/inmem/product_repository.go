@@ -1,58 +1,285 @@
 package inmem
 
 import (
+	"errors"
+	"sync"
+	"time"
+
 	"github.com/yauritux/cartsvc/pkg/adapter/repository/inmem/model"
+	"github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
 	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
 	uc "github.com/yauritux/cartsvc/pkg/usecase/products"
 )
 
 type ProductRepository struct {
-	data []*model.Product
+	mu    sync.RWMutex
+	data  []*model.Product
+	index *invertedIndex
 }
 
 func NewProductRepository() *ProductRepository {
 	productRecords := make([]*model.Product, 0)
 	productRecords = append(productRecords, &model.Product{
-		ID:    "001",
-		Name:  "Shuriken",
-		Stock: 1500,
-		Price: 250.50,
-		Disc:  0.0,
+		ID:             "001",
+		Name:           "Shuriken",
+		Stock:          1500,
+		WarehouseStock: map[string]int{"main": 1000, "surabaya": 300, "medan": 200},
+		Price:          250.50,
+		Disc:           0.0,
+		CategoryIDs:    []string{"throwing-weapons"},
+	})
+	productRecords = append(productRecords, &model.Product{
+		ID:          "002",
+		Name:        "Sai",
+		Stock:       950,
+		Price:       175.25,
+		Disc:        0.0,
+		CategoryIDs: []string{"melee-weapons"},
+	})
+	productRecords = append(productRecords, &model.Product{
+		ID:          "003",
+		Name:        "Ninja Gi",
+		Price:       320.00,
+		CategoryIDs: []string{"apparel"},
+		Variants: []*model.Variant{
+			{SKU: "003-BLK-M", Options: map[string]string{"color": "black", "size": "M"}, Stock: 40, Price: 320.00},
+			{SKU: "003-BLK-L", Options: map[string]string{"color": "black", "size": "L"}, Stock: 25, Price: 320.00},
+			{SKU: "003-NVY-M", Options: map[string]string{"color": "navy", "size": "M"}, Stock: 10, Price: 335.00},
+		},
 	})
 	productRecords = append(productRecords, &model.Product{
-		ID:    "002",
-		Name:  "Sai",
-		Stock: 950,
-		Price: 175.25,
-		Disc:  0.0,
+		ID:          "004",
+		Name:        "Ninja Training Set",
+		Type:        enum.BundleProduct,
+		CategoryIDs: []string{"weapons"},
+		Components: []*model.BundleComponent{
+			{ProductID: "001", Qty: 1},
+			{ProductID: "002", Qty: 1},
+		},
+		BundlePricing: &model.BundlePricing{Mode: enum.PercentOffBundle, Value: 10},
 	})
-	return &ProductRepository{data: productRecords}
+	return NewProductRepositoryWith(productRecords)
+}
+
+// NewProductRepositoryWith creates the repository upon the given product records
+func NewProductRepositoryWith(records []*model.Product) *ProductRepository {
+	r := &ProductRepository{data: records, index: newInvertedIndex()}
+	for _, p := range records {
+		if p.DeletedAt == nil {
+			r.index.add(p.ID, p.Name)
+		}
+	}
+	return r
+}
+
+func (r *ProductRepository) Records() []*model.Product {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.data
 }
 
 func (r *ProductRepository) FindByProductID(id string) (interface{}, error) {
+	return r.find(id, false)
+}
+
+func (r *ProductRepository) FindAnyByProductID(id string) (interface{}, error) {
+	return r.find(id, true)
+}
+
+func (r *ProductRepository) find(id string, withDeleted bool) (interface{}, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	if id == "" {
 		return nil, e.NewErrNoData("please provide product id")
 	}
 	for i, p := range r.data {
-		if p.ID == id {
+		if p.ID == id && (p.DeletedAt == nil || withDeleted) {
 			return r.BuildProductUsecaseModel(r.data[i]), nil
 		}
 	}
 	return nil, e.NewErrNoData("no product found for id " + id)
 }
 
+func (r *ProductRepository) Create(product interface{}) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ucProduct, ok := product.(*uc.Product)
+	if !ok {
+		return errors.New("failed to create product, invalid type of product")
+	}
+
+	for _, p := range r.data {
+		if p.ID == ucProduct.ID {
+			return e.NewErrDuplicateData("product " + ucProduct.ID + " already exists")
+		}
+	}
+
+	r.data = append(r.data, r.BuildProductRepositoryModel(ucProduct))
+	r.index.add(ucProduct.ID, ucProduct.Name)
+	return nil
+}
+
+func (r *ProductRepository) Update(product interface{}) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ucProduct, ok := product.(*uc.Product)
+	if !ok {
+		return errors.New("failed to update product, invalid type of product")
+	}
+
+	for i, p := range r.data {
+		if p.ID == ucProduct.ID && p.DeletedAt == nil {
+			r.index.remove(p.ID, p.Name)
+			r.data[i] = r.BuildProductRepositoryModel(ucProduct)
+			r.index.add(ucProduct.ID, ucProduct.Name)
+			return nil
+		}
+	}
+	return e.NewErrNoData("no product found for id " + ucProduct.ID)
+}
+
+func (r *ProductRepository) Delete(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, p := range r.data {
+		if p.ID == id && p.DeletedAt == nil {
+			deletedAt := time.Now()
+			p.DeletedAt = &deletedAt
+			r.index.remove(p.ID, p.Name)
+			return nil
+		}
+	}
+	return e.NewErrNoData("no product found for id " + id)
+}
+
 func (r *ProductRepository) BuildProductUsecaseModel(prod interface{}) *uc.Product {
 	switch prod.(type) {
 	case *model.Product:
 		u := prod.(*model.Product)
-		return &uc.Product{
-			ID:    u.ID,
-			Name:  u.Name,
-			Stock: u.Stock,
-			Price: u.Price,
-			Disc:  u.Disc,
+		ucProduct := &uc.Product{
+			ID:             u.ID,
+			Name:           u.Name,
+			Type:           u.Type,
+			Stock:          u.Stock,
+			WarehouseStock: copyStockLevels(u.WarehouseStock),
+			Price:          u.Price,
+			Disc:           u.Disc,
+			CategoryIDs:    u.CategoryIDs,
+			Variants:       buildVariantUsecaseModels(u.Variants),
+			Components:     make([]*uc.BundleComponent, 0),
+		}
+		for _, c := range u.Components {
+			ucProduct.Components = append(ucProduct.Components, &uc.BundleComponent{
+				ProductID: c.ProductID,
+				SKU:       c.SKU,
+				Qty:       c.Qty,
+			})
+		}
+		if u.BundlePricing != nil {
+			ucProduct.BundlePricing = &uc.BundlePricing{Mode: u.BundlePricing.Mode, Value: u.BundlePricing.Value}
 		}
+		if l := u.PurchaseLimit; l != nil {
+			ucProduct.PurchaseLimit = &uc.PurchaseLimit{MaxPerOrder: l.MaxPerOrder, MaxPerCustomer: l.MaxPerCustomer, Period: l.Period}
+		}
+		for _, s := range u.PriceSchedules {
+			schedule := uc.PriceSchedule(*s)
+			ucProduct.PriceSchedules = append(ucProduct.PriceSchedules, &schedule)
+		}
+		if u.StockPolicy != nil {
+			policy := uc.StockPolicy(*u.StockPolicy)
+			ucProduct.StockPolicy = &policy
+		}
+		return ucProduct
 	default:
 		return nil
 	}
 }
+
+func (r *ProductRepository) BuildProductRepositoryModel(prod *uc.Product) *model.Product {
+	modelProduct := &model.Product{
+		ID:             prod.ID,
+		Name:           prod.Name,
+		Type:           prod.Type,
+		Stock:          prod.Stock,
+		WarehouseStock: copyStockLevels(prod.WarehouseStock),
+		Price:          prod.Price,
+		Disc:           prod.Disc,
+		CategoryIDs:    prod.CategoryIDs,
+		Variants:       buildVariantRepositoryModels(prod.Variants),
+		Components:     make([]*model.BundleComponent, 0),
+	}
+	for _, c := range prod.Components {
+		modelProduct.Components = append(modelProduct.Components, &model.BundleComponent{
+			ProductID: c.ProductID,
+			SKU:       c.SKU,
+			Qty:       c.Qty,
+		})
+	}
+	if prod.BundlePricing != nil {
+		modelProduct.BundlePricing = &model.BundlePricing{Mode: prod.BundlePricing.Mode, Value: prod.BundlePricing.Value}
+	}
+	if l := prod.PurchaseLimit; l != nil {
+		modelProduct.PurchaseLimit = &model.PurchaseLimit{MaxPerOrder: l.MaxPerOrder, MaxPerCustomer: l.MaxPerCustomer, Period: l.Period}
+	}
+	for _, s := range prod.PriceSchedules {
+		schedule := model.PriceSchedule(*s)
+		modelProduct.PriceSchedules = append(modelProduct.PriceSchedules, &schedule)
+	}
+	if prod.StockPolicy != nil {
+		policy := model.StockPolicy(*prod.StockPolicy)
+		modelProduct.StockPolicy = &policy
+	}
+	return modelProduct
+}
+
+func buildVariantUsecaseModels(variants []*model.Variant) []*uc.Variant {
+	ucVariants := make([]*uc.Variant, 0)
+	for _, v := range variants {
+		ucVariants = append(ucVariants, &uc.Variant{
+			SKU:            v.SKU,
+			Options:        copyOptions(v.Options),
+			Stock:          v.Stock,
+			WarehouseStock: copyStockLevels(v.WarehouseStock),
+			Price:          v.Price,
+			Disc:           v.Disc,
+		})
+	}
+	return ucVariants
+}
+
+func buildVariantRepositoryModels(variants []*uc.Variant) []*model.Variant {
+	modelVariants := make([]*model.Variant, 0)
+	for _, v := range variants {
+		modelVariants = append(modelVariants, &model.Variant{
+			SKU:            v.SKU,
+			Options:        copyOptions(v.Options),
+			Stock:          v.Stock,
+			WarehouseStock: copyStockLevels(v.WarehouseStock),
+			Price:          v.Price,
+			Disc:           v.Disc,
+		})
+	}
+	return modelVariants
+}
+
+func copyOptions(options map[string]string) map[string]string {
+	if options == nil {
+		return nil
+	}
+	copied := make(map[string]string, len(options))
+	for k, v := range options {
+		copied[k] = v
+	}
+	return copied
+}
+
+func copyStockLevels(levels map[string]int) map[string]int {
+	if len(levels) == 0 {
+		return nil
+	}
+	copied := make(map[string]int, len(levels))
+	for k, v := range levels {
+		copied[k] = v
+	}
+	return copied
+}
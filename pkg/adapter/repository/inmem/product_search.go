@@ -0,0 +1,366 @@
+package inmem
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/yauritux/cartsvc/pkg/adapter/repository/inmem/model"
+	"github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+	uc "github.com/yauritux/cartsvc/pkg/usecase/products"
+)
+
+// gramSize is the length of the longest n-grams indexed, the shorter ones are indexed as well so that
+// a keyword token as short is looked up at once
+const gramSize = 3
+
+// invertedIndex maps every token of the product name into the IDs of the products having it, and
+// every n-gram of those tokens into the tokens containing it
+type invertedIndex struct {
+	tokens map[string]map[string]struct{}
+	grams  map[string]map[string]struct{}
+}
+
+type searchCursor struct {
+	Value string `json:"v"`
+	ID    string `json:"id"`
+}
+
+func newInvertedIndex() *invertedIndex {
+	return &invertedIndex{tokens: make(map[string]map[string]struct{}), grams: make(map[string]map[string]struct{})}
+}
+
+func (idx *invertedIndex) add(id string, text string) {
+	for _, token := range tokenize(text) {
+		ids, ok := idx.tokens[token]
+		if !ok {
+			ids = make(map[string]struct{})
+			idx.tokens[token] = ids
+			for _, gram := range ngrams(token, 1, gramSize) {
+				tokens, ok := idx.grams[gram]
+				if !ok {
+					tokens = make(map[string]struct{})
+					idx.grams[gram] = tokens
+				}
+				tokens[token] = struct{}{}
+			}
+		}
+		ids[id] = struct{}{}
+	}
+}
+
+func (idx *invertedIndex) remove(id string, text string) {
+	for _, token := range tokenize(text) {
+		ids, ok := idx.tokens[token]
+		if !ok {
+			continue
+		}
+		delete(ids, id)
+		if len(ids) > 0 {
+			continue
+		}
+		delete(idx.tokens, token)
+		for _, gram := range ngrams(token, 1, gramSize) {
+			delete(idx.grams[gram], token)
+			if len(idx.grams[gram]) == 0 {
+				delete(idx.grams, gram)
+			}
+		}
+	}
+}
+
+// lookup returns the IDs of the products matching every keyword token, a keyword token
+// matches any indexed token containing it so that partial words are found as well
+func (idx *invertedIndex) lookup(keyword string) map[string]struct{} {
+	var found map[string]struct{}
+	for _, term := range tokenize(keyword) {
+		matches := make(map[string]struct{})
+		for token := range idx.tokensContaining(term) {
+			for id := range idx.tokens[token] {
+				if found == nil {
+					matches[id] = struct{}{}
+				} else if _, ok := found[id]; ok {
+					matches[id] = struct{}{}
+				}
+			}
+		}
+		found = matches
+		if len(found) == 0 {
+			break
+		}
+	}
+	return found
+}
+
+// tokensContaining returns the indexed tokens containing the term. A term as short as the n-grams
+// is one of them, a longer one is looked for among the tokens having all of its n-grams.
+func (idx *invertedIndex) tokensContaining(term string) map[string]struct{} {
+	if len([]rune(term)) <= gramSize {
+		return idx.grams[term]
+	}
+
+	var candidates map[string]struct{}
+	for i, gram := range ngrams(term, gramSize, gramSize) {
+		tokens := idx.grams[gram]
+		if i == 0 {
+			candidates = tokens
+		} else {
+			narrowed := make(map[string]struct{})
+			for token := range candidates {
+				if _, ok := tokens[token]; ok {
+					narrowed[token] = struct{}{}
+				}
+			}
+			candidates = narrowed
+		}
+		if len(candidates) == 0 {
+			return nil
+		}
+	}
+
+	found := make(map[string]struct{})
+	for token := range candidates {
+		if strings.Contains(token, term) {
+			found[token] = struct{}{}
+		}
+	}
+	return found
+}
+
+// ngrams returns the substrings of the token from min up to max runes long
+func ngrams(token string, min int, max int) []string {
+	runes := []rune(token)
+	grams := make([]string, 0)
+	for n := min; n <= max && n <= len(runes); n++ {
+		for i := 0; i+n <= len(runes); i++ {
+			grams = append(grams, string(runes[i:i+n]))
+		}
+	}
+	return grams
+}
+
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+func (r *ProductRepository) Search(query interface{}) (interface{}, error) {
+	q, ok := query.(*uc.ProductQuery)
+	if !ok {
+		return nil, e.NewErrConversion("failed to search products, invalid type of product query")
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var candidates map[string]struct{}
+	if strings.TrimSpace(q.Keyword) != "" {
+		candidates = r.index.lookup(q.Keyword)
+	}
+
+	catalog := make(map[string]*model.Product)
+	for _, p := range r.data {
+		if p.DeletedAt == nil {
+			catalog[p.ID] = p
+		}
+	}
+
+	results := make([]*uc.Product, 0)
+	for _, p := range r.data {
+		if p.DeletedAt != nil {
+			continue
+		}
+		if candidates != nil {
+			if _, ok := candidates[p.ID]; !ok {
+				continue
+			}
+		}
+		if q.InStockOnly && !inStock(p, catalog) {
+			continue
+		}
+		listed := r.listProduct(p, catalog, q.PricedAt)
+		if !matchProductQuery(listed, q) {
+			continue
+		}
+		results = append(results, listed)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return compareProducts(results[i], sortValue(results[j], q.SortBy), results[j].ID, q) < 0
+	})
+
+	if q.Cursor != "" {
+		cur, err := decodeCursor(q.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		start := len(results)
+		for i, p := range results {
+			if compareProducts(p, cur.Value, cur.ID, q) > 0 {
+				start = i
+				break
+			}
+		}
+		results = results[start:]
+	}
+
+	// a limit which is not positive returns every match within a single page
+	page := &uc.ProductPage{Items: make([]*uc.Product, 0)}
+	for i, p := range results {
+		if q.Limit > 0 && i == q.Limit {
+			last := results[i-1]
+			page.NextCursor = encodeCursor(&searchCursor{Value: sortValue(last, q.SortBy), ID: last.ID})
+			break
+		}
+		page.Items = append(page.Items, p)
+	}
+	return page, nil
+}
+
+// listProduct returns the product as it is listed, a bundle being priced and stocked out of its components
+// so that it gets filtered and sorted along with the other products. A bundle missing a component is listed
+// as it is stored.
+func (r *ProductRepository) listProduct(p *model.Product, catalog map[string]*model.Product, at time.Time) *uc.Product {
+	listed := r.BuildProductUsecaseModel(p)
+	if p.Type != enum.BundleProduct {
+		return listed
+	}
+	components := make([]*uc.Product, 0)
+	for _, c := range p.Components {
+		component, ok := catalog[c.ProductID]
+		if !ok {
+			return listed
+		}
+		components = append(components, r.BuildProductUsecaseModel(component))
+	}
+	priced, err := uc.PriceBundle(listed, components, at)
+	if err != nil {
+		return listed
+	}
+	return priced
+}
+
+func matchProductQuery(p *uc.Product, q *uc.ProductQuery) bool {
+	if q.MinPrice != nil && p.Price < *q.MinPrice {
+		return false
+	}
+	if q.MaxPrice != nil && p.Price > *q.MaxPrice {
+		return false
+	}
+	if len(q.CategoryIDs) > 0 && !hasAnyCategory(p, q.CategoryIDs) {
+		return false
+	}
+	return true
+}
+
+// inStock tells whether a unit of the product can be shipped, a bundle being in stock when every
+// one of its components is
+func inStock(p *model.Product, catalog map[string]*model.Product) bool {
+	if p.Type != enum.BundleProduct {
+		return unitsOnHand(p, "") > 0
+	}
+	for _, c := range p.Components {
+		component, ok := catalog[c.ProductID]
+		if !ok || unitsOnHand(component, c.SKU) < c.Qty {
+			return false
+		}
+	}
+	return len(p.Components) > 0
+}
+
+// unitsOnHand returns the stock of the product variant identified by the SKU, or the stock of the
+// product summed up over its variants when there's no SKU. The stock spread among the warehouses
+// is summed up as well.
+func unitsOnHand(p *model.Product, sku string) int {
+	if sku != "" {
+		for _, v := range p.Variants {
+			if v.SKU == sku {
+				return sumStockLevels(v.Stock, v.WarehouseStock)
+			}
+		}
+		return 0
+	}
+	if len(p.Variants) == 0 {
+		return sumStockLevels(p.Stock, p.WarehouseStock)
+	}
+	units := 0
+	for _, v := range p.Variants {
+		units += sumStockLevels(v.Stock, v.WarehouseStock)
+	}
+	return units
+}
+
+func sumStockLevels(stock int, levels map[string]int) int {
+	if len(levels) == 0 {
+		return stock
+	}
+	units := 0
+	for _, level := range levels {
+		units += level
+	}
+	return units
+}
+
+func hasAnyCategory(p *uc.Product, categoryIDs []string) bool {
+	for _, id := range p.CategoryIDs {
+		for _, wanted := range categoryIDs {
+			if id == wanted {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func sortValue(p *uc.Product, field uc.SortField) string {
+	if field == uc.SortByPrice {
+		return strconv.FormatFloat(p.Price, 'f', -1, 64)
+	}
+	return strings.ToLower(p.Name)
+}
+
+// compareProducts compares the product against the sort key (value, id) within the query order
+func compareProducts(p *uc.Product, value string, id string, q *uc.ProductQuery) int {
+	var c int
+	if q.SortBy == uc.SortByPrice {
+		price, _ := strconv.ParseFloat(value, 64)
+		switch {
+		case p.Price < price:
+			c = -1
+		case p.Price > price:
+			c = 1
+		}
+	} else {
+		c = strings.Compare(strings.ToLower(p.Name), value)
+	}
+	if q.Descending {
+		c = -c
+	}
+	if c == 0 {
+		c = strings.Compare(p.ID, id)
+	}
+	return c
+}
+
+func encodeCursor(c *searchCursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (*searchCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, e.NewErrInvalidData("invalid cursor " + s)
+	}
+	c := &searchCursor{}
+	if err := json.Unmarshal(b, c); err != nil {
+		return nil, e.NewErrInvalidData("invalid cursor " + s)
+	}
+	return c, nil
+}
@@ -0,0 +1,200 @@
+package inmem
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/yauritux/cartsvc/pkg/adapter/repository/inmem/model"
+	"github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+	uc "github.com/yauritux/cartsvc/pkg/usecase/products"
+)
+
+func TestProductSearch(t *testing.T) {
+
+	Convey("1. Given a catalog indexed by the product names", t, func() {
+
+		repo := NewProductRepositoryWith([]*model.Product{
+			{ID: "001", Name: "Shuriken", Stock: 1500, Price: 250.50},
+			{ID: "002", Name: "Sai", Stock: 950, Price: 175.25},
+			{ID: "003", Name: "Ninja Katana", Stock: 0, Price: 750},
+			{ID: "004", Name: "Training Katana", Stock: 20, Price: 90},
+			{ID: "005", Name: "Ninja Smoke Bomb", Stock: 300, Price: 15},
+		})
+
+		search := func(q *uc.ProductQuery) *uc.ProductPage {
+			if q.SortBy == "" {
+				q.SortBy = uc.SortByName
+			}
+			if q.Limit == 0 {
+				q.Limit = 10
+			}
+			res, err := repo.Search(q)
+			So(err, ShouldBeNil)
+			return res.(*uc.ProductPage)
+		}
+		ids := func(page *uc.ProductPage) []string {
+			res := make([]string, 0)
+			for _, p := range page.Items {
+				res = append(res, p.ID)
+			}
+			return res
+		}
+
+		Convey("-> Should match every keyword token, including the partial ones", func() {
+			So(ids(search(&uc.ProductQuery{Keyword: "katana"})), ShouldResemble, []string{"003", "004"})
+			So(ids(search(&uc.ProductQuery{Keyword: "ninja kat"})), ShouldResemble, []string{"003"})
+			So(ids(search(&uc.ProductQuery{Keyword: "rike"})), ShouldResemble, []string{"001"})
+		})
+
+		Convey("-> Should filter by price range and stock availability", func() {
+			min, max := 50.0, 800.0
+			page := search(&uc.ProductQuery{MinPrice: &min, MaxPrice: &max, InStockOnly: true, SortBy: uc.SortByPrice})
+			So(ids(page), ShouldResemble, []string{"004", "002", "001"})
+		})
+
+		Convey("-> Should walk through the pages by cursor", func() {
+			first := search(&uc.ProductQuery{SortBy: uc.SortByPrice, Descending: true, Limit: 2})
+			So(ids(first), ShouldResemble, []string{"003", "001"})
+			second := search(&uc.ProductQuery{SortBy: uc.SortByPrice, Descending: true, Limit: 2, Cursor: first.NextCursor})
+			So(ids(second), ShouldResemble, []string{"002", "004"})
+			third := search(&uc.ProductQuery{SortBy: uc.SortByPrice, Descending: true, Limit: 2, Cursor: second.NextCursor})
+			So(ids(third), ShouldResemble, []string{"005"})
+			So(third.NextCursor, ShouldBeEmpty)
+		})
+
+		Convey("-> Should return every match within a single page when the limit is not positive", func() {
+			for _, limit := range []int{0, -1} {
+				res, err := repo.Search(&uc.ProductQuery{SortBy: uc.SortByName, Limit: limit})
+				So(err, ShouldBeNil)
+				page := res.(*uc.ProductPage)
+				So(ids(page), ShouldResemble, []string{"003", "005", "002", "001", "004"})
+				So(page.NextCursor, ShouldBeEmpty)
+			}
+		})
+
+		Convey("-> Should keep the index up to date", func() {
+			So(repo.Update(&uc.Product{ID: "002", Name: "Ninja Sai", Stock: 950, Price: 175.25}), ShouldBeNil)
+			So(repo.Delete("003"), ShouldBeNil)
+			So(ids(search(&uc.ProductQuery{Keyword: "ninja"})), ShouldResemble, []string{"002", "005"})
+		})
+	})
+
+	Convey("2. Given a catalog of variants, bundles and warehouses", t, func() {
+
+		repo := NewProductRepositoryWith([]*model.Product{
+			{ID: "001", Name: "Shuriken", Stock: 3},
+			{ID: "002", Name: "Sai", Stock: 0},
+			{ID: "003", Name: "Ninja Gi", Variants: []*model.Variant{
+				{SKU: "003-BLK-M", Stock: 0},
+				{SKU: "003-NVY-M", Stock: 10},
+			}},
+			{ID: "004", Name: "Ninja Hood", Variants: []*model.Variant{{SKU: "004-BLK", Stock: 0}}},
+			{ID: "005", Name: "Kunai", WarehouseStock: map[string]int{"main": 0, "medan": 4}},
+			{ID: "006", Name: "Shuriken Kit", Type: enum.BundleProduct, Components: []*model.BundleComponent{
+				{ProductID: "001", Qty: 2},
+				{ProductID: "003", SKU: "003-NVY-M", Qty: 1},
+			}},
+			{ID: "007", Name: "Sai Kit", Type: enum.BundleProduct, Components: []*model.BundleComponent{
+				{ProductID: "001", Qty: 1},
+				{ProductID: "002", Qty: 1},
+			}},
+			{ID: "008", Name: "Gi Kit", Type: enum.BundleProduct, Components: []*model.BundleComponent{
+				{ProductID: "003", SKU: "003-BLK-M", Qty: 1},
+			}},
+			{ID: "009", Name: "Heavy Kit", Type: enum.BundleProduct, Components: []*model.BundleComponent{
+				{ProductID: "001", Qty: 4},
+			}},
+		})
+
+		Convey("-> Should tell the availability out of the variants, the bundle components and the warehouses", func() {
+			res, err := repo.Search(&uc.ProductQuery{SortBy: uc.SortByName, InStockOnly: true})
+			So(err, ShouldBeNil)
+			ids := make([]string, 0)
+			for _, p := range res.(*uc.ProductPage).Items {
+				ids = append(ids, p.ID)
+			}
+			So(ids, ShouldResemble, []string{"005", "003", "001", "006"})
+		})
+	})
+
+	Convey("3. Given the n-gram index of the product names", t, func() {
+
+		idx := newInvertedIndex()
+		idx.add("001", "Shuriken")
+		idx.add("002", "Ninja Katana")
+		idx.add("003", "Katana Stand")
+
+		keys := func(set map[string]struct{}) []string {
+			res := make([]string, 0)
+			for k := range set {
+				res = append(res, k)
+			}
+			sort.Strings(res)
+			return res
+		}
+
+		Convey("-> Should look up the terms as short as the n-grams at once", func() {
+			So(keys(idx.tokensContaining("ka")), ShouldResemble, []string{"katana"})
+			So(keys(idx.tokensContaining("n")), ShouldResemble, []string{"katana", "ninja", "shuriken", "stand"})
+		})
+		Convey("-> Should narrow the longer terms down by their n-grams", func() {
+			So(keys(idx.tokensContaining("urike")), ShouldResemble, []string{"shuriken"})
+			So(keys(idx.tokensContaining("katana")), ShouldResemble, []string{"katana"})
+			So(idx.tokensContaining("nanak"), ShouldBeEmpty)
+			So(keys(idx.lookup("kata")), ShouldResemble, []string{"002", "003"})
+		})
+		Convey("-> Should drop the n-grams of the tokens no longer indexed", func() {
+			idx.remove("001", "Shuriken")
+			So(idx.tokensContaining("uri"), ShouldBeEmpty)
+			_, ok := idx.grams["ken"]
+			So(ok, ShouldBeFalse)
+			idx.remove("002", "Ninja Katana")
+			So(keys(idx.lookup("katana")), ShouldResemble, []string{"003"})
+		})
+	})
+
+	Convey("4. Given a catalog holding a bundle priced out of its components", t, func() {
+
+		now := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+		repo := NewProductRepositoryWith([]*model.Product{
+			{ID: "001", Name: "Shuriken", Stock: 10, Price: 100},
+			{ID: "002", Name: "Sai", Stock: 3, Price: 50, PriceSchedules: []*model.PriceSchedule{
+				{ID: "1", Price: 30, StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour)},
+			}},
+			{ID: "003", Name: "Training Set", Type: enum.BundleProduct, Components: []*model.BundleComponent{
+				{ProductID: "001", Qty: 1},
+				{ProductID: "002", Qty: 1},
+			}},
+			{ID: "004", Name: "Katana", Stock: 5, Price: 120},
+		})
+
+		search := func(q *uc.ProductQuery) []*uc.Product {
+			q.Limit = 10
+			res, err := repo.Search(q)
+			So(err, ShouldBeNil)
+			return res.(*uc.ProductPage).Items
+		}
+		ids := func(items []*uc.Product) []string {
+			res := make([]string, 0)
+			for _, p := range items {
+				res = append(res, p.ID)
+			}
+			return res
+		}
+
+		Convey("-> Should list the bundle at the price and the stock of its components", func() {
+			items := search(&uc.ProductQuery{SortBy: uc.SortByPrice, PricedAt: now})
+			So(ids(items), ShouldResemble, []string{"002", "001", "004", "003"})
+			So(items[3].Price, ShouldEqual, 130)
+			So(items[3].Stock, ShouldEqual, 3)
+		})
+
+		Convey("-> Should filter the bundle by the price of its components at the time of the search", func() {
+			min := 140.0
+			So(ids(search(&uc.ProductQuery{SortBy: uc.SortByPrice, MinPrice: &min, PricedAt: now})), ShouldBeEmpty)
+			So(ids(search(&uc.ProductQuery{SortBy: uc.SortByPrice, MinPrice: &min, PricedAt: now.Add(2 * time.Hour)})), ShouldResemble, []string{"003"})
+		})
+	})
+}
@@ -0,0 +1,68 @@
+package inmem
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/yauritux/cartsvc/pkg/adapter/repository/inmem/model"
+	. "github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+	uc "github.com/yauritux/cartsvc/pkg/usecase/carts"
+)
+
+type SavedListRepository struct {
+	mu   sync.RWMutex
+	data map[string]*model.SavedList
+}
+
+func NewSavedListRepository() *SavedListRepository {
+	return &SavedListRepository{data: make(map[string]*model.SavedList)}
+}
+
+func (r *SavedListRepository) FetchSavedList(userID string, name string) (interface{}, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	list, ok := r.data[savedListKey(userID, name)]
+	if !ok {
+		return &uc.SavedList{
+			UserID: userID,
+			Name:   SavedListName(name),
+			Items:  make([]*uc.CartItem, 0),
+		}, nil
+	}
+
+	ucList := &uc.SavedList{
+		UserID:    list.UserID,
+		Name:      list.Name,
+		Items:     make([]*uc.CartItem, 0),
+		UpdatedAt: list.UpdatedAt,
+	}
+	for _, v := range list.Items {
+		ucList.Items = append(ucList.Items, buildCartItemUsecaseModel(v))
+	}
+	return ucList, nil
+}
+
+func (r *SavedListRepository) Save(list interface{}) error {
+	l, ok := list.(*uc.SavedList)
+	if !ok {
+		return errors.New("failed to save list, invalid type of saved list")
+	}
+
+	items := make([]*model.CartItem, 0)
+	for _, v := range l.Items {
+		items = append(items, buildCartItemRepositoryModel(v))
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.data[savedListKey(l.UserID, string(l.Name))] = &model.SavedList{
+		UserID:    l.UserID,
+		Name:      l.Name,
+		Items:     items,
+		UpdatedAt: l.UpdatedAt,
+	}
+	return nil
+}
+
+func savedListKey(userID string, name string) string {
+	return userID + "/" + name
+}
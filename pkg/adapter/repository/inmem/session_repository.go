@@ -0,0 +1,58 @@
+package inmem
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/yauritux/cartsvc/pkg/adapter/repository/inmem/model"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+	uc "github.com/yauritux/cartsvc/pkg/usecase/auth"
+)
+
+type SessionRepository struct {
+	mu   sync.RWMutex
+	data map[string]*model.Session
+}
+
+func NewSessionRepository() *SessionRepository {
+	return &SessionRepository{data: make(map[string]*model.Session)}
+}
+
+func (r *SessionRepository) Save(session interface{}) error {
+	s, ok := session.(*uc.Session)
+	if !ok {
+		return errors.New("failed to save session, invalid type of session")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.data[s.Token] = &model.Session{
+		Token:     s.Token,
+		UserID:    s.UserID,
+		Role:      s.Role,
+		ExpiresAt: s.ExpiresAt,
+	}
+	return nil
+}
+
+func (r *SessionRepository) FindByToken(token string) (interface{}, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	s, ok := r.data[token]
+	if !ok {
+		return nil, e.NewErrNoData("no session found for the given token")
+	}
+	return &uc.Session{
+		Token:     s.Token,
+		UserID:    s.UserID,
+		Role:      s.Role,
+		ExpiresAt: s.ExpiresAt,
+	}, nil
+}
+
+func (r *SessionRepository) Delete(token string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.data, token)
+	return nil
+}
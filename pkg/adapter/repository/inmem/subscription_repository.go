@@ -0,0 +1,92 @@
+package inmem
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/yauritux/cartsvc/pkg/adapter/repository/inmem/model"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+	uc "github.com/yauritux/cartsvc/pkg/usecase/subscriptions"
+)
+
+type SubscriptionRepository struct {
+	mu   sync.RWMutex
+	data []*model.Subscription
+}
+
+func NewSubscriptionRepository() *SubscriptionRepository {
+	return NewSubscriptionRepositoryWith(make([]*model.Subscription, 0))
+}
+
+// NewSubscriptionRepositoryWith creates the repository upon the given subscription records
+func NewSubscriptionRepositoryWith(records []*model.Subscription) *SubscriptionRepository {
+	return &SubscriptionRepository{data: records}
+}
+
+// Records returns the subscription records, which the persistent backends store
+func (r *SubscriptionRepository) Records() []*model.Subscription {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.data
+}
+
+func (r *SubscriptionRepository) FetchUserSubscriptions(userID string) (interface{}, error) {
+	return r.fetch(func(s *model.Subscription) bool {
+		return s.UserID == userID
+	}), nil
+}
+
+func (r *SubscriptionRepository) FetchSKUSubscriptions(sku string) (interface{}, error) {
+	return r.fetch(func(s *model.Subscription) bool {
+		return s.SKU == sku
+	}), nil
+}
+
+func (r *SubscriptionRepository) Save(subscription interface{}) error {
+	s, ok := subscription.(*uc.Subscription)
+	if !ok {
+		return errors.New("failed to save subscription, invalid type of subscription")
+	}
+
+	record := model.Subscription(*s)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, v := range r.data {
+		if v.UserID == s.UserID && v.SKU == s.SKU {
+			r.data[i] = &record
+			return nil
+		}
+	}
+	r.data = append(r.data, &record)
+	return nil
+}
+
+func (r *SubscriptionRepository) Remove(userID string, sku string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, v := range r.data {
+		if v.UserID == userID && v.SKU == sku {
+			r.data = append(r.data[:i], r.data[i+1:]...)
+			return nil
+		}
+	}
+	return e.NewErrNoData(fmt.Sprintf("user %s has not subscribed to %s", userID, sku))
+}
+
+func (r *SubscriptionRepository) fetch(match func(*model.Subscription) bool) []*uc.Subscription {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	subscriptions := make([]*uc.Subscription, 0)
+	for _, v := range r.data {
+		if match(v) {
+			s := uc.Subscription(*v)
+			subscriptions = append(subscriptions, &s)
+		}
+	}
+	sort.SliceStable(subscriptions, func(i, j int) bool {
+		return subscriptions[i].CreatedAt.Before(subscriptions[j].CreatedAt)
+	})
+	return subscriptions
+}
@@ -1,11 +1,16 @@
 package inmem
 
 import (
+	"errors"
+	"sync"
+
 	"github.com/yauritux/cartsvc/pkg/adapter/repository/inmem/model"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
 	uc "github.com/yauritux/cartsvc/pkg/usecase/users"
 )
 
 type UserRepository struct {
+	mu   sync.RWMutex
 	data []*model.User
 }
 
@@ -16,10 +21,13 @@ func NewUserRepository() *UserRepository {
 		Name:  "Yauri Attamimi",
 		Phone: "+62822xxxxxx",
 		Email: "yauritux@gmail.com",
+		Role:  "customer",
+		// password: shinobi
+		PasswordHash: "$2a$10$mtBCupATbmiovq.x9jg33uHupH0OvlBbmWX5lxgzNqScmEQmjQjcG",
 		BillingAddress: &model.Address{
 			StreetName:  "Kalibata Raya No.1",
 			City:        "Jakarta",
-			Region:      "South Jakarta",
+			Region:      "Jakarta Selatan",
 			Province:    "DKI Jakarta",
 			Postal:      "12750",
 			Country:     "Indonesia",
@@ -28,19 +36,41 @@ func NewUserRepository() *UserRepository {
 		ShippingAddress: &model.Address{
 			StreetName:  "Kalibata Raya No.1",
 			City:        "Jakarta",
-			Region:      "DKI Jakarta",
+			Region:      "Jakarta Selatan",
+			Province:    "DKI Jakarta",
 			Postal:      "12750",
 			Country:     "Indonesia",
 			AddressType: "shipping_address",
 		},
 	}
-	userRecords = append(userRecords, user)
-	return &UserRepository{data: userRecords}
+	admin := &model.User{
+		ID:    "admin",
+		Name:  "Cart Administrator",
+		Email: "admin@cartsvc.local",
+		Role:  "admin",
+		// password: hokage
+		PasswordHash: "$2a$10$kkO1SaEDGB3hXWkgBdUQ1.LTvSQJRbj9sKSxJWvpNl95l6M9x8YQC",
+	}
+	userRecords = append(userRecords, user, admin)
+	return NewUserRepositoryWith(userRecords)
+}
+
+// NewUserRepositoryWith creates the repository upon the given user records
+func NewUserRepositoryWith(records []*model.User) *UserRepository {
+	return &UserRepository{data: records}
+}
+
+func (r *UserRepository) Records() []*model.User {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.data
 }
 
 func (r *UserRepository) FindByUserID(uid string) (interface{}, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	if uid == "" {
-		return nil, nil
+		return nil, e.NewErrNoData("please provide user id")
 	}
 	for i, u := range r.data {
 		if u.ID != uid {
@@ -48,7 +78,25 @@ func (r *UserRepository) FindByUserID(uid string) (interface{}, error) {
 		}
 		return r.BuildUserUsecaseModel(r.data[i]), nil
 	}
-	return nil, nil
+	return nil, e.NewErrNoData("no user found for id " + uid)
+}
+
+func (r *UserRepository) Create(user interface{}) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ucUser, ok := user.(*uc.User)
+	if !ok {
+		return errors.New("failed to create user, invalid type of user")
+	}
+
+	for _, u := range r.data {
+		if u.ID == ucUser.ID {
+			return e.NewErrDuplicateData("user " + ucUser.ID + " already exists")
+		}
+	}
+
+	r.data = append(r.data, r.BuildUserRepositoryModel(ucUser))
+	return nil
 }
 
 func (r *UserRepository) BuildUserUsecaseModel(user interface{}) *uc.User {
@@ -56,30 +104,59 @@ func (r *UserRepository) BuildUserUsecaseModel(user interface{}) *uc.User {
 	case *model.User:
 		u := user.(*model.User)
 		return &uc.User{
-			ID:       u.ID,
-			Username: u.Name,
-			Phone:    u.Phone,
-			Email:    u.Email,
-			BillingAddr: &uc.Address{
-				Street:      u.BillingAddress.StreetName,
-				City:        u.BillingAddress.City,
-				Postal:      u.BillingAddress.Postal,
-				Province:    u.BillingAddress.Province,
-				Region:      u.BillingAddress.Region,
-				Country:     u.BillingAddress.Country,
-				AddressType: u.BillingAddress.AddressType,
-			},
-			ShippingAddr: &uc.Address{
-				Street:      u.ShippingAddress.StreetName,
-				City:        u.ShippingAddress.City,
-				Postal:      u.ShippingAddress.Postal,
-				Province:    u.ShippingAddress.Province,
-				Region:      u.ShippingAddress.Region,
-				Country:     u.ShippingAddress.Country,
-				AddressType: u.ShippingAddress.AddressType,
-			},
+			ID:           u.ID,
+			Username:     u.Name,
+			Phone:        u.Phone,
+			Email:        u.Email,
+			BillingAddr:  buildAddressUsecaseModel(u.BillingAddress),
+			ShippingAddr: buildAddressUsecaseModel(u.ShippingAddress),
+			Role:         u.Role,
+			PasswordHash: u.PasswordHash,
 		}
 	default:
 		return nil
 	}
 }
+
+func (r *UserRepository) BuildUserRepositoryModel(user *uc.User) *model.User {
+	return &model.User{
+		ID:              user.ID,
+		Name:            user.Username,
+		Phone:           user.Phone,
+		Email:           user.Email,
+		BillingAddress:  buildAddressRepositoryModel(user.BillingAddr),
+		ShippingAddress: buildAddressRepositoryModel(user.ShippingAddr),
+		Role:            user.Role,
+		PasswordHash:    user.PasswordHash,
+	}
+}
+
+func buildAddressUsecaseModel(addr *model.Address) *uc.Address {
+	if addr == nil {
+		return nil
+	}
+	return &uc.Address{
+		Street:      addr.StreetName,
+		City:        addr.City,
+		Postal:      addr.Postal,
+		Province:    addr.Province,
+		Region:      addr.Region,
+		Country:     addr.Country,
+		AddressType: addr.AddressType,
+	}
+}
+
+func buildAddressRepositoryModel(addr *uc.Address) *model.Address {
+	if addr == nil {
+		return nil
+	}
+	return &model.Address{
+		StreetName:  addr.Street,
+		City:        addr.City,
+		Postal:      addr.Postal,
+		Province:    addr.Province,
+		Region:      addr.Region,
+		Country:     addr.Country,
+		AddressType: addr.AddressType,
+	}
+}
@@ -0,0 +1,59 @@
+package inmem
+
+import (
+	"sync"
+
+	"github.com/yauritux/cartsvc/pkg/adapter/repository/inmem/model"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+	uc "github.com/yauritux/cartsvc/pkg/usecase/products"
+)
+
+type WarehouseRepository struct {
+	mu   sync.RWMutex
+	data []*model.Warehouse
+}
+
+func NewWarehouseRepository() *WarehouseRepository {
+	return NewWarehouseRepositoryWith([]*model.Warehouse{
+		{ID: "main", Name: "Jakarta Main Warehouse", City: "Jakarta", Province: "DKI Jakarta", Region: "Java", Postal: "12750", Country: "Indonesia"},
+		{ID: "surabaya", Name: "Surabaya Warehouse", City: "Surabaya", Province: "Jawa Timur", Region: "Java", Postal: "60111", Country: "Indonesia"},
+		{ID: "medan", Name: "Medan Warehouse", City: "Medan", Province: "Sumatera Utara", Region: "Sumatra", Postal: "20111", Country: "Indonesia"},
+	})
+}
+
+// NewWarehouseRepositoryWith creates the repository upon the given warehouse records
+func NewWarehouseRepositoryWith(records []*model.Warehouse) *WarehouseRepository {
+	return &WarehouseRepository{data: records}
+}
+
+func (r *WarehouseRepository) Records() []*model.Warehouse {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.data
+}
+
+func (r *WarehouseRepository) FindByWarehouseID(id string) (interface{}, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, w := range r.data {
+		if w.ID == id {
+			return buildWarehouseUsecaseModel(w), nil
+		}
+	}
+	return nil, e.NewErrNoData("no warehouse found for id " + id)
+}
+
+func (r *WarehouseRepository) FetchAll() (interface{}, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	warehouses := make([]*uc.Warehouse, 0)
+	for _, w := range r.data {
+		warehouses = append(warehouses, buildWarehouseUsecaseModel(w))
+	}
+	return warehouses, nil
+}
+
+func buildWarehouseUsecaseModel(w *model.Warehouse) *uc.Warehouse {
+	warehouse := uc.Warehouse(*w)
+	return &warehouse
+}
@@ -0,0 +1,483 @@
+package rest
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+	authUsecase "github.com/yauritux/cartsvc/pkg/usecase/auth"
+	cartUsecase "github.com/yauritux/cartsvc/pkg/usecase/carts"
+	loyaltyUsecase "github.com/yauritux/cartsvc/pkg/usecase/loyalty"
+	prodUsecase "github.com/yauritux/cartsvc/pkg/usecase/products"
+	subscriptionUsecase "github.com/yauritux/cartsvc/pkg/usecase/subscriptions"
+	userUsecase "github.com/yauritux/cartsvc/pkg/usecase/users"
+)
+
+type Handler struct {
+	auth          *authUsecase.AuthUsecase
+	users         *userUsecase.UserUsecase
+	carts         *cartUsecase.CartUsecase
+	products      *prodUsecase.ProductUsecase
+	subscriptions *subscriptionUsecase.SubscriptionUsecase
+	loyalty       *loyaltyUsecase.LoyaltyUsecase
+}
+
+type loginRequest struct {
+	UserID   string `json:"user_id"`
+	Password string `json:"password"`
+	// GuestToken is the session token of the guest cart to be merged into the user cart
+	GuestToken string `json:"guest_token"`
+}
+
+// registerRequest always registers a customer, the billing address defaults to the shipping one
+type registerRequest struct {
+	UserID          string          `json:"user_id"`
+	Name            string          `json:"name"`
+	Email           string          `json:"email"`
+	Phone           string          `json:"phone"`
+	Password        string          `json:"password"`
+	ShippingAddress *addressRequest `json:"shipping_address"`
+	BillingAddress  *addressRequest `json:"billing_address"`
+}
+
+type addressRequest struct {
+	Street   string `json:"street"`
+	City     string `json:"city"`
+	Postal   string `json:"postal"`
+	Province string `json:"province"`
+	Region   string `json:"region"`
+	Country  string `json:"country"`
+}
+
+type addItemRequest struct {
+	ProductID string `json:"product_id"`
+	SKU       string `json:"sku"`
+	Qty       int    `json:"qty"`
+}
+
+// checkoutRequest is optional, no points are redeemed without it
+type checkoutRequest struct {
+	Points int `json:"points"`
+}
+
+type subscribeRequest struct {
+	ProductID string `json:"product_id"`
+	SKU       string `json:"sku"`
+}
+
+func NewHandler(a *authUsecase.AuthUsecase, u *userUsecase.UserUsecase, c *cartUsecase.CartUsecase, p *prodUsecase.ProductUsecase,
+	s *subscriptionUsecase.SubscriptionUsecase, l *loyaltyUsecase.LoyaltyUsecase) *Handler {
+	return &Handler{
+		auth:          a,
+		users:         u,
+		carts:         c,
+		products:      p,
+		subscriptions: s,
+		loyalty:       l,
+	}
+}
+
+// Routes exposes the HTTP endpoints:
+//
+//	POST /users                     public, registers a customer
+//	POST /login                     public, opens the user cart, merging the cart of the guest_token session if any
+//	POST /guest                     public, starts a guest session owning a cart
+//	GET  /products                  public, see parseProductQuery for the parameters
+//	GET  /products/{id}             public
+//	POST /logout                    authenticated
+//	GET  /carts/{user_id}           authenticated, owner or admin, the latest cart which may be checked out already
+//	POST /carts/{user_id}/items     authenticated, owner or admin
+//	POST /carts/{user_id}/refresh   authenticated, owner or admin, reprices the cart
+//	POST /carts/{user_id}/checkout  authenticated, owner or admin, redeems the loyalty points of {"points":n} if any
+//	GET  /subscriptions/{user_id}   authenticated, owner or admin, the restocks the user waits for
+//	POST /subscriptions/{user_id}   authenticated, owner or admin, notifies the user once the product is restocked
+//	DELETE /subscriptions/{user_id}/{sku}  authenticated, owner or admin
+//	GET  /loyalty/{user_id}         authenticated, owner or admin, the loyalty points along with their ledger
+func (h *Handler) Routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/users", h.register)
+	mux.HandleFunc("/login", h.login)
+	mux.HandleFunc("/guest", h.startGuest)
+	mux.HandleFunc("/products", h.searchProducts)
+	mux.HandleFunc("/products/", h.getProduct)
+	mux.Handle("/logout", Authenticate(h.auth, http.HandlerFunc(h.logout)))
+	mux.Handle("/carts/", Authenticate(h.auth, http.HandlerFunc(h.cart)))
+	mux.Handle("/subscriptions/", Authenticate(h.auth, http.HandlerFunc(h.subscription)))
+	mux.Handle("/loyalty/", Authenticate(h.auth, http.HandlerFunc(h.balance)))
+	return mux
+}
+
+func (h *Handler) register(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req registerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, e.NewErrInvalidData("invalid registration request body"))
+		return
+	}
+	if req.BillingAddress == nil {
+		req.BillingAddress = req.ShippingAddress
+	}
+
+	u, err := h.users.RegisterUser(&userUsecase.User{
+		ID:           req.UserID,
+		Username:     req.Name,
+		Email:        req.Email,
+		Phone:        req.Phone,
+		Password:     req.Password,
+		ShippingAddr: buildAddress(req.ShippingAddress),
+		BillingAddr:  buildAddress(req.BillingAddress),
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, buildUserResponse(u.(*userUsecase.User)))
+}
+
+func buildAddress(req *addressRequest) *userUsecase.Address {
+	if req == nil {
+		return nil
+	}
+	return &userUsecase.Address{
+		Street:   req.Street,
+		City:     req.City,
+		Postal:   req.Postal,
+		Province: req.Province,
+		Region:   req.Region,
+		Country:  req.Country,
+	}
+}
+
+func (h *Handler) login(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req loginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, e.NewErrInvalidData("invalid login request body"))
+		return
+	}
+
+	//the guest session is checked first, a wrong guest token should not leave a session behind
+	var guest *authUsecase.Principal
+	if req.GuestToken != "" {
+		p, err := h.auth.Authenticate(req.GuestToken)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if guest = p.(*authUsecase.Principal); !guest.IsGuest() {
+			writeError(w, e.NewErrInvalidData("'guest_token' does not belong to a guest session"))
+			return
+		}
+	}
+
+	s, err := h.auth.Login(req.UserID, req.Password)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	session := s.(*authUsecase.Session)
+	res := &loginResponse{sessionResponse: buildSessionResponse(session)}
+	carts := h.carts.ForPrincipal(&authUsecase.Principal{UserID: session.UserID, Role: session.Role})
+	if guest == nil {
+		if err := carts.OpenCart(session.UserID); err != nil {
+			h.auth.Logout(session.Token)
+			writeError(w, err)
+			return
+		}
+	} else {
+		merged, err := carts.MergeCarts(guest.UserID, session.UserID)
+		if err != nil {
+			//the token is never handed out, the session would be left behind otherwise
+			h.auth.Logout(session.Token)
+			writeError(w, err)
+			return
+		}
+		h.auth.Logout(req.GuestToken)
+		res.Cart, res.LeftOut = buildCartMergeResponse(merged.(*cartUsecase.CartMergeResult))
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (h *Handler) startGuest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	s, err := h.auth.StartGuestSession()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	session := s.(*authUsecase.Session)
+	carts := h.carts.ForPrincipal(&authUsecase.Principal{UserID: session.UserID, Role: session.Role})
+	if err := carts.OpenCart(session.UserID); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, buildSessionResponse(session))
+}
+
+func (h *Handler) logout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := h.auth.Logout(bearerToken(r)); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) getProduct(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	p, err := h.products.ShowProduct(strings.TrimPrefix(r.URL.Path, "/products/"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, buildProductResponse(p.(*prodUsecase.Product)))
+}
+
+func (h *Handler) searchProducts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	query, err := parseProductQuery(r.URL.Query())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	page, err := h.products.SearchProducts(query)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, buildProductPageResponse(page.(*prodUsecase.ProductPage)))
+}
+
+// parseProductQuery reads the q, min_price, max_price, in_stock, sort, desc, cursor and limit parameters
+func parseProductQuery(params url.Values) (*prodUsecase.ProductQuery, error) {
+	query := &prodUsecase.ProductQuery{
+		Keyword: params.Get("q"),
+		SortBy:  prodUsecase.SortField(params.Get("sort")),
+		Cursor:  params.Get("cursor"),
+	}
+
+	var err error
+	if v := params.Get("min_price"); v != "" {
+		if query.MinPrice, err = parsePrice(v); err != nil {
+			return nil, e.NewErrInvalidData("invalid 'min_price' " + v)
+		}
+	}
+	if v := params.Get("max_price"); v != "" {
+		if query.MaxPrice, err = parsePrice(v); err != nil {
+			return nil, e.NewErrInvalidData("invalid 'max_price' " + v)
+		}
+	}
+	if v := params.Get("in_stock"); v != "" {
+		if query.InStockOnly, err = strconv.ParseBool(v); err != nil {
+			return nil, e.NewErrInvalidData("invalid 'in_stock' " + v)
+		}
+	}
+	if v := params.Get("desc"); v != "" {
+		if query.Descending, err = strconv.ParseBool(v); err != nil {
+			return nil, e.NewErrInvalidData("invalid 'desc' " + v)
+		}
+	}
+	if v := params.Get("limit"); v != "" {
+		if query.Limit, err = strconv.Atoi(v); err != nil {
+			return nil, e.NewErrInvalidData("invalid 'limit' " + v)
+		}
+	}
+	return query, nil
+}
+
+func parsePrice(v string) (*float64, error) {
+	price, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &price, nil
+}
+
+func (h *Handler) cart(w http.ResponseWriter, r *http.Request) {
+	principal, ok := authUsecase.FromContext(r.Context())
+	if !ok {
+		writeError(w, e.NewErrUnauthorized("unauthenticated request"))
+		return
+	}
+	carts := h.carts.ForPrincipal(principal)
+
+	segments := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/carts/"), "/"), "/")
+	userID := segments[0]
+
+	switch {
+	case len(segments) == 1 && r.Method == http.MethodGet:
+		h.showLatestCart(w, carts, userID)
+	case len(segments) == 2 && segments[1] == "items" && r.Method == http.MethodPost:
+		h.addItem(w, r, carts, userID)
+	case len(segments) == 2 && segments[1] == "refresh" && r.Method == http.MethodPost:
+		h.refresh(w, carts, userID)
+	case len(segments) == 2 && segments[1] == "checkout" && r.Method == http.MethodPost:
+		h.checkout(w, r, carts, userID)
+	default:
+		writeError(w, e.NewErrNoData("no route found for "+r.Method+" "+r.URL.Path))
+	}
+}
+
+func (h *Handler) showCart(w http.ResponseWriter, carts *cartUsecase.CartUsecase, userID string) {
+	c, err := carts.FetchUserCart(userID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, buildCartResponse(c.(*cartUsecase.Cart)))
+}
+
+// showLatestCart shows the open cart of the user, or the order just checked out until a new cart is opened
+func (h *Handler) showLatestCart(w http.ResponseWriter, carts *cartUsecase.CartUsecase, userID string) {
+	c, err := carts.FetchLatestCart(userID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, buildCartResponse(c.(*cartUsecase.Cart)))
+}
+
+func (h *Handler) addItem(w http.ResponseWriter, r *http.Request, carts *cartUsecase.CartUsecase, userID string) {
+	var req addItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, e.NewErrInvalidData("invalid cart item request body"))
+		return
+	}
+	if req.Qty <= 0 {
+		writeError(w, e.NewErrInvalidData("'qty' should be greater than zero"))
+		return
+	}
+
+	if err := carts.AddToCart(userID, &cartUsecase.CartItem{ID: req.ProductID, SKU: req.SKU, Qty: req.Qty}); err != nil {
+		writeError(w, err)
+		return
+	}
+	h.showCart(w, carts, userID)
+}
+
+func (h *Handler) refresh(w http.ResponseWriter, carts *cartUsecase.CartUsecase, userID string) {
+	res, err := carts.RefreshCart(userID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, buildCartRefreshResponse(res.(*cartUsecase.CartRefresh)))
+}
+
+func (h *Handler) checkout(w http.ResponseWriter, r *http.Request, carts *cartUsecase.CartUsecase, userID string) {
+	var req checkoutRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		writeError(w, e.NewErrInvalidData("invalid checkout request body"))
+		return
+	}
+
+	c, err := carts.CheckoutWithPoints(userID, req.Points)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, buildCartResponse(c.(*cartUsecase.Cart)))
+}
+
+func (h *Handler) subscription(w http.ResponseWriter, r *http.Request) {
+	principal, ok := authUsecase.FromContext(r.Context())
+	if !ok {
+		writeError(w, e.NewErrUnauthorized("unauthenticated request"))
+		return
+	}
+	subscriptions := h.subscriptions.ForPrincipal(principal)
+
+	segments := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/subscriptions/"), "/"), "/")
+	userID := segments[0]
+
+	switch {
+	case len(segments) == 1 && r.Method == http.MethodGet:
+		h.listSubscriptions(w, subscriptions, userID)
+	case len(segments) == 1 && r.Method == http.MethodPost:
+		h.subscribe(w, r, subscriptions, userID)
+	case len(segments) == 2 && r.Method == http.MethodDelete:
+		if err := subscriptions.Unsubscribe(userID, segments[1]); err != nil {
+			writeError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, e.NewErrNoData("no route found for "+r.Method+" "+r.URL.Path))
+	}
+}
+
+func (h *Handler) listSubscriptions(w http.ResponseWriter, subscriptions *subscriptionUsecase.SubscriptionUsecase, userID string) {
+	res, err := subscriptions.FetchSubscriptions(userID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	views := make([]*subscriptionResponse, 0)
+	for _, s := range res.([]*subscriptionUsecase.Subscription) {
+		views = append(views, buildSubscriptionResponse(s))
+	}
+	writeJSON(w, http.StatusOK, views)
+}
+
+func (h *Handler) subscribe(w http.ResponseWriter, r *http.Request, subscriptions *subscriptionUsecase.SubscriptionUsecase, userID string) {
+	var req subscribeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, e.NewErrInvalidData("invalid subscription request body"))
+		return
+	}
+
+	s, err := subscriptions.Subscribe(userID, req.ProductID, req.SKU)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, buildSubscriptionResponse(s.(*subscriptionUsecase.Subscription)))
+}
+
+func (h *Handler) balance(w http.ResponseWriter, r *http.Request) {
+	principal, ok := authUsecase.FromContext(r.Context())
+	if !ok {
+		writeError(w, e.NewErrUnauthorized("unauthenticated request"))
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := strings.Trim(strings.TrimPrefix(r.URL.Path, "/loyalty/"), "/")
+	res, err := h.loyalty.ForPrincipal(principal).FetchBalance(userID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, buildBalanceResponse(res.(*loyaltyUsecase.Balance)))
+}
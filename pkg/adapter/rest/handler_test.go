@@ -0,0 +1,430 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/yauritux/cartsvc/pkg/adapter/address/local"
+	"github.com/yauritux/cartsvc/pkg/adapter/repository/inmem"
+	"github.com/yauritux/cartsvc/pkg/adapter/repository/inmem/model"
+	"github.com/yauritux/cartsvc/pkg/adapter/security"
+	"github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+	authUsecase "github.com/yauritux/cartsvc/pkg/usecase/auth"
+	cartUsecase "github.com/yauritux/cartsvc/pkg/usecase/carts"
+	loyaltyUsecase "github.com/yauritux/cartsvc/pkg/usecase/loyalty"
+	prodUsecase "github.com/yauritux/cartsvc/pkg/usecase/products"
+	subscriptionUsecase "github.com/yauritux/cartsvc/pkg/usecase/subscriptions"
+	userUsecase "github.com/yauritux/cartsvc/pkg/usecase/users"
+)
+
+type testServer struct {
+	routes   http.Handler
+	products *prodUsecase.ProductUsecase
+	carts    *cartUsecase.CartUsecase
+	sessions *recordingSessions
+}
+
+// recordingSessions keeps track of the sessions ever saved which are not deleted yet
+type recordingSessions struct {
+	*inmem.SessionRepository
+	live map[string]string
+}
+
+func (r *recordingSessions) Save(session interface{}) error {
+	if err := r.SessionRepository.Save(session); err != nil {
+		return err
+	}
+	s := session.(*authUsecase.Session)
+	r.live[s.Token] = s.UserID
+	return nil
+}
+
+func (r *recordingSessions) Delete(token string) error {
+	delete(r.live, token)
+	return r.SessionRepository.Delete(token)
+}
+
+func (r *recordingSessions) liveSessionsOf(userID string) int {
+	count := 0
+	for _, v := range r.live {
+		if v == userID {
+			count++
+		}
+	}
+	return count
+}
+
+// newTestServer wires the handler to the seeded inmem repositories, the yauritux customer owning an empty cart
+func newTestServer() *testServer {
+	prodRepo := inmem.NewProductRepository()
+	userRepo := inmem.NewUserRepository()
+	cartRepo := inmem.NewCartRepositoryWith(make([]*model.Cart, 0))
+	cartRepo.Open("yauritux", time.Now())
+	warehouseRepo := inmem.NewWarehouseRepository()
+
+	sessions := &recordingSessions{SessionRepository: inmem.NewSessionRepository(), live: make(map[string]string)}
+	hasher := security.NewBcryptHasher(bcrypt.MinCost)
+	auth := authUsecase.NewAuthUsecase(userRepo, sessions, hasher)
+	users := userUsecase.NewUserUsecase(userRepo,
+		userUsecase.WithAddressValidator(local.NewAddressValidator()),
+		userUsecase.WithPasswordHasher(hasher),
+	)
+	products := prodUsecase.NewProductUsecase(prodRepo, prodUsecase.WithWarehouseRepository(warehouseRepo))
+	loyalty := loyaltyUsecase.NewLoyaltyUsecase(inmem.NewLoyaltyRepository())
+	carts := cartUsecase.NewCartUsecase(cartRepo, prodRepo,
+		cartUsecase.WithUserRepository(userRepo),
+		cartUsecase.WithAddressValidator(local.NewAddressValidator()),
+		cartUsecase.WithWarehouseRepository(warehouseRepo),
+		cartUsecase.WithLoyaltyProgram(loyalty),
+	)
+	subscriptions := subscriptionUsecase.NewSubscriptionUsecase(inmem.NewSubscriptionRepository(), prodRepo, userRepo)
+
+	return &testServer{
+		routes:   NewHandler(auth, users, carts, products, subscriptions, loyalty).Routes(),
+		products: products,
+		carts:    carts,
+		sessions: sessions,
+	}
+}
+
+func (s *testServer) serve(method string, path string, authorization string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	rec := httptest.NewRecorder()
+	s.routes.ServeHTTP(rec, req)
+	return rec
+}
+
+func (s *testServer) login(userID string, password string) string {
+	rec := s.serve(http.MethodPost, "/login", "", `{"user_id":"`+userID+`","password":"`+password+`"}`)
+	So(rec.Code, ShouldEqual, http.StatusOK)
+	var res sessionResponse
+	So(json.NewDecoder(rec.Body).Decode(&res), ShouldBeNil)
+	return "Bearer " + res.Token
+}
+
+func decodeError(rec *httptest.ResponseRecorder) string {
+	var res errorResponse
+	So(json.NewDecoder(rec.Body).Decode(&res), ShouldBeNil)
+	return res.Error
+}
+
+func decodeCart(rec *httptest.ResponseRecorder) *cartResponse {
+	var res cartResponse
+	So(json.NewDecoder(rec.Body).Decode(&res), ShouldBeNil)
+	return &res
+}
+
+func TestHandler(t *testing.T) {
+
+	Convey("1. Given a request to an authenticated endpoint", t, func() {
+
+		srv := newTestServer()
+
+		Convey("-> Negative Scenarios", func() {
+			Convey("-> Should reject a request without any bearer token", func() {
+				rec := srv.serve(http.MethodGet, "/carts/yauritux", "", "")
+				So(rec.Code, ShouldEqual, http.StatusUnauthorized)
+				So(decodeError(rec), ShouldEqual, "missing bearer token")
+			})
+			Convey("-> Should reject a request authorized by another scheme", func() {
+				rec := srv.serve(http.MethodGet, "/carts/yauritux", "Basic eWF1cml0dXg6c2hpbm9iaQ==", "")
+				So(rec.Code, ShouldEqual, http.StatusUnauthorized)
+			})
+			Convey("-> Should reject an unknown token", func() {
+				rec := srv.serve(http.MethodGet, "/carts/yauritux", "Bearer unknown", "")
+				So(rec.Code, ShouldEqual, http.StatusUnauthorized)
+				So(decodeError(rec), ShouldEqual, "invalid session token")
+			})
+			Convey("-> Should reject the token of a session logged out", func() {
+				token := srv.login("yauritux", "shinobi")
+				So(srv.serve(http.MethodPost, "/logout", token, "").Code, ShouldEqual, http.StatusNoContent)
+				So(srv.serve(http.MethodGet, "/carts/yauritux", token, "").Code, ShouldEqual, http.StatusUnauthorized)
+			})
+		})
+
+		Convey("-> Positive Scenarios", func() {
+			Convey("-> Should read the bearer token whatever the case of the scheme", func() {
+				token := strings.TrimPrefix(srv.login("yauritux", "shinobi"), "Bearer ")
+				rec := srv.serve(http.MethodGet, "/carts/yauritux", "bearer  "+token+" ", "")
+				So(rec.Code, ShouldEqual, http.StatusOK)
+			})
+		})
+	})
+
+	Convey("2. Given a user logging in", t, func() {
+
+		srv := newTestServer()
+
+		Convey("-> Negative Scenarios", func() {
+			Convey("-> Should reject a wrong password", func() {
+				rec := srv.serve(http.MethodPost, "/login", "", `{"user_id":"yauritux","password":"ronin"}`)
+				So(rec.Code, ShouldEqual, http.StatusUnauthorized)
+			})
+			Convey("-> Should reject an invalid request body", func() {
+				rec := srv.serve(http.MethodPost, "/login", "", `{"user_id":`)
+				So(rec.Code, ShouldEqual, http.StatusBadRequest)
+			})
+			Convey("-> Should only accept the POST method", func() {
+				So(srv.serve(http.MethodGet, "/login", "", "").Code, ShouldEqual, http.StatusMethodNotAllowed)
+			})
+		})
+	})
+
+	Convey("3. Given the carts of the customers and the admin", t, func() {
+
+		srv := newTestServer()
+		customer := srv.login("yauritux", "shinobi")
+		admin := srv.login("admin", "hokage")
+
+		Convey("-> Negative Scenarios", func() {
+			Convey("-> A customer should not access the cart of another user", func() {
+				rec := srv.serve(http.MethodGet, "/carts/admin", customer, "")
+				So(rec.Code, ShouldEqual, http.StatusForbidden)
+				rec = srv.serve(http.MethodPost, "/carts/admin/items", customer, `{"product_id":"001","qty":1}`)
+				So(rec.Code, ShouldEqual, http.StatusForbidden)
+			})
+			Convey("-> A customer should not access the loyalty points of another user", func() {
+				So(srv.serve(http.MethodGet, "/loyalty/admin", customer, "").Code, ShouldEqual, http.StatusForbidden)
+			})
+			Convey("-> Should return not found for an unknown route, product or cart", func() {
+				So(srv.serve(http.MethodGet, "/carts/yauritux/unknown", customer, "").Code, ShouldEqual, http.StatusNotFound)
+				So(srv.serve(http.MethodGet, "/products/404", "", "").Code, ShouldEqual, http.StatusNotFound)
+				So(srv.serve(http.MethodGet, "/carts/kotaro", admin, "").Code, ShouldEqual, http.StatusNotFound)
+			})
+			Convey("-> Should return a conflict when redeeming more points than the balance", func() {
+				rec := srv.serve(http.MethodPost, "/carts/yauritux/items", customer, `{"product_id":"001","qty":1}`)
+				So(rec.Code, ShouldEqual, http.StatusOK)
+				rec = srv.serve(http.MethodPost, "/carts/yauritux/checkout", customer, `{"points":100}`)
+				So(rec.Code, ShouldEqual, http.StatusConflict)
+			})
+			Convey("-> Should return a conflict telling the product is out of stock", func() {
+				rec := srv.serve(http.MethodPost, "/carts/yauritux/items", customer, `{"product_id":"001","qty":99999}`)
+				So(rec.Code, ShouldEqual, http.StatusConflict)
+				var res errorResponse
+				So(json.NewDecoder(rec.Body).Decode(&res), ShouldBeNil)
+				So(res.Code, ShouldEqual, "out_of_stock")
+			})
+			Convey("-> Should reject an invalid quantity", func() {
+				rec := srv.serve(http.MethodPost, "/carts/yauritux/items", customer, `{"product_id":"001","qty":0}`)
+				So(rec.Code, ShouldEqual, http.StatusBadRequest)
+			})
+		})
+
+		Convey("-> Positive Scenarios", func() {
+			Convey("-> A customer should access their own cart", func() {
+				rec := srv.serve(http.MethodPost, "/carts/yauritux/items", customer, `{"product_id":"001","qty":2}`)
+				So(rec.Code, ShouldEqual, http.StatusOK)
+				var cart cartResponse
+				So(json.NewDecoder(rec.Body).Decode(&cart), ShouldBeNil)
+				So(cart.UserID, ShouldEqual, "yauritux")
+				So(cart.Items[0].Qty, ShouldEqual, 2)
+				So(cart.Items[0].Fulfillment, ShouldEqual, "in_stock")
+			})
+			Convey("-> An admin should access the cart of any user", func() {
+				So(srv.serve(http.MethodGet, "/carts/yauritux", admin, "").Code, ShouldEqual, http.StatusOK)
+				So(srv.serve(http.MethodGet, "/loyalty/yauritux", admin, "").Code, ShouldEqual, http.StatusOK)
+			})
+		})
+	})
+
+	Convey("4. Given a guest shopping before logging in", t, func() {
+
+		srv := newTestServer()
+		rec := srv.serve(http.MethodPost, "/guest", "", "")
+		So(rec.Code, ShouldEqual, http.StatusCreated)
+		var guest sessionResponse
+		So(json.NewDecoder(rec.Body).Decode(&guest), ShouldBeNil)
+		So(guest.Role, ShouldEqual, string(enum.Guest))
+		guestToken := "Bearer " + guest.Token
+
+		rec = srv.serve(http.MethodPost, "/carts/"+guest.UserID+"/items", guestToken, `{"product_id":"001","qty":3}`)
+		So(rec.Code, ShouldEqual, http.StatusOK)
+
+		Convey("-> Negative Scenarios", func() {
+			Convey("-> A guest should not access the cart of a user", func() {
+				So(srv.serve(http.MethodGet, "/carts/yauritux", guestToken, "").Code, ShouldEqual, http.StatusForbidden)
+			})
+			Convey("-> Should reject a guest token which does not belong to a guest session", func() {
+				customer := strings.TrimPrefix(srv.login("yauritux", "shinobi"), "Bearer ")
+				rec := srv.serve(http.MethodPost, "/login", "",
+					`{"user_id":"yauritux","password":"shinobi","guest_token":"`+customer+`"}`)
+				So(rec.Code, ShouldEqual, http.StatusBadRequest)
+			})
+			Convey("-> Should not leave the user session behind when the guest cart cannot be merged", func() {
+				_, err := srv.carts.CancelCart(guest.UserID)
+				So(err, ShouldBeNil)
+				rec := srv.serve(http.MethodPost, "/login", "",
+					`{"user_id":"yauritux","password":"shinobi","guest_token":"`+guest.Token+`"}`)
+				So(rec.Code, ShouldNotEqual, http.StatusOK)
+				So(srv.sessions.liveSessionsOf("yauritux"), ShouldEqual, 0)
+			})
+			Convey("-> Should reject an unknown guest token", func() {
+				rec := srv.serve(http.MethodPost, "/login", "",
+					`{"user_id":"yauritux","password":"shinobi","guest_token":"unknown"}`)
+				So(rec.Code, ShouldEqual, http.StatusUnauthorized)
+			})
+		})
+
+		Convey("-> Positive Scenarios", func() {
+			Convey("-> Should merge the guest cart into the user cart upon login and end the guest session", func() {
+				rec := srv.serve(http.MethodPost, "/login", "",
+					`{"user_id":"yauritux","password":"shinobi","guest_token":"`+guest.Token+`"}`)
+				So(rec.Code, ShouldEqual, http.StatusOK)
+				res := loginResponse{sessionResponse: &sessionResponse{}}
+				So(json.NewDecoder(rec.Body).Decode(&res), ShouldBeNil)
+				So(res.Token, ShouldNotBeEmpty)
+				So(res.Cart.UserID, ShouldEqual, "yauritux")
+				So(res.Cart.Items[0].ID, ShouldEqual, "001")
+				So(res.Cart.Items[0].Qty, ShouldEqual, 3)
+
+				So(srv.serve(http.MethodGet, "/carts/"+guest.UserID, guestToken, "").Code, ShouldEqual, http.StatusUnauthorized)
+			})
+		})
+	})
+
+	Convey("5. Given a cart line running out of a product which can be backordered", t, func() {
+
+		srv := newTestServer()
+		customer := srv.login("yauritux", "shinobi")
+		rec := srv.serve(http.MethodPost, "/carts/yauritux/items", customer, `{"product_id":"002","qty":2}`)
+		So(rec.Code, ShouldEqual, http.StatusOK)
+		_, err := srv.products.SetStockPolicy("002", &prodUsecase.StockPolicy{Mode: enum.Backorder})
+		So(err, ShouldBeNil)
+		_, err = srv.products.AdjustStock("002", -950)
+		So(err, ShouldBeNil)
+
+		Convey("-> Negative Scenarios", func() {
+			Convey("-> Should not checkout the cart before the buyer reviews the backordered line", func() {
+				rec := srv.serve(http.MethodPost, "/carts/yauritux/checkout", customer, "")
+				So(rec.Code, ShouldEqual, http.StatusConflict)
+				So(decodeError(rec), ShouldContainSubstring, "Sai is out of stock, the 2 units are backordered")
+			})
+		})
+
+		Convey("-> Positive Scenarios", func() {
+			Convey("-> Should tell the buyer the line is backordered upon refresh", func() {
+				rec := srv.serve(http.MethodPost, "/carts/yauritux/refresh", customer, "")
+				So(rec.Code, ShouldEqual, http.StatusOK)
+				var res cartRefreshResponse
+				So(json.NewDecoder(rec.Body).Decode(&res), ShouldBeNil)
+				So(res.Cart.Items[0].Fulfillment, ShouldEqual, "backorder")
+				So(res.Changes[0].Notice, ShouldEqual,
+					"Sai is out of stock, the 2 units are backordered and expected once the stock is replenished")
+			})
+		})
+	})
+
+	Convey("6. Given the users shopping once logged in", t, func() {
+
+		srv := newTestServer()
+		customer := srv.login("yauritux", "shinobi")
+
+		Convey("-> Positive Scenarios", func() {
+			Convey("-> Should open a cart for a user who has got none upon login", func() {
+				admin := srv.login("admin", "hokage")
+				rec := srv.serve(http.MethodGet, "/carts/admin", admin, "")
+				So(rec.Code, ShouldEqual, http.StatusOK)
+				rec = srv.serve(http.MethodPost, "/carts/admin/items", admin, `{"product_id":"001","qty":1}`)
+				So(rec.Code, ShouldEqual, http.StatusOK)
+			})
+			Convey("-> Should add the items into a new cart once the previous one is checked out", func() {
+				rec := srv.serve(http.MethodPost, "/carts/yauritux/items", customer, `{"product_id":"001","qty":1}`)
+				So(rec.Code, ShouldEqual, http.StatusOK)
+				rec = srv.serve(http.MethodPost, "/carts/yauritux/checkout", customer, "")
+				So(rec.Code, ShouldEqual, http.StatusOK)
+				var order cartResponse
+				So(json.NewDecoder(rec.Body).Decode(&order), ShouldBeNil)
+				So(order.Status, ShouldEqual, string(enum.PaymentProcessing))
+				rec = srv.serve(http.MethodGet, "/carts/yauritux", customer, "")
+				So(rec.Code, ShouldEqual, http.StatusOK)
+				So(decodeCart(rec).ID, ShouldEqual, order.ID)
+
+				rec = srv.serve(http.MethodPost, "/carts/yauritux/items", customer, `{"product_id":"002","qty":1}`)
+				So(rec.Code, ShouldEqual, http.StatusOK)
+				var cart cartResponse
+				So(json.NewDecoder(rec.Body).Decode(&cart), ShouldBeNil)
+				So(cart.ID, ShouldNotEqual, order.ID)
+				So(cart.Status, ShouldEqual, string(enum.Open))
+				So(len(cart.Items), ShouldEqual, 1)
+				So(cart.Items[0].ID, ShouldEqual, "002")
+			})
+		})
+	})
+
+	Convey("7. Given a visitor registering", t, func() {
+
+		srv := newTestServer()
+		body := `{"user_id":"hanzo","name":"Hattori Hanzo","email":"hanzo@iga.jp","password":"ninja","role":"admin",
+			"shipping_address":{"street":"Kalibata Raya No.2","city":"jakarta","postal":"12750","province":"dki jakarta",
+			"region":"jakarta selatan","country":"indonesia"}}`
+
+		Convey("-> Negative Scenarios", func() {
+			Convey("-> Should reject a user already registered", func() {
+				rec := srv.serve(http.MethodPost, "/users", "", `{"user_id":"yauritux","email":"y@cartsvc.local","password":"ronin"}`)
+				So(rec.Code, ShouldEqual, http.StatusConflict)
+			})
+			Convey("-> Should reject a registration without any password", func() {
+				rec := srv.serve(http.MethodPost, "/users", "", `{"user_id":"hanzo","email":"hanzo@iga.jp"}`)
+				So(rec.Code, ShouldEqual, http.StatusBadRequest)
+			})
+			Convey("-> Should reject an invalid address", func() {
+				rec := srv.serve(http.MethodPost, "/users", "",
+					`{"user_id":"hanzo","email":"hanzo@iga.jp","password":"ninja","shipping_address":{"city":"atlantis"}}`)
+				So(rec.Code, ShouldNotEqual, http.StatusCreated)
+			})
+		})
+
+		Convey("-> Positive Scenarios", func() {
+			Convey("-> Should register a customer whatever the role asked for, who can then login and checkout", func() {
+				rec := srv.serve(http.MethodPost, "/users", "", body)
+				So(rec.Code, ShouldEqual, http.StatusCreated)
+				var user userResponse
+				So(json.NewDecoder(rec.Body).Decode(&user), ShouldBeNil)
+				So(user.ID, ShouldEqual, "hanzo")
+				So(user.Role, ShouldEqual, string(enum.Customer))
+				So(user.ShippingAddress.Province, ShouldEqual, "DKI Jakarta")
+				So(user.BillingAddress, ShouldResemble, user.ShippingAddress)
+
+				token := srv.login("hanzo", "ninja")
+				rec = srv.serve(http.MethodPost, "/carts/hanzo/items", token, `{"product_id":"001","qty":1}`)
+				So(rec.Code, ShouldEqual, http.StatusOK)
+				So(srv.serve(http.MethodPost, "/carts/hanzo/checkout", token, "").Code, ShouldEqual, http.StatusOK)
+			})
+		})
+	})
+
+	Convey("8. Given the errors returned by the use cases", t, func() {
+
+		Convey("-> Should map every error into its status code", func() {
+			So(statusCode(e.NewErrNoData("")), ShouldEqual, http.StatusNotFound)
+			So(statusCode(e.NewErrInvalidData("")), ShouldEqual, http.StatusBadRequest)
+			So(statusCode(e.NewErrConversion("")), ShouldEqual, http.StatusBadRequest)
+			So(statusCode(e.NewErrDuplicateData("")), ShouldEqual, http.StatusConflict)
+			So(statusCode(e.NewErrConflict("")), ShouldEqual, http.StatusConflict)
+			So(statusCode(e.NewErrOutOfStock("")), ShouldEqual, http.StatusConflict)
+			So(statusCode(e.NewErrUnauthorized("")), ShouldEqual, http.StatusUnauthorized)
+			So(statusCode(e.NewErrForbidden("")), ShouldEqual, http.StatusForbidden)
+			So(statusCode(e.NewErrOrderLimitExceeded("")), ShouldEqual, http.StatusUnprocessableEntity)
+			So(statusCode(e.NewErrCustomerLimitExceeded("")), ShouldEqual, http.StatusUnprocessableEntity)
+			So(statusCode(errors.New("")), ShouldEqual, http.StatusInternalServerError)
+		})
+		Convey("-> Should tell the purchase limits and the stock running out apart by their error code", func() {
+			So(errorCode(e.NewErrOrderLimitExceeded("")), ShouldEqual, "order_limit_exceeded")
+			So(errorCode(e.NewErrCustomerLimitExceeded("")), ShouldEqual, "customer_limit_exceeded")
+			So(errorCode(e.NewErrOutOfStock("")), ShouldEqual, "out_of_stock")
+			So(errorCode(e.NewErrConflict("")), ShouldBeEmpty)
+		})
+	})
+}
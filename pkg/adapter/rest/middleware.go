@@ -0,0 +1,42 @@
+package rest
+
+import (
+	"net/http"
+	"strings"
+
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+	authUsecase "github.com/yauritux/cartsvc/pkg/usecase/auth"
+)
+
+// Authenticate resolves the bearer token of the request into a principal and injects it
+// into the request context, the request is rejected when the token is missing or invalid
+func Authenticate(auth *authUsecase.AuthUsecase, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := bearerToken(r)
+		if token == "" {
+			writeError(w, e.NewErrUnauthorized("missing bearer token"))
+			return
+		}
+
+		p, err := auth.Authenticate(token)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		principal, ok := p.(*authUsecase.Principal)
+		if !ok {
+			writeError(w, e.NewErrConversion("invalid type of principal"))
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(authUsecase.NewContext(r.Context(), principal)))
+	})
+}
+
+func bearerToken(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	if len(header) < 7 || !strings.EqualFold(header[:7], "bearer ") {
+		return ""
+	}
+	return strings.TrimSpace(header[7:])
+}
@@ -0,0 +1,399 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+	authUsecase "github.com/yauritux/cartsvc/pkg/usecase/auth"
+	cartUsecase "github.com/yauritux/cartsvc/pkg/usecase/carts"
+	loyaltyUsecase "github.com/yauritux/cartsvc/pkg/usecase/loyalty"
+	prodUsecase "github.com/yauritux/cartsvc/pkg/usecase/products"
+	subscriptionUsecase "github.com/yauritux/cartsvc/pkg/usecase/subscriptions"
+	userUsecase "github.com/yauritux/cartsvc/pkg/usecase/users"
+)
+
+type errorResponse struct {
+	Error string `json:"error"`
+	// Code tells apart the errors sharing the same status
+	Code string `json:"code,omitempty"`
+}
+
+type sessionResponse struct {
+	Token     string    `json:"token"`
+	UserID    string    `json:"user_id"`
+	Role      string    `json:"role"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type loginResponse struct {
+	*sessionResponse
+	// Cart is the user cart once the guest cart is merged into it
+	Cart    *cartResponse       `json:"cart,omitempty"`
+	LeftOut []*cartItemResponse `json:"left_out,omitempty"`
+}
+
+// userResponse never carries the password hash
+type userResponse struct {
+	ID              string           `json:"user_id"`
+	Name            string           `json:"name,omitempty"`
+	Email           string           `json:"email"`
+	Phone           string           `json:"phone,omitempty"`
+	Role            string           `json:"role"`
+	ShippingAddress *addressResponse `json:"shipping_address,omitempty"`
+	BillingAddress  *addressResponse `json:"billing_address,omitempty"`
+}
+
+type addressResponse struct {
+	Street   string `json:"street"`
+	City     string `json:"city"`
+	Postal   string `json:"postal"`
+	Province string `json:"province"`
+	Region   string `json:"region"`
+	Country  string `json:"country"`
+}
+
+type productResponse struct {
+	ID         string               `json:"id"`
+	Name       string               `json:"name"`
+	Type       string               `json:"type"`
+	Stock      int                  `json:"stock"`
+	Warehouses map[string]int       `json:"warehouse_stock,omitempty"`
+	Price      float64              `json:"price"`
+	Disc       float64              `json:"disc"`
+	Variants   []*variantResponse   `json:"variants,omitempty"`
+	Components []*componentResponse `json:"components,omitempty"`
+	// StockPolicy tells the buyers the product can be ordered beyond its stock
+	StockPolicy *stockPolicyResponse `json:"stock_policy,omitempty"`
+}
+
+type stockPolicyResponse struct {
+	Mode        string     `json:"mode"`
+	AvailableAt *time.Time `json:"available_at,omitempty"`
+}
+
+type componentResponse struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+	SKU  string `json:"sku,omitempty"`
+	Qty  int    `json:"qty"`
+}
+
+type variantResponse struct {
+	SKU        string            `json:"sku"`
+	Options    map[string]string `json:"options"`
+	Stock      int               `json:"stock"`
+	Warehouses map[string]int    `json:"warehouse_stock,omitempty"`
+	Price      float64           `json:"price"`
+	Disc       float64           `json:"disc"`
+}
+
+type productPageResponse struct {
+	Items      []*productResponse `json:"items"`
+	NextCursor string             `json:"next_cursor,omitempty"`
+}
+
+type cartResponse struct {
+	ID        string              `json:"id"`
+	UserID    string              `json:"user_id"`
+	Status    string              `json:"status"`
+	Items     []*cartItemResponse `json:"items"`
+	CreatedAt time.Time           `json:"created_at"`
+	Shipments []*shipmentResponse `json:"shipments,omitempty"`
+	// Redemption is the discount paid with the loyalty points upon checkout
+	Redemption *redemptionResponse `json:"redemption,omitempty"`
+}
+
+type redemptionResponse struct {
+	Points int     `json:"points"`
+	Amount float64 `json:"amount"`
+}
+
+type shipmentResponse struct {
+	WarehouseID string               `json:"warehouse_id,omitempty"`
+	Items       []*componentResponse `json:"items"`
+	Fulfillment string               `json:"fulfillment"`
+	AvailableAt *time.Time           `json:"available_at,omitempty"`
+}
+
+type cartItemResponse struct {
+	ID         string               `json:"id"`
+	Name       string               `json:"name"`
+	SKU        string               `json:"sku"`
+	Options    map[string]string    `json:"options,omitempty"`
+	Qty        int                  `json:"qty"`
+	Price      float64              `json:"price"`
+	Disc       float64              `json:"disc"`
+	Components []*componentResponse `json:"components,omitempty"`
+	// Fulfillment is in_stock unless the line waits for the stock, expected at AvailableAt
+	Fulfillment string     `json:"fulfillment"`
+	AvailableAt *time.Time `json:"available_at,omitempty"`
+}
+
+type cartRefreshResponse struct {
+	Cart    *cartResponse         `json:"cart"`
+	Changes []*cartChangeResponse `json:"changes"`
+}
+
+type cartChangeResponse struct {
+	SKU       string  `json:"sku"`
+	Name      string  `json:"name"`
+	Kind      string  `json:"kind"`
+	OldPrice  float64 `json:"old_price"`
+	NewPrice  float64 `json:"new_price"`
+	Qty       int     `json:"qty"`
+	Available int     `json:"available"`
+	Notice    string  `json:"notice"`
+	// AvailableAt is when the stock of a deferred line is expected
+	AvailableAt *time.Time `json:"available_at,omitempty"`
+}
+
+type balanceResponse struct {
+	UserID         string                 `json:"user_id"`
+	Points         int                    `json:"points"`
+	Value          float64                `json:"value"`
+	NextExpiry     *time.Time             `json:"next_expiry,omitempty"`
+	ExpiringPoints int                    `json:"expiring_points,omitempty"`
+	Entries        []*ledgerEntryResponse `json:"entries"`
+}
+
+type ledgerEntryResponse struct {
+	ID        string     `json:"id"`
+	CartID    string     `json:"cart_id,omitempty"`
+	Kind      string     `json:"kind"`
+	Points    int        `json:"points"`
+	RefID     string     `json:"ref_id,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+type subscriptionResponse struct {
+	ProductID   string    `json:"product_id"`
+	SKU         string    `json:"sku"`
+	ProductName string    `json:"product_name"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func buildSessionResponse(s *authUsecase.Session) *sessionResponse {
+	return &sessionResponse{
+		Token:     s.Token,
+		UserID:    s.UserID,
+		Role:      string(s.Role),
+		ExpiresAt: s.ExpiresAt,
+	}
+}
+
+func buildUserResponse(u *userUsecase.User) *userResponse {
+	return &userResponse{
+		ID:              u.ID,
+		Name:            u.Username,
+		Email:           u.Email,
+		Phone:           u.Phone,
+		Role:            string(u.Role),
+		ShippingAddress: buildAddressResponse(u.ShippingAddr),
+		BillingAddress:  buildAddressResponse(u.BillingAddr),
+	}
+}
+
+func buildAddressResponse(addr *userUsecase.Address) *addressResponse {
+	if addr == nil {
+		return nil
+	}
+	return &addressResponse{
+		Street:   addr.Street,
+		City:     addr.City,
+		Postal:   addr.Postal,
+		Province: addr.Province,
+		Region:   addr.Region,
+		Country:  addr.Country,
+	}
+}
+
+func buildProductResponse(p *prodUsecase.Product) *productResponse {
+	res := &productResponse{
+		ID:         p.ID,
+		Name:       p.Name,
+		Type:       string(p.Type),
+		Stock:      p.Stock,
+		Warehouses: p.WarehouseStock,
+		Price:      p.Price,
+		Disc:       p.Disc,
+	}
+	if res.Type == "" {
+		res.Type = string(enum.SimpleProduct)
+	}
+	for _, v := range p.Variants {
+		res.Variants = append(res.Variants, &variantResponse{
+			SKU:        v.SKU,
+			Options:    v.Options,
+			Stock:      v.Stock,
+			Warehouses: v.WarehouseStock,
+			Price:      v.Price,
+			Disc:       v.Disc,
+		})
+	}
+	for _, c := range p.Components {
+		res.Components = append(res.Components, &componentResponse{ID: c.ProductID, SKU: c.SKU, Qty: c.Qty})
+	}
+	if s := p.StockPolicy; s != nil {
+		res.StockPolicy = &stockPolicyResponse{Mode: string(s.Mode), AvailableAt: s.AvailableAt}
+	}
+	return res
+}
+
+func buildProductPageResponse(page *prodUsecase.ProductPage) *productPageResponse {
+	items := make([]*productResponse, 0)
+	for _, p := range page.Items {
+		items = append(items, buildProductResponse(p))
+	}
+	return &productPageResponse{Items: items, NextCursor: page.NextCursor}
+}
+
+func buildCartResponse(c *cartUsecase.Cart) *cartResponse {
+	items := make([]*cartItemResponse, 0)
+	for _, v := range c.Items {
+		items = append(items, buildCartItemResponse(v))
+	}
+	res := &cartResponse{
+		ID:        c.ID,
+		UserID:    c.UserID,
+		Status:    string(c.Status),
+		Items:     items,
+		CreatedAt: c.CreatedAt,
+	}
+	for _, s := range c.Shipments {
+		shipment := &shipmentResponse{
+			WarehouseID: s.WarehouseID,
+			Items:       make([]*componentResponse, 0),
+			Fulfillment: fulfillment(s.Fulfillment),
+			AvailableAt: s.AvailableAt,
+		}
+		for _, v := range s.Items {
+			shipment.Items = append(shipment.Items, &componentResponse{ID: v.ID, Name: v.Name, SKU: v.SKU, Qty: v.Qty})
+		}
+		res.Shipments = append(res.Shipments, shipment)
+	}
+	if c.Redemption != nil {
+		res.Redemption = &redemptionResponse{Points: c.Redemption.Points, Amount: c.Redemption.Amount}
+	}
+	return res
+}
+
+func buildCartItemResponse(v *cartUsecase.CartItem) *cartItemResponse {
+	item := &cartItemResponse{
+		ID:          v.ID,
+		Name:        v.Name,
+		SKU:         v.SKU,
+		Options:     v.Options,
+		Qty:         v.Qty,
+		Price:       v.Price,
+		Disc:        v.Disc,
+		Fulfillment: fulfillment(v.Fulfillment),
+		AvailableAt: v.AvailableAt,
+	}
+	for _, comp := range v.Components {
+		item.Components = append(item.Components, &componentResponse{
+			ID: comp.ID, Name: comp.Name, SKU: comp.SKU, Qty: comp.Qty,
+		})
+	}
+	return item
+}
+
+func buildCartMergeResponse(m *cartUsecase.CartMergeResult) (*cartResponse, []*cartItemResponse) {
+	leftOut := make([]*cartItemResponse, 0)
+	for _, v := range m.LeftOut {
+		leftOut = append(leftOut, buildCartItemResponse(v))
+	}
+	return buildCartResponse(m.Cart), leftOut
+}
+
+func buildCartRefreshResponse(r *cartUsecase.CartRefresh) *cartRefreshResponse {
+	res := &cartRefreshResponse{Cart: buildCartResponse(r.Cart), Changes: make([]*cartChangeResponse, 0)}
+	for _, v := range r.Changes {
+		res.Changes = append(res.Changes, &cartChangeResponse{
+			SKU:         v.SKU,
+			Name:        v.Name,
+			Kind:        string(v.Kind),
+			OldPrice:    v.OldPrice,
+			NewPrice:    v.NewPrice,
+			Qty:         v.Qty,
+			Available:   v.Available,
+			Notice:      v.Notice,
+			AvailableAt: v.AvailableAt,
+		})
+	}
+	return res
+}
+
+func buildSubscriptionResponse(s *subscriptionUsecase.Subscription) *subscriptionResponse {
+	return &subscriptionResponse{ProductID: s.ProductID, SKU: s.SKU, ProductName: s.ProductName, CreatedAt: s.CreatedAt}
+}
+
+func buildBalanceResponse(b *loyaltyUsecase.Balance) *balanceResponse {
+	res := &balanceResponse{
+		UserID:         b.UserID,
+		Points:         b.Points,
+		Value:          b.Value,
+		NextExpiry:     b.NextExpiry,
+		ExpiringPoints: b.ExpiringPoints,
+		Entries:        make([]*ledgerEntryResponse, 0),
+	}
+	for _, v := range b.Entries {
+		res.Entries = append(res.Entries, &ledgerEntryResponse{
+			ID: v.ID, CartID: v.CartID, Kind: string(v.Kind), Points: v.Points, RefID: v.RefID, CreatedAt: v.CreatedAt, ExpiresAt: v.ExpiresAt,
+		})
+	}
+	return res
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	writeJSON(w, statusCode(err), &errorResponse{Error: err.Error(), Code: errorCode(err)})
+}
+
+func statusCode(err error) int {
+	switch err.(type) {
+	case *e.ErrNoData:
+		return http.StatusNotFound
+	case *e.ErrInvalidData, *e.ErrConversion:
+		return http.StatusBadRequest
+	case *e.ErrDuplicateData, *e.ErrConflict, *e.ErrOutOfStock:
+		return http.StatusConflict
+	case *e.ErrUnauthorized:
+		return http.StatusUnauthorized
+	case *e.ErrForbidden:
+		return http.StatusForbidden
+	case *e.ErrOrderLimitExceeded, *e.ErrCustomerLimitExceeded:
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func errorCode(err error) string {
+	switch err.(type) {
+	case *e.ErrOrderLimitExceeded:
+		return "order_limit_exceeded"
+	case *e.ErrCustomerLimitExceeded:
+		return "customer_limit_exceeded"
+	case *e.ErrOutOfStock:
+		return "out_of_stock"
+	default:
+		return ""
+	}
+}
+
+// fulfillment spells out the fulfillment of the lines and shipments shipped out of the stock
+func fulfillment(f enum.Fulfillment) string {
+	if f == "" {
+		return string(enum.InStock)
+	}
+	return string(f)
+}
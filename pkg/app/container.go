@@ -0,0 +1,219 @@
+package app
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/yauritux/cartsvc/pkg/adapter/address/local"
+	event "github.com/yauritux/cartsvc/pkg/adapter/event/local"
+	notifier "github.com/yauritux/cartsvc/pkg/adapter/notifier/local"
+	"github.com/yauritux/cartsvc/pkg/adapter/repository/boltdb"
+	"github.com/yauritux/cartsvc/pkg/adapter/repository/file"
+	"github.com/yauritux/cartsvc/pkg/adapter/repository/inmem"
+	"github.com/yauritux/cartsvc/pkg/adapter/security"
+	"github.com/yauritux/cartsvc/pkg/config"
+	"github.com/yauritux/cartsvc/pkg/domain/repository"
+	"github.com/yauritux/cartsvc/pkg/domain/service"
+	authSvc "github.com/yauritux/cartsvc/pkg/usecase/auth"
+	cartSvc "github.com/yauritux/cartsvc/pkg/usecase/carts"
+	categorySvc "github.com/yauritux/cartsvc/pkg/usecase/categories"
+	loyaltySvc "github.com/yauritux/cartsvc/pkg/usecase/loyalty"
+	productSvc "github.com/yauritux/cartsvc/pkg/usecase/products"
+	subscriptionSvc "github.com/yauritux/cartsvc/pkg/usecase/subscriptions"
+	userSvc "github.com/yauritux/cartsvc/pkg/usecase/users"
+)
+
+// Container is the composition root, it wires the use cases to the adapters chosen by the config
+type Container struct {
+	Config *config.Config
+
+	ProductRepository repository.ProductRepository
+	UserRepository    repository.UserRepository
+	CartRepository    repository.CartRepository
+	SessionRepository repository.SessionRepository
+	// SavedListRepository holds the wishlists and the saved for later lists, they remain in memory
+	// whatever the backend
+	SavedListRepository repository.SavedListRepository
+	// WarehouseRepository lists the warehouses the orders are shipped from
+	WarehouseRepository repository.WarehouseRepository
+	// SubscriptionRepository holds who waits for a product to be restocked
+	SubscriptionRepository repository.SubscriptionRepository
+	// LoyaltyRepository holds the loyalty points ledger of every user
+	LoyaltyRepository repository.LoyaltyRepository
+	// CategoryRepository lets the earning rules target a category, the file backend keeps the
+	// seeded categories in memory
+	CategoryRepository repository.CategoryRepository
+
+	UserUsecase         *userSvc.UserUsecase
+	ProductUsecase      *productSvc.ProductUsecase
+	CartUsecase         *cartSvc.CartUsecase
+	AuthUsecase         *authSvc.AuthUsecase
+	SubscriptionUsecase *subscriptionSvc.SubscriptionUsecase
+	LoyaltyUsecase      *loyaltySvc.LoyaltyUsecase
+
+	// Events carries what happened within the use cases (e.g. the expired carts) to the subscribers,
+	// the restocks are handed to the SubscriptionUsecase and the closed or refunded carts to the LoyaltyUsecase
+	Events *event.Bus
+
+	close func() error
+}
+
+func New(cfg *config.Config) (*Container, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	c := &Container{Config: cfg, Events: event.NewBus(), close: func() error { return nil }}
+	switch cfg.Backend {
+	case config.InMem:
+		c.ProductRepository = inmem.NewProductRepository()
+		c.UserRepository = inmem.NewUserRepository()
+		c.CartRepository = inmem.NewCartRepository()
+		c.SessionRepository = inmem.NewSessionRepository()
+		c.SubscriptionRepository = inmem.NewSubscriptionRepository()
+		c.LoyaltyRepository = inmem.NewLoyaltyRepository()
+		c.CategoryRepository = inmem.NewCategoryRepository()
+		c.WarehouseRepository = inmem.NewWarehouseRepository()
+	case config.File:
+		if err := c.openFileStore(cfg.DataPath); err != nil {
+			return nil, err
+		}
+	case config.Bolt:
+		if err := c.openBoltDB(cfg.DataPath); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown backend %s", cfg.Backend)
+	}
+
+	c.SavedListRepository = inmem.NewSavedListRepository()
+
+	notifications, err := openNotifier(cfg.NotificationFile)
+	if err != nil {
+		c.close()
+		return nil, err
+	}
+	closeBackend := c.close
+	c.close = func() error {
+		notifications.Close()
+		return closeBackend()
+	}
+
+	clock := service.SystemClock{}
+	addresses := local.NewAddressValidator()
+	hasher := security.NewBcryptHasher(0)
+	c.UserUsecase = userSvc.NewUserUsecase(c.UserRepository,
+		userSvc.WithAddressValidator(addresses),
+		userSvc.WithPasswordHasher(hasher),
+	)
+
+	rules := make([]*loyaltySvc.EarningRule, 0)
+	for _, r := range cfg.EarningRules {
+		rules = append(rules, &loyaltySvc.EarningRule{CategoryID: r.CategoryID, Per: r.Per, Points: r.Points})
+	}
+	c.LoyaltyUsecase = loyaltySvc.NewLoyaltyUsecase(c.LoyaltyRepository,
+		loyaltySvc.WithEarningRules(rules...),
+		loyaltySvc.WithCategoryMatcher(categorySvc.NewCategoryUsecase(c.CategoryRepository, c.ProductRepository)),
+		loyaltySvc.WithPointValue(cfg.PointValue),
+		loyaltySvc.WithPointsValidity(cfg.PointsValidity),
+		loyaltySvc.WithClock(clock),
+	)
+
+	c.ProductUsecase = productSvc.NewProductUsecase(c.ProductRepository,
+		productSvc.WithWarehouseRepository(c.WarehouseRepository),
+		productSvc.WithEventPublisher(c.Events),
+		productSvc.WithClock(clock),
+	)
+	c.CartUsecase = cartSvc.NewCartUsecase(c.CartRepository, c.ProductRepository,
+		cartSvc.WithUserRepository(c.UserRepository),
+		cartSvc.WithAddressValidator(addresses),
+		cartSvc.WithCartTTL(cfg.CartTTL),
+		cartSvc.WithEventPublisher(c.Events),
+		cartSvc.WithNotifier(notifications),
+		cartSvc.WithRecoveryPolicy(cfg.RemindAfter, cfg.ReminderLimit),
+		cartSvc.WithCurrency(cfg.Currency),
+		cartSvc.WithMergeRule(cfg.MergeRule),
+		cartSvc.WithSavedListRepository(c.SavedListRepository),
+		cartSvc.WithWarehouseRepository(c.WarehouseRepository),
+		cartSvc.WithLoyaltyProgram(c.LoyaltyUsecase),
+		cartSvc.WithClock(clock),
+	)
+	c.AuthUsecase = authSvc.NewAuthUsecase(c.UserRepository, c.SessionRepository, hasher, authSvc.WithClock(clock))
+	c.SubscriptionUsecase = subscriptionSvc.NewSubscriptionUsecase(c.SubscriptionRepository, c.ProductRepository, c.UserRepository,
+		subscriptionSvc.WithNotifier(notifications),
+		subscriptionSvc.WithClock(clock),
+	)
+	c.Events.Subscribe(c.SubscriptionUsecase.HandleRestock)
+	c.Events.Subscribe(c.LoyaltyUsecase.HandleCartEvent)
+
+	//the in memory backend starts with the cart of the default user, as it always did
+	if cfg.Backend == config.InMem {
+		if err := c.OpenCart(cfg.DefaultUser); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// OpenCart makes sure the given user owns an open cart in the chosen backend
+func (c *Container) OpenCart(userID string) error {
+	return c.CartUsecase.OpenCart(userID)
+}
+
+// Close releases the resources held by the backend
+func (c *Container) Close() error {
+	return c.close()
+}
+
+// openNotifier writes the notifications to the given file, or to the standard output when there's none
+func openNotifier(path string) (*notifier.Notifier, error) {
+	if path == "" {
+		return notifier.NewNotifier(os.Stdout), nil
+	}
+	return notifier.OpenFile(path)
+}
+
+// openFileStore wires the repositories to the JSON files of the data directory, the sessions
+// are short lived and remain in memory
+func (c *Container) openFileStore(dir string) error {
+	store, err := file.Open(dir)
+	if err != nil {
+		return err
+	}
+	if c.ProductRepository, err = file.NewProductRepository(store); err != nil {
+		store.Close()
+		return err
+	}
+	if c.UserRepository, err = file.NewUserRepository(store); err != nil {
+		store.Close()
+		return err
+	}
+	if c.WarehouseRepository, err = file.NewWarehouseRepository(store); err != nil {
+		store.Close()
+		return err
+	}
+	c.CartRepository = file.NewCartRepository(store)
+	c.SubscriptionRepository = file.NewSubscriptionRepository(store)
+	c.LoyaltyRepository = file.NewLoyaltyRepository(store)
+	c.CategoryRepository = inmem.NewCategoryRepository()
+	c.SessionRepository = inmem.NewSessionRepository()
+	c.close = store.Close
+	return nil
+}
+
+func (c *Container) openBoltDB(path string) error {
+	db, err := boltdb.Open(path)
+	if err != nil {
+		return err
+	}
+	c.ProductRepository = boltdb.NewProductRepository(db)
+	c.UserRepository = boltdb.NewUserRepository(db)
+	c.CartRepository = boltdb.NewCartRepository(db)
+	c.SessionRepository = boltdb.NewSessionRepository(db)
+	c.SubscriptionRepository = boltdb.NewSubscriptionRepository(db)
+	c.LoyaltyRepository = boltdb.NewLoyaltyRepository(db)
+	c.CategoryRepository = boltdb.NewCategoryRepository(db)
+	c.WarehouseRepository = boltdb.NewWarehouseRepository(db)
+	c.close = db.Close
+	return nil
+}
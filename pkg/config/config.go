@@ -0,0 +1,407 @@
+package config
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+)
+
+type Backend string
+
+const (
+	InMem Backend = "inmem"
+	File  Backend = "file"
+	Bolt  Backend = "bolt"
+)
+
+type LogLevel string
+
+const (
+	Debug LogLevel = "debug"
+	Info  LogLevel = "info"
+	Warn  LogLevel = "warn"
+	Error LogLevel = "error"
+)
+
+var logLevels = map[LogLevel]int{Debug: 0, Info: 1, Warn: 2, Error: 3}
+
+// Enables reports whether messages of the given level should be logged
+func (l LogLevel) Enables(level LogLevel) bool {
+	return logLevels[level] >= logLevels[l]
+}
+
+// environment variables overriding the config file, they are in turn overridden by the flags
+const (
+	EnvConfigFile  = "CARTSVC_CONFIG"
+	EnvBackend     = "CARTSVC_BACKEND"
+	EnvDataPath    = "CARTSVC_DATA_PATH"
+	EnvCurrency    = "CARTSVC_CURRENCY"
+	EnvCartTTL     = "CARTSVC_CART_TTL"
+	EnvHTTPPort    = "CARTSVC_HTTP_PORT"
+	EnvLogLevel    = "CARTSVC_LOG_LEVEL"
+	EnvDefaultUser = "CARTSVC_USER"
+
+	EnvRemindAfter      = "CARTSVC_REMIND_AFTER"
+	EnvReminderLimit    = "CARTSVC_REMINDER_LIMIT"
+	EnvNotificationFile = "CARTSVC_NOTIFICATION_FILE"
+	EnvMergeRule        = "CARTSVC_MERGE_RULE"
+
+	EnvPointValue     = "CARTSVC_POINT_VALUE"
+	EnvPointsValidity = "CARTSVC_POINTS_VALIDITY"
+)
+
+// EarningRule earns Points for every Per spent on the products of the category, or on any product when
+// CategoryID is empty, the first rule matching a cart line applies
+type EarningRule struct {
+	CategoryID string  `json:"category_id"`
+	Per        float64 `json:"per"`
+	Points     int     `json:"points"`
+}
+
+type Config struct {
+	Backend     Backend
+	DataPath    string
+	Currency    string
+	CartTTL     time.Duration
+	HTTPPort    int
+	LogLevel    LogLevel
+	DefaultUser string
+
+	// RemindAfter is how long an open cart stays idle before its owner gets reminded, 0 never reminds
+	RemindAfter   time.Duration
+	ReminderLimit int
+	// NotificationFile receives the notifications, they are written to the standard output when empty
+	NotificationFile string
+	// MergeRule settles the lines found in both the guest and the user carts upon login
+	MergeRule enum.CartMergeRule
+
+	// PointValue is the discount a loyalty point is worth upon redemption
+	PointValue float64
+	// PointsValidity is how long the earned loyalty points remain redeemable, 0 never expires
+	PointsValidity time.Duration
+	// EarningRules are only read from the config file
+	EarningRules []EarningRule
+}
+
+// fileConfig is the layout of the JSON config file, the fields left out keep their previous value
+type fileConfig struct {
+	Backend     Backend  `json:"backend"`
+	DataPath    string   `json:"data_path"`
+	Currency    string   `json:"currency"`
+	CartTTL     string   `json:"cart_ttl"`
+	HTTPPort    int      `json:"http_port"`
+	LogLevel    LogLevel `json:"log_level"`
+	DefaultUser string   `json:"default_user"`
+
+	RemindAfter      string `json:"remind_after"`
+	ReminderLimit    *int   `json:"reminder_limit"`
+	NotificationFile string `json:"notification_file"`
+
+	MergeRule enum.CartMergeRule `json:"merge_rule"`
+
+	PointValue     float64       `json:"point_value"`
+	PointsValidity string        `json:"points_validity"`
+	EarningRules   []EarningRule `json:"earning_rules"`
+}
+
+func Default() *Config {
+	return &Config{
+		Backend:     InMem,
+		DataPath:    "data",
+		Currency:    "IDR",
+		CartTTL:     24 * time.Hour,
+		HTTPPort:    8080,
+		LogLevel:    Info,
+		DefaultUser: "yauritux",
+
+		RemindAfter:   4 * time.Hour,
+		ReminderLimit: 2,
+
+		MergeRule: enum.SumQuantities,
+
+		PointValue:     1,
+		PointsValidity: 365 * 24 * time.Hour,
+		EarningRules:   []EarningRule{{Per: 1000, Points: 10}},
+	}
+}
+
+// Load builds the config out of the defaults, the config file, the environment variables
+// and the flags found in args, in that order of precedence. It returns the arguments left
+// after the flags.
+func Load(name string, args []string) (*Config, []string, error) {
+	return load(name, args, os.Getenv)
+}
+
+func load(name string, args []string, getenv func(string) string) (*Config, []string, error) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	path := fs.String("config", "", "path of the JSON config file")
+	backend := fs.String("backend", "", "repository backend, inmem, file or bolt")
+	dataPath := fs.String("data", "", "directory or file holding the persisted data")
+	currency := fs.String("currency", "", "default currency")
+	cartTTL := fs.Duration("cart-ttl", 0, "idle time after which an open cart expires, 0 never expires")
+	port := fs.Int("port", 0, "HTTP port")
+	logLevel := fs.String("log-level", "", "debug, info, warn or error")
+	user := fs.String("user", "", "user the interactive CLI acts on behalf of")
+	remindAfter := fs.Duration("remind-after", 0, "idle time after which the owner of an open cart is reminded, 0 never reminds")
+	reminderLimit := fs.Int("reminder-limit", 0, "maximum number of reminders sent per cart")
+	mergeRule := fs.String("merge-rule", "", "sum, max or latest, settles the lines found in both the guest and the user carts")
+	notificationFile := fs.String("notification-file", "", "file receiving the notifications, the standard output by default")
+	pointValue := fs.Float64("point-value", 0, "discount a loyalty point is worth upon redemption")
+	pointsValidity := fs.Duration("points-validity", 0, "how long the earned loyalty points remain redeemable, 0 never expires")
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, e.NewErrInvalidData(fmt.Sprintf("%s: %v", name, err))
+	}
+
+	cfg := Default()
+	if *path == "" {
+		*path = getenv(EnvConfigFile)
+	}
+	if *path != "" {
+		if err := cfg.readFile(*path); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := cfg.readEnv(getenv); err != nil {
+		return nil, nil, err
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "backend":
+			cfg.Backend = Backend(*backend)
+		case "data":
+			cfg.DataPath = *dataPath
+		case "currency":
+			cfg.Currency = *currency
+		case "cart-ttl":
+			cfg.CartTTL = *cartTTL
+		case "port":
+			cfg.HTTPPort = *port
+		case "log-level":
+			cfg.LogLevel = LogLevel(*logLevel)
+		case "user":
+			cfg.DefaultUser = *user
+		case "remind-after":
+			cfg.RemindAfter = *remindAfter
+		case "reminder-limit":
+			cfg.ReminderLimit = *reminderLimit
+		case "notification-file":
+			cfg.NotificationFile = *notificationFile
+		case "merge-rule":
+			cfg.MergeRule = enum.CartMergeRule(*mergeRule)
+		case "point-value":
+			cfg.PointValue = *pointValue
+		case "points-validity":
+			cfg.PointsValidity = *pointsValidity
+		}
+	})
+
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, err
+	}
+	return cfg, fs.Args(), nil
+}
+
+func (c *Config) readFile(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("cannot read config file: %v", err)
+	}
+	var f fileConfig
+	if err := json.Unmarshal(data, &f); err != nil {
+		return e.NewErrInvalidData(fmt.Sprintf("invalid config file %s: %v", path, err))
+	}
+
+	if f.Backend != "" {
+		c.Backend = f.Backend
+	}
+	if f.DataPath != "" {
+		c.DataPath = f.DataPath
+	}
+	if f.Currency != "" {
+		c.Currency = f.Currency
+	}
+	if f.CartTTL != "" {
+		ttl, err := time.ParseDuration(f.CartTTL)
+		if err != nil {
+			return e.NewErrInvalidData(fmt.Sprintf("invalid cart_ttl %s in config file %s", f.CartTTL, path))
+		}
+		c.CartTTL = ttl
+	}
+	if f.HTTPPort != 0 {
+		c.HTTPPort = f.HTTPPort
+	}
+	if f.LogLevel != "" {
+		c.LogLevel = f.LogLevel
+	}
+	if f.DefaultUser != "" {
+		c.DefaultUser = f.DefaultUser
+	}
+	if f.RemindAfter != "" {
+		after, err := time.ParseDuration(f.RemindAfter)
+		if err != nil {
+			return e.NewErrInvalidData(fmt.Sprintf("invalid remind_after %s in config file %s", f.RemindAfter, path))
+		}
+		c.RemindAfter = after
+	}
+	if f.ReminderLimit != nil {
+		c.ReminderLimit = *f.ReminderLimit
+	}
+	if f.NotificationFile != "" {
+		c.NotificationFile = f.NotificationFile
+	}
+	if f.MergeRule != "" {
+		c.MergeRule = f.MergeRule
+	}
+	if f.PointValue != 0 {
+		c.PointValue = f.PointValue
+	}
+	if f.PointsValidity != "" {
+		validity, err := time.ParseDuration(f.PointsValidity)
+		if err != nil {
+			return e.NewErrInvalidData(fmt.Sprintf("invalid points_validity %s in config file %s", f.PointsValidity, path))
+		}
+		c.PointsValidity = validity
+	}
+	if f.EarningRules != nil {
+		c.EarningRules = f.EarningRules
+	}
+	return nil
+}
+
+func (c *Config) readEnv(getenv func(string) string) error {
+	if v := getenv(EnvBackend); v != "" {
+		c.Backend = Backend(v)
+	}
+	if v := getenv(EnvDataPath); v != "" {
+		c.DataPath = v
+	}
+	if v := getenv(EnvCurrency); v != "" {
+		c.Currency = v
+	}
+	if v := getenv(EnvCartTTL); v != "" {
+		ttl, err := time.ParseDuration(v)
+		if err != nil {
+			return e.NewErrInvalidData(fmt.Sprintf("invalid %s %s", EnvCartTTL, v))
+		}
+		c.CartTTL = ttl
+	}
+	if v := getenv(EnvHTTPPort); v != "" {
+		port, err := strconv.Atoi(v)
+		if err != nil {
+			return e.NewErrInvalidData(fmt.Sprintf("invalid %s %s", EnvHTTPPort, v))
+		}
+		c.HTTPPort = port
+	}
+	if v := getenv(EnvLogLevel); v != "" {
+		c.LogLevel = LogLevel(v)
+	}
+	if v := getenv(EnvDefaultUser); v != "" {
+		c.DefaultUser = v
+	}
+	if v := getenv(EnvRemindAfter); v != "" {
+		after, err := time.ParseDuration(v)
+		if err != nil {
+			return e.NewErrInvalidData(fmt.Sprintf("invalid %s %s", EnvRemindAfter, v))
+		}
+		c.RemindAfter = after
+	}
+	if v := getenv(EnvReminderLimit); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			return e.NewErrInvalidData(fmt.Sprintf("invalid %s %s", EnvReminderLimit, v))
+		}
+		c.ReminderLimit = limit
+	}
+	if v := getenv(EnvNotificationFile); v != "" {
+		c.NotificationFile = v
+	}
+	if v := getenv(EnvMergeRule); v != "" {
+		c.MergeRule = enum.CartMergeRule(v)
+	}
+	if v := getenv(EnvPointValue); v != "" {
+		value, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return e.NewErrInvalidData(fmt.Sprintf("invalid %s %s", EnvPointValue, v))
+		}
+		c.PointValue = value
+	}
+	if v := getenv(EnvPointsValidity); v != "" {
+		validity, err := time.ParseDuration(v)
+		if err != nil {
+			return e.NewErrInvalidData(fmt.Sprintf("invalid %s %s", EnvPointsValidity, v))
+		}
+		c.PointsValidity = validity
+	}
+	return nil
+}
+
+func (c *Config) Validate() error {
+	switch c.Backend {
+	case InMem:
+	case File, Bolt:
+		if c.DataPath == "" {
+			return e.NewErrInvalidData(fmt.Sprintf("data path is required by the %s backend", c.Backend))
+		}
+	default:
+		return e.NewErrInvalidData(fmt.Sprintf("unknown backend %s, should be inmem, file or bolt", c.Backend))
+	}
+	if !isCurrencyCode(c.Currency) {
+		return e.NewErrInvalidData(fmt.Sprintf("invalid currency %s, should be a 3 letters ISO 4217 code", c.Currency))
+	}
+	if c.CartTTL < 0 {
+		return e.NewErrInvalidData("cart TTL cannot be negative")
+	}
+	if c.HTTPPort <= 0 || c.HTTPPort > 65535 {
+		return e.NewErrInvalidData(fmt.Sprintf("invalid HTTP port %d", c.HTTPPort))
+	}
+	if _, ok := logLevels[c.LogLevel]; !ok {
+		return e.NewErrInvalidData(fmt.Sprintf("unknown log level %s, should be debug, info, warn or error", c.LogLevel))
+	}
+	if c.DefaultUser == "" {
+		return e.NewErrInvalidData("default user cannot be empty")
+	}
+	if c.RemindAfter < 0 {
+		return e.NewErrInvalidData("remind after cannot be negative")
+	}
+	if c.ReminderLimit < 0 {
+		return e.NewErrInvalidData("reminder limit cannot be negative")
+	}
+	switch c.MergeRule {
+	case enum.SumQuantities, enum.KeepMaxQuantity, enum.KeepLatestQuantity:
+	default:
+		return e.NewErrInvalidData(fmt.Sprintf("unknown merge rule %s, should be sum, max or latest", c.MergeRule))
+	}
+	if c.PointValue <= 0 {
+		return e.NewErrInvalidData("point value should be greater than zero")
+	}
+	if c.PointsValidity < 0 {
+		return e.NewErrInvalidData("points validity cannot be negative")
+	}
+	for i, r := range c.EarningRules {
+		if r.Per <= 0 || r.Points <= 0 {
+			return e.NewErrInvalidData(fmt.Sprintf("invalid earning rule #%d, 'per' and 'points' should be greater than zero", i+1))
+		}
+	}
+	return nil
+}
+
+func isCurrencyCode(s string) bool {
+	if len(s) != 3 {
+		return false
+	}
+	for _, r := range s {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,174 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+)
+
+func env(vars map[string]string) func(string) string {
+	return func(key string) string {
+		return vars[key]
+	}
+}
+
+func writeConfigFile(t *testing.T, content string) string {
+	dir, err := ioutil.TempDir("", "cartsvc-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "config.json")
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+
+	Convey("1. Given no config file, environment variable nor flag", t, func() {
+		Convey("-> Should fall back to the defaults", func() {
+			cfg, args, err := load("test", []string{"cart", "show"}, env(nil))
+			So(err, ShouldBeNil)
+			So(cfg, ShouldResemble, Default())
+			So(args, ShouldResemble, []string{"cart", "show"})
+		})
+	})
+
+	Convey("2. Given settings coming from several sources", t, func() {
+		path := writeConfigFile(t, `{"backend":"file","data_path":"/var/lib/cartsvc","currency":"USD","cart_ttl":"2h","http_port":9000,"log_level":"warn","reminder_limit":0}`)
+		defer os.RemoveAll(filepath.Dir(path))
+
+		Convey("-> The config file should override the defaults", func() {
+			cfg, _, err := load("test", []string{"--config", path}, env(nil))
+			So(err, ShouldBeNil)
+			So(cfg.Backend, ShouldEqual, File)
+			So(cfg.DataPath, ShouldEqual, "/var/lib/cartsvc")
+			So(cfg.Currency, ShouldEqual, "USD")
+			So(cfg.CartTTL, ShouldEqual, 2*time.Hour)
+			So(cfg.HTTPPort, ShouldEqual, 9000)
+			So(cfg.LogLevel, ShouldEqual, Warn)
+			So(cfg.DefaultUser, ShouldEqual, "yauritux")
+			So(cfg.RemindAfter, ShouldEqual, 4*time.Hour)
+			So(cfg.ReminderLimit, ShouldEqual, 0)
+			So(cfg.EarningRules, ShouldResemble, Default().EarningRules)
+		})
+		Convey("-> The loyalty settings should be read from the config file and overridden as well", func() {
+			path := writeConfigFile(t, `{"point_value":0.5,"points_validity":"720h","earning_rules":[{"category_id":"apparel","per":500,"points":2},{"per":1000,"points":1}]}`)
+			defer os.RemoveAll(filepath.Dir(path))
+			cfg, _, err := load("test", []string{"--config", path, "--points-validity", "0"}, env(map[string]string{EnvPointValue: "2"}))
+			So(err, ShouldBeNil)
+			So(cfg.PointValue, ShouldEqual, 2)
+			So(cfg.PointsValidity, ShouldEqual, 0)
+			So(cfg.EarningRules, ShouldResemble, []EarningRule{{CategoryID: "apparel", Per: 500, Points: 2}, {Per: 1000, Points: 1}})
+		})
+		Convey("-> The environment variables should override the config file", func() {
+			cfg, _, err := load("test", nil, env(map[string]string{
+				EnvConfigFile: path,
+				EnvCurrency:   "EUR",
+				EnvHTTPPort:   "9090",
+			}))
+			So(err, ShouldBeNil)
+			So(cfg.Backend, ShouldEqual, File)
+			So(cfg.Currency, ShouldEqual, "EUR")
+			So(cfg.HTTPPort, ShouldEqual, 9090)
+		})
+		Convey("-> The flags should override the environment variables", func() {
+			cfg, args, err := load("test", []string{"--config", path, "--backend", "inmem", "--port", "8000", "--cart-ttl", "0", "--merge-rule", "latest", "repl"},
+				env(map[string]string{EnvBackend: "bolt", EnvHTTPPort: "9090", EnvLogLevel: "debug", EnvMergeRule: "max"}))
+			So(err, ShouldBeNil)
+			So(cfg.Backend, ShouldEqual, InMem)
+			So(cfg.HTTPPort, ShouldEqual, 8000)
+			So(cfg.CartTTL, ShouldEqual, 0)
+			So(cfg.LogLevel, ShouldEqual, Debug)
+			So(cfg.MergeRule, ShouldEqual, enum.KeepLatestQuantity)
+			So(args, ShouldResemble, []string{"repl"})
+		})
+	})
+
+	Convey("3. Given invalid settings", t, func() {
+		Convey("-> Should reject an unknown backend", func() {
+			_, _, err := load("test", []string{"--backend", "mongo"}, env(nil))
+			So(err, ShouldHaveSameTypeAs, &e.ErrInvalidData{})
+			So(err.Error(), ShouldEqual, "unknown backend mongo, should be inmem, file or bolt")
+		})
+		Convey("-> Should reject the sqlite backend, which is not available", func() {
+			_, _, err := load("test", []string{"--backend", "sqlite", "--data", "/tmp/cartsvc"}, env(nil))
+			So(err, ShouldHaveSameTypeAs, &e.ErrInvalidData{})
+			So(err.Error(), ShouldEqual, "unknown backend sqlite, should be inmem, file or bolt")
+		})
+		Convey("-> Should require a data path for the persistent backends", func() {
+			_, _, err := load("test", []string{"--backend", "file", "--data", ""}, env(nil))
+			So(err.Error(), ShouldEqual, "data path is required by the file backend")
+		})
+		Convey("-> Should reject an invalid currency", func() {
+			_, _, err := load("test", nil, env(map[string]string{EnvCurrency: "rupiah"}))
+			So(err.Error(), ShouldEqual, "invalid currency rupiah, should be a 3 letters ISO 4217 code")
+		})
+		Convey("-> Should reject a negative cart TTL", func() {
+			_, _, err := load("test", []string{"--cart-ttl", "-1h"}, env(nil))
+			So(err.Error(), ShouldEqual, "cart TTL cannot be negative")
+		})
+		Convey("-> Should reject a negative reminder limit", func() {
+			_, _, err := load("test", nil, env(map[string]string{EnvReminderLimit: "-1"}))
+			So(err.Error(), ShouldEqual, "reminder limit cannot be negative")
+		})
+		Convey("-> Should reject an unknown merge rule", func() {
+			_, _, err := load("test", []string{"--merge-rule", "min"}, env(nil))
+			So(err.Error(), ShouldEqual, "unknown merge rule min, should be sum, max or latest")
+		})
+		Convey("-> Should reject a point value which is not positive", func() {
+			_, _, err := load("test", []string{"--point-value", "0"}, env(nil))
+			So(err.Error(), ShouldEqual, "point value should be greater than zero")
+		})
+		Convey("-> Should reject a negative points validity", func() {
+			_, _, err := load("test", nil, env(map[string]string{EnvPointsValidity: "-24h"}))
+			So(err.Error(), ShouldEqual, "points validity cannot be negative")
+		})
+		Convey("-> Should reject an earning rule earning nothing", func() {
+			path := writeConfigFile(t, `{"earning_rules":[{"per":1000,"points":1},{"category_id":"apparel","per":0,"points":5}]}`)
+			defer os.RemoveAll(filepath.Dir(path))
+			_, _, err := load("test", []string{"--config", path}, env(nil))
+			So(err.Error(), ShouldEqual, "invalid earning rule #2, 'per' and 'points' should be greater than zero")
+		})
+		Convey("-> Should reject an unparsable environment variable", func() {
+			_, _, err := load("test", nil, env(map[string]string{EnvHTTPPort: "eighty"}))
+			So(err.Error(), ShouldEqual, "invalid CARTSVC_HTTP_PORT eighty")
+		})
+		Convey("-> Should reject an out of range port", func() {
+			_, _, err := load("test", []string{"--port", "70000"}, env(nil))
+			So(err.Error(), ShouldEqual, "invalid HTTP port 70000")
+		})
+		Convey("-> Should reject an unknown log level", func() {
+			_, _, err := load("test", []string{"--log-level", "verbose"}, env(nil))
+			So(err.Error(), ShouldEqual, "unknown log level verbose, should be debug, info, warn or error")
+		})
+		Convey("-> Should reject a malformed config file", func() {
+			path := writeConfigFile(t, `{"backend":`)
+			defer os.RemoveAll(filepath.Dir(path))
+			_, _, err := load("test", []string{"--config", path}, env(nil))
+			So(err, ShouldHaveSameTypeAs, &e.ErrInvalidData{})
+		})
+		Convey("-> Should reject an unknown flag", func() {
+			_, _, err := load("test", []string{"--verbose"}, env(nil))
+			So(err, ShouldHaveSameTypeAs, &e.ErrInvalidData{})
+		})
+	})
+}
+
+func TestLogLevel(t *testing.T) {
+	Convey("Given the warn log level", t, func() {
+		Convey("-> Should only enable warnings and errors", func() {
+			So(Warn.Enables(Debug), ShouldBeFalse)
+			So(Warn.Enables(Info), ShouldBeFalse)
+			So(Warn.Enables(Warn), ShouldBeTrue)
+			So(Warn.Enables(Error), ShouldBeTrue)
+		})
+	})
+}
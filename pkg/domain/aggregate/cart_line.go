@@ -0,0 +1,47 @@
+package aggregate
+
+import (
+	"fmt"
+
+	"github.com/yauritux/cartsvc/pkg/domain/entity"
+	vo "github.com/yauritux/cartsvc/pkg/domain/valueobject"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+)
+
+// NewProductLine prices qty units of the product at its current price, the variant can only be nil
+// for a product without variants. The stock is left to the cart the line goes into.
+func NewProductLine(prod *entity.Product, variant *entity.Variant, qty int) (*vo.CartItem, error) {
+	if variant == nil && len(prod.Variants) > 0 {
+		return nil, e.NewErrInvalidData(fmt.Sprintf("please choose a variant of product %s", prod.Name))
+	}
+	line := &vo.CartItem{
+		ProdID:   prod.ID,
+		ProdName: prod.Name,
+		SKU:      prod.ID,
+		Qty:      qty,
+		Price:    prod.Price,
+		Disc:     prod.Disc,
+	}
+	if variant != nil {
+		line.SKU = variant.SKU
+		line.Options = variant.Options
+		line.Price = variant.Price
+		line.Disc = variant.Disc
+	}
+	return line, nil
+}
+
+// NewBundleLine prices qty units of the bundle as a single line carrying its components
+func NewBundleLine(bundle *ProductBundle, qty int) *vo.CartItem {
+	info := bundle.FetchBundleInfo()
+	price, disc := bundle.Price()
+	return &vo.CartItem{
+		ProdID:     info.ID,
+		ProdName:   info.Name,
+		SKU:        info.ID,
+		Qty:        qty,
+		Price:      price,
+		Disc:       disc,
+		Components: bundle.Components(),
+	}
+}
@@ -0,0 +1,147 @@
+package aggregate
+
+import (
+	"fmt"
+
+	"github.com/yauritux/cartsvc/pkg/domain/entity"
+	vo "github.com/yauritux/cartsvc/pkg/domain/valueobject"
+	"github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+)
+
+// CartMerge tells how the lines of the merged cart ended up in the cart they were merged into
+type CartMerge struct {
+	Added   []*vo.CartItem
+	Updated []*vo.CartItem
+	// LeftOut holds the units of the merged lines which could not be taken for the lack of stock
+	LeftOut []*vo.CartItem
+}
+
+// MergeCart folds the lines of the other cart into this one, the quantity of a line found in both
+// carts is settled by the rule. A line never grows beyond the stock left for it, stock holds the
+// units available for every SKU consumed by the merged lines, yet a line is never lowered for the
+// lack of stock either.
+func (userCart *UserCart) MergeCart(other *entity.Cart, rule enum.CartMergeRule, stock map[string]int) (*CartMerge, error) {
+	if err := userCart.Validate(); err != nil {
+		return nil, err
+	}
+	if userCart.cart.Status != enum.Open {
+		return nil, fmt.Errorf("cannot merge into a cart with status as %s", userCart.cart.Status)
+	}
+	if other.Status != enum.Open {
+		return nil, fmt.Errorf("cannot merge a cart with status as %s", other.Status)
+	}
+	switch rule {
+	case enum.SumQuantities, enum.KeepMaxQuantity, enum.KeepLatestQuantity:
+	default:
+		return nil, e.NewErrInvalidData(fmt.Sprintf("unknown cart merge rule %s", rule))
+	}
+
+	merge := &CartMerge{
+		Added:   make([]*vo.CartItem, 0),
+		Updated: make([]*vo.CartItem, 0),
+		LeftOut: make([]*vo.CartItem, 0),
+	}
+	otherIsLatest := other.LastActivityAt.After(userCart.cart.LastActivityAt)
+	for _, line := range other.Items {
+		index := -1
+		for i, v := range userCart.cart.Items {
+			if itemSKU(v) == itemSKU(line) {
+				index = i
+				break
+			}
+		}
+
+		current := 0
+		merged := *line
+		//the line of the latest cart replaces the other one as a whole, not only its quantity
+		replaced := rule == enum.KeepLatestQuantity && otherIsLatest
+		if index >= 0 {
+			current = userCart.cart.Items[index].Qty
+			if !replaced {
+				merged = *userCart.cart.Items[index]
+			}
+		}
+		wanted := mergedQty(rule, current, line.Qty, otherIsLatest)
+
+		merged.Qty = wanted
+		if wanted > current {
+			merged.Qty = current + userCart.capacityFor(&merged, index, wanted-current, stock)
+		}
+		if merged.Qty < wanted {
+			leftOut := *line
+			leftOut.Qty = wanted - merged.Qty
+			merge.LeftOut = append(merge.LeftOut, &leftOut)
+		}
+
+		switch {
+		case index >= 0 && merged.Qty == current && !replaced:
+		case index >= 0:
+			userCart.cart.Items[index] = &merged
+			merge.Updated = append(merge.Updated, &merged)
+		case merged.Qty > 0:
+			userCart.cart.Items = append(userCart.cart.Items, &merged)
+			merge.Added = append(merge.Added, &merged)
+		}
+	}
+	return merge, nil
+}
+
+func mergedQty(rule enum.CartMergeRule, current int, other int, otherIsLatest bool) int {
+	switch {
+	case current == 0:
+		return other
+	case rule == enum.SumQuantities:
+		return current + other
+	case rule == enum.KeepMaxQuantity && other > current:
+		return other
+	case rule == enum.KeepLatestQuantity && otherIsLatest:
+		return other
+	default:
+		return current
+	}
+}
+
+// capacityFor tells how many of the extra units of the line the stock can still take,
+// the line found at index (if any) is left out of the demand of the cart
+func (userCart *UserCart) capacityFor(line *vo.CartItem, index int, extra int, stock map[string]int) int {
+	current := 0
+	if index >= 0 {
+		current = userCart.cart.Items[index].Qty
+	}
+
+	capacity := userCart.unitsLeftFor(line, index, stock) - current
+	if capacity > extra {
+		capacity = extra
+	}
+	if capacity < 0 {
+		return 0
+	}
+	return capacity
+}
+
+// unitsLeftFor tells how many units of the line the stock can take once the demand of the other
+// lines is met, the line found at index (if any) is left out of that demand
+func (userCart *UserCart) unitsLeftFor(line *vo.CartItem, index int, stock map[string]int) int {
+	demand := make(map[string]int)
+	for i, v := range userCart.cart.Items {
+		if i == index {
+			continue
+		}
+		for _, d := range lineDemand(v, v.Qty) {
+			demand[d.SKU] += d.Qty
+		}
+	}
+
+	units, first := 0, true
+	for _, d := range lineDemand(line, 1) {
+		left := (stock[d.SKU] - demand[d.SKU]) / d.Qty
+		if first || left < units {
+			units, first = left, false
+		}
+	}
+	if units < 0 {
+		return 0
+	}
+	return units
+}
@@ -0,0 +1,76 @@
+package aggregate
+
+import (
+	"fmt"
+
+	vo "github.com/yauritux/cartsvc/pkg/domain/valueobject"
+	"github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+)
+
+// CartLineChange is a cart line which differs from the current catalog, Line is the line as repriced
+type CartLineChange struct {
+	Line     *vo.CartItem
+	Kind     enum.CartChangeKind
+	OldPrice float64
+	OldDisc  float64
+	// Available is the number of units of the line the stock can still take
+	Available int
+}
+
+// CartRepricing holds the lines to be persisted with their new price along with the changes
+// the buyer should be told about
+type CartRepricing struct {
+	Repriced []*vo.CartItem
+	Changes  []*CartLineChange
+}
+
+// RepriceCart brings the price of the cart lines up to date. current holds the lines as priced by the
+// catalog today keyed by their SKU, a line missing from it is no longer available. stock holds the units
+// which can be ordered for every SKU consumed by the lines. The quantities are left as they are, a line the stock
+// cannot cover anymore is only flagged.
+func (userCart *UserCart) RepriceCart(current map[string]*vo.CartItem, stock map[string]int) (*CartRepricing, error) {
+	if err := userCart.Validate(); err != nil {
+		return nil, err
+	}
+	if userCart.cart.Status != enum.Open {
+		return nil, fmt.Errorf("cannot reprice a cart with status as %s", userCart.cart.Status)
+	}
+
+	repricing := &CartRepricing{
+		Repriced: make([]*vo.CartItem, 0),
+		Changes:  make([]*CartLineChange, 0),
+	}
+	for i, line := range userCart.cart.Items {
+		fresh, ok := current[itemSKU(line)]
+		if !ok {
+			repricing.Changes = append(repricing.Changes, &CartLineChange{
+				Line: line, Kind: enum.Unavailable, OldPrice: line.Price, OldDisc: line.Disc,
+			})
+			continue
+		}
+
+		repriced := *line
+		if fresh.Price != line.Price || fresh.Disc != line.Disc {
+			repriced.Price = fresh.Price
+			repriced.Disc = fresh.Disc
+			userCart.cart.Items[i] = &repriced
+			repricing.Repriced = append(repricing.Repriced, &repriced)
+		}
+		change := &CartLineChange{Line: &repriced, OldPrice: line.Price, OldDisc: line.Disc}
+		switch net, oldNet := fresh.Price-fresh.Disc, line.Price-line.Disc; {
+		case net > oldNet:
+			change.Kind = enum.PriceIncreased
+			repricing.Changes = append(repricing.Changes, change)
+		case net < oldNet:
+			change.Kind = enum.PriceDecreased
+			repricing.Changes = append(repricing.Changes, change)
+		}
+
+		if available := userCart.unitsLeftFor(line, i, stock); available < line.Qty {
+			repricing.Changes = append(repricing.Changes, &CartLineChange{
+				Line: &repriced, Kind: enum.StockShort, OldPrice: line.Price, OldDisc: line.Disc, Available: available,
+			})
+		}
+	}
+	return repricing, nil
+}
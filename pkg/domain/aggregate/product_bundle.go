@@ -0,0 +1,140 @@
+package aggregate
+
+import (
+	"fmt"
+
+	"github.com/yauritux/cartsvc/pkg/domain/entity"
+	vo "github.com/yauritux/cartsvc/pkg/domain/valueobject"
+	"github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+)
+
+// ProductBundle is the aggregate of a bundle product along with its component products,
+// it prices the bundle and tells how many units of it can be made out of the components stock
+type ProductBundle struct {
+	bundle     *entity.Product
+	components map[string]*entity.Product
+}
+
+func NewProductBundle(bundle *entity.Product, components []*entity.Product) (*ProductBundle, error) {
+	if bundle.Type != enum.BundleProduct {
+		return nil, e.NewErrInvalidData(fmt.Sprintf("product %s is not a bundle", bundle.ID))
+	}
+	if len(bundle.Components) == 0 {
+		return nil, e.NewErrInvalidData(fmt.Sprintf("bundle %s has no components", bundle.ID))
+	}
+
+	productBundle := &ProductBundle{
+		bundle:     bundle,
+		components: make(map[string]*entity.Product),
+	}
+	for _, p := range components {
+		productBundle.components[p.ID] = p
+	}
+	for _, c := range bundle.Components {
+		p, ok := productBundle.components[c.ProdID]
+		if !ok {
+			return nil, e.NewErrNoData(fmt.Sprintf("component %s of bundle %s is not found", c.ProdID, bundle.ID))
+		}
+		if p.Type == enum.BundleProduct {
+			return nil, e.NewErrInvalidData(fmt.Sprintf("bundle %s cannot contain another bundle %s", bundle.ID, p.ID))
+		}
+		if componentSKU(c) != p.ID && findVariant(p, componentSKU(c)) == nil {
+			return nil, e.NewErrNoData(fmt.Sprintf("component %s of bundle %s is not found", componentSKU(c), bundle.ID))
+		}
+	}
+	return productBundle, nil
+}
+
+func (b *ProductBundle) FetchBundleInfo() *entity.Product {
+	return b.bundle
+}
+
+// Price returns the bundle unit price as the sum of its components prices,
+// and the bundle discount according to the bundle pricing rule
+func (b *ProductBundle) Price() (float64, float64) {
+	var price, componentDisc float64
+	for _, c := range b.bundle.Components {
+		unitPrice, unitDisc := b.unitPrice(c)
+		price += unitPrice * float64(c.Qty)
+		componentDisc += unitDisc * float64(c.Qty)
+	}
+
+	disc := componentDisc
+	if pricing := b.bundle.BundlePricing; pricing != nil {
+		switch pricing.Mode {
+		case enum.FixedBundlePrice:
+			disc = price - pricing.Value
+		case enum.PercentOffBundle:
+			disc = price * pricing.Value / 100
+		}
+	}
+	if disc < 0 {
+		disc = 0
+	}
+	if disc > price {
+		disc = price
+	}
+	return price, disc
+}
+
+// Components returns the components consumed by a single unit of the bundle
+func (b *ProductBundle) Components() []*vo.BundleComponent {
+	lines := make([]*vo.BundleComponent, 0)
+	for _, c := range b.bundle.Components {
+		lines = append(lines, &vo.BundleComponent{
+			ProdID:   c.ProdID,
+			ProdName: b.components[c.ProdID].Name,
+			SKU:      componentSKU(c),
+			Qty:      c.Qty,
+		})
+	}
+	return lines
+}
+
+// Available returns the units of the bundle which can be made out of the components stock
+func (b *ProductBundle) Available() int {
+	units := -1
+	for _, c := range b.Components() {
+		if n := b.StockOf(c) / c.Qty; units < 0 || n < units {
+			units = n
+		}
+	}
+	if units < 0 {
+		return 0
+	}
+	return units
+}
+
+// StockOf returns the stock of the component product (or variant) identified by the SKU
+func (b *ProductBundle) StockOf(c *vo.BundleComponent) int {
+	p := b.components[c.ProdID]
+	if v := findVariant(p, componentSKU(c)); v != nil {
+		return v.Stock
+	}
+	return p.Stock
+}
+
+func (b *ProductBundle) unitPrice(c *vo.BundleComponent) (float64, float64) {
+	p := b.components[c.ProdID]
+	if v := findVariant(p, componentSKU(c)); v != nil {
+		return v.Price, v.Disc
+	}
+	return p.Price, p.Disc
+}
+
+func componentSKU(c *vo.BundleComponent) string {
+	if c.SKU != "" {
+		return c.SKU
+	}
+	return c.ProdID
+}
+
+func findVariant(p *entity.Product, sku string) *entity.Variant {
+	for _, v := range p.Variants {
+		if v.SKU == sku {
+			return v
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package aggregate
+
+import (
+	"time"
+
+	"github.com/yauritux/cartsvc/pkg/domain/entity"
+	vo "github.com/yauritux/cartsvc/pkg/domain/valueobject"
+)
+
+// ActivePriceSchedule returns the schedule pricing qty units of the SKU at the given time, or nil when the
+// regular price applies. A schedule dedicated to the SKU takes over the one of the whole product, and a
+// flash sale only applies as long as the units left cover the quantity.
+func ActivePriceSchedule(prod *entity.Product, sku string, qty int, now time.Time) *vo.PriceSchedule {
+	var active *vo.PriceSchedule
+	for _, s := range prod.PriceSchedules {
+		if !scheduleCovers(s, now, qty) {
+			continue
+		}
+		switch s.SKU {
+		case sku:
+			if sku != "" && sku != prod.ID {
+				return s
+			}
+			active = s
+		case "", prod.ID:
+			active = s
+		}
+	}
+	return active
+}
+
+// PriceProductAt returns a copy of the product carrying the prices in effect at the given time
+// for qty units of each of its SKUs
+func PriceProductAt(prod *entity.Product, qty int, now time.Time) *entity.Product {
+	if len(prod.PriceSchedules) == 0 {
+		return prod
+	}
+
+	priced := *prod
+	priced.Price, priced.Disc = scheduledPrice(ActivePriceSchedule(prod, prod.ID, qty, now), prod.Price, prod.Disc)
+	priced.Variants = make([]*entity.Variant, 0, len(prod.Variants))
+	for _, v := range prod.Variants {
+		variant := *v
+		variant.Price, variant.Disc = scheduledPrice(ActivePriceSchedule(prod, v.SKU, qty, now), v.Price, v.Disc)
+		priced.Variants = append(priced.Variants, &variant)
+	}
+	return &priced
+}
+
+func scheduledPrice(s *vo.PriceSchedule, price float64, disc float64) (float64, float64) {
+	if s == nil {
+		return price, disc
+	}
+	if s.Price > 0 {
+		price = s.Price
+	}
+	disc = s.Disc
+	if disc > price {
+		disc = price
+	}
+	return price, disc
+}
+
+func scheduleCovers(s *vo.PriceSchedule, now time.Time, qty int) bool {
+	if now.Before(s.StartsAt) || !now.Before(s.EndsAt) {
+		return false
+	}
+	return s.Quantity <= 0 || s.Quantity-s.Sold >= qty
+}
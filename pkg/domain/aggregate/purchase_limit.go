@@ -0,0 +1,113 @@
+package aggregate
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/yauritux/cartsvc/pkg/domain/entity"
+	vo "github.com/yauritux/cartsvc/pkg/domain/valueobject"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+)
+
+// CheckPurchaseLimits makes sure the cart can be checked out under the purchase limits of its products,
+// limits is keyed by the product ID and orders holds the carts of the user checked out so far
+func (userCart *UserCart) CheckPurchaseLimits(limits map[string]*vo.PurchaseLimit, orders []*entity.Cart, now time.Time) error {
+	ids := make([]string, 0, len(limits))
+	for id := range limits {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		limit := limits[id]
+		units := unitsOf(userCart.cart.Items, id)
+		if units == 0 || limit == nil {
+			continue
+		}
+		name := userCart.productName(id)
+		if err := checkOrderLimit(name, limit, units); err != nil {
+			return err
+		}
+		if limit.MaxPerCustomer <= 0 {
+			continue
+		}
+
+		bought := 0
+		for _, o := range orders {
+			if o.ID == userCart.cart.ID || !checkedOutWithin(o, limit.Period, now) {
+				continue
+			}
+			bought += unitsOf(o.Items, id)
+		}
+		if bought+units > limit.MaxPerCustomer {
+			return e.NewErrCustomerLimitExceeded(fmt.Sprintf("cannot buy more than %d of %s%s, %d already bought",
+				limit.MaxPerCustomer, name, formatPeriod(limit.Period), bought))
+		}
+	}
+	return nil
+}
+
+// checkOrderLimit makes sure a single order does not hold more units of the product than allowed
+func checkOrderLimit(name string, limit *vo.PurchaseLimit, units int) error {
+	if limit == nil || limit.MaxPerOrder <= 0 || units <= limit.MaxPerOrder {
+		return nil
+	}
+	return e.NewErrOrderLimitExceeded(fmt.Sprintf("cannot order more than %d of %s at once", limit.MaxPerOrder, name))
+}
+
+// unitsOf tells how many units of the product the items hold, on their own or within the bundles
+func unitsOf(items []*vo.CartItem, prodID string) int {
+	units := 0
+	for _, v := range items {
+		if v.ProdID == prodID {
+			units += v.Qty
+		}
+		for _, c := range v.Components {
+			if c.ProdID == prodID {
+				units += c.Qty * v.Qty
+			}
+		}
+	}
+	return units
+}
+
+func (userCart *UserCart) productName(prodID string) string {
+	for _, v := range userCart.cart.Items {
+		if v.ProdID == prodID {
+			return v.ProdName
+		}
+		for _, c := range v.Components {
+			if c.ProdID == prodID && c.ProdName != "" {
+				return c.ProdName
+			}
+		}
+	}
+	return prodID
+}
+
+// checkedOutWithin tells whether the order was checked out within the period before now, any period
+// is fine when it is zero. The orders checked out before the checkout got stamped fall back to their last activity.
+func checkedOutWithin(order *entity.Cart, period time.Duration, now time.Time) bool {
+	if period <= 0 {
+		return true
+	}
+	checkedOutAt := order.LastActivityAt
+	if order.CheckedOutAt != nil {
+		checkedOutAt = *order.CheckedOutAt
+	}
+	return checkedOutAt.After(now.Add(-period))
+}
+
+func formatPeriod(period time.Duration) string {
+	switch {
+	case period <= 0:
+		return ""
+	case period == 24*time.Hour:
+		return " per day"
+	case period%(24*time.Hour) == 0:
+		return fmt.Sprintf(" within %d days", period/(24*time.Hour))
+	default:
+		return fmt.Sprintf(" within %s", period)
+	}
+}
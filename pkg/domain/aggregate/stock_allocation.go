@@ -0,0 +1,159 @@
+package aggregate
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/yauritux/cartsvc/pkg/domain/entity"
+	vo "github.com/yauritux/cartsvc/pkg/domain/valueobject"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+)
+
+// StockAllocation tells which warehouses ship the stock demanded by a cart, one shipment per warehouse
+type StockAllocation struct {
+	Shipments []*vo.Shipment
+}
+
+// StockLevels returns the stock of the product (or of its variant identified by the SKU) per warehouse
+func StockLevels(prod *entity.Product, sku string) map[string]int {
+	stock, levels := prod.Stock, prod.WarehouseStock
+	if v := findVariant(prod, sku); v != nil {
+		stock, levels = v.Stock, v.WarehouseStock
+	}
+	if len(levels) == 0 {
+		return map[string]int{entity.MainWarehouse: stock}
+	}
+	return levels
+}
+
+// RankWarehouses orders the warehouses from the nearest to the farthest of the shipping address, the
+// nearness being told by the part of the address they share. The order is kept among the ones as near.
+func RankWarehouses(warehouses []*entity.Warehouse, to *vo.BuyerAddress) []*entity.Warehouse {
+	ranked := make([]*entity.Warehouse, len(warehouses))
+	copy(ranked, warehouses)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return distance(ranked[i], to) < distance(ranked[j], to)
+	})
+	return ranked
+}
+
+func distance(w *entity.Warehouse, to *vo.BuyerAddress) int {
+	same := func(a string, b string) bool {
+		return a != "" && strings.EqualFold(a, b)
+	}
+	switch {
+	case to == nil:
+		return 0
+	case same(w.Postal, to.Postal):
+		return 0
+	case same(w.City, to.City):
+		return 1
+	case same(w.Region, to.Region):
+		return 2
+	case same(w.Province, to.Province):
+		return 3
+	case same(w.Country, to.Country):
+		return 4
+	default:
+		return 5
+	}
+}
+
+// AllocateStock picks the warehouses shipping the demand out of their stock levels, keyed by the SKU then
+// by the warehouse ID. The nearest warehouse able to ship the whole demand is preferred, otherwise the demand
+// is split into as few shipments as possible, each time from the warehouse shipping the most units left.
+// The ranked warehouses come first, then the other warehouses holding some stock by their ID.
+func AllocateStock(demand []*vo.BundleComponent, levels map[string]map[string]int, ranked []*entity.Warehouse) (*StockAllocation, error) {
+	left := make(map[string]int)
+	for _, d := range demand {
+		available := 0
+		for _, units := range levels[d.SKU] {
+			available += units
+		}
+		if available < d.Qty {
+			return nil, e.NewErrOutOfStock(fmt.Sprintf("out of stock, only %d of %s left", available, d.ProdName))
+		}
+		left[d.SKU] = d.Qty
+	}
+
+	order := warehouseOrder(levels, ranked)
+	shippable := func(warehouseID string) int {
+		units := 0
+		for _, d := range demand {
+			units += minUnits(left[d.SKU], levels[d.SKU][warehouseID])
+		}
+		return units
+	}
+
+	allocation := &StockAllocation{Shipments: make([]*vo.Shipment, 0)}
+	remaining := 0
+	for _, d := range demand {
+		remaining += d.Qty
+	}
+	if remaining == 0 {
+		return allocation, nil
+	}
+	for _, id := range order {
+		if shippable(id) == remaining {
+			allocation.Shipments = append(allocation.Shipments, ship(demand, levels, left, id))
+			return allocation, nil
+		}
+	}
+
+	//a warehouse ships all it can at once, it is left out afterwards
+	shipped := make(map[string]bool)
+	for remaining > 0 {
+		best, bestUnits := "", 0
+		for _, id := range order {
+			if units := shippable(id); !shipped[id] && units > bestUnits {
+				best, bestUnits = id, units
+			}
+		}
+		allocation.Shipments = append(allocation.Shipments, ship(demand, levels, left, best))
+		shipped[best] = true
+		remaining -= bestUnits
+	}
+	return allocation, nil
+}
+
+// ship takes out of the units left the ones the warehouse can ship
+func ship(demand []*vo.BundleComponent, levels map[string]map[string]int, left map[string]int, warehouseID string) *vo.Shipment {
+	shipment := &vo.Shipment{WarehouseID: warehouseID, Items: make([]*vo.BundleComponent, 0)}
+	for _, d := range demand {
+		units := minUnits(left[d.SKU], levels[d.SKU][warehouseID])
+		if units == 0 {
+			continue
+		}
+		left[d.SKU] -= units
+		shipment.Items = append(shipment.Items, &vo.BundleComponent{ProdID: d.ProdID, ProdName: d.ProdName, SKU: d.SKU, Qty: units})
+	}
+	return shipment
+}
+
+func warehouseOrder(levels map[string]map[string]int, ranked []*entity.Warehouse) []string {
+	order := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, w := range ranked {
+		order = append(order, w.ID)
+		seen[w.ID] = true
+	}
+	others := make([]string, 0)
+	for _, stock := range levels {
+		for id := range stock {
+			if !seen[id] {
+				others = append(others, id)
+				seen[id] = true
+			}
+		}
+	}
+	sort.Strings(others)
+	return append(order, others...)
+}
+
+func minUnits(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,129 @@
+package aggregate
+
+import (
+	"math"
+	"time"
+
+	"github.com/yauritux/cartsvc/pkg/domain/entity"
+	vo "github.com/yauritux/cartsvc/pkg/domain/valueobject"
+	"github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+)
+
+// StockPolicyAt returns the stock policy of the product in effect at the given time, or nil when the product
+// only sells its stock. A pre-order is over upon the release, the units then ship out of the stock as usual.
+func StockPolicyAt(prod *entity.Product, now time.Time) *vo.StockPolicy {
+	p := prod.StockPolicy
+	if p == nil || (p.Mode == enum.PreOrder && p.AvailableAt != nil && !now.Before(*p.AvailableAt)) {
+		return nil
+	}
+	return p
+}
+
+// OrderableUnits returns how many units of a SKU can be ordered out of its stock on hand under the policy,
+// a pre-order only counts the units left under its limit
+func OrderableUnits(policy *vo.StockPolicy, stock int) int {
+	if policy == nil {
+		return stock
+	}
+	allowance := math.MaxInt32
+	if policy.Limit > 0 {
+		allowance = policy.Limit - policy.Committed
+		if allowance < 0 {
+			allowance = 0
+		}
+	}
+	if policy.Mode == enum.PreOrder {
+		return allowance
+	}
+	return stock + allowance
+}
+
+// DeferredUnits returns how many of the demanded units of a SKU wait for the stock under the policy
+func DeferredUnits(policy *vo.StockPolicy, demand int, stock int) int {
+	switch {
+	case policy == nil:
+		return 0
+	case policy.Mode == enum.PreOrder:
+		return demand
+	case demand > stock:
+		return demand - stock
+	default:
+		return 0
+	}
+}
+
+// FlagFulfillment flags the cart lines waiting for the stock given the stock on hand and the stock policies in
+// effect, both keyed by the SKU, the lines of a SKU missing from the stock are left as they are. It returns the lines flagged differently than before, to be persisted, along with
+// the changes the buyer should be told about, i.e. the lines which started waiting for the stock. A bundle line
+// always ships out of the stock.
+func (userCart *UserCart) FlagFulfillment(stock map[string]int, policies map[string]*vo.StockPolicy) ([]*vo.CartItem, []*CartLineChange) {
+	flagged := make([]*vo.CartItem, 0)
+	changes := make([]*CartLineChange, 0)
+	for i, line := range userCart.cart.Items {
+		if len(line.Components) > 0 {
+			continue
+		}
+		sku := itemSKU(line)
+		if _, ok := stock[sku]; !ok {
+			continue
+		}
+		policy := policies[sku]
+		deferred := minUnits(DeferredUnits(policy, userCart.demandFor(sku), stock[sku]), line.Qty)
+		mode, availableAt := enum.Fulfillment(""), (*time.Time)(nil)
+		if deferred > 0 {
+			mode, availableAt = policy.Mode, policy.AvailableAt
+		}
+		if mode == line.Fulfillment && sameTime(availableAt, line.AvailableAt) {
+			continue
+		}
+
+		updated := *line
+		updated.Fulfillment, updated.AvailableAt = mode, availableAt
+		userCart.cart.Items[i] = &updated
+		flagged = append(flagged, &updated)
+		if mode != "" {
+			changes = append(changes, &CartLineChange{
+				Line: &updated, Kind: enum.Deferred, OldPrice: line.Price, OldDisc: line.Disc, Available: line.Qty - deferred,
+			})
+		}
+	}
+	return flagged, changes
+}
+
+// SplitDemand splits the stock demand of a cart between the units shipped out of the stock on hand and the ones
+// waiting for the stock, the latter being grouped into a shipment per fulfillment and expected availability
+func SplitDemand(demand []*vo.BundleComponent, stock map[string]int, policies map[string]*vo.StockPolicy) ([]*vo.BundleComponent, []*vo.Shipment) {
+	onHand := make([]*vo.BundleComponent, 0)
+	deferred := make([]*vo.Shipment, 0)
+	index := make(map[string]*vo.Shipment)
+	for _, d := range demand {
+		policy := policies[d.SKU]
+		units := DeferredUnits(policy, d.Qty, stock[d.SKU])
+		if units < d.Qty {
+			onHand = append(onHand, &vo.BundleComponent{ProdID: d.ProdID, ProdName: d.ProdName, SKU: d.SKU, Qty: d.Qty - units})
+		}
+		if units == 0 {
+			continue
+		}
+
+		key := string(policy.Mode)
+		if policy.AvailableAt != nil {
+			key += "@" + policy.AvailableAt.UTC().Format(time.RFC3339)
+		}
+		shipment, ok := index[key]
+		if !ok {
+			shipment = &vo.Shipment{Items: make([]*vo.BundleComponent, 0), Fulfillment: policy.Mode, AvailableAt: policy.AvailableAt}
+			index[key] = shipment
+			deferred = append(deferred, shipment)
+		}
+		shipment.Items = append(shipment.Items, &vo.BundleComponent{ProdID: d.ProdID, ProdName: d.ProdName, SKU: d.SKU, Qty: units})
+	}
+	return onHand, deferred
+}
+
+func sameTime(a *time.Time, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
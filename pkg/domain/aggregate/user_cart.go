@@ -31,15 +31,63 @@ func NewUserCart(user *entity.User, cart *entity.Cart) *UserCart {
 }
 
 func (userCart *UserCart) AddItemToCart(prod *entity.Product, qty int) (*vo.CartItem, error) {
-	if qty > prod.Stock {
-		return nil, errors.New("out of stock")
+	return userCart.AddVariantToCart(prod, nil, qty)
+}
+
+// AddVariantToCart adds the chosen variant of the product into the cart, the variant can only be nil
+// for a product without variants. Items are merged by their SKU, hence the same product in two
+// different variants lives in two separate cart lines. The stock policy of the product is the one in
+// effect, the line is flagged when some of its units wait for the stock.
+func (userCart *UserCart) AddVariantToCart(prod *entity.Product, variant *entity.Variant, qty int) (*vo.CartItem, error) {
+	if qty <= 0 {
+		return nil, e.NewErrInvalidData("quantity should be greater than zero")
+	}
+	addedItem, err := NewProductLine(prod, variant, qty)
+	if err != nil {
+		return nil, err
+	}
+	stock := prod.Stock
+	if variant != nil {
+		stock = variant.Stock
+	}
+	demand := userCart.demandFor(addedItem.SKU) + qty
+	if demand > OrderableUnits(prod.StockPolicy, stock) {
+		return nil, e.NewErrOutOfStock("out of stock")
+	}
+	if DeferredUnits(prod.StockPolicy, demand, stock) > 0 {
+		addedItem.Fulfillment, addedItem.AvailableAt = prod.StockPolicy.Mode, prod.StockPolicy.AvailableAt
+	}
+	if err := checkOrderLimit(prod.Name, prod.PurchaseLimit, unitsOf(userCart.cart.Items, prod.ID)+qty); err != nil {
+		return nil, err
+	}
+	if err := userCart.Validate(); err != nil {
+		return nil, err
+	}
+	if userCart.cart.Status != enum.Open {
+		return nil, fmt.Errorf("cannot add item to a cart with status as %s", userCart.cart.Status)
 	}
-	addedItem := &vo.CartItem{
-		ProdID:   prod.ID,
-		ProdName: prod.Name,
-		Qty:      qty,
-		Price:    prod.Price,
-		Disc:     prod.Disc,
+
+	return userCart.mergeItem(addedItem)
+}
+
+// AddBundleToCart adds the bundle as a single cart line, the stock is checked against
+// every component including the units already consumed by the other lines of the cart
+func (userCart *UserCart) AddBundleToCart(bundle *ProductBundle, qty int) (*vo.CartItem, error) {
+	if qty <= 0 {
+		return nil, e.NewErrInvalidData("quantity should be greater than zero")
+	}
+	for _, c := range bundle.Components() {
+		if userCart.demandFor(c.SKU)+c.Qty*qty > bundle.StockOf(c) {
+			return nil, e.NewErrOutOfStock(fmt.Sprintf("out of stock, not enough %s left for the bundle", c.ProdName))
+		}
+		component := bundle.components[c.ProdID]
+		if err := checkOrderLimit(component.Name, component.PurchaseLimit, unitsOf(userCart.cart.Items, c.ProdID)+c.Qty*qty); err != nil {
+			return nil, err
+		}
+	}
+	info := bundle.FetchBundleInfo()
+	if err := checkOrderLimit(info.Name, info.PurchaseLimit, unitsOf(userCart.cart.Items, info.ID)+qty); err != nil {
+		return nil, err
 	}
 	if err := userCart.Validate(); err != nil {
 		return nil, err
@@ -48,10 +96,58 @@ func (userCart *UserCart) AddItemToCart(prod *entity.Product, qty int) (*vo.Cart
 		return nil, fmt.Errorf("cannot add item to a cart with status as %s", userCart.cart.Status)
 	}
 
+	return userCart.mergeItem(NewBundleLine(bundle, qty))
+}
+
+// ExpandStockDemand expands the cart lines into the stock being consumed per SKU,
+// a bundle line consumes the stock of its components rather than its own
+func (userCart *UserCart) ExpandStockDemand() []*vo.BundleComponent {
+	demand := make([]*vo.BundleComponent, 0)
+	index := make(map[string]*vo.BundleComponent)
+	add := func(prodID string, prodName string, sku string, qty int) {
+		if d, ok := index[sku]; ok {
+			d.Qty += qty
+			return
+		}
+		d := &vo.BundleComponent{ProdID: prodID, ProdName: prodName, SKU: sku, Qty: qty}
+		index[sku] = d
+		demand = append(demand, d)
+	}
+
+	for _, v := range userCart.cart.Items {
+		for _, d := range lineDemand(v, v.Qty) {
+			add(d.ProdID, d.ProdName, d.SKU, d.Qty)
+		}
+	}
+	return demand
+}
+
+// lineDemand expands qty units of the cart line into the stock they consume
+func lineDemand(line *vo.CartItem, qty int) []*vo.BundleComponent {
+	if len(line.Components) == 0 {
+		return []*vo.BundleComponent{{ProdID: line.ProdID, ProdName: line.ProdName, SKU: itemSKU(line), Qty: qty}}
+	}
+	demand := make([]*vo.BundleComponent, 0)
+	for _, c := range line.Components {
+		demand = append(demand, &vo.BundleComponent{ProdID: c.ProdID, ProdName: c.ProdName, SKU: componentSKU(c), Qty: c.Qty * qty})
+	}
+	return demand
+}
+
+func (userCart *UserCart) demandFor(sku string) int {
+	for _, d := range userCart.ExpandStockDemand() {
+		if d.SKU == sku {
+			return d.Qty
+		}
+	}
+	return 0
+}
+
+func (userCart *UserCart) mergeItem(addedItem *vo.CartItem) (*vo.CartItem, error) {
 	qtyOverride := false
 	for i, v := range userCart.cart.Items {
-		if prod.ID == v.ProdID {
-			userCart.cart.Items[i].Qty = userCart.cart.Items[i].Qty + qty
+		if addedItem.SKU == itemSKU(v) {
+			userCart.cart.Items[i].Qty = userCart.cart.Items[i].Qty + addedItem.Qty
 			addedItem.Qty = userCart.cart.Items[i].Qty
 			userCart.cart.Items[i] = addedItem
 			qtyOverride = true
@@ -72,7 +168,7 @@ func (userCart *UserCart) UpdateItemInCart(item *vo.CartItem) error {
 	}
 	itemUpdated := false
 	for i, v := range userCart.cart.Items {
-		if item.ProdID == v.ProdID {
+		if itemSKU(item) == itemSKU(v) {
 			userCart.cart.Items[i] = item
 			itemUpdated = true
 			break
@@ -88,6 +184,53 @@ func (userCart *UserCart) UpdateItemInCart(item *vo.CartItem) error {
 	return nil
 }
 
+// ChangeItemQty sets the quantity of the cart line identified by its SKU, stock holds the units which can be
+// ordered for every SKU consumed by the line (the line's own SKU, or the components SKU for a bundle) and limits
+// holds the purchase limits of the products consumed by the line, keyed by the product ID
+func (userCart *UserCart) ChangeItemQty(sku string, qty int, stock map[string]int, limits map[string]*vo.PurchaseLimit) (*vo.CartItem, error) {
+	if qty <= 0 {
+		return nil, e.NewErrInvalidData("quantity should be greater than zero, remove the item instead")
+	}
+	if userCart.cart.Status != enum.Open {
+		return nil, fmt.Errorf("cannot update item of a cart with status as %s", userCart.cart.Status)
+	}
+
+	var line *vo.CartItem
+	for _, v := range userCart.cart.Items {
+		if itemSKU(v) == sku {
+			line = v
+			break
+		}
+	}
+	if line == nil {
+		return nil, e.NewErrNoData(fmt.Sprintf("cannot find cart item with ID %s", sku))
+	}
+
+	//lowering the quantity is always allowed, even when the stock has dropped in the meantime
+	if qty > line.Qty {
+		for _, d := range lineDemand(line, qty-line.Qty) {
+			if userCart.demandFor(d.SKU)+d.Qty > stock[d.SKU] {
+				return nil, e.NewErrOutOfStock(fmt.Sprintf("out of stock, not enough %s left", d.ProdName))
+			}
+		}
+		extra := &vo.CartItem{ProdID: line.ProdID, Qty: qty - line.Qty, Components: line.Components}
+		for id, limit := range limits {
+			units := unitsOf(userCart.cart.Items, id) + unitsOf([]*vo.CartItem{extra}, id)
+			if err := checkOrderLimit(userCart.productName(id), limit, units); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	updated := *line
+	updated.Qty = qty
+	if err := userCart.UpdateItemInCart(&updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// RemoveItemFromCart removes the cart line identified by its SKU
 func (userCart *UserCart) RemoveItemFromCart(itemID string) error {
 	if userCart.cart.Items == nil || len(userCart.cart.Items) == 0 {
 		return e.NewErrNoData("cart is still empty")
@@ -96,7 +239,7 @@ func (userCart *UserCart) RemoveItemFromCart(itemID string) error {
 	updatedCartItems := make([]*vo.CartItem, 0)
 
 	for i, v := range userCart.cart.Items {
-		if v.ProdID == itemID {
+		if itemSKU(v) == itemID {
 			updatedCartItems = append(updatedCartItems, v)
 			userCart.cart.Items = append(userCart.cart.Items[:i], userCart.cart.Items[i+1:]...)
 			break
@@ -104,7 +247,7 @@ func (userCart *UserCart) RemoveItemFromCart(itemID string) error {
 	}
 
 	if len(updatedCartItems) == 0 {
-		return fmt.Errorf("cannot find cart item with ID %s", itemID)
+		return e.NewErrNoData(fmt.Sprintf("cannot find cart item with ID %s", itemID))
 	}
 
 	return nil
@@ -130,3 +273,11 @@ func (userCart *UserCart) Validate() error {
 	}
 	return nil
 }
+
+// itemSKU falls back to the product ID for the cart items created before the SKU was introduced
+func itemSKU(item *vo.CartItem) string {
+	if item.SKU != "" {
+		return item.SKU
+	}
+	return item.ProdID
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/yauritux/cartsvc/pkg/domain/entity"
 	vo "github.com/yauritux/cartsvc/pkg/domain/valueobject"
 	"github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
 
 	. "github.com/smartystreets/goconvey/convey"
 )
@@ -82,7 +83,20 @@ func TestSpec(t *testing.T) {
 				Convey("-> Should return error", func() {
 					res, err := userCart.AddItemToCart(p, 105)
 					So(res, ShouldBeNil)
-					So(err, ShouldNotBeEmpty)
+					So(err, ShouldHaveSameTypeAs, &e.ErrOutOfStock{})
+				})
+			})
+			Convey("-> When the quantity is not greater than zero", func() {
+				Convey("-> Should return error and leave the existing line untouched", func() {
+					_, err := userCart.AddItemToCart(p, 2)
+					So(err, ShouldBeEmpty)
+					res, err := userCart.AddItemToCart(p, -5)
+					So(res, ShouldBeNil)
+					So(err, ShouldHaveSameTypeAs, &e.ErrInvalidData{})
+					res, err = userCart.AddItemToCart(p, 0)
+					So(res, ShouldBeNil)
+					So(err, ShouldHaveSameTypeAs, &e.ErrInvalidData{})
+					So(c.Items[0].Qty, ShouldEqual, 2)
 				})
 			})
 			Convey("-> When cart session id is missing", func() {
@@ -224,4 +238,609 @@ func TestSpec(t *testing.T) {
 			})
 		})
 	})
+
+	Convey("5. Given adding a product variant to cart", t, func() {
+
+		setup()
+		gi := &entity.Product{
+			ID:    "003",
+			Name:  "Ninja Gi",
+			Price: 320,
+			Variants: []*entity.Variant{
+				{SKU: "003-BLK-M", Options: map[string]string{"size": "M"}, Stock: 10, Price: 320},
+				{SKU: "003-BLK-L", Options: map[string]string{"size": "L"}, Stock: 2, Price: 330},
+			},
+		}
+		userCart := NewUserCart(u, c)
+
+		Convey("-> Negative Scenarios", func() {
+			Convey("-> When no variant is chosen for a product having variants", func() {
+				Convey("-> Should return error", func() {
+					res, err := userCart.AddItemToCart(gi, 1)
+					So(res, ShouldBeNil)
+					So(err.Error(), ShouldEqual, "please choose a variant of product Ninja Gi")
+				})
+			})
+			Convey("-> When the chosen variant is out of stock", func() {
+				Convey("-> Should return error", func() {
+					res, err := userCart.AddVariantToCart(gi, gi.Variants[1], 3)
+					So(res, ShouldBeNil)
+					So(err, ShouldHaveSameTypeAs, &e.ErrOutOfStock{})
+				})
+			})
+		})
+
+		Convey("-> Positive Scenarios", func() {
+			Convey("-> The same product in two variants should live in two separate lines", func() {
+				_, err := userCart.AddVariantToCart(gi, gi.Variants[0], 1)
+				So(err, ShouldBeEmpty)
+				addedItem, err := userCart.AddVariantToCart(gi, gi.Variants[1], 1)
+				So(err, ShouldBeEmpty)
+				So(addedItem.SKU, ShouldEqual, "003-BLK-L")
+				So(addedItem.Price, ShouldEqual, 330)
+				So(userCart.cart.Items, ShouldHaveLength, 2)
+			})
+			Convey("-> The same variant should be merged into the existing line", func() {
+				userCart.AddVariantToCart(gi, gi.Variants[0], 1)
+				addedItem, err := userCart.AddVariantToCart(gi, gi.Variants[0], 2)
+				So(err, ShouldNotBeEmpty)
+				So(addedItem.Qty, ShouldEqual, 3)
+				So(userCart.cart.Items, ShouldHaveLength, 1)
+			})
+			Convey("-> A line should be removed by its SKU", func() {
+				userCart.AddVariantToCart(gi, gi.Variants[0], 1)
+				userCart.AddVariantToCart(gi, gi.Variants[1], 1)
+				So(userCart.RemoveItemFromCart("003-BLK-M"), ShouldBeEmpty)
+				So(userCart.cart.Items, ShouldHaveLength, 1)
+				So(userCart.cart.Items[0].SKU, ShouldEqual, "003-BLK-L")
+			})
+		})
+	})
+
+	Convey("6. Given adding a product bundle to cart", t, func() {
+
+		setup()
+		sai := &entity.Product{ID: "002", Name: "Sai", Stock: 3, Price: 200, Disc: 20}
+		kit := &entity.Product{
+			ID:   "004",
+			Name: "Ninja Training Set",
+			Type: enum.BundleProduct,
+			Components: []*vo.BundleComponent{
+				{ProdID: "001", Qty: 2},
+				{ProdID: "002", Qty: 1},
+			},
+		}
+		userCart := NewUserCart(u, c)
+
+		Convey("-> Negative Scenarios", func() {
+			Convey("-> When a component of the bundle is missing", func() {
+				Convey("-> Should return error", func() {
+					bundle, err := NewProductBundle(kit, []*entity.Product{p})
+					So(bundle, ShouldBeNil)
+					So(err.Error(), ShouldEqual, "component 002 of bundle 004 is not found")
+				})
+			})
+			Convey("-> When a component runs out of stock", func() {
+				Convey("-> Should return error", func() {
+					bundle, _ := NewProductBundle(kit, []*entity.Product{p, sai})
+					res, err := userCart.AddBundleToCart(bundle, 4)
+					So(res, ShouldBeNil)
+					So(err, ShouldHaveSameTypeAs, &e.ErrOutOfStock{})
+					So(err.Error(), ShouldEqual, "out of stock, not enough Sai left for the bundle")
+				})
+			})
+			Convey("-> When the quantity is not greater than zero", func() {
+				Convey("-> Should return error", func() {
+					bundle, _ := NewProductBundle(kit, []*entity.Product{p, sai})
+					_, err := userCart.AddBundleToCart(bundle, 1)
+					So(err, ShouldBeEmpty)
+					res, err := userCart.AddBundleToCart(bundle, -1)
+					So(res, ShouldBeNil)
+					So(err, ShouldHaveSameTypeAs, &e.ErrInvalidData{})
+					So(c.Items[0].Qty, ShouldEqual, 1)
+				})
+			})
+			Convey("-> When the component stock is already consumed by another line", func() {
+				Convey("-> Should return error", func() {
+					bundle, _ := NewProductBundle(kit, []*entity.Product{p, sai})
+					_, err := userCart.AddItemToCart(sai, 2)
+					So(err, ShouldBeEmpty)
+					res, err := userCart.AddBundleToCart(bundle, 2)
+					So(res, ShouldBeNil)
+					So(err, ShouldNotBeEmpty)
+				})
+			})
+		})
+
+		Convey("-> Positive Scenarios", func() {
+			Convey("-> Bundle should be priced as the sum of its components by default", func() {
+				bundle, _ := NewProductBundle(kit, []*entity.Product{p, sai})
+				price, disc := bundle.Price()
+				So(price, ShouldEqual, 451)
+				So(disc, ShouldEqual, 20)
+			})
+			Convey("-> Bundle pricing rule should override the components discount", func() {
+				kit.BundlePricing = &vo.BundlePricing{Mode: enum.FixedBundlePrice, Value: 400}
+				bundle, _ := NewProductBundle(kit, []*entity.Product{p, sai})
+				_, disc := bundle.Price()
+				So(disc, ShouldEqual, 51)
+
+				kit.BundlePricing = &vo.BundlePricing{Mode: enum.PercentOffBundle, Value: 10}
+				_, disc = bundle.Price()
+				So(disc, ShouldAlmostEqual, 45.1)
+			})
+			Convey("-> Bundle should be expanded into its components stock demand", func() {
+				bundle, _ := NewProductBundle(kit, []*entity.Product{p, sai})
+				addedItem, err := userCart.AddBundleToCart(bundle, 2)
+				So(err, ShouldBeEmpty)
+				So(addedItem.SKU, ShouldEqual, "004")
+				So(addedItem.Components, ShouldHaveLength, 2)
+				_, err = userCart.AddItemToCart(p, 1)
+				So(err, ShouldBeEmpty)
+
+				demand := userCart.ExpandStockDemand()
+				So(demand, ShouldHaveLength, 2)
+				So(demand[0].SKU, ShouldEqual, "001")
+				So(demand[0].Qty, ShouldEqual, 5)
+				So(demand[1].SKU, ShouldEqual, "002")
+				So(demand[1].Qty, ShouldEqual, 2)
+			})
+		})
+	})
+
+	Convey("7. Given merging a guest cart into the user cart", t, func() {
+
+		setup()
+		now := time.Now()
+		c.LastActivityAt = now
+		guest := &entity.Cart{
+			ID: "456", UserID: "guest-1", Status: enum.Open, LastActivityAt: now.Add(time.Minute),
+			Items: []*vo.CartItem{
+				{ProdID: "001", ProdName: "Shuriken", SKU: "001", Qty: 3, Price: 125.5},
+				{ProdID: "002", ProdName: "Sai", SKU: "002", Qty: 2, Price: 200, Disc: 20},
+			},
+		}
+		userCart := NewUserCart(u, c)
+		_, err := userCart.AddItemToCart(p, 2)
+		So(err, ShouldBeEmpty)
+		stock := map[string]int{"001": 100, "002": 3}
+
+		Convey("-> Negative Scenarios", func() {
+			Convey("-> Should reject an unknown rule", func() {
+				res, err := userCart.MergeCart(guest, enum.CartMergeRule("min"), stock)
+				So(res, ShouldBeNil)
+				So(err.Error(), ShouldEqual, "unknown cart merge rule min")
+			})
+			Convey("-> Should reject a guest cart which is not open", func() {
+				guest.Status = enum.Canceled
+				_, err := userCart.MergeCart(guest, enum.SumQuantities, stock)
+				So(err.Error(), ShouldEqual, "cannot merge a cart with status as canceled")
+			})
+		})
+
+		Convey("-> Positive Scenarios", func() {
+			Convey("-> Sum should add the quantities of the lines found in both carts", func() {
+				res, err := userCart.MergeCart(guest, enum.SumQuantities, stock)
+				So(err, ShouldBeNil)
+				So(res.Updated, ShouldHaveLength, 1)
+				So(res.Updated[0].Qty, ShouldEqual, 5)
+				So(res.Added, ShouldHaveLength, 1)
+				So(res.Added[0].SKU, ShouldEqual, "002")
+				So(res.LeftOut, ShouldBeEmpty)
+				So(userCart.FetchCartInfo().Items, ShouldHaveLength, 2)
+			})
+			Convey("-> Max should keep the greatest quantity", func() {
+				guest.Items[0].Qty = 1
+				res, err := userCart.MergeCart(guest, enum.KeepMaxQuantity, stock)
+				So(err, ShouldBeNil)
+				So(res.Updated, ShouldBeEmpty)
+				So(userCart.FetchCartInfo().Items[0].Qty, ShouldEqual, 2)
+			})
+			Convey("-> Latest should keep the line of the cart with the latest activity", func() {
+				guest.Items[0].Qty = 1
+				res, err := userCart.MergeCart(guest, enum.KeepLatestQuantity, stock)
+				So(err, ShouldBeNil)
+				So(res.Updated, ShouldHaveLength, 1)
+				So(userCart.FetchCartInfo().Items[0].Qty, ShouldEqual, 1)
+
+				guest.LastActivityAt = now.Add(-time.Minute)
+				guest.Items[0].Qty = 7
+				res, err = userCart.MergeCart(guest, enum.KeepLatestQuantity, stock)
+				So(err, ShouldBeNil)
+				So(res.Updated, ShouldBeEmpty)
+				So(userCart.FetchCartInfo().Items[0].Qty, ShouldEqual, 1)
+			})
+			Convey("-> A line should be capped to the stock left and the rest reported", func() {
+				_, err := userCart.AddItemToCart(&entity.Product{ID: "002", Name: "Sai", Stock: 3, Price: 200}, 2)
+				So(err, ShouldBeEmpty)
+				res, err := userCart.MergeCart(guest, enum.SumQuantities, stock)
+				So(err, ShouldBeNil)
+				So(res.Updated, ShouldHaveLength, 2)
+				So(res.Updated[1].Qty, ShouldEqual, 3)
+				So(res.LeftOut, ShouldHaveLength, 1)
+				So(res.LeftOut[0].SKU, ShouldEqual, "002")
+				So(res.LeftOut[0].Qty, ShouldEqual, 1)
+			})
+			Convey("-> A bundle should be capped by the stock of its components", func() {
+				guest.Items = []*vo.CartItem{{
+					ProdID: "004", ProdName: "Ninja Training Set", SKU: "004", Qty: 2, Price: 451,
+					Components: []*vo.BundleComponent{{ProdID: "001", SKU: "001", Qty: 2}, {ProdID: "002", SKU: "002", Qty: 2}},
+				}}
+				res, err := userCart.MergeCart(guest, enum.SumQuantities, stock)
+				So(err, ShouldBeNil)
+				So(res.Added, ShouldHaveLength, 1)
+				So(res.Added[0].Qty, ShouldEqual, 1)
+				So(res.LeftOut[0].Qty, ShouldEqual, 1)
+			})
+		})
+	})
+
+	Convey("8. Given repricing the cart against the current catalog", t, func() {
+
+		setup()
+		userCart := NewUserCart(u, c)
+		_, err := userCart.AddItemToCart(p, 2)
+		So(err, ShouldBeEmpty)
+		_, err = userCart.AddItemToCart(&entity.Product{ID: "002", Name: "Sai", Stock: 10, Price: 200, Disc: 20}, 3)
+		So(err, ShouldBeEmpty)
+		current := map[string]*vo.CartItem{
+			"001": {ProdID: "001", SKU: "001", Price: 125.5},
+			"002": {ProdID: "002", SKU: "002", Price: 200, Disc: 20},
+		}
+		stock := map[string]int{"001": 100, "002": 10}
+
+		Convey("-> Negative Scenarios", func() {
+			Convey("-> Should reject a cart which is not open", func() {
+				c.Status = enum.PaymentProcessing
+				res, err := userCart.RepriceCart(current, stock)
+				So(res, ShouldBeNil)
+				So(err.Error(), ShouldEqual, "cannot reprice a cart with status as payment_processing")
+			})
+		})
+
+		Convey("-> Positive Scenarios", func() {
+			Convey("-> Should report nothing when the catalog is unchanged", func() {
+				res, err := userCart.RepriceCart(current, stock)
+				So(err, ShouldBeNil)
+				So(res.Repriced, ShouldBeEmpty)
+				So(res.Changes, ShouldBeEmpty)
+			})
+			Convey("-> Should update the prices and tell whether they rose or dropped", func() {
+				current["001"].Price = 130
+				current["002"].Disc = 50
+				res, err := userCart.RepriceCart(current, stock)
+				So(err, ShouldBeNil)
+				So(res.Repriced, ShouldHaveLength, 2)
+				So(res.Changes, ShouldHaveLength, 2)
+				So(res.Changes[0].Kind, ShouldEqual, enum.PriceIncreased)
+				So(res.Changes[0].OldPrice, ShouldEqual, 125.5)
+				So(res.Changes[1].Kind, ShouldEqual, enum.PriceDecreased)
+				So(userCart.FetchCartInfo().Items[0].Price, ShouldEqual, 130)
+				So(userCart.FetchCartInfo().Items[1].Disc, ShouldEqual, 50)
+			})
+			Convey("-> A price and discount raised alike should be updated silently", func() {
+				current["002"].Price = 210
+				current["002"].Disc = 30
+				res, err := userCart.RepriceCart(current, stock)
+				So(err, ShouldBeNil)
+				So(res.Repriced, ShouldHaveLength, 1)
+				So(res.Changes, ShouldBeEmpty)
+			})
+			Convey("-> Should flag the lines the stock cannot cover and the products gone", func() {
+				delete(current, "001")
+				stock["002"] = 1
+				res, err := userCart.RepriceCart(current, stock)
+				So(err, ShouldBeNil)
+				So(res.Changes, ShouldHaveLength, 2)
+				So(res.Changes[0].Kind, ShouldEqual, enum.Unavailable)
+				So(res.Changes[1].Kind, ShouldEqual, enum.StockShort)
+				So(res.Changes[1].Available, ShouldEqual, 1)
+				So(userCart.FetchCartInfo().Items[1].Qty, ShouldEqual, 3)
+			})
+		})
+	})
+
+	Convey("9. Given products with purchase limits", t, func() {
+
+		setup()
+		p.PurchaseLimit = &vo.PurchaseLimit{MaxPerOrder: 5, MaxPerCustomer: 10, Period: 7 * 24 * time.Hour}
+		sai := &entity.Product{ID: "002", Name: "Sai", Stock: 10, Price: 200}
+		kit := &entity.Product{
+			ID:   "004",
+			Name: "Ninja Training Set",
+			Type: enum.BundleProduct,
+			Components: []*vo.BundleComponent{
+				{ProdID: "001", Qty: 2},
+				{ProdID: "002", Qty: 1},
+			},
+		}
+		limits := map[string]*vo.PurchaseLimit{"001": p.PurchaseLimit}
+		stock := map[string]int{"001": 100, "002": 10}
+		now := time.Now()
+		daysAgo := func(days int) *time.Time {
+			at := now.Add(-time.Duration(days) * 24 * time.Hour)
+			return &at
+		}
+		order := func(id string, qty int, checkedOutAt *time.Time) *entity.Cart {
+			return &entity.Cart{
+				ID:           id,
+				UserID:       "yauritux",
+				Status:       enum.Closed,
+				Items:        []*vo.CartItem{{ProdID: "001", SKU: "001", ProdName: "Shuriken", Qty: qty}},
+				CheckedOutAt: checkedOutAt,
+			}
+		}
+		userCart := NewUserCart(u, c)
+
+		Convey("-> Negative Scenarios", func() {
+			Convey("-> Should not add more units than allowed per order", func() {
+				_, err := userCart.AddItemToCart(p, 4)
+				So(err, ShouldBeEmpty)
+				res, err := userCart.AddItemToCart(p, 2)
+				So(res, ShouldBeNil)
+				So(err, ShouldHaveSameTypeAs, &e.ErrOrderLimitExceeded{})
+				So(err.Error(), ShouldEqual, "cannot order more than 5 of Shuriken at once")
+			})
+			Convey("-> Should count the units consumed by a bundle", func() {
+				_, err := userCart.AddItemToCart(p, 2)
+				So(err, ShouldBeEmpty)
+				bundle, _ := NewProductBundle(kit, []*entity.Product{p, sai})
+				res, err := userCart.AddBundleToCart(bundle, 2)
+				So(res, ShouldBeNil)
+				So(err, ShouldHaveSameTypeAs, &e.ErrOrderLimitExceeded{})
+			})
+			Convey("-> Should not raise the quantity over the limit", func() {
+				_, err := userCart.AddItemToCart(p, 3)
+				So(err, ShouldBeEmpty)
+				res, err := userCart.ChangeItemQty("001", 6, stock, limits)
+				So(res, ShouldBeNil)
+				So(err, ShouldHaveSameTypeAs, &e.ErrOrderLimitExceeded{})
+			})
+			Convey("-> Should not check out more units than allowed per customer over the period", func() {
+				_, err := userCart.AddItemToCart(p, 4)
+				So(err, ShouldBeEmpty)
+				orders := []*entity.Cart{order("100", 5, daysAgo(2)), order("101", 5, daysAgo(10))}
+				So(userCart.CheckPurchaseLimits(limits, orders, now), ShouldBeEmpty)
+				orders = append(orders, order("102", 2, daysAgo(6)))
+				err = userCart.CheckPurchaseLimits(limits, orders, now)
+				So(err, ShouldHaveSameTypeAs, &e.ErrCustomerLimitExceeded{})
+				So(err.Error(), ShouldEqual, "cannot buy more than 10 of Shuriken within 7 days, 7 already bought")
+			})
+		})
+
+		Convey("-> Positive Scenarios", func() {
+			Convey("-> Should add and lower the quantity within the limit", func() {
+				_, err := userCart.AddItemToCart(p, 5)
+				So(err, ShouldBeEmpty)
+				p.PurchaseLimit.MaxPerOrder = 2
+				res, err := userCart.ChangeItemQty("001", 4, stock, limits)
+				So(err, ShouldBeEmpty)
+				So(res.Qty, ShouldEqual, 4)
+			})
+			Convey("-> Should count every order when the limit has no period", func() {
+				p.PurchaseLimit.Period = 0
+				_, err := userCart.AddItemToCart(p, 1)
+				So(err, ShouldBeEmpty)
+				err = userCart.CheckPurchaseLimits(limits, []*entity.Cart{order("100", 9, daysAgo(365))}, now)
+				So(err, ShouldBeEmpty)
+				err = userCart.CheckPurchaseLimits(limits, []*entity.Cart{order("100", 9, daysAgo(365)), order("101", 1, nil)}, now)
+				So(err.Error(), ShouldEqual, "cannot buy more than 10 of Shuriken, 10 already bought")
+			})
+		})
+	})
+
+	Convey("10. Given products with scheduled prices", t, func() {
+
+		setup()
+		now := time.Date(2020, time.May, 1, 12, 0, 0, 0, time.UTC)
+		gi := &entity.Product{
+			ID: "003", Name: "Ninja Gi", Price: 320,
+			Variants: []*entity.Variant{
+				{SKU: "003-BLK-M", Stock: 5, Price: 320},
+				{SKU: "003-RED-M", Stock: 5, Price: 340, Disc: 20},
+			},
+			PriceSchedules: []*vo.PriceSchedule{
+				{ID: "1", Disc: 50, StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour)},
+				{ID: "2", SKU: "003-RED-M", Price: 300, StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour), Quantity: 3, Sold: 1},
+			},
+		}
+		p.PriceSchedules = []*vo.PriceSchedule{
+			{ID: "1", Price: 99, StartsAt: now.Add(time.Hour), EndsAt: now.Add(2 * time.Hour)},
+		}
+
+		Convey("-> Negative Scenarios", func() {
+			Convey("-> The regular price should apply outside of the schedules", func() {
+				So(ActivePriceSchedule(p, "001", 1, now), ShouldBeNil)
+				priced := PriceProductAt(p, 1, now.Add(2*time.Hour))
+				So(priced.Price, ShouldEqual, 125.5)
+			})
+			Convey("-> A flash sale should not apply beyond the units left", func() {
+				So(ActivePriceSchedule(gi, "003-RED-M", 3, now).ID, ShouldEqual, "1")
+				priced := PriceProductAt(gi, 3, now)
+				So(priced.Variants[1].Price, ShouldEqual, 340)
+				So(priced.Variants[1].Disc, ShouldEqual, 50)
+			})
+		})
+
+		Convey("-> Positive Scenarios", func() {
+			Convey("-> Should price the product within its schedule", func() {
+				line, err := NewProductLine(PriceProductAt(p, 1, now.Add(time.Hour)), nil, 1)
+				So(err, ShouldBeEmpty)
+				So(line.Price, ShouldEqual, 99)
+				So(p.Price, ShouldEqual, 125.5)
+			})
+			Convey("-> A schedule dedicated to the variant should take over the one of the product", func() {
+				priced := PriceProductAt(gi, 2, now)
+				So(priced.Variants[0].Price, ShouldEqual, 320)
+				So(priced.Variants[0].Disc, ShouldEqual, 50)
+				So(priced.Variants[1].Price, ShouldEqual, 300)
+				So(priced.Variants[1].Disc, ShouldEqual, 0)
+				So(gi.Variants[1].Price, ShouldEqual, 340)
+			})
+		})
+	})
+
+	Convey("11. Given the stock spread among several warehouses", t, func() {
+
+		setup()
+		warehouses := []*entity.Warehouse{
+			{ID: "main", City: "Jakarta", Region: "Java", Postal: "12750", Country: "Indonesia"},
+			{ID: "surabaya", City: "Surabaya", Region: "Java", Postal: "60111", Country: "Indonesia"},
+			{ID: "medan", City: "Medan", Region: "Sumatra", Postal: "20111", Country: "Indonesia"},
+		}
+		toMedan := &vo.BuyerAddress{City: "Medan", Region: "Sumatra", Postal: "20112", Country: "Indonesia"}
+		demand := []*vo.BundleComponent{
+			{ProdID: "001", ProdName: "Shuriken", SKU: "001", Qty: 5},
+			{ProdID: "002", ProdName: "Sai", SKU: "002", Qty: 2},
+		}
+		levels := map[string]map[string]int{
+			"001": {"main": 10, "surabaya": 2, "medan": 3},
+			"002": {"main": 1, "medan": 1},
+		}
+
+		Convey("-> Negative Scenarios", func() {
+			Convey("-> Should refuse a demand beyond the stock of all the warehouses", func() {
+				levels["002"] = map[string]int{"medan": 1}
+				_, err := AllocateStock(demand, levels, RankWarehouses(warehouses, toMedan))
+				So(err, ShouldHaveSameTypeAs, &e.ErrOutOfStock{})
+				So(err.Error(), ShouldEqual, "out of stock, only 1 of Sai left")
+			})
+		})
+
+		Convey("-> Positive Scenarios", func() {
+			Convey("-> Should rank the warehouses by their nearness to the shipping address", func() {
+				ranked := RankWarehouses(warehouses, toMedan)
+				So([]string{ranked[0].ID, ranked[1].ID, ranked[2].ID}, ShouldResemble, []string{"medan", "main", "surabaya"})
+				ranked = RankWarehouses(warehouses, &vo.BuyerAddress{City: "Surabaya", Country: "Indonesia"})
+				So(ranked[0].ID, ShouldEqual, "surabaya")
+				So(warehouses[0].ID, ShouldEqual, "main")
+			})
+			Convey("-> Should ship the whole demand from the nearest warehouse able to", func() {
+				levels["002"]["main"] = 2
+				allocation, err := AllocateStock(demand, levels, RankWarehouses(warehouses, toMedan))
+				So(err, ShouldBeEmpty)
+				So(len(allocation.Shipments), ShouldEqual, 1)
+				So(allocation.Shipments[0].WarehouseID, ShouldEqual, "main")
+				So(len(allocation.Shipments[0].Items), ShouldEqual, 2)
+			})
+			Convey("-> Should split the demand into the fewest shipments", func() {
+				allocation, err := AllocateStock(demand, levels, RankWarehouses(warehouses, toMedan))
+				So(err, ShouldBeEmpty)
+				So(len(allocation.Shipments), ShouldEqual, 2)
+				So(allocation.Shipments[0].WarehouseID, ShouldEqual, "main")
+				So(allocation.Shipments[0].Items[0].Qty, ShouldEqual, 5)
+				So(allocation.Shipments[0].Items[1].Qty, ShouldEqual, 1)
+				So(allocation.Shipments[1].WarehouseID, ShouldEqual, "medan")
+				So(allocation.Shipments[1].Items, ShouldResemble, []*vo.BundleComponent{{ProdID: "002", ProdName: "Sai", SKU: "002", Qty: 1}})
+			})
+			Convey("-> Should not ship twice from the same warehouse", func() {
+				allocation, err := AllocateStock(demand[:1], map[string]map[string]int{"001": {"main": 4, "medan": 3}}, nil)
+				So(err, ShouldBeEmpty)
+				So(allocation.Shipments, ShouldHaveLength, 2)
+				So(allocation.Shipments[0].Items[0].Qty, ShouldEqual, 4)
+				So(allocation.Shipments[1].WarehouseID, ShouldEqual, "medan")
+				So(allocation.Shipments[1].Items[0].Qty, ShouldEqual, 1)
+			})
+			Convey("-> Should fall back to the main warehouse for the stock which is not spread", func() {
+				prod := &entity.Product{ID: "002", Stock: 7}
+				So(StockLevels(prod, "002"), ShouldResemble, map[string]int{entity.MainWarehouse: 7})
+				allocation, err := AllocateStock(demand[1:], map[string]map[string]int{"002": StockLevels(prod, "002")}, nil)
+				So(err, ShouldBeEmpty)
+				So(allocation.Shipments[0].WarehouseID, ShouldEqual, entity.MainWarehouse)
+			})
+		})
+	})
+
+	Convey("12. Given a product which can be ordered beyond its stock", t, func() {
+
+		setup()
+		releasedAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+		backorder := &vo.StockPolicy{Mode: enum.Backorder, Limit: 10}
+		preorder := &vo.StockPolicy{Mode: enum.PreOrder, AvailableAt: &releasedAt}
+		p.Stock = 3
+
+		Convey("-> Negative Scenarios", func() {
+			Convey("-> Should refuse the units beyond the limit of the backorder", func() {
+				p.StockPolicy = backorder
+				backorder.Committed = 8
+				_, err := NewUserCart(u, c).AddItemToCart(p, 6)
+				So(err.Error(), ShouldEqual, "out of stock")
+			})
+			Convey("-> Should only sell the stock once the pre-order is released", func() {
+				p.StockPolicy = preorder
+				So(StockPolicyAt(p, releasedAt.Add(-time.Hour)), ShouldEqual, preorder)
+				p.StockPolicy = StockPolicyAt(p, releasedAt)
+				So(p.StockPolicy, ShouldBeNil)
+				_, err := NewUserCart(u, c).AddItemToCart(p, 5)
+				So(err.Error(), ShouldEqual, "out of stock")
+			})
+		})
+
+		Convey("-> Positive Scenarios", func() {
+			Convey("-> Should flag the line backordered beyond the stock on hand", func() {
+				p.StockPolicy = backorder
+				userCart := NewUserCart(u, c)
+				item, err := userCart.AddItemToCart(p, 2)
+				So(err, ShouldBeEmpty)
+				So(item.Fulfillment, ShouldBeEmpty)
+				item, err = userCart.AddItemToCart(p, 5)
+				So(err, ShouldHaveSameTypeAs, &e.ErrDuplicateData{})
+				So(item.Qty, ShouldEqual, 7)
+				So(item.Fulfillment, ShouldEqual, enum.Backorder)
+			})
+			Convey("-> Should count a pre-order against its limit only", func() {
+				preorder.Limit = 4
+				So(OrderableUnits(preorder, 100), ShouldEqual, 4)
+				So(OrderableUnits(backorder, 3), ShouldEqual, 13)
+				So(OrderableUnits(nil, 3), ShouldEqual, 3)
+				So(DeferredUnits(preorder, 2, 100), ShouldEqual, 2)
+				So(DeferredUnits(backorder, 5, 3), ShouldEqual, 2)
+			})
+			Convey("-> Should flag anew the lines once the stock has changed", func() {
+				userCart := NewUserCart(u, c)
+				_, err := userCart.AddItemToCart(p, 3)
+				So(err, ShouldBeEmpty)
+				policies := map[string]*vo.StockPolicy{"001": backorder}
+				flagged, changes := userCart.FlagFulfillment(map[string]int{"001": 1}, policies)
+				So(flagged, ShouldHaveLength, 1)
+				So(flagged[0].Fulfillment, ShouldEqual, enum.Backorder)
+				So(changes, ShouldHaveLength, 1)
+				So(changes[0].Kind, ShouldEqual, enum.Deferred)
+				So(changes[0].Available, ShouldEqual, 1)
+
+				flagged, changes = userCart.FlagFulfillment(map[string]int{"001": 1}, policies)
+				So(flagged, ShouldBeEmpty)
+				So(changes, ShouldBeEmpty)
+				flagged, changes = userCart.FlagFulfillment(map[string]int{"001": 5}, policies)
+				So(flagged[0].Fulfillment, ShouldBeEmpty)
+				So(changes, ShouldBeEmpty)
+			})
+			Convey("-> Should ship the units waiting for the stock apart", func() {
+				demand := []*vo.BundleComponent{
+					{ProdID: "001", ProdName: "Shuriken", SKU: "001", Qty: 5},
+					{ProdID: "002", ProdName: "Sai", SKU: "002", Qty: 2},
+					{ProdID: "003", ProdName: "Kunai", SKU: "003", Qty: 1},
+				}
+				stock := map[string]int{"001": 3, "002": 0, "003": 1}
+				policies := map[string]*vo.StockPolicy{"001": backorder, "002": preorder}
+				onHand, deferred := SplitDemand(demand, stock, policies)
+				So(onHand, ShouldResemble, []*vo.BundleComponent{
+					{ProdID: "001", ProdName: "Shuriken", SKU: "001", Qty: 3},
+					{ProdID: "003", ProdName: "Kunai", SKU: "003", Qty: 1},
+				})
+				So(deferred, ShouldHaveLength, 2)
+				So(deferred[0].Fulfillment, ShouldEqual, enum.Backorder)
+				So(deferred[0].WarehouseID, ShouldBeEmpty)
+				So(deferred[0].Items[0].Qty, ShouldEqual, 2)
+				So(deferred[1].Fulfillment, ShouldEqual, enum.PreOrder)
+				So(*deferred[1].AvailableAt, ShouldEqual, releasedAt)
+				So(deferred[1].Items[0].Qty, ShouldEqual, 2)
+			})
+			Convey("-> Should not allocate any warehouse to an empty demand", func() {
+				allocation, err := AllocateStock(nil, nil, []*entity.Warehouse{{ID: "main"}})
+				So(err, ShouldBeEmpty)
+				So(allocation.Shipments, ShouldBeEmpty)
+			})
+		})
+	})
 }
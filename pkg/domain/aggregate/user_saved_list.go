@@ -0,0 +1,75 @@
+package aggregate
+
+import (
+	"fmt"
+
+	"github.com/yauritux/cartsvc/pkg/domain/entity"
+	vo "github.com/yauritux/cartsvc/pkg/domain/valueobject"
+	"github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+)
+
+// UserSavedList is the wishlist or the saved for later list of a user, the lines are merged by their SKU
+type UserSavedList struct {
+	user *entity.User
+	list *entity.SavedList
+}
+
+func NewUserSavedList(user *entity.User, list *entity.SavedList) *UserSavedList {
+	if list.Items == nil {
+		list.Items = make([]*vo.CartItem, 0)
+	}
+	return &UserSavedList{
+		user: user,
+		list: list,
+	}
+}
+
+// SaveItem parks the line into the list, a line already saved under the same SKU gets the quantities
+// summed up and the price of the latest save. It returns the line as saved.
+func (savedList *UserSavedList) SaveItem(item *vo.CartItem) (*vo.CartItem, error) {
+	if err := savedList.Validate(); err != nil {
+		return nil, err
+	}
+	if item.Qty <= 0 {
+		return nil, e.NewErrInvalidData("quantity should be greater than zero")
+	}
+
+	saved := *item
+	for i, v := range savedList.list.Items {
+		if itemSKU(v) == itemSKU(item) {
+			saved.Qty += v.Qty
+			savedList.list.Items[i] = &saved
+			return &saved, nil
+		}
+	}
+	savedList.list.Items = append(savedList.list.Items, &saved)
+	return &saved, nil
+}
+
+// TakeItem removes the line identified by its SKU out of the list and returns it
+func (savedList *UserSavedList) TakeItem(sku string) (*vo.CartItem, error) {
+	for i, v := range savedList.list.Items {
+		if itemSKU(v) == sku {
+			savedList.list.Items = append(savedList.list.Items[:i], savedList.list.Items[i+1:]...)
+			return v, nil
+		}
+	}
+	return nil, e.NewErrNoData(fmt.Sprintf("cannot find item %s within the %s", sku, savedList.list.Name))
+}
+
+func (savedList *UserSavedList) FetchListInfo() *entity.SavedList {
+	return savedList.list
+}
+
+func (savedList *UserSavedList) Validate() error {
+	if savedList.user.UserID == "" || savedList.list.UserID != savedList.user.UserID {
+		return e.NewErrInvalidData("saved list 'user_id' is missing")
+	}
+	switch savedList.list.Name {
+	case enum.Wishlist, enum.SavedForLater:
+		return nil
+	default:
+		return e.NewErrInvalidData(fmt.Sprintf("unknown saved list %s, should be wishlist or saved_for_later", savedList.list.Name))
+	}
+}
@@ -0,0 +1,57 @@
+package aggregate
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/yauritux/cartsvc/pkg/domain/entity"
+	vo "github.com/yauritux/cartsvc/pkg/domain/valueobject"
+	"github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+)
+
+func TestUserSavedList(t *testing.T) {
+
+	Convey("1. Given the saved for later list of a user", t, func() {
+
+		setup()
+		list := NewUserSavedList(u, &entity.SavedList{UserID: u.UserID, Name: enum.SavedForLater})
+		shuriken := &vo.CartItem{ProdID: "001", ProdName: "Shuriken", SKU: "001", Qty: 2, Price: 125.5}
+
+		Convey("-> Negative Scenarios", func() {
+			Convey("-> Should reject an unknown list", func() {
+				list := NewUserSavedList(u, &entity.SavedList{UserID: u.UserID, Name: "favorites"})
+				_, err := list.SaveItem(shuriken)
+				So(err.Error(), ShouldEqual, "unknown saved list favorites, should be wishlist or saved_for_later")
+			})
+			Convey("-> Should reject an empty quantity", func() {
+				_, err := list.SaveItem(&vo.CartItem{ProdID: "001", SKU: "001"})
+				So(err, ShouldHaveSameTypeAs, &e.ErrInvalidData{})
+			})
+			Convey("-> Should not take a missing item", func() {
+				_, err := list.TakeItem("002")
+				So(err.Error(), ShouldEqual, "cannot find item 002 within the saved_for_later")
+			})
+		})
+
+		Convey("-> Positive Scenarios", func() {
+			Convey("-> Saving the same SKU twice should sum the quantities at the latest price", func() {
+				_, err := list.SaveItem(shuriken)
+				So(err, ShouldBeNil)
+				saved, err := list.SaveItem(&vo.CartItem{ProdID: "001", ProdName: "Shuriken", SKU: "001", Qty: 1, Price: 99})
+				So(err, ShouldBeNil)
+				So(saved.Qty, ShouldEqual, 3)
+				So(saved.Price, ShouldEqual, 99)
+				So(list.FetchListInfo().Items, ShouldHaveLength, 1)
+				So(shuriken.Qty, ShouldEqual, 2)
+			})
+			Convey("-> A taken item should leave the list", func() {
+				list.SaveItem(shuriken)
+				taken, err := list.TakeItem("001")
+				So(err, ShouldBeNil)
+				So(taken.Qty, ShouldEqual, 2)
+				So(list.FetchListInfo().Items, ShouldBeEmpty)
+			})
+		})
+	})
+}
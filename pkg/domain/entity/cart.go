@@ -8,10 +8,15 @@ import (
 )
 
 type Cart struct {
-	ID         string
-	UserID     string
-	Status     CartStatus
-	Items      []*vo.CartItem
-	CreatedAt  time.Time
-	CanceledAt *time.Time
+	ID             string
+	UserID         string
+	Status         CartStatus
+	Items          []*vo.CartItem
+	CreatedAt      time.Time
+	LastActivityAt time.Time
+	CanceledAt     *time.Time
+	CheckedOutAt   *time.Time
+	ClosedAt       *time.Time
+	RemindersSent  int
+	LastRemindedAt *time.Time
 }
@@ -0,0 +1,7 @@
+package entity
+
+type Category struct {
+	ID       string
+	Name     string
+	ParentID string
+}
@@ -1,9 +1,26 @@
 package entity
 
+import (
+	vo "github.com/yauritux/cartsvc/pkg/domain/valueobject"
+	. "github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+)
+
 type Product struct {
-	ID    string
-	Name  string
-	Stock int
-	Price float64
-	Disc  float64
+	ID            string
+	Name          string
+	Type          ProductType
+	Stock         int
+	Price         float64
+	Disc          float64
+	CategoryIDs   []string
+	Variants      []*Variant
+	Components    []*vo.BundleComponent
+	BundlePricing *vo.BundlePricing
+	PurchaseLimit *vo.PurchaseLimit
+	// PriceSchedules are the scheduled price changes and flash sales, see aggregate.PriceProductAt
+	PriceSchedules []*vo.PriceSchedule
+	// WarehouseStock spreads the Stock among the warehouses, it all sits in the MainWarehouse when empty
+	WarehouseStock map[string]int
+	// StockPolicy lets the product be ordered beyond its stock, see aggregate.StockPolicyAt
+	StockPolicy *vo.StockPolicy
 }
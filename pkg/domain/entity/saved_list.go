@@ -0,0 +1,16 @@
+package entity
+
+import (
+	"time"
+
+	vo "github.com/yauritux/cartsvc/pkg/domain/valueobject"
+	. "github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+)
+
+// SavedList holds the items a user parks without buying them, each line keeps the price it was saved at
+type SavedList struct {
+	UserID    string
+	Name      SavedListName
+	Items     []*vo.CartItem
+	UpdatedAt time.Time
+}
@@ -0,0 +1,12 @@
+package entity
+
+// Variant is the purchasable SKU of a product having option attributes (e.g. size, color)
+type Variant struct {
+	SKU     string
+	Options map[string]string
+	Stock   int
+	Price   float64
+	Disc    float64
+	// WarehouseStock spreads the Stock among the warehouses, it all sits in the MainWarehouse when empty
+	WarehouseStock map[string]int
+}
@@ -0,0 +1,15 @@
+package entity
+
+// MainWarehouse holds the whole stock of the products which do not spread it among the warehouses
+const MainWarehouse = "main"
+
+// Warehouse is a place the orders are shipped from, located by its address
+type Warehouse struct {
+	ID       string
+	Name     string
+	City     string
+	Province string
+	Region   string
+	Postal   string
+	Country  string
+}
@@ -1,11 +1,32 @@
 package repository
 
+import "time"
+
+// CartRepository stores the carts, the times given to its operations are stamped on the cart as they are
+// (e.g. the last activity of the cart) so that the use case keeps the one clock of the service
 type CartRepository interface {
+	// Open makes sure the user owns an open cart, a new one is created at openedAt unless there's one already
+	Open(userID string, openedAt time.Time) error
+	// FetchUserCart returns the open cart of the user, ErrNoData when the user has got none
 	FetchUserCart(userID string) (interface{}, error)
-	AddToCart(cartID string, item interface{}) error
-	RemoveItem(cartID string, itemID string) error
-	UpdateItem(cartID string, item interface{}) error
-	Checkout(cartID string) interface{}
-	Canceled(cartID string) error
-	Close(cartID string) error
+	// FetchLatestCart returns the cart the user opened last whatever its status, e.g. the order just checked out
+	FetchLatestCart(userID string) (interface{}, error)
+	AddToCart(cartID string, item interface{}, at time.Time) error
+	RemoveItem(cartID string, itemID string, at time.Time) error
+	UpdateItem(cartID string, item interface{}, at time.Time) error
+	Checkout(cartID string, checkedOutAt time.Time) interface{}
+	Canceled(cartID string, canceledAt time.Time) error
+	Close(cartID string, closedAt time.Time) error
+	// Refunded marks a closed cart as refunded
+	Refunded(cartID string) error
+	// FetchIdleCarts returns the open and payment_processing carts having no activity since the given time
+	FetchIdleCarts(idleSince time.Time) (interface{}, error)
+	// FetchOrders returns the carts of the user checked out since the given time, whether paid for or not
+	FetchOrders(userID string, since time.Time) (interface{}, error)
+	// RecordShipments stores the warehouses the stock of the cart is shipped from, upon checkout
+	RecordShipments(cartID string, shipments interface{}) error
+	// RecordRedemption stores the loyalty points redeemed for the cart upon checkout, nil clears them
+	RecordRedemption(cartID string, redemption interface{}) error
+	// RecordReminder counts a recovery reminder sent for an open cart, it is not an activity of the cart
+	RecordReminder(cartID string, sentAt time.Time) error
 }
@@ -0,0 +1,7 @@
+package repository
+
+type CategoryRepository interface {
+	FindByCategoryID(string) (interface{}, error)
+	FetchAll() (interface{}, error)
+	Create(interface{}) error
+}
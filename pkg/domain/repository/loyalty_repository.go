@@ -0,0 +1,8 @@
+package repository
+
+type LoyaltyRepository interface {
+	// FetchLedger returns the loyalty ledger of the user, an empty one when the user has no entry yet
+	FetchLedger(userID string) (interface{}, error)
+	// SaveLedger replaces the loyalty ledger of its user
+	SaveLedger(ledger interface{}) error
+}
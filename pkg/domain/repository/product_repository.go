@@ -2,4 +2,10 @@ package repository
 
 type ProductRepository interface {
 	FindByProductID(string) (interface{}, error)
+	// FindAnyByProductID finds the product even when it has been deleted, for the orders still referencing it
+	FindAnyByProductID(string) (interface{}, error)
+	Create(interface{}) error
+	Update(interface{}) error
+	Delete(string) error
+	Search(query interface{}) (interface{}, error)
 }
@@ -0,0 +1,8 @@
+package repository
+
+type SavedListRepository interface {
+	// FetchSavedList returns the named list of the user, an empty one when nothing has been saved yet
+	FetchSavedList(userID string, name string) (interface{}, error)
+	// Save replaces the whole list
+	Save(list interface{}) error
+}
@@ -0,0 +1,7 @@
+package repository
+
+type SessionRepository interface {
+	Save(session interface{}) error
+	FindByToken(token string) (interface{}, error)
+	Delete(token string) error
+}
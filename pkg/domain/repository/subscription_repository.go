@@ -0,0 +1,11 @@
+package repository
+
+type SubscriptionRepository interface {
+	// FetchUserSubscriptions returns the subscriptions of the user, the oldest first
+	FetchUserSubscriptions(userID string) (interface{}, error)
+	// FetchSKUSubscriptions returns the subscriptions waiting for the SKU to be restocked, the oldest first
+	FetchSKUSubscriptions(sku string) (interface{}, error)
+	// Save adds the subscription, or replaces the one of the user to the same SKU
+	Save(subscription interface{}) error
+	Remove(userID string, sku string) error
+}
@@ -2,4 +2,5 @@ package repository
 
 type UserRepository interface {
 	FindByUserID(string) (interface{}, error)
+	Create(interface{}) error
 }
@@ -0,0 +1,6 @@
+package repository
+
+type WarehouseRepository interface {
+	FindByWarehouseID(string) (interface{}, error)
+	FetchAll() (interface{}, error)
+}
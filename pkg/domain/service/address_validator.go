@@ -0,0 +1,11 @@
+package service
+
+import (
+	vo "github.com/yauritux/cartsvc/pkg/domain/valueobject"
+)
+
+// AddressValidator normalizes a buyer address against a reference dataset
+// and rejects the one with an inconsistent combination of fields
+type AddressValidator interface {
+	Validate(addr vo.BuyerAddress) (vo.BuyerAddress, error)
+}
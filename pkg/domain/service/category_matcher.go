@@ -0,0 +1,7 @@
+package service
+
+// CategoryMatcher tells whether a product matches a category, i.e. it is assigned to the
+// category itself or to any of its descendants
+type CategoryMatcher interface {
+	ProductInCategory(productID string, categoryID string) (bool, error)
+}
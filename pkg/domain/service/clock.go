@@ -0,0 +1,15 @@
+package service
+
+import "time"
+
+// Clock tells the current time, it lets the time based rules (e.g. cart expiry) be tested without sleeping
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock is the Clock telling the wall clock time
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
@@ -0,0 +1,6 @@
+package service
+
+// EventPublisher notifies the interested parties of what happened within the use cases
+type EventPublisher interface {
+	Publish(event interface{}) error
+}
@@ -0,0 +1,9 @@
+package service
+
+// LoyaltyProgram trades the loyalty points of a user for a discount on the cart being checked out.
+// RedeemPoints returns the amount the points are worth, which may not exceed upTo, and RestorePoints
+// gives the points redeemed for the cart back when its checkout falls through.
+type LoyaltyProgram interface {
+	RedeemPoints(userID string, cartID string, points int, upTo float64) (float64, error)
+	RestorePoints(userID string, cartID string) error
+}
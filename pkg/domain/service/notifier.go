@@ -0,0 +1,10 @@
+package service
+
+import (
+	vo "github.com/yauritux/cartsvc/pkg/domain/valueobject"
+)
+
+// Notifier delivers a notification to its recipient through the notification's channel
+type Notifier interface {
+	Send(n vo.Notification) error
+}
@@ -0,0 +1,7 @@
+package service
+
+// PasswordHasher hashes the user's password so that the plain one never gets stored
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	Compare(hash string, password string) error
+}
@@ -0,0 +1,19 @@
+package valueobject
+
+import (
+	. "github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+)
+
+// BundleComponent is a product (or one of its variants) along with the quantity
+// being consumed by a single unit of the bundle
+type BundleComponent struct {
+	ProdID   string
+	ProdName string
+	SKU      string
+	Qty      int
+}
+
+type BundlePricing struct {
+	Mode  BundlePricingMode
+	Value float64
+}
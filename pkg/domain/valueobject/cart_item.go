@@ -1,9 +1,23 @@
 package valueobject
 
+import (
+	"time"
+
+	"github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+)
+
 type CartItem struct {
 	ProdID   string
 	ProdName string
-	Qty      int
-	Price    float64
-	Disc     float64
+	// SKU identifies the purchasable unit, it equals to the ProdID for a product without variants
+	SKU     string
+	Options map[string]string
+	Qty     int
+	Price   float64
+	Disc    float64
+	// Components are the products consumed by a single unit of a bundle line
+	Components []*BundleComponent
+	// Fulfillment flags a line waiting for the stock, AvailableAt being when the stock is expected
+	Fulfillment enum.Fulfillment
+	AvailableAt *time.Time
 }
@@ -0,0 +1,13 @@
+package valueobject
+
+import (
+	. "github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+)
+
+// Notification is a rendered message ready to be delivered, Subject is left empty for an SMS
+type Notification struct {
+	Channel   NotificationChannel
+	Recipient string
+	Subject   string
+	Body      string
+}
@@ -0,0 +1,17 @@
+package valueobject
+
+import "time"
+
+// PriceSchedule overrides the regular price of a product, or of one of its variants when SKU is set,
+// from StartsAt until EndsAt. A zero Price keeps the regular price and only applies the discount.
+// A flash sale caps the units sold at the scheduled price to Quantity, zero means no cap.
+type PriceSchedule struct {
+	ID       string
+	SKU      string
+	Price    float64
+	Disc     float64
+	StartsAt time.Time
+	EndsAt   time.Time
+	Quantity int
+	Sold     int
+}
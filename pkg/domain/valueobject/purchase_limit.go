@@ -0,0 +1,11 @@
+package valueobject
+
+import "time"
+
+// PurchaseLimit caps the units of a product a customer can buy, a zero value means no limit.
+// MaxPerCustomer counts the units checked out within the last Period, or ever when Period is zero.
+type PurchaseLimit struct {
+	MaxPerOrder    int
+	MaxPerCustomer int
+	Period         time.Duration
+}
@@ -0,0 +1,16 @@
+package valueobject
+
+import (
+	"time"
+
+	"github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+)
+
+// Shipment is the part of an order sent from a single warehouse. The units waiting for the stock are
+// shipped apart, such a shipment has no warehouse yet but its Fulfillment and the expected AvailableAt.
+type Shipment struct {
+	WarehouseID string
+	Items       []*BundleComponent
+	Fulfillment enum.Fulfillment
+	AvailableAt *time.Time
+}
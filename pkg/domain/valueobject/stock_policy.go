@@ -0,0 +1,17 @@
+package valueobject
+
+import (
+	"time"
+
+	"github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+)
+
+// StockPolicy lets a product be ordered beyond its stock. A backorder ships the units the stock cannot
+// cover once it is replenished, a pre-order ships all of them upon the release at AvailableAt.
+// Limit caps the units waiting for the stock, zero means no cap, Committed counts the ones checked out so far.
+type StockPolicy struct {
+	Mode        enum.Fulfillment
+	AvailableAt *time.Time
+	Limit       int
+	Committed   int
+}
@@ -0,0 +1,15 @@
+package enum
+
+// CartChangeKind tells how a cart line differs from the current catalog
+type CartChangeKind string
+
+const (
+	PriceIncreased CartChangeKind = "price_increased"
+	PriceDecreased CartChangeKind = "price_decreased"
+	// StockShort means the stock left cannot cover the quantity of the line anymore
+	StockShort CartChangeKind = "stock_short"
+	// Deferred means the stock on hand no longer covers the line, the units left are backordered or pre-ordered
+	Deferred CartChangeKind = "deferred"
+	// Unavailable means the product (or its variant) has been removed from the catalog
+	Unavailable CartChangeKind = "unavailable"
+)
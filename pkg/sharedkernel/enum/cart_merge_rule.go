@@ -0,0 +1,13 @@
+package enum
+
+// CartMergeRule settles the quantity of a line found in both carts being merged
+type CartMergeRule string
+
+const (
+	// SumQuantities adds the quantities of both lines
+	SumQuantities CartMergeRule = "sum"
+	// KeepMaxQuantity keeps the greatest quantity of both lines
+	KeepMaxQuantity CartMergeRule = "max"
+	// KeepLatestQuantity keeps the quantity of the cart with the latest activity
+	KeepLatestQuantity CartMergeRule = "latest"
+)
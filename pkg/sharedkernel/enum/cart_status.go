@@ -7,4 +7,6 @@ const (
 	PaymentProcessing CartStatus = "payment_processing"
 	Canceled          CartStatus = "canceled"
 	Closed            CartStatus = "closed"
+	// Refunded is a closed cart whose payment has been given back
+	Refunded CartStatus = "refunded"
 )
@@ -0,0 +1,13 @@
+package enum
+
+// Fulfillment tells how the units of a cart line get shipped
+type Fulfillment string
+
+const (
+	// InStock units ship out of the stock on hand, a line without any fulfillment is in stock too
+	InStock Fulfillment = "in_stock"
+	// Backorder units are shipped once the stock is replenished
+	Backorder Fulfillment = "backorder"
+	// PreOrder units are shipped upon the release of a product not in stock yet
+	PreOrder Fulfillment = "preorder"
+)
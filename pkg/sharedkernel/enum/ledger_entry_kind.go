@@ -0,0 +1,15 @@
+package enum
+
+// LedgerEntryKind tells how an entry of the loyalty ledger moves the points of a user
+type LedgerEntryKind string
+
+const (
+	// Earned points are credited for a closed cart
+	Earned LedgerEntryKind = "earned"
+	// Redeemed points are debited for the discount they gave upon checkout
+	Redeemed LedgerEntryKind = "redeemed"
+	// Expired points are debited once their validity is over
+	Expired LedgerEntryKind = "expired"
+	// Reversed undoes another entry, e.g. when the cart is refunded
+	Reversed LedgerEntryKind = "reversed"
+)
@@ -0,0 +1,8 @@
+package enum
+
+type NotificationChannel string
+
+const (
+	EmailChannel NotificationChannel = "email"
+	SMSChannel   NotificationChannel = "sms"
+)
@@ -0,0 +1,17 @@
+package enum
+
+type ProductType string
+
+const (
+	SimpleProduct ProductType = "simple"
+	BundleProduct ProductType = "bundle"
+)
+
+type BundlePricingMode string
+
+const (
+	// FixedBundlePrice sells the bundle at a fixed price regardless of its components prices
+	FixedBundlePrice BundlePricingMode = "fixed_price"
+	// PercentOffBundle sells the bundle at a percentage off the sum of its components prices
+	PercentOffBundle BundlePricingMode = "percent_off"
+)
@@ -0,0 +1,9 @@
+package enum
+
+// SavedListName tells apart the lists a user parks the items into
+type SavedListName string
+
+const (
+	Wishlist      SavedListName = "wishlist"
+	SavedForLater SavedListName = "saved_for_later"
+)
@@ -0,0 +1,10 @@
+package enum
+
+type UserRole string
+
+const (
+	Customer UserRole = "customer"
+	Admin    UserRole = "admin"
+	// Guest is the anonymous shopper, known by its session only
+	Guest UserRole = "guest"
+)
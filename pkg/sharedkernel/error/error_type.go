@@ -35,3 +35,90 @@ func NewErrDuplicateData(msg string) *ErrDuplicateData {
 func (e *ErrDuplicateData) Error() string {
 	return e.message
 }
+
+type ErrInvalidData struct {
+	message string
+}
+
+func NewErrInvalidData(msg string) *ErrInvalidData {
+	return &ErrInvalidData{msg}
+}
+
+func (e *ErrInvalidData) Error() string {
+	return e.message
+}
+
+type ErrUnauthorized struct {
+	message string
+}
+
+func NewErrUnauthorized(msg string) *ErrUnauthorized {
+	return &ErrUnauthorized{msg}
+}
+
+func (e *ErrUnauthorized) Error() string {
+	return e.message
+}
+
+type ErrForbidden struct {
+	message string
+}
+
+func NewErrForbidden(msg string) *ErrForbidden {
+	return &ErrForbidden{msg}
+}
+
+func (e *ErrForbidden) Error() string {
+	return e.message
+}
+
+type ErrConflict struct {
+	message string
+}
+
+func NewErrConflict(msg string) *ErrConflict {
+	return &ErrConflict{msg}
+}
+
+func (e *ErrConflict) Error() string {
+	return e.message
+}
+
+// ErrOutOfStock is raised when the stock left (or the units allowed beyond it) cannot cover the units requested
+type ErrOutOfStock struct {
+	message string
+}
+
+func NewErrOutOfStock(msg string) *ErrOutOfStock {
+	return &ErrOutOfStock{msg}
+}
+
+func (e *ErrOutOfStock) Error() string {
+	return e.message
+}
+
+// ErrOrderLimitExceeded is raised when a single order holds more units of a product than allowed
+type ErrOrderLimitExceeded struct {
+	message string
+}
+
+func NewErrOrderLimitExceeded(msg string) *ErrOrderLimitExceeded {
+	return &ErrOrderLimitExceeded{msg}
+}
+
+func (e *ErrOrderLimitExceeded) Error() string {
+	return e.message
+}
+
+// ErrCustomerLimitExceeded is raised when a customer buys more units of a product than allowed over time
+type ErrCustomerLimitExceeded struct {
+	message string
+}
+
+func NewErrCustomerLimitExceeded(msg string) *ErrCustomerLimitExceeded {
+	return &ErrCustomerLimitExceeded{msg}
+}
+
+func (e *ErrCustomerLimitExceeded) Error() string {
+	return e.message
+}
@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"time"
+
 	"github.com/stretchr/testify/mock"
 )
 
@@ -8,6 +10,11 @@ type MockCartRepository struct {
 	mock.Mock
 }
 
+func (m *MockCartRepository) Open(userID string, openedAt time.Time) error {
+	call := m.Called(userID, openedAt)
+	return call.Error(0)
+}
+
 func (m *MockCartRepository) FetchUserCart(userID string) (interface{}, error) {
 	call := m.Called(userID)
 	res := call.Get(0)
@@ -17,32 +24,79 @@ func (m *MockCartRepository) FetchUserCart(userID string) (interface{}, error) {
 	return res, nil
 }
 
-func (m *MockCartRepository) AddToCart(cartID string, item interface{}) error {
-	call := m.Called(cartID, item)
+func (m *MockCartRepository) FetchLatestCart(userID string) (interface{}, error) {
+	call := m.Called(userID)
+	res := call.Get(0)
+	if res == nil {
+		return nil, call.Error(1)
+	}
+	return res, nil
+}
+
+func (m *MockCartRepository) AddToCart(cartID string, item interface{}, at time.Time) error {
+	call := m.Called(cartID, item, at)
 	return call.Error(0)
 }
 
-func (m *MockCartRepository) RemoveItem(cartID string, itemID string) error {
-	call := m.Called(cartID, itemID)
+func (m *MockCartRepository) RemoveItem(cartID string, itemID string, at time.Time) error {
+	call := m.Called(cartID, itemID, at)
 	return call.Error(0)
 }
 
-func (m *MockCartRepository) UpdateItem(cartID string, item interface{}) error {
-	call := m.Called(cartID, item)
+func (m *MockCartRepository) UpdateItem(cartID string, item interface{}, at time.Time) error {
+	call := m.Called(cartID, item, at)
 	return call.Error(0)
 }
 
-func (m *MockCartRepository) Checkout(cartID string) interface{} {
-	call := m.Called(cartID)
+func (m *MockCartRepository) Checkout(cartID string, checkedOutAt time.Time) interface{} {
+	call := m.Called(cartID, checkedOutAt)
 	return call.Get(0)
 }
 
-func (m *MockCartRepository) Canceled(cartID string) error {
-	call := m.Called(cartID)
+func (m *MockCartRepository) Canceled(cartID string, canceledAt time.Time) error {
+	call := m.Called(cartID, canceledAt)
+	return call.Error(0)
+}
+
+func (m *MockCartRepository) Close(cartID string, closedAt time.Time) error {
+	call := m.Called(cartID, closedAt)
 	return call.Error(0)
 }
 
-func (m *MockCartRepository) Close(cartID string) error {
+func (m *MockCartRepository) Refunded(cartID string) error {
 	call := m.Called(cartID)
 	return call.Error(0)
 }
+
+func (m *MockCartRepository) FetchIdleCarts(idleSince time.Time) (interface{}, error) {
+	call := m.Called(idleSince)
+	res := call.Get(0)
+	if res == nil {
+		return nil, call.Error(1)
+	}
+	return res, nil
+}
+
+func (m *MockCartRepository) FetchOrders(userID string, since time.Time) (interface{}, error) {
+	call := m.Called(userID, since)
+	res := call.Get(0)
+	if res == nil {
+		return nil, call.Error(1)
+	}
+	return res, nil
+}
+
+func (m *MockCartRepository) RecordReminder(cartID string, sentAt time.Time) error {
+	call := m.Called(cartID, sentAt)
+	return call.Error(0)
+}
+
+func (m *MockCartRepository) RecordShipments(cartID string, shipments interface{}) error {
+	call := m.Called(cartID, shipments)
+	return call.Error(0)
+}
+
+func (m *MockCartRepository) RecordRedemption(cartID string, redemption interface{}) error {
+	call := m.Called(cartID, redemption)
+	return call.Error(0)
+}
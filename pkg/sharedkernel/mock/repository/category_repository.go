@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"github.com/stretchr/testify/mock"
+)
+
+type MockCategoryRepository struct {
+	mock.Mock
+}
+
+func (m *MockCategoryRepository) FindByCategoryID(id string) (interface{}, error) {
+	call := m.Called(id)
+	res := call.Get(0)
+	if res == nil {
+		return nil, call.Error(1)
+	}
+	return res, nil
+}
+
+func (m *MockCategoryRepository) FetchAll() (interface{}, error) {
+	call := m.Called()
+	res := call.Get(0)
+	if res == nil {
+		return nil, call.Error(1)
+	}
+	return res, nil
+}
+
+func (m *MockCategoryRepository) Create(category interface{}) error {
+	call := m.Called(category)
+	return call.Error(0)
+}
@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"github.com/stretchr/testify/mock"
+)
+
+type MockLoyaltyRepository struct {
+	mock.Mock
+}
+
+func (m *MockLoyaltyRepository) FetchLedger(userID string) (interface{}, error) {
+	call := m.Called(userID)
+	res := call.Get(0)
+	if res == nil {
+		return nil, call.Error(1)
+	}
+	return res, nil
+}
+
+func (m *MockLoyaltyRepository) SaveLedger(ledger interface{}) error {
+	call := m.Called(ledger)
+	return call.Error(0)
+}
@@ -16,3 +16,36 @@ func (m *MockProductRepository) FindByProductID(id string) (interface{}, error)
 	}
 	return res, nil
 }
+
+func (m *MockProductRepository) FindAnyByProductID(id string) (interface{}, error) {
+	call := m.Called(id)
+	res := call.Get(0)
+	if res == nil {
+		return nil, call.Error(1)
+	}
+	return res, nil
+}
+
+func (m *MockProductRepository) Create(product interface{}) error {
+	call := m.Called(product)
+	return call.Error(0)
+}
+
+func (m *MockProductRepository) Update(product interface{}) error {
+	call := m.Called(product)
+	return call.Error(0)
+}
+
+func (m *MockProductRepository) Delete(id string) error {
+	call := m.Called(id)
+	return call.Error(0)
+}
+
+func (m *MockProductRepository) Search(query interface{}) (interface{}, error) {
+	call := m.Called(query)
+	res := call.Get(0)
+	if res == nil {
+		return nil, call.Error(1)
+	}
+	return res, nil
+}
@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"github.com/stretchr/testify/mock"
+)
+
+type MockSavedListRepository struct {
+	mock.Mock
+}
+
+func (m *MockSavedListRepository) FetchSavedList(userID string, name string) (interface{}, error) {
+	call := m.Called(userID, name)
+	res := call.Get(0)
+	if res == nil {
+		return nil, call.Error(1)
+	}
+	return res, nil
+}
+
+func (m *MockSavedListRepository) Save(list interface{}) error {
+	call := m.Called(list)
+	return call.Error(0)
+}
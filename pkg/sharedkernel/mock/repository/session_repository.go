@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"github.com/stretchr/testify/mock"
+)
+
+type MockSessionRepository struct {
+	mock.Mock
+}
+
+func (m *MockSessionRepository) Save(session interface{}) error {
+	call := m.Called(session)
+	return call.Error(0)
+}
+
+func (m *MockSessionRepository) FindByToken(token string) (interface{}, error) {
+	call := m.Called(token)
+	res := call.Get(0)
+	if res == nil {
+		return nil, call.Error(1)
+	}
+	return res, nil
+}
+
+func (m *MockSessionRepository) Delete(token string) error {
+	call := m.Called(token)
+	return call.Error(0)
+}
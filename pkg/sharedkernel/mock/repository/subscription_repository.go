@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"github.com/stretchr/testify/mock"
+)
+
+type MockSubscriptionRepository struct {
+	mock.Mock
+}
+
+func (m *MockSubscriptionRepository) FetchUserSubscriptions(userID string) (interface{}, error) {
+	call := m.Called(userID)
+	res := call.Get(0)
+	if res == nil {
+		return nil, call.Error(1)
+	}
+	return res, nil
+}
+
+func (m *MockSubscriptionRepository) FetchSKUSubscriptions(sku string) (interface{}, error) {
+	call := m.Called(sku)
+	res := call.Get(0)
+	if res == nil {
+		return nil, call.Error(1)
+	}
+	return res, nil
+}
+
+func (m *MockSubscriptionRepository) Save(subscription interface{}) error {
+	call := m.Called(subscription)
+	return call.Error(0)
+}
+
+func (m *MockSubscriptionRepository) Remove(userID string, sku string) error {
+	call := m.Called(userID, sku)
+	return call.Error(0)
+}
@@ -16,3 +16,8 @@ func (m *MockUserRepository) FindByUserID(uid string) (interface{}, error) {
 	}
 	return res, nil
 }
+
+func (m *MockUserRepository) Create(user interface{}) error {
+	call := m.Called(user)
+	return call.Error(0)
+}
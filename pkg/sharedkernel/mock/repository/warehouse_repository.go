@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"github.com/stretchr/testify/mock"
+)
+
+type MockWarehouseRepository struct {
+	mock.Mock
+}
+
+func (m *MockWarehouseRepository) FindByWarehouseID(id string) (interface{}, error) {
+	call := m.Called(id)
+	res := call.Get(0)
+	if res == nil {
+		return nil, call.Error(1)
+	}
+	return res, nil
+}
+
+func (m *MockWarehouseRepository) FetchAll() (interface{}, error) {
+	call := m.Called()
+	res := call.Get(0)
+	if res == nil {
+		return nil, call.Error(1)
+	}
+	return res, nil
+}
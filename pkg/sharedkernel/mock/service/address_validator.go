@@ -0,0 +1,15 @@
+package service
+
+import (
+	"github.com/stretchr/testify/mock"
+	vo "github.com/yauritux/cartsvc/pkg/domain/valueobject"
+)
+
+type MockAddressValidator struct {
+	mock.Mock
+}
+
+func (m *MockAddressValidator) Validate(addr vo.BuyerAddress) (vo.BuyerAddress, error) {
+	call := m.Called(addr)
+	return call.Get(0).(vo.BuyerAddress), call.Error(1)
+}
@@ -0,0 +1,14 @@
+package service
+
+import (
+	"github.com/stretchr/testify/mock"
+)
+
+type MockCategoryMatcher struct {
+	mock.Mock
+}
+
+func (m *MockCategoryMatcher) ProductInCategory(productID string, categoryID string) (bool, error) {
+	call := m.Called(productID, categoryID)
+	return call.Bool(0), call.Error(1)
+}
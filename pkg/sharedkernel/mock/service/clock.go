@@ -0,0 +1,16 @@
+package service
+
+import (
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockClock struct {
+	mock.Mock
+}
+
+func (m *MockClock) Now() time.Time {
+	call := m.Called()
+	return call.Get(0).(time.Time)
+}
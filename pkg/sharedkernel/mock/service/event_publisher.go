@@ -0,0 +1,14 @@
+package service
+
+import (
+	"github.com/stretchr/testify/mock"
+)
+
+type MockEventPublisher struct {
+	mock.Mock
+}
+
+func (m *MockEventPublisher) Publish(event interface{}) error {
+	call := m.Called(event)
+	return call.Error(0)
+}
@@ -0,0 +1,19 @@
+package service
+
+import (
+	"github.com/stretchr/testify/mock"
+)
+
+type MockLoyaltyProgram struct {
+	mock.Mock
+}
+
+func (m *MockLoyaltyProgram) RedeemPoints(userID string, cartID string, points int, upTo float64) (float64, error) {
+	call := m.Called(userID, cartID, points, upTo)
+	return call.Get(0).(float64), call.Error(1)
+}
+
+func (m *MockLoyaltyProgram) RestorePoints(userID string, cartID string) error {
+	call := m.Called(userID, cartID)
+	return call.Error(0)
+}
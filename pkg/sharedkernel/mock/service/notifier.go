@@ -0,0 +1,15 @@
+package service
+
+import (
+	"github.com/stretchr/testify/mock"
+	vo "github.com/yauritux/cartsvc/pkg/domain/valueobject"
+)
+
+type MockNotifier struct {
+	mock.Mock
+}
+
+func (m *MockNotifier) Send(n vo.Notification) error {
+	call := m.Called(n)
+	return call.Error(0)
+}
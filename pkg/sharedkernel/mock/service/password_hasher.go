@@ -0,0 +1,19 @@
+package service
+
+import (
+	"github.com/stretchr/testify/mock"
+)
+
+type MockPasswordHasher struct {
+	mock.Mock
+}
+
+func (m *MockPasswordHasher) Hash(password string) (string, error) {
+	call := m.Called(password)
+	return call.String(0), call.Error(1)
+}
+
+func (m *MockPasswordHasher) Compare(hash string, password string) error {
+	call := m.Called(hash, password)
+	return call.Error(0)
+}
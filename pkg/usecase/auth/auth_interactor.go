@@ -0,0 +1,194 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/yauritux/cartsvc/pkg/domain/repository"
+	"github.com/yauritux/cartsvc/pkg/domain/service"
+	. "github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+	userUsecase "github.com/yauritux/cartsvc/pkg/usecase/users"
+)
+
+const defaultSessionTTL = 24 * time.Hour
+
+type principalKey struct{}
+
+type AuthUsecase struct {
+	userRepo    repository.UserRepository
+	sessionRepo repository.SessionRepository
+	hasher      service.PasswordHasher
+	sessionTTL  time.Duration
+	clock       service.Clock
+}
+
+type Session struct {
+	Token     string
+	UserID    string
+	Role      UserRole
+	ExpiresAt time.Time
+}
+
+// Principal is the authenticated caller of the use cases
+type Principal struct {
+	UserID string
+	Role   UserRole
+}
+
+func (p *Principal) IsAdmin() bool {
+	return p.Role == Admin
+}
+
+func (p *Principal) IsGuest() bool {
+	return p.Role == Guest
+}
+
+// CanAccess tells whether the principal may act upon resources owned by the given user
+func (p *Principal) CanAccess(userID string) bool {
+	return p.IsAdmin() || p.UserID == userID
+}
+
+// Option configures the optional settings of the AuthUsecase
+type Option func(*AuthUsecase)
+
+func WithSessionTTL(ttl time.Duration) Option {
+	return func(uc *AuthUsecase) {
+		if ttl > 0 {
+			uc.sessionTTL = ttl
+		}
+	}
+}
+
+// WithClock sets the clock the sessions expire by, the system clock by default
+func WithClock(c service.Clock) Option {
+	return func(uc *AuthUsecase) {
+		uc.clock = c
+	}
+}
+
+func NewAuthUsecase(r1 repository.UserRepository, r2 repository.SessionRepository, h service.PasswordHasher, opts ...Option) *AuthUsecase {
+	uc := &AuthUsecase{
+		userRepo:    r1,
+		sessionRepo: r2,
+		hasher:      h,
+		sessionTTL:  defaultSessionTTL,
+		clock:       service.SystemClock{},
+	}
+	for _, opt := range opts {
+		opt(uc)
+	}
+	return uc
+}
+
+func (a *AuthUsecase) Login(userID string, password string) (interface{}, error) {
+	if userID == "" || password == "" {
+		return nil, e.NewErrUnauthorized("invalid user id or password")
+	}
+
+	u, err := a.userRepo.FindByUserID(userID)
+	if err != nil {
+		if _, ok := err.(*e.ErrNoData); ok {
+			return nil, e.NewErrUnauthorized("invalid user id or password")
+		}
+		return nil, err
+	}
+	user, ok := u.(*userUsecase.User)
+	if !ok || user == nil {
+		return nil, e.NewErrUnauthorized("invalid user id or password")
+	}
+	if user.PasswordHash == "" || a.hasher.Compare(user.PasswordHash, password) != nil {
+		return nil, e.NewErrUnauthorized("invalid user id or password")
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	role := user.Role
+	if role == "" {
+		role = Customer
+	}
+	session := &Session{
+		Token:     token,
+		UserID:    user.ID,
+		Role:      role,
+		ExpiresAt: a.clock.Now().Add(a.sessionTTL),
+	}
+	if err := a.sessionRepo.Save(session); err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// StartGuestSession issues a session to an anonymous shopper, the session token is then the only
+// way to get back to the guest cart until the guest logs in
+func (a *AuthUsecase) StartGuestSession() (interface{}, error) {
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	id, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	session := &Session{
+		Token:     token,
+		UserID:    userUsecase.GuestIDPrefix + id[:16],
+		Role:      Guest,
+		ExpiresAt: a.clock.Now().Add(a.sessionTTL),
+	}
+	if err := a.sessionRepo.Save(session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func (a *AuthUsecase) Logout(token string) error {
+	return a.sessionRepo.Delete(token)
+}
+
+func (a *AuthUsecase) Authenticate(token string) (interface{}, error) {
+	if token == "" {
+		return nil, e.NewErrUnauthorized("missing session token")
+	}
+
+	s, err := a.sessionRepo.FindByToken(token)
+	if err != nil {
+		if _, ok := err.(*e.ErrNoData); ok {
+			return nil, e.NewErrUnauthorized("invalid session token")
+		}
+		return nil, err
+	}
+	session, ok := s.(*Session)
+	if !ok || session == nil {
+		return nil, e.NewErrUnauthorized("invalid session token")
+	}
+	if a.clock.Now().After(session.ExpiresAt) {
+		a.sessionRepo.Delete(token)
+		return nil, e.NewErrUnauthorized("session has expired, please login again")
+	}
+
+	return &Principal{UserID: session.UserID, Role: session.Role}, nil
+}
+
+// NewContext returns a copy of ctx carrying the authenticated principal
+func NewContext(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok && p != nil
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
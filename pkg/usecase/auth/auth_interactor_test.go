@@ -0,0 +1,141 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/stretchr/testify/mock"
+	"github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+	mockRepo "github.com/yauritux/cartsvc/pkg/sharedkernel/mock/repository"
+	mockService "github.com/yauritux/cartsvc/pkg/sharedkernel/mock/service"
+	userUsecase "github.com/yauritux/cartsvc/pkg/usecase/users"
+)
+
+func TestAuthUsecase(t *testing.T) {
+
+	Convey("1. Given a user login", t, func() {
+
+		userRepo := &mockRepo.MockUserRepository{}
+		sessionRepo := &mockRepo.MockSessionRepository{}
+		hasher := &mockService.MockPasswordHasher{}
+
+		Convey("-> Negative Scenarios", func() {
+			Convey("-> Should be unauthorized for an unknown user", func() {
+				userRepo.On("FindByUserID", "ninja").Return(nil, nil)
+				uc := NewAuthUsecase(userRepo, sessionRepo, hasher)
+				res, err := uc.Login("ninja", "secret")
+				So(res, ShouldBeNil)
+				So(err, ShouldHaveSameTypeAs, &e.ErrUnauthorized{})
+			})
+			Convey("-> Should be unauthorized for a wrong password", func() {
+				userRepo.On("FindByUserID", "yauritux").Return(&userUsecase.User{
+					ID: "yauritux", PasswordHash: "hashed",
+				}, nil)
+				hasher.On("Compare", "hashed", "wrong").Return(errors.New("mismatched"))
+				uc := NewAuthUsecase(userRepo, sessionRepo, hasher)
+				res, err := uc.Login("yauritux", "wrong")
+				So(res, ShouldBeNil)
+				So(err, ShouldHaveSameTypeAs, &e.ErrUnauthorized{})
+				So(err.Error(), ShouldEqual, "invalid user id or password")
+				sessionRepo.AssertNotCalled(t, "Save", mock.Anything)
+			})
+		})
+
+		Convey("-> Positive Scenarios", func() {
+			Convey("-> Should issue a session token for the user", func() {
+				userRepo.On("FindByUserID", "yauritux").Return(&userUsecase.User{
+					ID: "yauritux", PasswordHash: "hashed", Role: enum.Customer,
+				}, nil)
+				hasher.On("Compare", "hashed", "shinobi").Return(nil)
+				sessionRepo.On("Save", mock.Anything).Return(nil)
+				now := time.Date(2020, time.May, 1, 12, 0, 0, 0, time.UTC)
+				clock := &mockService.MockClock{}
+				clock.On("Now").Return(now)
+				uc := NewAuthUsecase(userRepo, sessionRepo, hasher, WithSessionTTL(time.Hour), WithClock(clock))
+				res, err := uc.Login("yauritux", "shinobi")
+				So(err, ShouldBeNil)
+				session := res.(*Session)
+				So(session.Token, ShouldHaveLength, 64)
+				So(session.UserID, ShouldEqual, "yauritux")
+				So(session.Role, ShouldEqual, enum.Customer)
+				So(session.ExpiresAt, ShouldEqual, now.Add(time.Hour))
+			})
+		})
+	})
+
+	Convey("2. Given a request carrying a session token", t, func() {
+
+		userRepo := &mockRepo.MockUserRepository{}
+		sessionRepo := &mockRepo.MockSessionRepository{}
+		hasher := &mockService.MockPasswordHasher{}
+
+		Convey("-> Negative Scenarios", func() {
+			Convey("-> Should be unauthorized for an unknown token", func() {
+				sessionRepo.On("FindByToken", "abc").Return(nil, e.NewErrNoData("no session found"))
+				uc := NewAuthUsecase(userRepo, sessionRepo, hasher)
+				res, err := uc.Authenticate("abc")
+				So(res, ShouldBeNil)
+				So(err, ShouldHaveSameTypeAs, &e.ErrUnauthorized{})
+			})
+			Convey("-> Should be unauthorized and drop the session once it has expired", func() {
+				now := time.Date(2020, time.May, 1, 12, 0, 0, 0, time.UTC)
+				clock := &mockService.MockClock{}
+				clock.On("Now").Return(now)
+				sessionRepo.On("FindByToken", "abc").Return(&Session{
+					Token: "abc", UserID: "yauritux", ExpiresAt: now.Add(-time.Minute),
+				}, nil)
+				sessionRepo.On("Delete", "abc").Return(nil)
+				uc := NewAuthUsecase(userRepo, sessionRepo, hasher, WithClock(clock))
+				res, err := uc.Authenticate("abc")
+				So(res, ShouldBeNil)
+				So(err.Error(), ShouldEqual, "session has expired, please login again")
+				sessionRepo.AssertCalled(t, "Delete", "abc")
+			})
+		})
+
+		Convey("-> Positive Scenarios", func() {
+			Convey("-> Should resolve the principal which can be carried by the context", func() {
+				sessionRepo.On("FindByToken", "abc").Return(&Session{
+					Token: "abc", UserID: "admin", Role: enum.Admin, ExpiresAt: time.Now().Add(time.Hour),
+				}, nil)
+				uc := NewAuthUsecase(userRepo, sessionRepo, hasher)
+				res, err := uc.Authenticate("abc")
+				So(err, ShouldBeNil)
+				ctx := NewContext(context.Background(), res.(*Principal))
+				p, ok := FromContext(ctx)
+				So(ok, ShouldBeTrue)
+				So(p.IsAdmin(), ShouldBeTrue)
+				So(p.CanAccess("yauritux"), ShouldBeTrue)
+			})
+		})
+	})
+
+	Convey("3. Given an anonymous shopper", t, func() {
+
+		userRepo := &mockRepo.MockUserRepository{}
+		sessionRepo := &mockRepo.MockSessionRepository{}
+		hasher := &mockService.MockPasswordHasher{}
+
+		Convey("-> Positive Scenarios", func() {
+			Convey("-> Should issue a guest session which can only access the guest's own cart", func() {
+				sessionRepo.On("Save", mock.Anything).Return(nil)
+				uc := NewAuthUsecase(userRepo, sessionRepo, hasher)
+				res, err := uc.StartGuestSession()
+				So(err, ShouldBeNil)
+				session := res.(*Session)
+				So(session.Token, ShouldHaveLength, 64)
+				So(userUsecase.IsGuestID(session.UserID), ShouldBeTrue)
+				So(session.Role, ShouldEqual, enum.Guest)
+
+				p := &Principal{UserID: session.UserID, Role: session.Role}
+				So(p.IsGuest(), ShouldBeTrue)
+				So(p.CanAccess(session.UserID), ShouldBeTrue)
+				So(p.CanAccess("yauritux"), ShouldBeFalse)
+			})
+		})
+	})
+}
@@ -0,0 +1,8 @@
+package auth
+
+type AuthInputPort interface {
+	Login(userID string, password string) (interface{}, error)
+	StartGuestSession() (interface{}, error)
+	Logout(token string) error
+	Authenticate(token string) (interface{}, error)
+}
@@ -0,0 +1,82 @@
+package carts
+
+import (
+	"github.com/yauritux/cartsvc/pkg/domain/aggregate"
+	"github.com/yauritux/cartsvc/pkg/domain/entity"
+	vo "github.com/yauritux/cartsvc/pkg/domain/valueobject"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+	prodUsecase "github.com/yauritux/cartsvc/pkg/usecase/products"
+)
+
+// allocateStock picks the warehouses shipping the demand, the nearest ones to the shipping address first
+func (this *CartUsecase) allocateStock(demand []*vo.BundleComponent, to *vo.BuyerAddress) (*aggregate.StockAllocation, error) {
+	levels := make(map[string]map[string]int)
+	for _, d := range demand {
+		p, err := this.prodRepo.FindByProductID(d.ProdID)
+		if err != nil {
+			return nil, err
+		}
+		product, ok := p.(*prodUsecase.Product)
+		if !ok {
+			return nil, e.NewErrConversion("cannot allocate stock, invalid type of product usecase model")
+		}
+		levels[d.SKU] = aggregate.StockLevels(prodUsecase.BuildProductEntity(product), d.SKU)
+	}
+
+	warehouses, err := this.fetchWarehouses()
+	if err != nil {
+		return nil, err
+	}
+	return aggregate.AllocateStock(demand, levels, aggregate.RankWarehouses(warehouses, to))
+}
+
+func (this *CartUsecase) fetchWarehouses() ([]*entity.Warehouse, error) {
+	warehouses := make([]*entity.Warehouse, 0)
+	if this.warehouseRepo == nil {
+		return warehouses, nil
+	}
+	res, err := this.warehouseRepo.FetchAll()
+	if err != nil {
+		return nil, err
+	}
+	ucWarehouses, ok := res.([]*prodUsecase.Warehouse)
+	if !ok {
+		return nil, e.NewErrConversion("cannot allocate stock, invalid type of warehouse usecase model")
+	}
+	for _, w := range ucWarehouses {
+		warehouses = append(warehouses, &entity.Warehouse{
+			ID:       w.ID,
+			Name:     w.Name,
+			City:     w.City,
+			Province: w.Province,
+			Region:   w.Region,
+			Postal:   w.Postal,
+			Country:  w.Country,
+		})
+	}
+	return warehouses, nil
+}
+
+func buildShipments(shipments []*vo.Shipment) []*Shipment {
+	ucShipments := make([]*Shipment, 0)
+	for _, s := range shipments {
+		shipment := &Shipment{WarehouseID: s.WarehouseID, Items: make([]*CartItemComponent, 0), Fulfillment: s.Fulfillment, AvailableAt: s.AvailableAt}
+		for _, d := range s.Items {
+			shipment.Items = append(shipment.Items, &CartItemComponent{ID: d.ProdID, Name: d.ProdName, SKU: d.SKU, Qty: d.Qty})
+		}
+		ucShipments = append(ucShipments, shipment)
+	}
+	return ucShipments
+}
+
+func buildStockShipments(shipments []*Shipment) []*vo.Shipment {
+	voShipments := make([]*vo.Shipment, 0)
+	for _, s := range shipments {
+		shipment := &vo.Shipment{WarehouseID: s.WarehouseID, Items: make([]*vo.BundleComponent, 0), Fulfillment: s.Fulfillment, AvailableAt: s.AvailableAt}
+		for _, c := range s.Items {
+			shipment.Items = append(shipment.Items, &vo.BundleComponent{ProdID: c.ID, ProdName: c.Name, SKU: c.SKU, Qty: c.Qty})
+		}
+		voShipments = append(voShipments, shipment)
+	}
+	return voShipments
+}
@@ -0,0 +1,157 @@
+package carts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yauritux/cartsvc/pkg/domain/entity"
+	vo "github.com/yauritux/cartsvc/pkg/domain/valueobject"
+	. "github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+)
+
+// CartExpired is published for every cart canceled by ExpireIdleCarts
+type CartExpired struct {
+	CartID         string
+	UserID         string
+	PreviousStatus CartStatus
+	LastActivityAt time.Time
+	ExpiredAt      time.Time
+	ReleasedStock  []*ReleasedItem
+	// RestoredPoints are the loyalty points redeemed upon checkout and given back to the owner
+	RestoredPoints int
+}
+
+// ReleasedItem is a product unit given back to the stock when a checked out cart expires,
+// the units waiting for the stock are only taken off their backorder or pre-order
+type ReleasedItem struct {
+	ProductID string
+	SKU       string
+	Qty       int
+}
+
+// ExpireIdleCarts cancels the carts left idle for longer than the cart TTL and returns them.
+// The stock reserved by a checked out cart is given back, and its units waiting for the stock are
+// no longer counted against their stock policy. A cart failing to expire does not
+// stop the other ones, the failures are reported altogether once every cart has been visited.
+func (this *CartUsecase) ExpireIdleCarts() (interface{}, error) {
+	expired := make([]*Cart, 0)
+	if this.cartTTL <= 0 {
+		return expired, nil
+	}
+
+	now := this.clock.Now()
+	res, err := this.cartRepo.FetchIdleCarts(now.Add(-this.cartTTL))
+	if err != nil {
+		return nil, err
+	}
+	idleCarts, ok := res.([]*Cart)
+	if !ok {
+		return nil, errors.New("conversion failed, invalid type of cart list usecase model")
+	}
+
+	failures := make([]string, 0)
+	for _, cart := range idleCarts {
+		event, err := this.expireCart(cart, now)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("cart %s: %v", cart.ID, err))
+			continue
+		}
+		expired = append(expired, cart)
+		if this.publisher == nil {
+			continue
+		}
+		if err := this.publisher.Publish(event); err != nil {
+			failures = append(failures, fmt.Sprintf("cart %s: cannot publish the expiry: %v", cart.ID, err))
+		}
+	}
+
+	return expired, batchError("expire", failures)
+}
+
+func (this *CartUsecase) expireCart(cart *Cart, now time.Time) (*CartExpired, error) {
+	if err := this.cartRepo.Canceled(cart.ID, now); err != nil {
+		return nil, err
+	}
+
+	event := &CartExpired{
+		CartID:         cart.ID,
+		UserID:         cart.UserID,
+		PreviousStatus: cart.Status,
+		LastActivityAt: cart.LastActivityAt,
+		ExpiredAt:      now,
+		ReleasedStock:  make([]*ReleasedItem, 0),
+	}
+	//only a checked out cart holds any reserved stock or redeemed points
+	if cart.Status == PaymentProcessing {
+		event.ReleasedStock, event.RestoredPoints = this.releaseCheckout(cart)
+	}
+
+	cart.Status = Canceled
+	cart.CanceledAt = &now
+	return event, nil
+}
+
+// releaseCheckout gives back what the checkout of the canceled cart took: the reserved stock, the units waiting
+// for the stock counted against their stock policy and the redeemed loyalty points. It is best effort, the cart
+// being canceled anyway, and returns the units given back to the stock along with the points restored.
+func (this *CartUsecase) releaseCheckout(cart *Cart) ([]*ReleasedItem, int) {
+	restoredPoints := 0
+	if cart.Redemption != nil && this.loyalty != nil {
+		if err := this.loyalty.RestorePoints(cart.UserID, cart.ID); err == nil {
+			restoredPoints = cart.Redemption.Points
+		}
+	}
+
+	released := make([]*ReleasedItem, 0)
+	shipments := buildStockShipments(cart.Shipments)
+	if len(shipments) == 0 {
+		//checked out before the stock was spread among the warehouses
+		shipments = []*vo.Shipment{{WarehouseID: entity.MainWarehouse, Items: buildUserCart(cart).ExpandStockDemand()}}
+	}
+	for _, s := range shipments {
+		if s.Fulfillment != "" {
+			this.releaseDeferredUnits(s.Items)
+			continue
+		}
+		this.releaseStock([]*vo.Shipment{s})
+		for _, d := range s.Items {
+			released = append(released, &ReleasedItem{ProductID: d.ProdID, SKU: d.SKU, Qty: d.Qty})
+		}
+	}
+	return released, restoredPoints
+}
+
+// RunExpirySweeper calls ExpireIdleCarts on every tick of the interval until ctx is done,
+// the errors are handed to onError when given
+func (this *CartUsecase) RunExpirySweeper(ctx context.Context, interval time.Duration, onError func(error)) {
+	runEvery(ctx, interval, func() error {
+		_, err := this.ExpireIdleCarts()
+		return err
+	}, onError)
+}
+
+func runEvery(ctx context.Context, interval time.Duration, fn func() error, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := fn(); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// batchError reports the carts which failed along a batch, nil when all of them went fine
+func batchError(action string, failures []string) error {
+	if len(failures) == 0 {
+		return nil
+	}
+	return fmt.Errorf("failed to %s %d cart(s): %s", action, len(failures), strings.Join(failures, "; "))
+}
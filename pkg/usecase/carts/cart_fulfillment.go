@@ -0,0 +1,125 @@
+package carts
+
+import (
+	"fmt"
+
+	"github.com/yauritux/cartsvc/pkg/domain/aggregate"
+	vo "github.com/yauritux/cartsvc/pkg/domain/valueobject"
+	. "github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+	prodUsecase "github.com/yauritux/cartsvc/pkg/usecase/products"
+)
+
+// stockSnapshot holds the stock on hand and the stock policy in effect of the SKUs consumed by a cart
+type stockSnapshot struct {
+	onHand   map[string]int
+	policies map[string]*vo.StockPolicy
+}
+
+func newStockSnapshot() *stockSnapshot {
+	return &stockSnapshot{onHand: make(map[string]int), policies: make(map[string]*vo.StockPolicy)}
+}
+
+// orderable returns the units of every SKU which can be ordered, the backordered and pre-ordered ones included
+func (s *stockSnapshot) orderable() map[string]int {
+	units := make(map[string]int)
+	for sku, stock := range s.onHand {
+		units[sku] = aggregate.OrderableUnits(s.policies[sku], stock)
+	}
+	return units
+}
+
+// snapshotStock collects the stock of the demand, a product gone since it was added has no stock left
+func (this *CartUsecase) snapshotStock(demand []*vo.BundleComponent) (*stockSnapshot, error) {
+	snapshot := newStockSnapshot()
+	for _, d := range demand {
+		if err := this.collectStock(snapshot, d.ProdID, d.SKU); err != nil {
+			if _, ok := err.(*e.ErrNoData); !ok {
+				return nil, err
+			}
+			snapshot.onHand[d.SKU] = 0
+		}
+	}
+	return snapshot, nil
+}
+
+// commitDeferredUnits counts the units waiting for the stock against the limit of their stock policy,
+// it's all or nothing like the stock reservation
+func (this *CartUsecase) commitDeferredUnits(shipments []*vo.Shipment) ([]*vo.BundleComponent, error) {
+	committed := make([]*vo.BundleComponent, 0)
+	for _, s := range shipments {
+		for _, d := range s.Items {
+			if err := this.countDeferredUnits(d, d.Qty); err != nil {
+				this.releaseDeferredUnits(committed)
+				return nil, err
+			}
+			committed = append(committed, d)
+		}
+	}
+	return committed, nil
+}
+
+func (this *CartUsecase) releaseDeferredUnits(committed []*vo.BundleComponent) {
+	for _, d := range committed {
+		//best effort, there's nothing left to roll back to if the release fails
+		_ = this.countDeferredUnits(d, -d.Qty)
+	}
+}
+
+func (this *CartUsecase) countDeferredUnits(d *vo.BundleComponent, delta int) error {
+	p, err := this.prodRepo.FindByProductID(d.ProdID)
+	if err != nil {
+		return err
+	}
+	product, ok := p.(*prodUsecase.Product)
+	if !ok {
+		return e.NewErrConversion("cannot count the units waiting for the stock, invalid type of product usecase model")
+	}
+	policy := product.StockPolicy
+	if policy == nil {
+		//the policy has been lifted in the meantime, there's nothing left to count
+		return nil
+	}
+	if left := policy.Limit - policy.Committed; delta > 0 && policy.Limit > 0 && delta > left {
+		if left < 0 {
+			left = 0
+		}
+		return e.NewErrOutOfStock(fmt.Sprintf("out of stock, only %d of %s left to %s", left, d.ProdName, fulfillmentLabel(policy.Mode)))
+	}
+	policy.Committed += delta
+	if policy.Committed < 0 {
+		policy.Committed = 0
+	}
+	return this.prodRepo.Update(product)
+}
+
+// fulfillmentNotice tells the buyer when the units of a line waiting for the stock are shipped
+func fulfillmentNotice(c *CartChange, name string) string {
+	expected := ""
+	if c.AvailableAt != nil {
+		expected = " on " + c.AvailableAt.Format("2006-01-02")
+	}
+	switch {
+	case c.Fulfillment == PreOrder:
+		return fmt.Sprintf("%s is a pre-order, it ships upon its release%s", name, expected)
+	case c.Available == 0:
+		return fmt.Sprintf("%s is out of stock, the %d units are backordered and expected%s", name, c.Qty, orLater(expected))
+	default:
+		return fmt.Sprintf("only %d of %s in stock, the other %d are backordered and expected%s", c.Available, name,
+			c.Qty-c.Available, orLater(expected))
+	}
+}
+
+func orLater(expected string) string {
+	if expected == "" {
+		return " once the stock is replenished"
+	}
+	return expected
+}
+
+func fulfillmentLabel(f Fulfillment) string {
+	if f == PreOrder {
+		return "pre-order"
+	}
+	return string(f)
+}
@@ -2,47 +2,248 @@ package carts
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/yauritux/cartsvc/pkg/domain/aggregate"
 	"github.com/yauritux/cartsvc/pkg/domain/entity"
 	"github.com/yauritux/cartsvc/pkg/domain/repository"
+	"github.com/yauritux/cartsvc/pkg/domain/service"
 	vo "github.com/yauritux/cartsvc/pkg/domain/valueobject"
 	. "github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
 	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+	authUsecase "github.com/yauritux/cartsvc/pkg/usecase/auth"
 	prodUsecase "github.com/yauritux/cartsvc/pkg/usecase/products"
+	userUsecase "github.com/yauritux/cartsvc/pkg/usecase/users"
 )
 
 type CartUsecase struct {
-	cartRepo repository.CartRepository
-	prodRepo repository.ProductRepository
+	cartRepo      repository.CartRepository
+	prodRepo      repository.ProductRepository
+	userRepo      repository.UserRepository
+	savedListRepo repository.SavedListRepository
+	warehouseRepo repository.WarehouseRepository
+	addrValidator service.AddressValidator
+	clock         service.Clock
+	publisher     service.EventPublisher
+	notifier      service.Notifier
+	loyalty       service.LoyaltyProgram
+	cartTTL       time.Duration
+	remindAfter   time.Duration
+	maxReminders  int
+	currency      string
+	mergeRule     CartMergeRule
+	principal     *authUsecase.Principal
 }
 
 type Cart struct {
-	ID         string
-	UserID     string
-	Status     CartStatus
-	Items      []*CartItem
-	CreatedAt  time.Time
-	CanceledAt *time.Time
+	ID             string
+	UserID         string
+	Status         CartStatus
+	Items          []*CartItem
+	CreatedAt      time.Time
+	LastActivityAt time.Time
+	CanceledAt     *time.Time
+	CheckedOutAt   *time.Time
+	ClosedAt       *time.Time
+	RemindersSent  int
+	LastRemindedAt *time.Time
+	Shipments      []*Shipment
+	// Redemption is the discount paid with loyalty points upon checkout
+	Redemption *PointsRedemption
 }
 
 type CartItem struct {
-	ID    string
-	Name  string
-	Qty   int
-	Price float64
-	Disc  float64
+	ID         string
+	Name       string
+	SKU        string
+	Options    map[string]string
+	Qty        int
+	Price      float64
+	Disc       float64
+	Components []*CartItemComponent
+	// Fulfillment flags a line waiting for the stock, which is expected at AvailableAt
+	Fulfillment Fulfillment
+	AvailableAt *time.Time
+}
+
+// PointsRedemption is the Amount taken off the cart in exchange for the loyalty Points of its owner
+type PointsRedemption struct {
+	Points int
+	Amount float64
+}
+
+// CartItemComponent is a product consumed by a single unit of a bundle cart item
+type CartItemComponent struct {
+	ID   string
+	Name string
+	SKU  string
+	Qty  int
+}
+
+// Shipment carries the units of the checked out cart shipped from a single warehouse, the units
+// waiting for the stock are shipped apart from any warehouse with their Fulfillment and AvailableAt
+type Shipment struct {
+	WarehouseID string
+	Items       []*CartItemComponent
+	Fulfillment Fulfillment
+	AvailableAt *time.Time
+}
+
+// CartTotals sums up the cart lines, Discount is the total of the per unit discounts. Due is what is left
+// to pay once the loyalty points Redeemed are taken off the Net.
+type CartTotals struct {
+	Units    int
+	Gross    float64
+	Discount float64
+	Net      float64
+	Redeemed float64
+	Due      float64
+}
+
+func (c *Cart) Totals() *CartTotals {
+	totals := &CartTotals{}
+	for _, v := range c.Items {
+		totals.Units += v.Qty
+		totals.Gross += v.Price * float64(v.Qty)
+		totals.Discount += v.Disc * float64(v.Qty)
+	}
+	totals.Net = totals.Gross - totals.Discount
+	if c.Redemption != nil {
+		totals.Redeemed = c.Redemption.Amount
+	}
+	totals.Due = totals.Net - totals.Redeemed
+	return totals
+}
+
+// Option configures the optional collaborators of the CartUsecase
+type Option func(*CartUsecase)
+
+// WithUserRepository lets the checkout verify the buyer's shipping address
+func WithUserRepository(r repository.UserRepository) Option {
+	return func(uc *CartUsecase) {
+		uc.userRepo = r
+	}
+}
+
+// WithSavedListRepository lets the users park the items into their wishlist and saved for later lists
+func WithSavedListRepository(r repository.SavedListRepository) Option {
+	return func(uc *CartUsecase) {
+		uc.savedListRepo = r
+	}
+}
+
+// WithWarehouseRepository lets the checkout ship the stock from the warehouses nearest to the buyer
+func WithWarehouseRepository(r repository.WarehouseRepository) Option {
+	return func(uc *CartUsecase) {
+		uc.warehouseRepo = r
+	}
+}
+
+func WithAddressValidator(v service.AddressValidator) Option {
+	return func(uc *CartUsecase) {
+		uc.addrValidator = v
+	}
+}
+
+// WithCartTTL sets how long a cart may stay idle before ExpireIdleCarts cancels it, 0 never expires
+func WithCartTTL(ttl time.Duration) Option {
+	return func(uc *CartUsecase) {
+		uc.cartTTL = ttl
+	}
+}
+
+func WithClock(c service.Clock) Option {
+	return func(uc *CartUsecase) {
+		uc.clock = c
+	}
+}
+
+func WithEventPublisher(p service.EventPublisher) Option {
+	return func(uc *CartUsecase) {
+		uc.publisher = p
+	}
 }
 
-func NewCartUsecase(r1 repository.CartRepository, r2 repository.ProductRepository) *CartUsecase {
-	return &CartUsecase{r1, r2}
+func WithNotifier(n service.Notifier) Option {
+	return func(uc *CartUsecase) {
+		uc.notifier = n
+	}
+}
+
+// WithLoyaltyProgram lets the buyers redeem their loyalty points as a discount upon checkout
+func WithLoyaltyProgram(l service.LoyaltyProgram) Option {
+	return func(uc *CartUsecase) {
+		uc.loyalty = l
+	}
+}
+
+// WithRecoveryPolicy reminds the owner of a cart left idle for remindAfter, up to maxReminders
+// times per cart, each reminder waiting remindAfter since the previous one
+func WithRecoveryPolicy(remindAfter time.Duration, maxReminders int) Option {
+	return func(uc *CartUsecase) {
+		uc.remindAfter = remindAfter
+		uc.maxReminders = maxReminders
+	}
+}
+
+// WithCurrency sets the currency the amounts are shown in to the buyers
+func WithCurrency(code string) Option {
+	return func(uc *CartUsecase) {
+		uc.currency = code
+	}
+}
+
+// WithMergeRule settles the quantity of a line found in both the guest and the user carts, SumQuantities by default
+func WithMergeRule(rule CartMergeRule) Option {
+	return func(uc *CartUsecase) {
+		uc.mergeRule = rule
+	}
+}
+
+func NewCartUsecase(r1 repository.CartRepository, r2 repository.ProductRepository, opts ...Option) *CartUsecase {
+	uc := &CartUsecase{cartRepo: r1, prodRepo: r2, clock: service.SystemClock{}, mergeRule: SumQuantities}
+	for _, opt := range opts {
+		opt(uc)
+	}
+	return uc
+}
+
+// ForPrincipal returns a copy of the use case bound to the authenticated caller,
+// every cart operation is then restricted to the caller's own cart unless the caller is an admin.
+// An unbound use case is meant for trusted callers only (e.g. the local CLI).
+func (this *CartUsecase) ForPrincipal(p *authUsecase.Principal) *CartUsecase {
+	bound := *this
+	bound.principal = p
+	return &bound
+}
+
+func (this *CartUsecase) authorize(userID string) error {
+	if this.principal == nil || this.principal.CanAccess(userID) {
+		return nil
+	}
+	return e.NewErrForbidden(fmt.Sprintf("user %s is not allowed to access the cart of user %s",
+		this.principal.UserID, userID))
+}
+
+// OpenCart makes sure the user owns an open cart, which is how a guest gets a cart
+func (this *CartUsecase) OpenCart(userID string) error {
+	if userID == "" {
+		return e.NewErrInvalidData("cannot open cart, 'user_id' is missing")
+	}
+	if err := this.authorize(userID); err != nil {
+		return err
+	}
+	return this.cartRepo.Open(userID, this.clock.Now())
 }
 
 func (this *CartUsecase) FetchUserCart(userID string) (interface{}, error) {
 	if userID == "" {
 		return nil, e.NewErrNoData("cannot fetch user cart, 'user_id' is missing")
 	}
+	if err := this.authorize(userID); err != nil {
+		return nil, err
+	}
 
 	cart, err := this.cartRepo.FetchUserCart(userID)
 	if err != nil {
@@ -53,18 +254,50 @@ func (this *CartUsecase) FetchUserCart(userID string) (interface{}, error) {
 	if !ok {
 		return nil, e.NewErrConversion("cannot fetch user cart, invalid type of cart usecase model")
 	}
+	if err := this.authorize(ucCart.UserID); err != nil {
+		return nil, err
+	}
+
+	return ucCart, nil
+}
+
+// FetchLatestCart returns the cart the user opened last whatever its status, e.g. the order just checked
+// out, only the open cart (see FetchUserCart) being up for changes
+func (this *CartUsecase) FetchLatestCart(userID string) (interface{}, error) {
+	if userID == "" {
+		return nil, e.NewErrNoData("cannot fetch user cart, 'user_id' is missing")
+	}
+	if err := this.authorize(userID); err != nil {
+		return nil, err
+	}
+
+	cart, err := this.cartRepo.FetchLatestCart(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	ucCart, ok := cart.(*Cart)
+	if !ok {
+		return nil, e.NewErrConversion("cannot fetch user cart, invalid type of cart usecase model")
+	}
+	if err := this.authorize(ucCart.UserID); err != nil {
+		return nil, err
+	}
 
 	return ucCart, nil
 }
 
 func (this *CartUsecase) AddToCart(userID string, item interface{}) error {
-	userCart, err := this.cartRepo.FetchUserCart(userID)
+	if err := this.authorize(userID); err != nil {
+		return err
+	}
+
+	currentCart, err := this.openUserCart(userID)
 	if err != nil {
 		return err
 	}
-	currentCart, ok := userCart.(*Cart)
-	if !ok {
-		return errors.New("conversion failed, invalid type of cart usecase model")
+	if err := this.authorize(currentCart.UserID); err != nil {
+		return err
 	}
 
 	prodItem, ok := item.(*CartItem)
@@ -72,44 +305,439 @@ func (this *CartUsecase) AddToCart(userID string, item interface{}) error {
 		return errors.New("conversion failed, invalid type of product item usecase model")
 	}
 
-	product, err := this.prodRepo.FindByProductID(prodItem.ID)
+	_, err = this.addItem(currentCart, prodItem)
+	return err
+}
+
+// openUserCart returns the user's open cart, a new one is opened when the user has got none,
+// e.g. once the previous one got checked out
+func (this *CartUsecase) openUserCart(userID string) (*Cart, error) {
+	userCart, err := this.cartRepo.FetchUserCart(userID)
+	if _, ok := err.(*e.ErrNoData); ok {
+		if err := this.OpenCart(userID); err != nil {
+			return nil, err
+		}
+		userCart, err = this.cartRepo.FetchUserCart(userID)
+	}
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	ucProduct, ok := product.(*prodUsecase.Product)
+	currentCart, ok := userCart.(*Cart)
 	if !ok {
-		return errors.New("conversion failed, invalid type of product usecase model")
+		return nil, errors.New("conversion failed, invalid type of cart usecase model")
 	}
+	return currentCart, nil
+}
 
-	cart := aggregate.NewUserCart(
-		&entity.User{
-			UserID: currentCart.UserID,
-		}, &entity.Cart{
-			ID:        currentCart.ID,
-			UserID:    currentCart.UserID,
-			Status:    currentCart.Status,
-			Items:     buildCartVOItems(currentCart.Items),
-			CreatedAt: currentCart.CreatedAt,
-		})
+// addItem adds the requested item into the cart at the current price of the product once the stock
+// is checked, it returns the cart line as stored
+func (this *CartUsecase) addItem(currentCart *Cart, prodItem *CartItem) (*vo.CartItem, error) {
+	ucProduct, productEntity, variant, err := this.resolveItem(prodItem)
+	if err != nil {
+		return nil, err
+	}
 
-	addedItem, err := cart.AddItemToCart(&entity.Product{
-		ID:    ucProduct.ID,
-		Name:  ucProduct.Name,
-		Stock: ucProduct.Stock,
-		Price: ucProduct.Price,
-		Disc:  ucProduct.Disc,
-	}, prodItem.Qty)
+	cart := buildUserCart(currentCart)
+
+	var addedItem *vo.CartItem
+	if ucProduct.IsBundle() {
+		bundle, bundleErr := this.buildProductBundle(ucProduct, prodItem.Qty)
+		if bundleErr != nil {
+			return nil, bundleErr
+		}
+		addedItem, err = cart.AddBundleToCart(bundle, prodItem.Qty)
+	} else {
+		addedItem, err = cart.AddVariantToCart(productEntity, variant, prodItem.Qty)
+	}
 	if err != nil {
 		switch err.(type) {
 		case *e.ErrDuplicateData:
-			return this.cartRepo.UpdateItem(cart.FetchCartInfo().ID, buildCartUsecaseItem(addedItem))
+			return addedItem, this.cartRepo.UpdateItem(cart.FetchCartInfo().ID, buildCartUsecaseItem(addedItem), this.clock.Now())
 		default:
+			return nil, err
+		}
+	}
+
+	return addedItem, this.cartRepo.AddToCart(cart.FetchCartInfo().ID, buildCartUsecaseItem(addedItem), this.clock.Now())
+}
+
+// resolveItem finds the product of the requested item along with the chosen variant, if any,
+// the product being priced for the requested quantity at the current time
+func (this *CartUsecase) resolveItem(prodItem *CartItem) (*prodUsecase.Product, *entity.Product, *entity.Variant, error) {
+	product, err := this.prodRepo.FindByProductID(prodItem.ID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	ucProduct, ok := product.(*prodUsecase.Product)
+	if !ok {
+		return nil, nil, nil, errors.New("conversion failed, invalid type of product usecase model")
+	}
+
+	now := this.clock.Now()
+	productEntity := aggregate.PriceProductAt(prodUsecase.BuildProductEntity(ucProduct), prodItem.Qty, now)
+	productEntity.StockPolicy = aggregate.StockPolicyAt(productEntity, now)
+	var variant *entity.Variant
+	if !ucProduct.IsBundle() && prodItem.SKU != "" && prodItem.SKU != ucProduct.ID {
+		if variant = findVariantEntity(productEntity, prodItem.SKU); variant == nil {
+			return nil, nil, nil, e.NewErrNoData(fmt.Sprintf("no variant %s found for product %s", prodItem.SKU, ucProduct.ID))
+		}
+	}
+	return ucProduct, productEntity, variant, nil
+}
+
+// RemoveFromCart removes the cart line identified by the SKU (the product ID for a product without variants)
+func (this *CartUsecase) RemoveFromCart(userID string, sku string) error {
+	userCart, err := this.FetchUserCart(userID)
+	if err != nil {
+		return err
+	}
+	currentCart := userCart.(*Cart)
+	if currentCart.Status != Open {
+		return fmt.Errorf("cannot remove item from a cart with status as %s", currentCart.Status)
+	}
+
+	if err := buildUserCart(currentCart).RemoveItemFromCart(sku); err != nil {
+		return err
+	}
+	return this.cartRepo.RemoveItem(currentCart.ID, sku, this.clock.Now())
+}
+
+// UpdateItemQty changes the quantity of the cart line identified by the SKU, the increase is checked
+// against the current stock of the product (or bundle components) and the line gets flagged anew
+// when some of its units wait for the stock
+func (this *CartUsecase) UpdateItemQty(userID string, sku string, qty int) error {
+	userCart, err := this.FetchUserCart(userID)
+	if err != nil {
+		return err
+	}
+	currentCart := userCart.(*Cart)
+
+	var line *CartItem
+	for _, v := range currentCart.Items {
+		if v.SKU == sku || (v.SKU == "" && v.ID == sku) {
+			line = v
+			break
+		}
+	}
+	if line == nil {
+		return e.NewErrNoData(fmt.Sprintf("cannot find cart item with ID %s", sku))
+	}
+
+	snapshot := newStockSnapshot()
+	if len(line.Components) == 0 {
+		if err := this.collectStock(snapshot, line.ID, sku); err != nil {
+			return err
+		}
+	}
+	for _, c := range line.Components {
+		if err := this.collectStock(snapshot, c.ID, c.SKU); err != nil {
+			return err
+		}
+	}
+
+	limits, err := this.purchaseLimits([]*CartItem{line})
+	if err != nil {
+		return err
+	}
+
+	cart := buildUserCart(currentCart)
+	updatedItem, err := cart.ChangeItemQty(sku, qty, snapshot.orderable(), limits)
+	if err != nil {
+		return err
+	}
+	now := this.clock.Now()
+	if err := this.cartRepo.UpdateItem(currentCart.ID, buildCartUsecaseItem(updatedItem), now); err != nil {
+		return err
+	}
+	flagged, _ := cart.FlagFulfillment(snapshot.onHand, snapshot.policies)
+	for _, v := range flagged {
+		if err := this.cartRepo.UpdateItem(currentCart.ID, buildCartUsecaseItem(v), now); err != nil {
 			return err
 		}
 	}
+	return nil
+}
+
+// CancelCart cancels the user's latest cart, be it open or checked out. An open cart holds nothing reserved yet,
+// a checked out one gives back its reserved stock, its units waiting for the stock and its redeemed points.
+func (this *CartUsecase) CancelCart(userID string) (interface{}, error) {
+	userCart, err := this.FetchLatestCart(userID)
+	if err != nil {
+		return nil, err
+	}
+	cart := userCart.(*Cart)
+	if cart.Status != Open && cart.Status != PaymentProcessing {
+		return nil, fmt.Errorf("cannot cancel the cart with status of %s", cart.Status)
+	}
+
+	canceledAt := this.clock.Now()
+	if err := this.cartRepo.Canceled(cart.ID, canceledAt); err != nil {
+		return nil, err
+	}
+	if cart.Status == PaymentProcessing {
+		this.releaseCheckout(cart)
+	}
+	cart.Status = Canceled
+	cart.CanceledAt = &canceledAt
+	return cart, nil
+}
+
+// collectStock adds the stock on hand of the SKU to the snapshot, along with the stock policy in effect
+func (this *CartUsecase) collectStock(snapshot *stockSnapshot, productID string, sku string) error {
+	p, err := this.prodRepo.FindByProductID(productID)
+	if err != nil {
+		return err
+	}
+	product, ok := p.(*prodUsecase.Product)
+	if !ok {
+		return errors.New("conversion failed, invalid type of product usecase model")
+	}
+	if sku == "" {
+		sku = product.ID
+	}
+	snapshot.onHand[sku] = product.Stock
+	if variant := product.FindVariant(sku); variant != nil {
+		snapshot.onHand[sku] = variant.Stock
+	}
+	if policy := aggregate.StockPolicyAt(prodUsecase.BuildProductEntity(product), this.clock.Now()); policy != nil {
+		snapshot.policies[sku] = policy
+	}
+	return nil
+}
+
+func (this *CartUsecase) Checkout(userID string) (interface{}, error) {
+	return this.CheckoutWithPoints(userID, 0)
+}
+
+// CheckoutWithPoints checks out the user's cart, the given loyalty points being redeemed as a discount
+// which cannot exceed the net total of the cart
+func (this *CartUsecase) CheckoutWithPoints(userID string, points int) (interface{}, error) {
+	if points < 0 {
+		return nil, e.NewErrInvalidData("cannot checkout, the points to redeem cannot be negative")
+	}
+	if points > 0 && this.loyalty == nil {
+		return nil, e.NewErrInvalidData("cannot checkout, there's no loyalty program to redeem the points with")
+	}
+	userCart, err := this.FetchUserCart(userID)
+	if err != nil {
+		return nil, err
+	}
+	cart := userCart.(*Cart)
+
+	if cart.Status != Open {
+		return nil, fmt.Errorf("failed to checkout cart with ID of %s, it is already in %s", cart.ID, cart.Status)
+	}
+	if userUsecase.IsGuestID(cart.UserID) {
+		return nil, e.NewErrForbidden("a guest cart cannot be checked out, please login first")
+	}
+	if len(cart.Items) == 0 {
+		return nil, e.NewErrNoData("cannot checkout an empty cart")
+	}
+
+	shippingAddr, err := this.verifyShippingAddress(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	//the buyer pays the current prices, the cart has to be reviewed first when they rose in the meantime
+	changes, err := this.refreshCart(cart)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkoutBlocker(changes); err != nil {
+		return nil, err
+	}
+	if err := this.checkPurchaseLimits(cart); err != nil {
+		return nil, err
+	}
+
+	//the units waiting for the stock are shipped apart, only the other ones are taken out of the warehouses
+	demand := buildUserCart(cart).ExpandStockDemand()
+	snapshot, err := this.snapshotStock(demand)
+	if err != nil {
+		return nil, err
+	}
+	stockDemand, deferred := aggregate.SplitDemand(demand, snapshot.onHand, snapshot.policies)
+	allocation, err := this.allocateStock(stockDemand, shippingAddr)
+	if err != nil {
+		return nil, err
+	}
+	reserved, err := this.reserveStock(allocation.Shipments)
+	if err != nil {
+		return nil, err
+	}
+	committed, err := this.commitDeferredUnits(deferred)
+	if err != nil {
+		this.releaseStock(reserved)
+		return nil, err
+	}
+	claims, err := this.claimSaleUnits(demand)
+	if err != nil {
+		this.releaseDeferredUnits(committed)
+		this.releaseStock(reserved)
+		return nil, err
+	}
+	var redemption *PointsRedemption
+	undo := func() {
+		if redemption != nil {
+			//best effort, the points are given back once for all
+			_ = this.loyalty.RestorePoints(cart.UserID, cart.ID)
+		}
+		this.releaseSaleUnits(claims)
+		this.releaseDeferredUnits(committed)
+		this.releaseStock(reserved)
+	}
+	if points > 0 {
+		amount, err := this.loyalty.RedeemPoints(cart.UserID, cart.ID, points, cart.Totals().Net)
+		if err != nil {
+			undo()
+			return nil, err
+		}
+		redemption = &PointsRedemption{Points: points, Amount: amount}
+	}
+
+	shipments := buildShipments(append(allocation.Shipments, deferred...))
+	if err := this.cartRepo.RecordShipments(cart.ID, shipments); err != nil {
+		undo()
+		return nil, err
+	}
+	if redemption != nil {
+		if err := this.cartRepo.RecordRedemption(cart.ID, redemption); err != nil {
+			_ = this.cartRepo.RecordShipments(cart.ID, nil)
+			undo()
+			return nil, err
+		}
+	}
+	if res := this.cartRepo.Checkout(cart.ID, this.clock.Now()); res != nil {
+		if err, ok := res.(error); ok {
+			//best effort, the cart stays open anyway
+			_ = this.cartRepo.RecordShipments(cart.ID, nil)
+			if redemption != nil {
+				_ = this.cartRepo.RecordRedemption(cart.ID, nil)
+			}
+			undo()
+			return nil, err
+		}
+	}
+	cart.Status = PaymentProcessing
+	cart.Shipments = shipments
+	cart.Redemption = redemption
+
+	return cart, nil
+}
+
+// reserveStock takes the shipped units out of the stock of their warehouses, it's all or nothing,
+// the units reserved so far are given back as soon as one of the products runs out of stock
+func (this *CartUsecase) reserveStock(shipments []*vo.Shipment) ([]*vo.Shipment, error) {
+	reserved := make([]*vo.Shipment, 0)
+	for _, s := range shipments {
+		shipment := &vo.Shipment{WarehouseID: s.WarehouseID, Items: make([]*vo.BundleComponent, 0)}
+		reserved = append(reserved, shipment)
+		for _, d := range s.Items {
+			if err := this.adjustStock(s.WarehouseID, d, -d.Qty); err != nil {
+				this.releaseStock(reserved)
+				return nil, err
+			}
+			shipment.Items = append(shipment.Items, d)
+		}
+	}
+	return reserved, nil
+}
+
+func (this *CartUsecase) releaseStock(reserved []*vo.Shipment) {
+	for _, s := range reserved {
+		for _, d := range s.Items {
+			//best effort, there's nothing left to roll back to if the release fails
+			_ = this.adjustStock(s.WarehouseID, d, d.Qty)
+		}
+	}
+}
+
+func (this *CartUsecase) adjustStock(warehouseID string, d *vo.BundleComponent, delta int) error {
+	p, err := this.prodRepo.FindByProductID(d.ProdID)
+	if err != nil {
+		return err
+	}
+	product, ok := p.(*prodUsecase.Product)
+	if !ok {
+		return e.NewErrConversion("cannot reserve stock, invalid type of product usecase model")
+	}
+
+	level := aggregate.StockLevels(prodUsecase.BuildProductEntity(product), d.SKU)[warehouseID]
+	if level+delta < 0 {
+		return e.NewErrOutOfStock(fmt.Sprintf("out of stock, only %d of %s left", level, d.ProdName))
+	}
+	if err := product.AdjustWarehouseStock(d.SKU, warehouseID, delta); err != nil {
+		return err
+	}
+
+	return this.prodRepo.Update(product)
+}
+
+// buildProductBundle prices the components of qty units of the bundle at the current time
+func (this *CartUsecase) buildProductBundle(p *prodUsecase.Product, qty int) (*aggregate.ProductBundle, error) {
+	now := this.clock.Now()
+	components := make([]*entity.Product, 0)
+	for _, c := range p.Components {
+		found, err := this.prodRepo.FindByProductID(c.ProductID)
+		if err != nil {
+			return nil, err
+		}
+		component, ok := found.(*prodUsecase.Product)
+		if !ok {
+			return nil, errors.New("conversion failed, invalid type of product usecase model")
+		}
+		components = append(components, aggregate.PriceProductAt(prodUsecase.BuildProductEntity(component), c.Qty*qty, now))
+	}
+	return aggregate.NewProductBundle(prodUsecase.BuildProductEntity(p), components)
+}
+
+// verifyShippingAddress returns the address the order is shipped to, nil when the buyers are unknown
+func (this *CartUsecase) verifyShippingAddress(userID string) (*vo.BuyerAddress, error) {
+	if this.userRepo == nil {
+		return nil, nil
+	}
+
+	user, err := this.userRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	buyer, ok := user.(*userUsecase.User)
+	if !ok || buyer == nil {
+		return nil, e.NewErrNoData("cannot checkout, no user found for id " + userID)
+	}
+	if buyer.ShippingAddr == nil {
+		return nil, e.NewErrInvalidData("cannot checkout, shipping address is missing")
+	}
 
-	return this.cartRepo.AddToCart(cart.FetchCartInfo().ID, buildCartUsecaseItem(addedItem))
+	addr := userUsecase.BuildBuyerAddress(buyer.ShippingAddr)
+	if this.addrValidator == nil {
+		return &addr, nil
+	}
+	if _, err := this.addrValidator.Validate(addr); err != nil {
+		return nil, e.NewErrInvalidData("cannot checkout, invalid shipping address: " + err.Error())
+	}
+	return &addr, nil
+}
+
+func buildUserCart(cart *Cart) *aggregate.UserCart {
+	return aggregate.NewUserCart(
+		&entity.User{
+			UserID: cart.UserID,
+		}, &entity.Cart{
+			ID:             cart.ID,
+			UserID:         cart.UserID,
+			Status:         cart.Status,
+			Items:          buildCartVOItems(cart.Items),
+			CreatedAt:      cart.CreatedAt,
+			LastActivityAt: cart.LastActivityAt,
+			CanceledAt:     cart.CanceledAt,
+			CheckedOutAt:   cart.CheckedOutAt,
+			ClosedAt:       cart.ClosedAt,
+			RemindersSent:  cart.RemindersSent,
+			LastRemindedAt: cart.LastRemindedAt,
+		})
 }
 
 func buildCartUsecaseItem(item interface{}) *CartItem {
@@ -118,11 +746,23 @@ func buildCartUsecaseItem(item interface{}) *CartItem {
 	case *vo.CartItem:
 		cartItem := item.(*vo.CartItem)
 		ucCartItem = &CartItem{
-			ID:    cartItem.ProdID,
-			Name:  cartItem.ProdName,
-			Qty:   cartItem.Qty,
-			Price: cartItem.Price,
-			Disc:  cartItem.Disc,
+			ID:          cartItem.ProdID,
+			Name:        cartItem.ProdName,
+			SKU:         cartItem.SKU,
+			Options:     cartItem.Options,
+			Qty:         cartItem.Qty,
+			Price:       cartItem.Price,
+			Disc:        cartItem.Disc,
+			Fulfillment: cartItem.Fulfillment,
+			AvailableAt: cartItem.AvailableAt,
+		}
+		for _, c := range cartItem.Components {
+			ucCartItem.Components = append(ucCartItem.Components, &CartItemComponent{
+				ID:   c.ProdID,
+				Name: c.ProdName,
+				SKU:  c.SKU,
+				Qty:  c.Qty,
+			})
 		}
 	}
 	return ucCartItem
@@ -134,14 +774,36 @@ func buildCartVOItems(items interface{}) []*vo.CartItem {
 	case []*CartItem:
 		cartItems := items.([]*CartItem)
 		for _, v := range cartItems {
-			voCartItems = append(voCartItems, &vo.CartItem{
-				ProdID:   v.ID,
-				ProdName: v.Name,
-				Qty:      v.Qty,
-				Price:    v.Price,
-				Disc:     v.Disc,
-			})
+			voCartItem := &vo.CartItem{
+				ProdID:      v.ID,
+				ProdName:    v.Name,
+				SKU:         v.SKU,
+				Options:     v.Options,
+				Qty:         v.Qty,
+				Price:       v.Price,
+				Disc:        v.Disc,
+				Fulfillment: v.Fulfillment,
+				AvailableAt: v.AvailableAt,
+			}
+			for _, c := range v.Components {
+				voCartItem.Components = append(voCartItem.Components, &vo.BundleComponent{
+					ProdID:   c.ID,
+					ProdName: c.Name,
+					SKU:      c.SKU,
+					Qty:      c.Qty,
+				})
+			}
+			voCartItems = append(voCartItems, voCartItem)
 		}
 	}
 	return voCartItems
 }
+
+func findVariantEntity(p *entity.Product, sku string) *entity.Variant {
+	for _, v := range p.Variants {
+		if v.SKU == sku {
+			return v
+		}
+	}
+	return nil
+}
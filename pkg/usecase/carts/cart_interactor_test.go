@@ -11,8 +11,12 @@ import (
 	"github.com/yauritux/cartsvc/pkg/domain/entity"
 	vo "github.com/yauritux/cartsvc/pkg/domain/valueobject"
 	"github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
 	mockRepo "github.com/yauritux/cartsvc/pkg/sharedkernel/mock/repository"
+	mockService "github.com/yauritux/cartsvc/pkg/sharedkernel/mock/service"
+	authUsecase "github.com/yauritux/cartsvc/pkg/usecase/auth"
 	prodUsecase "github.com/yauritux/cartsvc/pkg/usecase/products"
+	userUsecase "github.com/yauritux/cartsvc/pkg/usecase/users"
 )
 
 func TestCartUsecase(t *testing.T) {
@@ -115,6 +119,19 @@ func TestCartUsecase(t *testing.T) {
 				So(err, ShouldNotBeNil)
 				So(err.Error(), ShouldEqual, "conversion failed, invalid type of product usecase model")
 			})
+			Convey("-> Returns an error when the requested variant does not exist", func() {
+				cartRepo.On("FetchUserCart", "123").Return(&Cart{
+					ID: "123", UserID: "123", Status: enum.Open, CreatedAt: time.Now(),
+				}, nil)
+				prodRepo.On("FindByProductID", "003").Return(&prodUsecase.Product{
+					ID: "003", Name: "Ninja Gi", Price: 320,
+					Variants: []*prodUsecase.Variant{{SKU: "003-BLK-M", Stock: 10, Price: 320}},
+				}, nil)
+				uc := NewCartUsecase(cartRepo, prodRepo)
+				err := uc.AddToCart("123", &CartItem{ID: "003", SKU: "003-RED-XL", Qty: 1})
+				So(err, ShouldHaveSameTypeAs, &e.ErrNoData{})
+				So(err.Error(), ShouldEqual, "no variant 003-RED-XL found for product 003")
+			})
 		})
 
 		Convey("-> Positive Scenarios", func() {
@@ -125,11 +142,1283 @@ func TestCartUsecase(t *testing.T) {
 				prodRepo.On("FindByProductID", "001").Return(&prodUsecase.Product{
 					ID: "001", Name: "Shuriken", Price: 1250, Stock: 999, Disc: 0,
 				}, nil)
-				cartRepo.On("AddToCart", mock.Anything, mock.Anything).Return(nil)
+				cartRepo.On("AddToCart", mock.Anything, mock.Anything, mock.Anything).Return(nil)
 				uc := NewCartUsecase(cartRepo, prodRepo)
 				err := uc.AddToCart("123", &CartItem{ID: "001", Name: "Shuriken", Price: 1250, Qty: 1})
 				So(err, ShouldBeNil)
 			})
+			Convey("-> A bundle should be added as a single discounted line along with its components", func() {
+				cartRepo.On("FetchUserCart", "123").Return(&Cart{
+					ID: "123", UserID: "123", Status: enum.Open, CreatedAt: time.Now(),
+				}, nil)
+				prodRepo.On("FindByProductID", "004").Return(&prodUsecase.Product{
+					ID: "004", Name: "Ninja Training Set", Type: enum.BundleProduct,
+					Components: []*prodUsecase.BundleComponent{
+						{ProductID: "001", Qty: 2}, {ProductID: "002", Qty: 1},
+					},
+					BundlePricing: &prodUsecase.BundlePricing{Mode: enum.PercentOffBundle, Value: 10},
+				}, nil)
+				prodRepo.On("FindByProductID", "001").Return(&prodUsecase.Product{ID: "001", Name: "Shuriken", Price: 250, Stock: 10}, nil)
+				prodRepo.On("FindByProductID", "002").Return(&prodUsecase.Product{ID: "002", Name: "Sai", Price: 500, Stock: 10}, nil)
+				cartRepo.On("AddToCart", "123", mock.Anything, mock.Anything).Return(nil)
+				uc := NewCartUsecase(cartRepo, prodRepo)
+				err := uc.AddToCart("123", &CartItem{ID: "004", Qty: 1})
+				So(err, ShouldBeNil)
+
+				added := cartRepo.Calls[len(cartRepo.Calls)-1].Arguments.Get(1).(*CartItem)
+				So(added.SKU, ShouldEqual, "004")
+				So(added.Price, ShouldEqual, 1000)
+				So(added.Disc, ShouldEqual, 100)
+				So(len(added.Components), ShouldEqual, 2)
+				So(added.Components[0].Name, ShouldEqual, "Shuriken")
+				So(added.Components[0].Qty, ShouldEqual, 2)
+			})
+		})
+	})
+
+	Convey("3. Given a user checkout his cart", t, func() {
+
+		cartRepo := &mockRepo.MockCartRepository{}
+		prodRepo := &mockRepo.MockProductRepository{}
+		userRepo := &mockRepo.MockUserRepository{}
+		addrValidator := &mockService.MockAddressValidator{}
+
+		openCart := func() *Cart {
+			return &Cart{
+				ID: "001", UserID: "123", Status: enum.Open, CreatedAt: time.Now(),
+				Items: []*CartItem{{ID: "001", Name: "Shuriken", Qty: 2, Price: 250.5}},
+			}
+		}
+		buyer := &userUsecase.User{
+			ID: "123",
+			ShippingAddr: &userUsecase.Address{
+				Street: "Kalibata Raya No.1", City: "Jakarta", Postal: "12750", Country: "Indonesia",
+			},
+		}
+
+		Convey("-> Negative Scenarios", func() {
+			Convey("-> Should return an error when the cart is empty", func() {
+				cartRepo.On("FetchUserCart", "123").Return(&Cart{
+					ID: "001", UserID: "123", Status: enum.Open, CreatedAt: time.Now(),
+				}, nil)
+				uc := NewCartUsecase(cartRepo, prodRepo)
+				res, err := uc.Checkout("123")
+				So(res, ShouldBeNil)
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "cannot checkout an empty cart")
+			})
+			Convey("-> Should return an error when the shipping address is missing", func() {
+				cartRepo.On("FetchUserCart", "123").Return(openCart(), nil)
+				userRepo.On("FindByUserID", "123").Return(&userUsecase.User{ID: "123"}, nil)
+				uc := NewCartUsecase(cartRepo, prodRepo, WithUserRepository(userRepo))
+				res, err := uc.Checkout("123")
+				So(res, ShouldBeNil)
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "cannot checkout, shipping address is missing")
+			})
+			Convey("-> Should return an error when the shipping address is inconsistent", func() {
+				cartRepo.On("FetchUserCart", "123").Return(openCart(), nil)
+				userRepo.On("FindByUserID", "123").Return(buyer, nil)
+				addrValidator.On("Validate", mock.Anything).Return(
+					vo.BuyerAddress{}, e.NewErrInvalidData("unknown postal code 00000"),
+				)
+				uc := NewCartUsecase(cartRepo, prodRepo, WithUserRepository(userRepo), WithAddressValidator(addrValidator))
+				res, err := uc.Checkout("123")
+				So(res, ShouldBeNil)
+				So(err, ShouldHaveSameTypeAs, &e.ErrInvalidData{})
+				cartRepo.AssertNotCalled(t, "Checkout", mock.Anything, mock.Anything)
+			})
+			Convey("-> Should return an error when the stock ran out since the item was added", func() {
+				cartRepo.On("FetchUserCart", "123").Return(openCart(), nil)
+				prodRepo.On("FindByProductID", "001").Return(&prodUsecase.Product{ID: "001", Name: "Shuriken", Stock: 1, Price: 250.5}, nil)
+				uc := NewCartUsecase(cartRepo, prodRepo)
+				res, err := uc.Checkout("123")
+				So(res, ShouldBeNil)
+				So(err, ShouldHaveSameTypeAs, &e.ErrConflict{})
+				So(err.Error(), ShouldEqual, "the cart has changed, please review it before checking out: "+
+					"only 1 of Shuriken left, please lower the quantity from 2")
+				cartRepo.AssertNotCalled(t, "Checkout", mock.Anything, mock.Anything)
+			})
+			Convey("-> Should ask for a review when the price rose since the item was added", func() {
+				cartRepo.On("FetchUserCart", "123").Return(openCart(), nil)
+				cartRepo.On("UpdateItem", "001", mock.Anything, mock.Anything).Return(nil)
+				prodRepo.On("FindByProductID", "001").Return(&prodUsecase.Product{ID: "001", Name: "Shuriken", Stock: 10, Price: 275}, nil)
+				uc := NewCartUsecase(cartRepo, prodRepo, WithCurrency("IDR"))
+				res, err := uc.Checkout("123")
+				So(res, ShouldBeNil)
+				So(err, ShouldHaveSameTypeAs, &e.ErrConflict{})
+				So(err.Error(), ShouldEqual, "the cart has changed, please review it before checking out: "+
+					"the price of Shuriken has risen from IDR 250.50 to IDR 275.00")
+				cartRepo.AssertCalled(t, "UpdateItem", "001", mock.MatchedBy(func(item *CartItem) bool {
+					return item.Price == 275
+				}), mock.Anything)
+				cartRepo.AssertNotCalled(t, "Checkout", mock.Anything, mock.Anything)
+			})
+			Convey("-> Should return the error raised by the system repository and release the reserved stock", func() {
+				shuriken := &prodUsecase.Product{ID: "001", Name: "Shuriken", Stock: 10, Price: 250.5}
+				cartRepo.On("FetchUserCart", "123").Return(openCart(), nil)
+				cartRepo.On("Checkout", "001", mock.Anything).Return(errors.New("Database error"))
+				cartRepo.On("RecordShipments", "001", mock.Anything).Return(nil)
+				prodRepo.On("FindByProductID", "001").Return(shuriken, nil)
+				prodRepo.On("Update", shuriken).Return(nil)
+				uc := NewCartUsecase(cartRepo, prodRepo)
+				res, err := uc.Checkout("123")
+				So(res, ShouldBeNil)
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "Database error")
+				So(shuriken.Stock, ShouldEqual, 10)
+			})
+		})
+
+		Convey("-> Positive Scenarios", func() {
+			Convey("-> Cart should be moved into payment processing and the stock reserved", func() {
+				shuriken := &prodUsecase.Product{ID: "001", Name: "Shuriken", Stock: 10, Price: 250.5}
+				cartRepo.On("FetchUserCart", "123").Return(openCart(), nil)
+				cartRepo.On("Checkout", "001", mock.Anything).Return(nil)
+				cartRepo.On("RecordShipments", "001", mock.Anything).Return(nil)
+				prodRepo.On("FindByProductID", "001").Return(shuriken, nil)
+				prodRepo.On("Update", shuriken).Return(nil)
+				userRepo.On("FindByUserID", "123").Return(buyer, nil)
+				addrValidator.On("Validate", mock.Anything).Return(vo.BuyerAddress{}, nil)
+				uc := NewCartUsecase(cartRepo, prodRepo, WithUserRepository(userRepo), WithAddressValidator(addrValidator))
+				res, err := uc.Checkout("123")
+				So(err, ShouldBeNil)
+				So(res.(*Cart).Status, ShouldEqual, enum.PaymentProcessing)
+				So(shuriken.Stock, ShouldEqual, 8)
+			})
+		})
+	})
+
+	Convey("4. Given an authenticated caller accessing a cart", t, func() {
+
+		cartRepo := &mockRepo.MockCartRepository{}
+		prodRepo := &mockRepo.MockProductRepository{}
+		cartRepo.On("FetchUserCart", "123").Return(&Cart{
+			ID: "001", UserID: "123", Status: enum.Open, CreatedAt: time.Now(),
+		}, nil)
+
+		Convey("-> Negative Scenarios", func() {
+			Convey("-> Should be forbidden to fetch another user's cart", func() {
+				uc := NewCartUsecase(cartRepo, prodRepo).ForPrincipal(&authUsecase.Principal{UserID: "456", Role: enum.Customer})
+				res, err := uc.FetchUserCart("123")
+				So(res, ShouldBeNil)
+				So(err, ShouldHaveSameTypeAs, &e.ErrForbidden{})
+				cartRepo.AssertNotCalled(t, "FetchUserCart", "123")
+			})
+			Convey("-> Should be forbidden to add an item into another user's cart", func() {
+				uc := NewCartUsecase(cartRepo, prodRepo).ForPrincipal(&authUsecase.Principal{UserID: "456", Role: enum.Customer})
+				err := uc.AddToCart("123", &CartItem{ID: "001", Qty: 1})
+				So(err, ShouldHaveSameTypeAs, &e.ErrForbidden{})
+			})
+		})
+
+		Convey("-> Positive Scenarios", func() {
+			Convey("-> The owner should be able to fetch the cart", func() {
+				uc := NewCartUsecase(cartRepo, prodRepo).ForPrincipal(&authUsecase.Principal{UserID: "123", Role: enum.Customer})
+				res, err := uc.FetchUserCart("123")
+				So(err, ShouldBeNil)
+				So(res.(*Cart).ID, ShouldEqual, "001")
+			})
+			Convey("-> An admin should be able to fetch any cart", func() {
+				uc := NewCartUsecase(cartRepo, prodRepo).ForPrincipal(&authUsecase.Principal{UserID: "admin", Role: enum.Admin})
+				res, err := uc.FetchUserCart("123")
+				So(err, ShouldBeNil)
+				So(res.(*Cart).ID, ShouldEqual, "001")
+			})
+		})
+	})
+
+	Convey("5. Given a user removes an item from his cart", t, func() {
+
+		cartRepo := &mockRepo.MockCartRepository{}
+		prodRepo := &mockRepo.MockProductRepository{}
+		cartRepo.On("FetchUserCart", "123").Return(&Cart{
+			ID: "001", UserID: "123", Status: enum.Open, CreatedAt: time.Now(),
+			Items: []*CartItem{
+				{ID: "001", Name: "Shuriken", SKU: "001", Qty: 2, Price: 250.5},
+				{ID: "003", Name: "Ninja Gi", SKU: "003-BLK-M", Qty: 1, Price: 320},
+			},
+		}, nil)
+
+		Convey("-> Negative Scenarios", func() {
+			Convey("-> Should return an error when the item is not in the cart", func() {
+				uc := NewCartUsecase(cartRepo, prodRepo)
+				err := uc.RemoveFromCart("123", "003-NVY-M")
+				So(err, ShouldHaveSameTypeAs, &e.ErrNoData{})
+				cartRepo.AssertNotCalled(t, "RemoveItem", mock.Anything, mock.Anything, mock.Anything)
+			})
+		})
+
+		Convey("-> Positive Scenarios", func() {
+			Convey("-> Should remove the line identified by its SKU", func() {
+				cartRepo.On("RemoveItem", "001", "003-BLK-M", mock.Anything).Return(nil)
+				uc := NewCartUsecase(cartRepo, prodRepo)
+				So(uc.RemoveFromCart("123", "003-BLK-M"), ShouldBeNil)
+				cartRepo.AssertCalled(t, "RemoveItem", "001", "003-BLK-M", mock.Anything)
+			})
+		})
+	})
+
+	Convey("6. Given a user changes the quantity of a cart item", t, func() {
+
+		cartRepo := &mockRepo.MockCartRepository{}
+		prodRepo := &mockRepo.MockProductRepository{}
+		cartRepo.On("FetchUserCart", "123").Return(&Cart{
+			ID: "001", UserID: "123", Status: enum.Open, CreatedAt: time.Now(),
+			Items: []*CartItem{{ID: "003", Name: "Ninja Gi", SKU: "003-BLK-M", Qty: 1, Price: 320}},
+		}, nil)
+		prodRepo.On("FindByProductID", "003").Return(&prodUsecase.Product{
+			ID: "003", Name: "Ninja Gi", Price: 320,
+			Variants: []*prodUsecase.Variant{{SKU: "003-BLK-M", Stock: 5, Price: 320}},
+		}, nil)
+
+		Convey("-> Negative Scenarios", func() {
+			Convey("-> Should return an error when the variant has not enough stock left", func() {
+				uc := NewCartUsecase(cartRepo, prodRepo)
+				err := uc.UpdateItemQty("123", "003-BLK-M", 6)
+				So(err, ShouldNotBeNil)
+				So(err, ShouldHaveSameTypeAs, &e.ErrOutOfStock{})
+				So(err.Error(), ShouldEqual, "out of stock, not enough Ninja Gi left")
+				cartRepo.AssertNotCalled(t, "UpdateItem", mock.Anything, mock.Anything, mock.Anything)
+			})
+			Convey("-> Should return an error for a zero quantity", func() {
+				uc := NewCartUsecase(cartRepo, prodRepo)
+				err := uc.UpdateItemQty("123", "003-BLK-M", 0)
+				So(err, ShouldHaveSameTypeAs, &e.ErrInvalidData{})
+			})
+		})
+
+		Convey("-> Positive Scenarios", func() {
+			Convey("-> Should save the new quantity", func() {
+				cartRepo.On("UpdateItem", "001", mock.Anything, mock.Anything).Return(nil)
+				uc := NewCartUsecase(cartRepo, prodRepo)
+				So(uc.UpdateItemQty("123", "003-BLK-M", 5), ShouldBeNil)
+				updated := cartRepo.Calls[len(cartRepo.Calls)-1].Arguments.Get(1).(*CartItem)
+				So(updated.Qty, ShouldEqual, 5)
+				So(updated.Price, ShouldEqual, 320)
+			})
+		})
+	})
+
+	Convey("7. Given a user cancels his cart", t, func() {
+
+		cartRepo := &mockRepo.MockCartRepository{}
+		prodRepo := &mockRepo.MockProductRepository{}
+		loyalty := &mockService.MockLoyaltyProgram{}
+
+		Convey("-> Negative Scenarios", func() {
+			Convey("-> Should not cancel a cart whose payment is settled", func() {
+				cartRepo.On("FetchLatestCart", "123").Return(&Cart{
+					ID: "001", UserID: "123", Status: enum.Closed, CreatedAt: time.Now(),
+				}, nil)
+				uc := NewCartUsecase(cartRepo, prodRepo)
+				res, err := uc.CancelCart("123")
+				So(res, ShouldBeNil)
+				So(err.Error(), ShouldEqual, "cannot cancel the cart with status of closed")
+				cartRepo.AssertNotCalled(t, "Canceled", mock.Anything, mock.Anything)
+			})
+		})
+
+		Convey("-> Positive Scenarios", func() {
+			Convey("-> The open cart should be canceled", func() {
+				cartRepo.On("FetchLatestCart", "123").Return(&Cart{
+					ID: "001", UserID: "123", Status: enum.Open, CreatedAt: time.Now(),
+				}, nil)
+				cartRepo.On("Canceled", "001", mock.Anything).Return(nil)
+				uc := NewCartUsecase(cartRepo, prodRepo)
+				res, err := uc.CancelCart("123")
+				So(err, ShouldBeNil)
+				So(res.(*Cart).Status, ShouldEqual, enum.Canceled)
+				So(res.(*Cart).CanceledAt, ShouldNotBeNil)
+				prodRepo.AssertNotCalled(t, "Update", mock.Anything)
+			})
+			Convey("-> A checked out cart should give back what its checkout took", func() {
+				shuriken := &prodUsecase.Product{
+					ID: "001", Name: "Shuriken", Stock: 0, Price: 250.5,
+					StockPolicy: &prodUsecase.StockPolicy{Mode: enum.Backorder, Limit: 5, Committed: 2},
+				}
+				prodRepo.On("FindByProductID", "001").Return(shuriken, nil)
+				prodRepo.On("Update", shuriken).Return(nil)
+				cartRepo.On("FetchLatestCart", "123").Return(&Cart{
+					ID: "001", UserID: "123", Status: enum.PaymentProcessing, CreatedAt: time.Now(),
+					Items: []*CartItem{{ID: "001", Name: "Shuriken", SKU: "001", Qty: 4, Price: 250.5, Fulfillment: enum.Backorder}},
+					Shipments: []*Shipment{
+						{WarehouseID: "main", Items: []*CartItemComponent{{ID: "001", Name: "Shuriken", SKU: "001", Qty: 2}}},
+						{Fulfillment: enum.Backorder, Items: []*CartItemComponent{{ID: "001", Name: "Shuriken", SKU: "001", Qty: 2}}},
+					},
+					Redemption: &PointsRedemption{Points: 100, Amount: 100},
+				}, nil)
+				cartRepo.On("Canceled", "001", mock.Anything).Return(nil)
+				loyalty.On("RestorePoints", "123", "001").Return(nil)
+				uc := NewCartUsecase(cartRepo, prodRepo, WithLoyaltyProgram(loyalty))
+				res, err := uc.CancelCart("123")
+				So(err, ShouldBeNil)
+				So(res.(*Cart).Status, ShouldEqual, enum.Canceled)
+				So(shuriken.Stock, ShouldEqual, 2)
+				So(shuriken.StockPolicy.Committed, ShouldEqual, 0)
+				loyalty.AssertCalled(t, "RestorePoints", "123", "001")
+			})
+		})
+	})
+
+	Convey("8. Given the carts left idle beyond the cart TTL", t, func() {
+
+		cartRepo := &mockRepo.MockCartRepository{}
+		prodRepo := &mockRepo.MockProductRepository{}
+		clock := &mockService.MockClock{}
+		publisher := &mockService.MockEventPublisher{}
+
+		now := time.Date(2020, time.May, 1, 12, 0, 0, 0, time.UTC)
+		clock.On("Now").Return(now)
+		idleSince := now.Add(-24 * time.Hour)
+		openCart := &Cart{
+			ID: "001", UserID: "123", Status: enum.Open, LastActivityAt: idleSince.Add(-time.Hour),
+			Items: []*CartItem{{ID: "001", Name: "Shuriken", Qty: 2, Price: 250.5}},
+		}
+		checkedOutCart := &Cart{
+			ID: "002", UserID: "456", Status: enum.PaymentProcessing, LastActivityAt: idleSince.Add(-time.Minute),
+			Items: []*CartItem{{ID: "002", Name: "Sai", Qty: 3, Price: 175.25}},
+		}
+
+		Convey("-> Negative Scenarios", func() {
+			Convey("-> Nothing should expire without a cart TTL", func() {
+				uc := NewCartUsecase(cartRepo, prodRepo, WithClock(clock))
+				res, err := uc.ExpireIdleCarts()
+				So(err, ShouldBeNil)
+				So(res.([]*Cart), ShouldBeEmpty)
+				cartRepo.AssertNotCalled(t, "FetchIdleCarts", mock.Anything)
+			})
+			Convey("-> A failing cart should not prevent the other ones from expiring", func() {
+				cartRepo.On("FetchIdleCarts", idleSince).Return([]*Cart{openCart, checkedOutCart}, nil)
+				cartRepo.On("Canceled", "001", mock.Anything).Return(errors.New("Database error"))
+				cartRepo.On("Canceled", "002", mock.Anything).Return(nil)
+				sai := &prodUsecase.Product{ID: "002", Name: "Sai", Stock: 5}
+				prodRepo.On("FindByProductID", "002").Return(sai, nil)
+				prodRepo.On("Update", sai).Return(nil)
+				publisher.On("Publish", mock.Anything).Return(nil)
+				uc := NewCartUsecase(cartRepo, prodRepo, WithCartTTL(24*time.Hour), WithClock(clock), WithEventPublisher(publisher))
+				res, err := uc.ExpireIdleCarts()
+				So(err.Error(), ShouldEqual, "failed to expire 1 cart(s): cart 001: Database error")
+				So(res.([]*Cart), ShouldHaveLength, 1)
+				So(res.([]*Cart)[0].ID, ShouldEqual, "002")
+				publisher.AssertNumberOfCalls(t, "Publish", 1)
+			})
+		})
+
+		Convey("-> Positive Scenarios", func() {
+			Convey("-> The idle carts should be canceled, the reserved stock released and the expiry published", func() {
+				cartRepo.On("FetchIdleCarts", idleSince).Return([]*Cart{openCart, checkedOutCart}, nil)
+				cartRepo.On("Canceled", mock.Anything, now).Return(nil)
+				sai := &prodUsecase.Product{ID: "002", Name: "Sai", Stock: 5}
+				prodRepo.On("FindByProductID", "002").Return(sai, nil)
+				prodRepo.On("Update", sai).Return(nil)
+				events := make([]*CartExpired, 0)
+				publisher.On("Publish", mock.Anything).Run(func(args mock.Arguments) {
+					events = append(events, args.Get(0).(*CartExpired))
+				}).Return(nil)
+				uc := NewCartUsecase(cartRepo, prodRepo, WithCartTTL(24*time.Hour), WithClock(clock), WithEventPublisher(publisher))
+				res, err := uc.ExpireIdleCarts()
+				So(err, ShouldBeNil)
+				So(res.([]*Cart), ShouldHaveLength, 2)
+				for _, c := range res.([]*Cart) {
+					So(c.Status, ShouldEqual, enum.Canceled)
+					So(*c.CanceledAt, ShouldEqual, now)
+				}
+				So(sai.Stock, ShouldEqual, 8)
+				prodRepo.AssertNotCalled(t, "FindByProductID", "001")
+
+				So(events, ShouldHaveLength, 2)
+				So(events[0].PreviousStatus, ShouldEqual, enum.Open)
+				So(events[0].ReleasedStock, ShouldBeEmpty)
+				So(events[1].PreviousStatus, ShouldEqual, enum.PaymentProcessing)
+				So(events[1].ExpiredAt, ShouldEqual, now)
+				So(events[1].ReleasedStock, ShouldResemble, []*ReleasedItem{{ProductID: "002", SKU: "002", Qty: 3}})
+			})
+		})
+	})
+
+	Convey("9. Given the open carts left idle with some items in them", t, func() {
+
+		cartRepo := &mockRepo.MockCartRepository{}
+		prodRepo := &mockRepo.MockProductRepository{}
+		userRepo := &mockRepo.MockUserRepository{}
+		clock := &mockService.MockClock{}
+		notifier := &mockService.MockNotifier{}
+
+		now := time.Date(2020, time.May, 1, 12, 0, 0, 0, time.UTC)
+		clock.On("Now").Return(now)
+		cutoff := now.Add(-4 * time.Hour)
+		remindedLately := now.Add(-time.Hour)
+		items := func() []*CartItem {
+			return []*CartItem{
+				{ID: "001", Name: "Shuriken", SKU: "001", Qty: 2, Price: 250.5},
+				{ID: "003", Name: "Ninja Gi", SKU: "003-BLK-M", Qty: 1, Price: 320, Disc: 20,
+					Options: map[string]string{"size": "M", "color": "black"}},
+			}
+		}
+		idleCarts := []*Cart{
+			{ID: "001", UserID: "123", Status: enum.Open, Items: items()},
+			{ID: "002", UserID: "456", Status: enum.Open, Items: items()[:1], RemindersSent: 1},
+			{ID: "003", UserID: "123", Status: enum.Open, Items: items(), RemindersSent: 2},
+			{ID: "004", UserID: "123", Status: enum.Open, Items: items(), RemindersSent: 1, LastRemindedAt: &remindedLately},
+			{ID: "005", UserID: "123", Status: enum.Open},
+			{ID: "006", UserID: "123", Status: enum.PaymentProcessing, Items: items()},
+			{ID: "007", UserID: "guest-1", Status: enum.Open, Items: items()},
+		}
+		newUsecase := func() *CartUsecase {
+			return NewCartUsecase(cartRepo, prodRepo, WithUserRepository(userRepo), WithClock(clock),
+				WithNotifier(notifier), WithRecoveryPolicy(4*time.Hour, 2), WithCurrency("IDR"))
+		}
+
+		Convey("-> Negative Scenarios", func() {
+			Convey("-> Nothing should be sent without a notifier", func() {
+				uc := NewCartUsecase(cartRepo, prodRepo, WithClock(clock), WithRecoveryPolicy(4*time.Hour, 2))
+				res, err := uc.SendRecoveryReminders()
+				So(err, ShouldBeNil)
+				So(res.([]*Cart), ShouldBeEmpty)
+				cartRepo.AssertNotCalled(t, "FetchIdleCarts", mock.Anything)
+			})
+			Convey("-> A user who cannot be reached should not prevent the other ones from being reminded", func() {
+				cartRepo.On("FetchIdleCarts", cutoff).Return(idleCarts, nil)
+				userRepo.On("FindByUserID", "123").Return(&userUsecase.User{ID: "123", Username: "yauritux"}, nil)
+				userRepo.On("FindByUserID", "456").Return(&userUsecase.User{ID: "456", Username: "hanzo", Phone: "+62822"}, nil)
+				notifier.On("Send", mock.Anything).Return(nil)
+				cartRepo.On("RecordReminder", "002", now).Return(nil)
+				res, err := newUsecase().SendRecoveryReminders()
+				So(err.Error(), ShouldEqual, "failed to remind 1 cart(s): cart 001: user 123 has neither an email nor a phone")
+				So(res.([]*Cart), ShouldHaveLength, 1)
+				So(res.([]*Cart)[0].ID, ShouldEqual, "002")
+				cartRepo.AssertNotCalled(t, "RecordReminder", "001", now)
+			})
+		})
+
+		Convey("-> Positive Scenarios", func() {
+			Convey("-> Only the carts due for a reminder should be reminded and the reminders recorded", func() {
+				cartRepo.On("FetchIdleCarts", cutoff).Return(idleCarts, nil)
+				cartRepo.On("RecordReminder", mock.Anything, now).Return(nil)
+				userRepo.On("FindByUserID", "123").Return(&userUsecase.User{ID: "123", Username: "yauritux", Email: "yauritux@gmail.com"}, nil)
+				userRepo.On("FindByUserID", "456").Return(&userUsecase.User{ID: "456", Username: "hanzo", Phone: "+62822"}, nil)
+				sent := make([]vo.Notification, 0)
+				notifier.On("Send", mock.Anything).Run(func(args mock.Arguments) {
+					sent = append(sent, args.Get(0).(vo.Notification))
+				}).Return(nil)
+
+				res, err := newUsecase().SendRecoveryReminders()
+				So(err, ShouldBeNil)
+				So(res.([]*Cart), ShouldHaveLength, 2)
+				So(res.([]*Cart)[0].RemindersSent, ShouldEqual, 1)
+				So(*res.([]*Cart)[0].LastRemindedAt, ShouldEqual, now)
+				So(res.([]*Cart)[1].RemindersSent, ShouldEqual, 2)
+				cartRepo.AssertNumberOfCalls(t, "RecordReminder", 2)
+
+				So(sent, ShouldHaveLength, 2)
+				So(sent[0], ShouldResemble, vo.Notification{
+					Channel:   enum.EmailChannel,
+					Recipient: "yauritux@gmail.com",
+					Subject:   "yauritux, you left 3 item(s) in your cart",
+					Body: `Hi yauritux,
+
+These items are still waiting in your cart:
+
+  2 x Shuriken  IDR 501.00
+  1 x Ninja Gi (color: black, size: M)  IDR 300.00
+
+  Gross     IDR 821.00
+  Discount  IDR 20.00
+  Total     IDR 801.00
+
+Complete your checkout before they run out of stock.
+`,
+				})
+				So(sent[1], ShouldResemble, vo.Notification{
+					Channel:   enum.SMSChannel,
+					Recipient: "+62822",
+					Body:      "Hi hanzo, 2 item(s) worth IDR 501.00 are still waiting in your cart. Complete your checkout before they run out of stock.",
+				})
+			})
+		})
+	})
+
+	Convey("10. Given a guest logs in with some items in the guest cart", t, func() {
+
+		cartRepo := &mockRepo.MockCartRepository{}
+		prodRepo := &mockRepo.MockProductRepository{}
+
+		now := time.Now()
+		guestCart := func() *Cart {
+			return &Cart{
+				ID: "g01", UserID: "guest-1", Status: enum.Open, LastActivityAt: now,
+				Items: []*CartItem{
+					{ID: "001", Name: "Shuriken", SKU: "001", Qty: 3, Price: 250.5},
+					{ID: "002", Name: "Sai", SKU: "002", Qty: 4, Price: 175.25},
+				},
+			}
+		}
+		userCart := func() *Cart {
+			return &Cart{
+				ID: "u01", UserID: "123", Status: enum.Open, LastActivityAt: now.Add(-time.Hour),
+				Items: []*CartItem{{ID: "001", Name: "Shuriken", SKU: "001", Qty: 2, Price: 250.5}},
+			}
+		}
+
+		Convey("-> Negative Scenarios", func() {
+			Convey("-> Should only merge a guest cart", func() {
+				uc := NewCartUsecase(cartRepo, prodRepo)
+				res, err := uc.MergeCarts("456", "123")
+				So(res, ShouldBeNil)
+				So(err, ShouldHaveSameTypeAs, &e.ErrInvalidData{})
+			})
+			Convey("-> Should be forbidden to merge into another user's cart", func() {
+				uc := NewCartUsecase(cartRepo, prodRepo).ForPrincipal(&authUsecase.Principal{UserID: "456", Role: enum.Customer})
+				_, err := uc.MergeCarts("guest-1", "123")
+				So(err, ShouldHaveSameTypeAs, &e.ErrForbidden{})
+				cartRepo.AssertNotCalled(t, "Open", mock.Anything, mock.Anything)
+			})
+			Convey("-> A guest cart cannot be checked out", func() {
+				cartRepo.On("FetchUserCart", "guest-1").Return(guestCart(), nil)
+				uc := NewCartUsecase(cartRepo, prodRepo)
+				_, err := uc.Checkout("guest-1")
+				So(err, ShouldHaveSameTypeAs, &e.ErrForbidden{})
+			})
+		})
+
+		Convey("-> Positive Scenarios", func() {
+			Convey("-> The guest lines should be merged within the stock left and the guest cart canceled", func() {
+				merged := userCart()
+				merged.Items[0].Qty = 5
+				merged.Items = append(merged.Items, &CartItem{ID: "002", Name: "Sai", SKU: "002", Qty: 3, Price: 175.25})
+				cartRepo.On("Open", "123", mock.Anything).Return(nil)
+				cartRepo.On("FetchUserCart", "guest-1").Return(guestCart(), nil)
+				cartRepo.On("FetchUserCart", "123").Return(userCart(), nil).Once()
+				cartRepo.On("FetchUserCart", "123").Return(merged, nil)
+				prodRepo.On("FindByProductID", "001").Return(&prodUsecase.Product{ID: "001", Name: "Shuriken", Stock: 10}, nil)
+				prodRepo.On("FindByProductID", "002").Return(&prodUsecase.Product{ID: "002", Name: "Sai", Stock: 3}, nil)
+				cartRepo.On("UpdateItem", "u01", mock.Anything, mock.Anything).Return(nil)
+				cartRepo.On("AddToCart", "u01", mock.Anything, mock.Anything).Return(nil)
+				cartRepo.On("Canceled", "g01", mock.Anything).Return(nil)
+
+				uc := NewCartUsecase(cartRepo, prodRepo).ForPrincipal(&authUsecase.Principal{UserID: "123", Role: enum.Customer})
+				res, err := uc.MergeCarts("guest-1", "123")
+				So(err, ShouldBeNil)
+				result := res.(*CartMergeResult)
+				So(result.Cart, ShouldEqual, merged)
+				So(result.LeftOut, ShouldHaveLength, 1)
+				So(result.LeftOut[0].SKU, ShouldEqual, "002")
+				So(result.LeftOut[0].Qty, ShouldEqual, 1)
+
+				cartRepo.AssertCalled(t, "UpdateItem", "u01", mock.MatchedBy(func(item *CartItem) bool {
+					return item.SKU == "001" && item.Qty == 5
+				}), mock.Anything)
+				cartRepo.AssertCalled(t, "AddToCart", "u01", mock.MatchedBy(func(item *CartItem) bool {
+					return item.SKU == "002" && item.Qty == 3
+				}), mock.Anything)
+				cartRepo.AssertCalled(t, "Canceled", "g01", mock.Anything)
+			})
+			Convey("-> The latest rule should keep the lines of the guest cart", func() {
+				cartRepo.On("Open", "123", mock.Anything).Return(nil)
+				cartRepo.On("FetchUserCart", "guest-1").Return(guestCart(), nil)
+				cartRepo.On("FetchUserCart", "123").Return(userCart(), nil)
+				prodRepo.On("FindByProductID", "001").Return(&prodUsecase.Product{ID: "001", Name: "Shuriken", Stock: 10}, nil)
+				prodRepo.On("FindByProductID", "002").Return(nil, e.NewErrNoData("no product found"))
+				cartRepo.On("UpdateItem", "u01", mock.Anything, mock.Anything).Return(nil)
+				cartRepo.On("Canceled", "g01", mock.Anything).Return(nil)
+
+				uc := NewCartUsecase(cartRepo, prodRepo, WithMergeRule(enum.KeepLatestQuantity))
+				res, err := uc.MergeCarts("guest-1", "123")
+				So(err, ShouldBeNil)
+				So(res.(*CartMergeResult).LeftOut, ShouldHaveLength, 1)
+				cartRepo.AssertCalled(t, "UpdateItem", "u01", mock.MatchedBy(func(item *CartItem) bool {
+					return item.SKU == "001" && item.Qty == 3
+				}), mock.Anything)
+				cartRepo.AssertNotCalled(t, "AddToCart", mock.Anything, mock.Anything, mock.Anything)
+			})
+		})
+	})
+
+	Convey("11. Given a user moves the items between his cart and his saved lists", t, func() {
+
+		cartRepo := &mockRepo.MockCartRepository{}
+		prodRepo := &mockRepo.MockProductRepository{}
+		listRepo := &mockRepo.MockSavedListRepository{}
+
+		userCart := func() *Cart {
+			return &Cart{
+				ID: "u01", UserID: "123", Status: enum.Open,
+				Items: []*CartItem{{ID: "001", Name: "Shuriken", SKU: "001", Qty: 2, Price: 250.5}},
+			}
+		}
+		wishlist := func() *SavedList {
+			return &SavedList{
+				UserID: "123", Name: enum.Wishlist,
+				Items: []*CartItem{{ID: "002", Name: "Sai", SKU: "002", Qty: 1, Price: 175.25}},
+			}
+		}
+		savedForLater := func() *SavedList {
+			return &SavedList{UserID: "123", Name: enum.SavedForLater, Items: make([]*CartItem, 0)}
+		}
+
+		Convey("-> Negative Scenarios", func() {
+			Convey("-> Should require a saved list repository", func() {
+				uc := NewCartUsecase(cartRepo, prodRepo)
+				_, err := uc.FetchSavedList("123", enum.Wishlist)
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "saved lists are not available, no saved list repository")
+			})
+			Convey("-> Should be forbidden to save into another user's list", func() {
+				uc := NewCartUsecase(cartRepo, prodRepo, WithSavedListRepository(listRepo)).
+					ForPrincipal(&authUsecase.Principal{UserID: "456", Role: enum.Customer})
+				err := uc.SaveToList("123", enum.Wishlist, &CartItem{ID: "002", Qty: 1})
+				So(err, ShouldHaveSameTypeAs, &e.ErrForbidden{})
+				listRepo.AssertNotCalled(t, "Save", mock.Anything)
+			})
+			Convey("-> Should reject an unknown list", func() {
+				listRepo.On("FetchSavedList", "123", "favourites").Return(&SavedList{UserID: "123", Name: "favourites"}, nil)
+				prodRepo.On("FindByProductID", "002").Return(&prodUsecase.Product{ID: "002", Name: "Sai", Stock: 5, Price: 175.25}, nil)
+				uc := NewCartUsecase(cartRepo, prodRepo, WithSavedListRepository(listRepo))
+				err := uc.SaveToList("123", "favourites", &CartItem{ID: "002", Qty: 1})
+				So(err, ShouldHaveSameTypeAs, &e.ErrInvalidData{})
+				listRepo.AssertNotCalled(t, "Save", mock.Anything)
+			})
+			Convey("-> An item out of stock should stay in the list", func() {
+				cartRepo.On("FetchUserCart", "123").Return(userCart(), nil)
+				listRepo.On("FetchSavedList", "123", "wishlist").Return(wishlist(), nil)
+				prodRepo.On("FindByProductID", "002").Return(&prodUsecase.Product{ID: "002", Name: "Sai", Stock: 0, Price: 175.25}, nil)
+				uc := NewCartUsecase(cartRepo, prodRepo, WithSavedListRepository(listRepo))
+				res, err := uc.MoveToCart("123", enum.Wishlist, "002")
+				So(res, ShouldBeNil)
+				So(err, ShouldNotBeNil)
+				cartRepo.AssertNotCalled(t, "AddToCart", mock.Anything, mock.Anything, mock.Anything)
+				listRepo.AssertNotCalled(t, "Save", mock.Anything)
+			})
+			Convey("-> The cart should be restored when the list cannot be saved", func() {
+				cartRepo.On("FetchUserCart", "123").Return(userCart(), nil)
+				listRepo.On("FetchSavedList", "123", "wishlist").Return(wishlist(), nil)
+				prodRepo.On("FindByProductID", "002").Return(&prodUsecase.Product{ID: "002", Name: "Sai", Stock: 5, Price: 175.25}, nil)
+				cartRepo.On("AddToCart", "u01", mock.Anything, mock.Anything).Return(nil)
+				cartRepo.On("RemoveItem", "u01", "002", mock.Anything).Return(nil)
+				listRepo.On("Save", mock.Anything).Return(errors.New("Database error"))
+				uc := NewCartUsecase(cartRepo, prodRepo, WithSavedListRepository(listRepo))
+				_, err := uc.MoveToCart("123", enum.Wishlist, "002")
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "Database error")
+				cartRepo.AssertCalled(t, "RemoveItem", "u01", "002", mock.Anything)
+			})
+			Convey("-> The list should be restored when the item cannot be removed from the cart", func() {
+				cartRepo.On("FetchUserCart", "123").Return(userCart(), nil)
+				listRepo.On("FetchSavedList", "123", "saved_for_later").Return(savedForLater(), nil)
+				prodRepo.On("FindByProductID", "001").Return(&prodUsecase.Product{ID: "001", Name: "Shuriken", Stock: 10, Price: 250.5}, nil)
+				listRepo.On("Save", mock.Anything).Return(nil)
+				cartRepo.On("RemoveItem", "u01", "001", mock.Anything).Return(errors.New("Database error"))
+				uc := NewCartUsecase(cartRepo, prodRepo, WithSavedListRepository(listRepo))
+				err := uc.MoveToSavedForLater("123", "001")
+				So(err, ShouldNotBeNil)
+				listRepo.AssertCalled(t, "Save", mock.MatchedBy(func(l *SavedList) bool {
+					return len(l.Items) == 0
+				}))
+			})
+		})
+
+		Convey("-> Positive Scenarios", func() {
+			Convey("-> Moving a saved item should add it to the cart at its current price", func() {
+				cartRepo.On("FetchUserCart", "123").Return(userCart(), nil)
+				listRepo.On("FetchSavedList", "123", "wishlist").Return(wishlist(), nil)
+				prodRepo.On("FindByProductID", "002").Return(&prodUsecase.Product{ID: "002", Name: "Sai", Stock: 5, Price: 180}, nil)
+				cartRepo.On("AddToCart", "u01", mock.Anything, mock.Anything).Return(nil)
+				listRepo.On("Save", mock.Anything).Return(nil)
+				uc := NewCartUsecase(cartRepo, prodRepo, WithSavedListRepository(listRepo))
+				res, err := uc.MoveToCart("123", enum.Wishlist, "002")
+				So(err, ShouldBeNil)
+				moved := res.(*MovedItem)
+				So(moved.Item.Price, ShouldEqual, 180)
+				So(moved.SavedPrice, ShouldEqual, 175.25)
+				So(moved.PriceChanged, ShouldBeTrue)
+				cartRepo.AssertCalled(t, "AddToCart", "u01", mock.MatchedBy(func(item *CartItem) bool {
+					return item.SKU == "002" && item.Qty == 1 && item.Price == 180
+				}), mock.Anything)
+				listRepo.AssertCalled(t, "Save", mock.MatchedBy(func(l *SavedList) bool {
+					return l.Name == enum.Wishlist && len(l.Items) == 0
+				}))
+			})
+			Convey("-> Saving a cart item for later should take it out of the cart", func() {
+				cartRepo.On("FetchUserCart", "123").Return(userCart(), nil)
+				listRepo.On("FetchSavedList", "123", "saved_for_later").Return(savedForLater(), nil)
+				prodRepo.On("FindByProductID", "001").Return(&prodUsecase.Product{ID: "001", Name: "Shuriken", Stock: 0, Price: 250.5}, nil)
+				listRepo.On("Save", mock.Anything).Return(nil)
+				cartRepo.On("RemoveItem", "u01", "001", mock.Anything).Return(nil)
+				uc := NewCartUsecase(cartRepo, prodRepo, WithSavedListRepository(listRepo))
+				So(uc.MoveToSavedForLater("123", "001"), ShouldBeNil)
+				listRepo.AssertCalled(t, "Save", mock.MatchedBy(func(l *SavedList) bool {
+					return l.Name == enum.SavedForLater && len(l.Items) == 1 && l.Items[0].Qty == 2
+				}))
+				cartRepo.AssertCalled(t, "RemoveItem", "u01", "001", mock.Anything)
+			})
+		})
+	})
+
+	Convey("12. Given a user refreshes his cart", t, func() {
+
+		cartRepo := &mockRepo.MockCartRepository{}
+		prodRepo := &mockRepo.MockProductRepository{}
+
+		userCart := func() *Cart {
+			return &Cart{
+				ID: "u01", UserID: "123", Status: enum.Open,
+				Items: []*CartItem{
+					{ID: "001", Name: "Shuriken", SKU: "001", Qty: 2, Price: 250.5},
+					{ID: "002", Name: "Sai", SKU: "002", Qty: 1, Price: 175.25},
+				},
+			}
+		}
+
+		Convey("-> Negative Scenarios", func() {
+			Convey("-> Should only refresh an open cart", func() {
+				cart := userCart()
+				cart.Status = enum.PaymentProcessing
+				cartRepo.On("FetchUserCart", "123").Return(cart, nil)
+				uc := NewCartUsecase(cartRepo, prodRepo)
+				res, err := uc.RefreshCart("123")
+				So(res, ShouldBeNil)
+				So(err.Error(), ShouldEqual, "cannot refresh a cart with status as payment_processing")
+			})
+			Convey("-> Should return the error raised by the system repository", func() {
+				cartRepo.On("FetchUserCart", "123").Return(userCart(), nil)
+				prodRepo.On("FindByProductID", "001").Return(nil, errors.New("Database error"))
+				uc := NewCartUsecase(cartRepo, prodRepo)
+				_, err := uc.RefreshCart("123")
+				So(err.Error(), ShouldEqual, "Database error")
+			})
+		})
+
+		Convey("-> Positive Scenarios", func() {
+			Convey("-> Should reprice the lines and tell about the products gone", func() {
+				cartRepo.On("FetchUserCart", "123").Return(userCart(), nil)
+				cartRepo.On("UpdateItem", "u01", mock.Anything, mock.Anything).Return(nil)
+				prodRepo.On("FindByProductID", "001").Return(&prodUsecase.Product{ID: "001", Name: "Shuriken", Stock: 10, Price: 250.5, Disc: 50.5}, nil)
+				prodRepo.On("FindByProductID", "002").Return(nil, e.NewErrNoData("no product found"))
+				uc := NewCartUsecase(cartRepo, prodRepo)
+				res, err := uc.RefreshCart("123")
+				So(err, ShouldBeNil)
+				refresh := res.(*CartRefresh)
+				So(refresh.Cart.Items[0].Disc, ShouldEqual, 50.5)
+				So(refresh.Changes, ShouldHaveLength, 2)
+				So(refresh.Changes[0].Kind, ShouldEqual, enum.PriceDecreased)
+				So(refresh.Changes[0].Notice, ShouldEqual, "the price of Shuriken has dropped from 250.50 to 200.00")
+				So(refresh.Changes[1].Kind, ShouldEqual, enum.Unavailable)
+				So(refresh.Changes[1].Notice, ShouldEqual, "Sai is no longer available, please remove it from the cart")
+				cartRepo.AssertNumberOfCalls(t, "UpdateItem", 1)
+			})
+		})
+	})
+
+	Convey("13. Given a user buys a product with purchase limits", t, func() {
+
+		cartRepo := &mockRepo.MockCartRepository{}
+		prodRepo := &mockRepo.MockProductRepository{}
+		clock := &mockService.MockClock{}
+
+		now := time.Date(2020, time.May, 1, 12, 0, 0, 0, time.UTC)
+		clock.On("Now").Return(now)
+		checkedOutAt := now.Add(-48 * time.Hour)
+		shuriken := &prodUsecase.Product{
+			ID: "001", Name: "Shuriken", Stock: 20, Price: 250.5,
+			PurchaseLimit: &prodUsecase.PurchaseLimit{MaxPerOrder: 5, MaxPerCustomer: 10, Period: 7 * 24 * time.Hour},
+		}
+		cartRepo.On("FetchUserCart", "123").Return(&Cart{
+			ID: "u01", UserID: "123", Status: enum.Open,
+			Items: []*CartItem{{ID: "001", Name: "Shuriken", SKU: "001", Qty: 4, Price: 250.5}},
+		}, nil)
+		prodRepo.On("FindByProductID", "001").Return(shuriken, nil)
+
+		Convey("-> Negative Scenarios", func() {
+			Convey("-> Should not raise the quantity over the order limit", func() {
+				uc := NewCartUsecase(cartRepo, prodRepo)
+				err := uc.UpdateItemQty("123", "001", 6)
+				So(err, ShouldHaveSameTypeAs, &e.ErrOrderLimitExceeded{})
+				So(err.Error(), ShouldEqual, "cannot order more than 5 of Shuriken at once")
+				cartRepo.AssertNotCalled(t, "UpdateItem", mock.Anything, mock.Anything, mock.Anything)
+			})
+			Convey("-> Should not check out more than the customer limit over the period", func() {
+				cartRepo.On("FetchOrders", "123", now.Add(-7*24*time.Hour)).Return([]*Cart{{
+					ID: "o01", UserID: "123", Status: enum.Closed, CheckedOutAt: &checkedOutAt,
+					Items: []*CartItem{{ID: "001", Name: "Shuriken", SKU: "001", Qty: 7, Price: 250.5}},
+				}}, nil)
+				uc := NewCartUsecase(cartRepo, prodRepo, WithClock(clock))
+				res, err := uc.Checkout("123")
+				So(res, ShouldBeNil)
+				So(err, ShouldHaveSameTypeAs, &e.ErrCustomerLimitExceeded{})
+				So(err.Error(), ShouldEqual, "cannot buy more than 10 of Shuriken within 7 days, 7 already bought")
+				cartRepo.AssertNotCalled(t, "Checkout", mock.Anything, mock.Anything)
+			})
+		})
+
+		Convey("-> Positive Scenarios", func() {
+			Convey("-> Should check out within the limits", func() {
+				cartRepo.On("FetchOrders", "123", mock.Anything).Return([]*Cart{}, nil)
+				cartRepo.On("Checkout", "u01", now).Return(nil)
+				cartRepo.On("RecordShipments", "u01", mock.Anything).Return(nil)
+				prodRepo.On("Update", shuriken).Return(nil)
+				uc := NewCartUsecase(cartRepo, prodRepo, WithClock(clock))
+				res, err := uc.Checkout("123")
+				So(err, ShouldBeNil)
+				So(res.(*Cart).Status, ShouldEqual, enum.PaymentProcessing)
+			})
+		})
+	})
+
+	Convey("14. Given a user buys a product on a flash sale", t, func() {
+
+		cartRepo := &mockRepo.MockCartRepository{}
+		prodRepo := &mockRepo.MockProductRepository{}
+		clock := &mockService.MockClock{}
+
+		now := time.Date(2020, time.May, 1, 12, 0, 0, 0, time.UTC)
+		clock.On("Now").Return(now)
+		shuriken := &prodUsecase.Product{
+			ID: "001", Name: "Shuriken", Stock: 20, Price: 250.5,
+			PriceSchedules: []*prodUsecase.PriceSchedule{
+				{ID: "1", Price: 200, Disc: 10, StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour), Quantity: 5, Sold: 1},
+			},
+		}
+		prodRepo.On("FindByProductID", "001").Return(shuriken, nil)
+		emptyCart := &Cart{ID: "u01", UserID: "123", Status: enum.Open, Items: []*CartItem{}}
+		cart := func(qty int, price float64, disc float64) *Cart {
+			return &Cart{
+				ID: "u01", UserID: "123", Status: enum.Open,
+				Items: []*CartItem{{ID: "001", Name: "Shuriken", SKU: "001", Qty: qty, Price: price, Disc: disc}},
+			}
+		}
+
+		Convey("-> Negative Scenarios", func() {
+			Convey("-> Should add the item at the regular price once the sale is over", func() {
+				cartRepo.On("FetchUserCart", "123").Return(emptyCart, nil)
+				cartRepo.On("AddToCart", "u01", mock.Anything, mock.Anything).Return(nil)
+				clock = &mockService.MockClock{}
+				clock.On("Now").Return(now.Add(time.Hour))
+				uc := NewCartUsecase(cartRepo, prodRepo, WithClock(clock))
+				So(uc.AddToCart("123", &CartItem{ID: "001", Qty: 2}), ShouldBeNil)
+				added := cartRepo.Calls[len(cartRepo.Calls)-1].Arguments.Get(1).(*CartItem)
+				So(added.Price, ShouldEqual, 250.5)
+				So(added.Disc, ShouldEqual, 0)
+			})
+			Convey("-> Should ask for a review when the sale units ran out before the checkout", func() {
+				shuriken.PriceSchedules[0].Sold = 4
+				cartRepo.On("FetchUserCart", "123").Return(cart(2, 200, 10), nil)
+				cartRepo.On("UpdateItem", "u01", mock.Anything, mock.Anything).Return(nil)
+				uc := NewCartUsecase(cartRepo, prodRepo, WithClock(clock))
+				res, err := uc.Checkout("123")
+				So(res, ShouldBeNil)
+				So(err, ShouldHaveSameTypeAs, &e.ErrConflict{})
+				cartRepo.AssertNotCalled(t, "Checkout", mock.Anything, mock.Anything)
+			})
+		})
+
+		Convey("-> Positive Scenarios", func() {
+			Convey("-> Should add the item at the sale price", func() {
+				cartRepo.On("FetchUserCart", "123").Return(emptyCart, nil)
+				cartRepo.On("AddToCart", "u01", mock.Anything, mock.Anything).Return(nil)
+				uc := NewCartUsecase(cartRepo, prodRepo, WithClock(clock))
+				So(uc.AddToCart("123", &CartItem{ID: "001", Qty: 2}), ShouldBeNil)
+				added := cartRepo.Calls[len(cartRepo.Calls)-1].Arguments.Get(1).(*CartItem)
+				So(added.Price, ShouldEqual, 200)
+				So(added.Disc, ShouldEqual, 10)
+			})
+			Convey("-> Should count the units checked out against the sale", func() {
+				cartRepo.On("FetchUserCart", "123").Return(cart(2, 200, 10), nil)
+				cartRepo.On("Checkout", "u01", mock.Anything).Return(nil)
+				cartRepo.On("RecordShipments", "u01", mock.Anything).Return(nil)
+				prodRepo.On("Update", shuriken).Return(nil)
+				uc := NewCartUsecase(cartRepo, prodRepo, WithClock(clock))
+				res, err := uc.Checkout("123")
+				So(err, ShouldBeNil)
+				So(res.(*Cart).Status, ShouldEqual, enum.PaymentProcessing)
+				So(shuriken.Stock, ShouldEqual, 18)
+				So(shuriken.PriceSchedules[0].Sold, ShouldEqual, 3)
+			})
+		})
+	})
+
+	Convey("15. Given a user checks out a cart shipped from several warehouses", t, func() {
+
+		cartRepo := &mockRepo.MockCartRepository{}
+		prodRepo := &mockRepo.MockProductRepository{}
+		userRepo := &mockRepo.MockUserRepository{}
+		warehouseRepo := &mockRepo.MockWarehouseRepository{}
+
+		shuriken := &prodUsecase.Product{
+			ID: "001", Name: "Shuriken", Stock: 13, Price: 250.5,
+			WarehouseStock: map[string]int{"main": 10, "medan": 3},
+		}
+		sai := &prodUsecase.Product{
+			ID: "002", Name: "Sai", Stock: 2, Price: 175.25,
+			WarehouseStock: map[string]int{"main": 1, "medan": 1},
+		}
+		prodRepo.On("FindByProductID", "001").Return(shuriken, nil)
+		prodRepo.On("FindByProductID", "002").Return(sai, nil)
+		prodRepo.On("Update", mock.Anything).Return(nil)
+		cartRepo.On("FetchUserCart", "123").Return(&Cart{
+			ID: "u01", UserID: "123", Status: enum.Open,
+			Items: []*CartItem{
+				{ID: "001", Name: "Shuriken", SKU: "001", Qty: 5, Price: 250.5},
+				{ID: "002", Name: "Sai", SKU: "002", Qty: 2, Price: 175.25},
+			},
+		}, nil)
+		userRepo.On("FindByUserID", "123").Return(&userUsecase.User{
+			ID: "123", ShippingAddr: &userUsecase.Address{Street: "Jl. Gatot Subroto", City: "Medan", Postal: "20112", Country: "Indonesia"},
+		}, nil)
+		warehouseRepo.On("FetchAll").Return([]*prodUsecase.Warehouse{
+			{ID: "main", Name: "Jakarta Main Warehouse", City: "Jakarta", Postal: "12750", Country: "Indonesia"},
+			{ID: "medan", Name: "Medan Warehouse", City: "Medan", Postal: "20111", Country: "Indonesia"},
+		}, nil)
+		newCartUsecase := func() *CartUsecase {
+			return NewCartUsecase(cartRepo, prodRepo, WithUserRepository(userRepo), WithWarehouseRepository(warehouseRepo))
+		}
+
+		Convey("-> Negative Scenarios", func() {
+			Convey("-> Should give the stock back to its warehouses when the shipments cannot be recorded", func() {
+				cartRepo.On("RecordShipments", "u01", mock.Anything).Return(errors.New("Database error"))
+				res, err := newCartUsecase().Checkout("123")
+				So(res, ShouldBeNil)
+				So(err.Error(), ShouldEqual, "Database error")
+				So(shuriken.WarehouseStock, ShouldResemble, map[string]int{"main": 10, "medan": 3})
+				So(sai.WarehouseStock, ShouldResemble, map[string]int{"main": 1, "medan": 1})
+				cartRepo.AssertNotCalled(t, "Checkout", mock.Anything, mock.Anything)
+			})
+			Convey("-> Should clear the shipments when the checkout fails", func() {
+				cartRepo.On("RecordShipments", "u01", mock.Anything).Return(nil)
+				cartRepo.On("Checkout", "u01", mock.Anything).Return(errors.New("Database error"))
+				res, err := newCartUsecase().Checkout("123")
+				So(res, ShouldBeNil)
+				So(err.Error(), ShouldEqual, "Database error")
+				So(shuriken.Stock, ShouldEqual, 13)
+				cartRepo.AssertCalled(t, "RecordShipments", "u01", nil)
+			})
+		})
+
+		Convey("-> Positive Scenarios", func() {
+			Convey("-> Should split the cart into the fewest shipments, the nearest warehouse first on a tie", func() {
+				cartRepo.On("RecordShipments", "u01", mock.Anything).Return(nil)
+				cartRepo.On("Checkout", "u01", mock.Anything).Return(nil)
+				res, err := newCartUsecase().Checkout("123")
+				So(err, ShouldBeNil)
+				shipments := res.(*Cart).Shipments
+				So(shipments, ShouldHaveLength, 2)
+				So(shipments[0].WarehouseID, ShouldEqual, "main")
+				So(shipments[0].Items, ShouldHaveLength, 2)
+				So(shipments[1].WarehouseID, ShouldEqual, "medan")
+				So(shipments[1].Items, ShouldResemble, []*CartItemComponent{{ID: "002", Name: "Sai", SKU: "002", Qty: 1}})
+				cartRepo.AssertCalled(t, "RecordShipments", "u01", shipments)
+
+				So(shuriken.Stock, ShouldEqual, 8)
+				So(shuriken.WarehouseStock, ShouldResemble, map[string]int{"main": 5, "medan": 3})
+				So(sai.Stock, ShouldEqual, 0)
+				So(sai.WarehouseStock, ShouldBeEmpty)
+			})
+			Convey("-> Should ship from the nearest warehouse holding the whole cart", func() {
+				shuriken.Stock, shuriken.WarehouseStock["medan"] = 15, 5
+				sai.Stock, sai.WarehouseStock["medan"] = 3, 2
+				cartRepo.On("RecordShipments", "u01", mock.Anything).Return(nil)
+				cartRepo.On("Checkout", "u01", mock.Anything).Return(nil)
+				res, err := newCartUsecase().Checkout("123")
+				So(err, ShouldBeNil)
+				shipments := res.(*Cart).Shipments
+				So(shipments, ShouldHaveLength, 1)
+				So(shipments[0].WarehouseID, ShouldEqual, "medan")
+				So(shuriken.WarehouseStock, ShouldResemble, map[string]int{"main": 10})
+			})
+			Convey("-> Should give the stock back to the warehouses it was shipped from upon expiry", func() {
+				clock := &mockService.MockClock{}
+				now := time.Date(2020, time.May, 1, 12, 0, 0, 0, time.UTC)
+				clock.On("Now").Return(now)
+				cartRepo.On("FetchIdleCarts", now.Add(-time.Hour)).Return([]*Cart{{
+					ID: "u01", UserID: "123", Status: enum.PaymentProcessing,
+					Items: []*CartItem{{ID: "001", Name: "Shuriken", SKU: "001", Qty: 2, Price: 250.5}},
+					Shipments: []*Shipment{
+						{WarehouseID: "medan", Items: []*CartItemComponent{{ID: "001", Name: "Shuriken", SKU: "001", Qty: 2}}},
+					},
+				}}, nil)
+				cartRepo.On("Canceled", "u01", mock.Anything).Return(nil)
+				uc := NewCartUsecase(cartRepo, prodRepo, WithCartTTL(time.Hour), WithClock(clock))
+				_, err := uc.ExpireIdleCarts()
+				So(err, ShouldBeNil)
+				So(shuriken.Stock, ShouldEqual, 15)
+				So(shuriken.WarehouseStock, ShouldResemble, map[string]int{"main": 10, "medan": 5})
+			})
+		})
+	})
+
+	Convey("16. Given a user orders products which are backordered or pre-ordered", t, func() {
+
+		cartRepo := &mockRepo.MockCartRepository{}
+		prodRepo := &mockRepo.MockProductRepository{}
+		clock := &mockService.MockClock{}
+
+		now := time.Date(2020, time.May, 1, 12, 0, 0, 0, time.UTC)
+		releasedAt := time.Date(2020, time.June, 1, 0, 0, 0, 0, time.UTC)
+		clock.On("Now").Return(now)
+		shuriken := &prodUsecase.Product{
+			ID: "001", Name: "Shuriken", Stock: 2, Price: 250.5,
+			StockPolicy: &prodUsecase.StockPolicy{Mode: enum.Backorder, Limit: 5},
+		}
+		kunai := &prodUsecase.Product{
+			ID: "003", Name: "Kunai", Stock: 0, Price: 99.5,
+			StockPolicy: &prodUsecase.StockPolicy{Mode: enum.PreOrder, AvailableAt: &releasedAt},
+		}
+		prodRepo.On("FindByProductID", "001").Return(shuriken, nil)
+		prodRepo.On("FindByProductID", "003").Return(kunai, nil)
+		prodRepo.On("Update", mock.Anything).Return(nil)
+		cart := &Cart{
+			ID: "u01", UserID: "123", Status: enum.Open,
+			Items: []*CartItem{
+				{ID: "001", Name: "Shuriken", SKU: "001", Qty: 4, Price: 250.5, Fulfillment: enum.Backorder},
+				{ID: "003", Name: "Kunai", SKU: "003", Qty: 1, Price: 99.5, Fulfillment: enum.PreOrder, AvailableAt: &releasedAt},
+			},
+		}
+		cartRepo.On("FetchUserCart", "123").Return(cart, nil)
+		cartRepo.On("UpdateItem", "u01", mock.Anything, mock.Anything).Return(nil)
+		newCartUsecase := func() *CartUsecase {
+			return NewCartUsecase(cartRepo, prodRepo, WithClock(clock))
+		}
+
+		Convey("-> Negative Scenarios", func() {
+			Convey("-> Should ask for a review once a line starts waiting for the stock", func() {
+				cart.Items[0].Fulfillment = ""
+				res, err := newCartUsecase().Checkout("123")
+				So(res, ShouldBeNil)
+				So(err, ShouldHaveSameTypeAs, &e.ErrConflict{})
+				So(err.Error(), ShouldContainSubstring, "only 2 of Shuriken in stock, the other 2 are backordered and expected once the stock is replenished")
+				cartRepo.AssertCalled(t, "UpdateItem", "u01", mock.MatchedBy(func(item *CartItem) bool {
+					return item.SKU == "001" && item.Fulfillment == enum.Backorder
+				}), mock.Anything)
+				cartRepo.AssertNotCalled(t, "Checkout", mock.Anything, mock.Anything)
+			})
+			Convey("-> Should refuse the units beyond the limit of the backorder", func() {
+				err := newCartUsecase().UpdateItemQty("123", "001", 8)
+				So(err.Error(), ShouldEqual, "out of stock, not enough Shuriken left")
+			})
+			Convey("-> Should refuse the checkout once the backorder runs out of units", func() {
+				shuriken.StockPolicy.Committed = 4
+				res, err := newCartUsecase().Checkout("123")
+				So(res, ShouldBeNil)
+				So(err.Error(), ShouldContainSubstring, "only 3 of Shuriken left, please lower the quantity from 4")
+				So(shuriken.Stock, ShouldEqual, 2)
+			})
+		})
+
+		Convey("-> Positive Scenarios", func() {
+			Convey("-> Should flag the line added beyond the stock on hand", func() {
+				cart.Items = make([]*CartItem, 0)
+				cartRepo.On("AddToCart", "u01", mock.Anything, mock.Anything).Return(nil)
+				err := newCartUsecase().AddToCart("123", &CartItem{ID: "001", Qty: 3})
+				So(err, ShouldBeNil)
+				cartRepo.AssertCalled(t, "AddToCart", "u01", mock.MatchedBy(func(item *CartItem) bool {
+					return item.Qty == 3 && item.Fulfillment == enum.Backorder
+				}), mock.Anything)
+				err = newCartUsecase().AddToCart("123", &CartItem{ID: "003", Qty: 1})
+				So(err, ShouldBeNil)
+				cartRepo.AssertCalled(t, "AddToCart", "u01", mock.MatchedBy(func(item *CartItem) bool {
+					return item.ID == "003" && item.Fulfillment == enum.PreOrder && item.AvailableAt.Equal(releasedAt)
+				}), mock.Anything)
+			})
+			Convey("-> Should ship the units waiting for the stock apart from the stock on hand", func() {
+				cartRepo.On("RecordShipments", "u01", mock.Anything).Return(nil)
+				cartRepo.On("Checkout", "u01", mock.Anything).Return(nil)
+				res, err := newCartUsecase().Checkout("123")
+				So(err, ShouldBeNil)
+				shipments := res.(*Cart).Shipments
+				So(shipments, ShouldHaveLength, 3)
+				So(shipments[0].WarehouseID, ShouldEqual, "main")
+				So(shipments[0].Items, ShouldResemble, []*CartItemComponent{{ID: "001", Name: "Shuriken", SKU: "001", Qty: 2}})
+				So(shipments[1].WarehouseID, ShouldBeEmpty)
+				So(shipments[1].Fulfillment, ShouldEqual, enum.Backorder)
+				So(shipments[1].Items, ShouldResemble, []*CartItemComponent{{ID: "001", Name: "Shuriken", SKU: "001", Qty: 2}})
+				So(shipments[2].Fulfillment, ShouldEqual, enum.PreOrder)
+				So(*shipments[2].AvailableAt, ShouldEqual, releasedAt)
+
+				So(shuriken.Stock, ShouldEqual, 0)
+				So(shuriken.StockPolicy.Committed, ShouldEqual, 2)
+				So(kunai.Stock, ShouldEqual, 0)
+				So(kunai.StockPolicy.Committed, ShouldEqual, 1)
+			})
+			Convey("-> Should no longer count the units waiting for the stock upon expiry", func() {
+				shuriken.StockPolicy.Committed, kunai.StockPolicy.Committed = 2, 1
+				cartRepo.On("FetchIdleCarts", now.Add(-time.Hour)).Return([]*Cart{{
+					ID: "u01", UserID: "123", Status: enum.PaymentProcessing, Items: cart.Items,
+					Shipments: []*Shipment{
+						{WarehouseID: "main", Items: []*CartItemComponent{{ID: "001", Name: "Shuriken", SKU: "001", Qty: 2}}},
+						{Fulfillment: enum.Backorder, Items: []*CartItemComponent{{ID: "001", Name: "Shuriken", SKU: "001", Qty: 2}}},
+						{Fulfillment: enum.PreOrder, AvailableAt: &releasedAt, Items: []*CartItemComponent{{ID: "003", Name: "Kunai", SKU: "003", Qty: 1}}},
+					},
+				}}, nil)
+				cartRepo.On("Canceled", "u01", mock.Anything).Return(nil)
+				uc := NewCartUsecase(cartRepo, prodRepo, WithCartTTL(time.Hour), WithClock(clock))
+				_, err := uc.ExpireIdleCarts()
+				So(err, ShouldBeNil)
+				So(shuriken.Stock, ShouldEqual, 4)
+				So(shuriken.StockPolicy.Committed, ShouldEqual, 0)
+				So(kunai.Stock, ShouldEqual, 0)
+				So(kunai.StockPolicy.Committed, ShouldEqual, 0)
+			})
+		})
+	})
+
+	Convey("17. Given a user redeems loyalty points upon checkout and settles the payment", t, func() {
+
+		cartRepo := &mockRepo.MockCartRepository{}
+		prodRepo := &mockRepo.MockProductRepository{}
+		loyalty := &mockService.MockLoyaltyProgram{}
+		publisher := &mockService.MockEventPublisher{}
+		clock := &mockService.MockClock{}
+
+		now := time.Date(2020, time.May, 1, 12, 0, 0, 0, time.UTC)
+		clock.On("Now").Return(now)
+		shuriken := &prodUsecase.Product{ID: "001", Name: "Shuriken", Stock: 10, Price: 250.5}
+		prodRepo.On("FindByProductID", "001").Return(shuriken, nil)
+		prodRepo.On("Update", shuriken).Return(nil)
+		items := []*CartItem{{ID: "001", Name: "Shuriken", Qty: 2, Price: 250.5}}
+		cartRepo.On("FetchUserCart", "123").Return(&Cart{ID: "001", UserID: "123", Status: enum.Open, Items: items}, nil)
+		cartRepo.On("RecordShipments", "001", mock.Anything).Return(nil)
+		publisher.On("Publish", mock.Anything).Return(nil)
+		newCartUsecase := func() *CartUsecase {
+			return NewCartUsecase(cartRepo, prodRepo, WithLoyaltyProgram(loyalty), WithEventPublisher(publisher), WithClock(clock))
+		}
+		order := func(status enum.CartStatus) *Cart {
+			return &Cart{
+				ID: "001", UserID: "123", Status: status, Items: items,
+				Redemption: &PointsRedemption{Points: 100, Amount: 100},
+			}
+		}
+
+		Convey("-> Negative Scenarios", func() {
+			Convey("-> Should refuse to redeem points without any loyalty program", func() {
+				res, err := NewCartUsecase(cartRepo, prodRepo).CheckoutWithPoints("123", 100)
+				So(res, ShouldBeNil)
+				So(err, ShouldHaveSameTypeAs, &e.ErrInvalidData{})
+			})
+			Convey("-> Should give the reserved stock back when the points cannot be redeemed", func() {
+				loyalty.On("RedeemPoints", "123", "001", 5000, 501.0).Return(0.0, e.NewErrConflict("cannot redeem 5000 points, only 120 left"))
+				res, err := newCartUsecase().CheckoutWithPoints("123", 5000)
+				So(res, ShouldBeNil)
+				So(err, ShouldHaveSameTypeAs, &e.ErrConflict{})
+				So(shuriken.Stock, ShouldEqual, 10)
+				loyalty.AssertNotCalled(t, "RestorePoints", mock.Anything, mock.Anything)
+				cartRepo.AssertNotCalled(t, "Checkout", mock.Anything, mock.Anything)
+			})
+			Convey("-> Should restore the redeemed points when the checkout falls through", func() {
+				loyalty.On("RedeemPoints", "123", "001", 100, 501.0).Return(100.0, nil)
+				loyalty.On("RestorePoints", "123", "001").Return(nil)
+				cartRepo.On("RecordRedemption", "001", mock.Anything).Return(nil)
+				cartRepo.On("Checkout", "001", mock.Anything).Return(errors.New("Database error"))
+				res, err := newCartUsecase().CheckoutWithPoints("123", 100)
+				So(res, ShouldBeNil)
+				So(err.Error(), ShouldEqual, "Database error")
+				So(shuriken.Stock, ShouldEqual, 10)
+				loyalty.AssertCalled(t, "RestorePoints", "123", "001")
+				cartRepo.AssertCalled(t, "RecordRedemption", "001", nil)
+			})
+			Convey("-> Should only let an admin settle the payment of a cart", func() {
+				uc := newCartUsecase().ForPrincipal(&authUsecase.Principal{UserID: "123", Role: enum.Customer})
+				res, err := uc.CloseCart("123", "001")
+				So(res, ShouldBeNil)
+				So(err, ShouldHaveSameTypeAs, &e.ErrForbidden{})
+				cartRepo.AssertNotCalled(t, "Close", mock.Anything, mock.Anything)
+			})
+			Convey("-> Should refuse to refund a cart whose payment is not settled", func() {
+				cartRepo.On("FetchOrders", "123", time.Time{}).Return([]*Cart{order(enum.PaymentProcessing)}, nil)
+				res, err := newCartUsecase().RefundCart("123", "001")
+				So(res, ShouldBeNil)
+				So(err.Error(), ShouldEqual, "failed to refund cart with ID of 001. It is payment_processing, only a closed cart can be refunded")
+				cartRepo.AssertNotCalled(t, "Refunded", mock.Anything)
+			})
+			Convey("-> Should refuse to close a closed cart again", func() {
+				cartRepo.On("FetchOrders", "123", time.Time{}).Return([]*Cart{order(enum.Closed)}, nil)
+				res, err := newCartUsecase().CloseCart("123", "001")
+				So(res, ShouldBeNil)
+				So(err.Error(), ShouldEqual, "failed to close cart with ID of 001. It is closed, please settle the payment first")
+				cartRepo.AssertNotCalled(t, "Close", mock.Anything, mock.Anything)
+				publisher.AssertNotCalled(t, "Publish", mock.Anything)
+			})
+			Convey("-> Should refuse to republish the closing of a cart not closed yet", func() {
+				cartRepo.On("FetchOrders", "123", time.Time{}).Return([]*Cart{order(enum.PaymentProcessing)}, nil)
+				res, err := newCartUsecase().RepublishClosing("123", "001")
+				So(res, ShouldBeNil)
+				So(err.Error(), ShouldEqual, "failed to publish the closing of cart with ID of 001. It is payment_processing, please close it first")
+				publisher.AssertNotCalled(t, "Publish", mock.Anything)
+			})
+			Convey("-> Should return ErrNoData for an unknown order", func() {
+				cartRepo.On("FetchOrders", "123", time.Time{}).Return([]*Cart{}, nil)
+				res, err := newCartUsecase().CloseCart("123", "404")
+				So(res, ShouldBeNil)
+				So(err, ShouldHaveSameTypeAs, &e.ErrNoData{})
+			})
+		})
+
+		Convey("-> Positive Scenarios", func() {
+			Convey("-> Should take the worth of the points off what is left to pay", func() {
+				loyalty.On("RedeemPoints", "123", "001", 100, 501.0).Return(100.0, nil)
+				cartRepo.On("RecordRedemption", "001", mock.Anything).Return(nil)
+				cartRepo.On("Checkout", "001", mock.Anything).Return(nil)
+				res, err := newCartUsecase().CheckoutWithPoints("123", 100)
+				So(err, ShouldBeNil)
+				cart := res.(*Cart)
+				So(cart.Status, ShouldEqual, enum.PaymentProcessing)
+				So(cart.Redemption, ShouldResemble, &PointsRedemption{Points: 100, Amount: 100})
+				So(cart.Totals().Redeemed, ShouldEqual, 100)
+				So(cart.Totals().Due, ShouldEqual, 401)
+				cartRepo.AssertCalled(t, "RecordRedemption", "001", &PointsRedemption{Points: 100, Amount: 100})
+			})
+			Convey("-> Closing the cart should publish what was paid for it", func() {
+				cartRepo.On("FetchOrders", "123", time.Time{}).Return([]*Cart{order(enum.PaymentProcessing)}, nil)
+				cartRepo.On("Close", "001", now).Return(nil)
+				res, err := newCartUsecase().CloseCart("123", "001")
+				So(err, ShouldBeNil)
+				So(res.(*Cart).Status, ShouldEqual, enum.Closed)
+				publisher.AssertCalled(t, "Publish", &CartClosed{
+					CartID: "001", UserID: "123", Items: items, Net: 501, Redeemed: 100, ClosedAt: now,
+				})
+			})
+			Convey("-> Republishing the closing should keep the time the cart was closed", func() {
+				closed := order(enum.Closed)
+				closedAt := now.Add(-time.Hour)
+				closed.ClosedAt = &closedAt
+				cartRepo.On("FetchOrders", "123", time.Time{}).Return([]*Cart{closed}, nil)
+				res, err := newCartUsecase().RepublishClosing("123", "001")
+				So(err, ShouldBeNil)
+				So(res.(*Cart).Status, ShouldEqual, enum.Closed)
+				cartRepo.AssertNotCalled(t, "Close", mock.Anything, mock.Anything)
+				publisher.AssertCalled(t, "Publish", &CartClosed{
+					CartID: "001", UserID: "123", Items: items, Net: 501, Redeemed: 100, ClosedAt: closedAt,
+				})
+			})
+			Convey("-> Refunding a closed cart should publish the refund", func() {
+				cartRepo.On("FetchOrders", "123", time.Time{}).Return([]*Cart{order(enum.Closed)}, nil)
+				cartRepo.On("Refunded", "001").Return(nil)
+				res, err := newCartUsecase().ForPrincipal(&authUsecase.Principal{UserID: "admin", Role: enum.Admin}).RefundCart("123", "001")
+				So(err, ShouldBeNil)
+				So(res.(*Cart).Status, ShouldEqual, enum.Refunded)
+				publisher.AssertCalled(t, "Publish", &CartRefunded{CartID: "001", UserID: "123", RefundedAt: now})
+			})
+			Convey("-> The cart should be settled even when the event cannot be published", func() {
+				failing := &mockService.MockEventPublisher{}
+				failing.On("Publish", mock.Anything).Return(errors.New("bus down"))
+				cartRepo.On("FetchOrders", "123", time.Time{}).Return([]*Cart{order(enum.PaymentProcessing)}, nil)
+				cartRepo.On("Close", "001", now).Return(nil)
+				res, err := NewCartUsecase(cartRepo, prodRepo, WithEventPublisher(failing), WithClock(clock)).CloseCart("123", "001")
+				So(res.(*Cart).Status, ShouldEqual, enum.Closed)
+				So(err.Error(), ShouldEqual, "cart 001: cannot publish the closing: bus down")
+			})
+			Convey("-> An expired checkout should give the redeemed points back", func() {
+				cartRepo.On("FetchIdleCarts", now.Add(-time.Hour)).Return([]*Cart{order(enum.PaymentProcessing)}, nil)
+				cartRepo.On("Canceled", "001", mock.Anything).Return(nil)
+				loyalty.On("RestorePoints", "123", "001").Return(nil)
+				uc := NewCartUsecase(cartRepo, prodRepo, WithLoyaltyProgram(loyalty), WithEventPublisher(publisher),
+					WithCartTTL(time.Hour), WithClock(clock))
+				_, err := uc.ExpireIdleCarts()
+				So(err, ShouldBeNil)
+				So(shuriken.Stock, ShouldEqual, 12)
+				publisher.AssertCalled(t, "Publish", mock.MatchedBy(func(ev *CartExpired) bool {
+					return ev.RestoredPoints == 100
+				}))
+			})
 		})
 	})
 }
@@ -1,8 +1,31 @@
 package carts
 
+import (
+	. "github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+)
+
 type CartInputPort interface {
+	OpenCart(userID string) error
 	FetchUserCart(userID string) (interface{}, error)
+	FetchLatestCart(userID string) (interface{}, error)
 	AddToCart(userID string, item interface{}) error
+	RemoveFromCart(userID string, sku string) error
+	UpdateItemQty(userID string, sku string, qty int) error
+	CancelCart(userID string) (interface{}, error)
+	RefreshCart(userID string) (interface{}, error)
+	Checkout(userID string) (interface{}, error)
+	CheckoutWithPoints(userID string, points int) (interface{}, error)
+	CloseCart(userID string, cartID string) (interface{}, error)
+	RepublishClosing(userID string, cartID string) (interface{}, error)
+	RefundCart(userID string, cartID string) (interface{}, error)
+	MergeCarts(guestID string, userID string) (interface{}, error)
+	FetchSavedList(userID string, name SavedListName) (interface{}, error)
+	SaveToList(userID string, name SavedListName, item interface{}) error
+	RemoveFromList(userID string, name SavedListName, sku string) error
+	MoveToCart(userID string, name SavedListName, sku string) (interface{}, error)
+	MoveToSavedForLater(userID string, sku string) error
+	ExpireIdleCarts() (interface{}, error)
+	SendRecoveryReminders() (interface{}, error)
 }
 
 type CartOutputPort interface {
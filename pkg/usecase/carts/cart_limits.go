@@ -0,0 +1,99 @@
+package carts
+
+import (
+	"time"
+
+	"github.com/yauritux/cartsvc/pkg/domain/entity"
+	vo "github.com/yauritux/cartsvc/pkg/domain/valueobject"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+	prodUsecase "github.com/yauritux/cartsvc/pkg/usecase/products"
+)
+
+// purchaseLimits collects the purchase limits of the products consumed by the items, keyed by the
+// product ID. The products without any limit, or gone since they were added, are left out.
+func (this *CartUsecase) purchaseLimits(items []*CartItem) (map[string]*vo.PurchaseLimit, error) {
+	limits := make(map[string]*vo.PurchaseLimit)
+	collect := func(productID string) error {
+		if _, ok := limits[productID]; ok {
+			return nil
+		}
+		p, err := this.prodRepo.FindByProductID(productID)
+		if err != nil {
+			if _, ok := err.(*e.ErrNoData); ok {
+				return nil
+			}
+			return err
+		}
+		product, ok := p.(*prodUsecase.Product)
+		if !ok {
+			return e.NewErrConversion("cannot check purchase limits, invalid type of product usecase model")
+		}
+		if limit := buildPurchaseLimit(product); limit != nil {
+			limits[productID] = limit
+		}
+		return nil
+	}
+
+	for _, v := range items {
+		if err := collect(v.ID); err != nil {
+			return nil, err
+		}
+		for _, c := range v.Components {
+			if err := collect(c.ID); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return limits, nil
+}
+
+// checkPurchaseLimits checks the cart against the purchase limits of its products, the orders of the
+// user are only fetched when one of them is limited per customer
+func (this *CartUsecase) checkPurchaseLimits(cart *Cart) error {
+	limits, err := this.purchaseLimits(cart.Items)
+	if err != nil || len(limits) == 0 {
+		return err
+	}
+
+	now := this.clock.Now()
+	var since *time.Time
+	for _, l := range limits {
+		if l.MaxPerCustomer <= 0 {
+			continue
+		}
+		from := time.Time{}
+		if l.Period > 0 {
+			from = now.Add(-l.Period)
+		}
+		if since == nil || from.Before(*since) {
+			since = &from
+		}
+	}
+
+	orders := make([]*entity.Cart, 0)
+	if since != nil {
+		res, err := this.cartRepo.FetchOrders(cart.UserID, *since)
+		if err != nil {
+			return err
+		}
+		carts, ok := res.([]*Cart)
+		if !ok {
+			return e.NewErrConversion("cannot check purchase limits, invalid type of cart usecase model")
+		}
+		for _, c := range carts {
+			orders = append(orders, buildUserCart(c).FetchCartInfo())
+		}
+	}
+	return buildUserCart(cart).CheckPurchaseLimits(limits, orders, now)
+}
+
+func buildPurchaseLimit(p *prodUsecase.Product) *vo.PurchaseLimit {
+	if p.PurchaseLimit == nil {
+		return nil
+	}
+	return &vo.PurchaseLimit{
+		MaxPerOrder:    p.PurchaseLimit.MaxPerOrder,
+		MaxPerCustomer: p.PurchaseLimit.MaxPerCustomer,
+		Period:         p.PurchaseLimit.Period,
+	}
+}
@@ -0,0 +1,88 @@
+package carts
+
+import (
+	"fmt"
+
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+	userUsecase "github.com/yauritux/cartsvc/pkg/usecase/users"
+)
+
+// CartMergeResult is the user cart once the guest cart is merged into it, LeftOut holds the units
+// of the guest lines which could not be merged for the lack of stock
+type CartMergeResult struct {
+	Cart    *Cart
+	LeftOut []*CartItem
+}
+
+// MergeCarts folds the open guest cart into the open cart of the user, typically upon login.
+// The lines found in both carts are settled by the merge rule and capped to the stock left,
+// the guest cart is canceled afterwards. The guest cart is not bound to the principal, the caller
+// should have authenticated the guest session beforehand.
+func (this *CartUsecase) MergeCarts(guestID string, userID string) (interface{}, error) {
+	if !userUsecase.IsGuestID(guestID) {
+		return nil, e.NewErrInvalidData(fmt.Sprintf("cannot merge the cart of %s, it is not a guest cart", guestID))
+	}
+	if userUsecase.IsGuestID(userID) {
+		return nil, e.NewErrInvalidData("cannot merge into a guest cart")
+	}
+	if err := this.OpenCart(userID); err != nil {
+		return nil, err
+	}
+
+	res, err := this.cartRepo.FetchUserCart(guestID)
+	if err != nil {
+		return nil, err
+	}
+	guestCart, ok := res.(*Cart)
+	if !ok {
+		return nil, e.NewErrConversion("cannot merge carts, invalid type of cart usecase model")
+	}
+	res, err = this.FetchUserCart(userID)
+	if err != nil {
+		return nil, err
+	}
+	userCart := res.(*Cart)
+
+	//a product gone since it was added cannot be merged at all
+	guest := buildUserCart(guestCart)
+	snapshot, err := this.snapshotStock(guest.ExpandStockDemand())
+	if err != nil {
+		return nil, err
+	}
+
+	target := buildUserCart(userCart)
+	merge, err := target.MergeCart(guest.FetchCartInfo(), this.mergeRule, snapshot.orderable())
+	if err != nil {
+		return nil, err
+	}
+	now := this.clock.Now()
+	for _, v := range merge.Added {
+		if err := this.cartRepo.AddToCart(userCart.ID, buildCartUsecaseItem(v), now); err != nil {
+			return nil, err
+		}
+	}
+	for _, v := range merge.Updated {
+		if err := this.cartRepo.UpdateItem(userCart.ID, buildCartUsecaseItem(v), now); err != nil {
+			return nil, err
+		}
+	}
+	flagged, _ := target.FlagFulfillment(snapshot.onHand, snapshot.policies)
+	for _, v := range flagged {
+		if err := this.cartRepo.UpdateItem(userCart.ID, buildCartUsecaseItem(v), now); err != nil {
+			return nil, err
+		}
+	}
+	if err := this.cartRepo.Canceled(guestCart.ID, now); err != nil {
+		return nil, err
+	}
+
+	merged, err := this.FetchUserCart(userID)
+	if err != nil {
+		return nil, err
+	}
+	result := &CartMergeResult{Cart: merged.(*Cart), LeftOut: make([]*CartItem, 0)}
+	for _, v := range merge.LeftOut {
+		result.LeftOut = append(result.LeftOut, buildCartUsecaseItem(v))
+	}
+	return result, nil
+}
@@ -0,0 +1,79 @@
+package carts
+
+import (
+	"github.com/yauritux/cartsvc/pkg/domain/aggregate"
+	vo "github.com/yauritux/cartsvc/pkg/domain/valueobject"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+	prodUsecase "github.com/yauritux/cartsvc/pkg/usecase/products"
+)
+
+// saleClaim is a number of units of a product checked out at the price of one of its flash sales
+type saleClaim struct {
+	prodID     string
+	scheduleID string
+	qty        int
+}
+
+// claimSaleUnits counts the units checked out at a flash sale price against the units of the sale,
+// it's all or nothing like the stock reservation
+func (this *CartUsecase) claimSaleUnits(demand []*vo.BundleComponent) ([]*saleClaim, error) {
+	now := this.clock.Now()
+	claims := make([]*saleClaim, 0)
+	for _, d := range demand {
+		product, err := this.findSaleProduct(d.ProdID)
+		if err != nil {
+			this.releaseSaleUnits(claims)
+			return nil, err
+		}
+		schedule := aggregate.ActivePriceSchedule(prodUsecase.BuildProductEntity(product), d.SKU, d.Qty, now)
+		if schedule == nil || schedule.Quantity <= 0 {
+			continue
+		}
+
+		claim := &saleClaim{prodID: d.ProdID, scheduleID: schedule.ID, qty: d.Qty}
+		if err := this.countSaleUnits(claim, claim.qty); err != nil {
+			this.releaseSaleUnits(claims)
+			return nil, err
+		}
+		claims = append(claims, claim)
+	}
+	return claims, nil
+}
+
+func (this *CartUsecase) releaseSaleUnits(claims []*saleClaim) {
+	for _, c := range claims {
+		//best effort, there's nothing left to roll back to if the release fails
+		_ = this.countSaleUnits(c, -c.qty)
+	}
+}
+
+func (this *CartUsecase) countSaleUnits(c *saleClaim, delta int) error {
+	product, err := this.findSaleProduct(c.prodID)
+	if err != nil {
+		return err
+	}
+	for _, s := range product.PriceSchedules {
+		if s.ID != c.scheduleID {
+			continue
+		}
+		s.Sold += delta
+		if s.Sold < 0 {
+			s.Sold = 0
+		}
+		return this.prodRepo.Update(product)
+	}
+	//the sale has been canceled in the meantime, there's nothing left to count
+	return nil
+}
+
+func (this *CartUsecase) findSaleProduct(productID string) (*prodUsecase.Product, error) {
+	p, err := this.prodRepo.FindByProductID(productID)
+	if err != nil {
+		return nil, err
+	}
+	product, ok := p.(*prodUsecase.Product)
+	if !ok {
+		return nil, e.NewErrConversion("cannot count the flash sale units, invalid type of product usecase model")
+	}
+	return product, nil
+}
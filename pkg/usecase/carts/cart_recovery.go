@@ -0,0 +1,124 @@
+package carts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	vo "github.com/yauritux/cartsvc/pkg/domain/valueobject"
+	. "github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+	userUsecase "github.com/yauritux/cartsvc/pkg/usecase/users"
+)
+
+// SendRecoveryReminders nudges the owners of the open carts left idle with some items in them,
+// it returns the reminded carts. The reminder goes by email, or by SMS to the users without any email.
+func (this *CartUsecase) SendRecoveryReminders() (interface{}, error) {
+	reminded := make([]*Cart, 0)
+	if this.notifier == nil || this.remindAfter <= 0 || this.maxReminders <= 0 {
+		return reminded, nil
+	}
+	if this.userRepo == nil {
+		return nil, errors.New("cannot send the recovery reminders without any user repository")
+	}
+
+	now := this.clock.Now()
+	cutoff := now.Add(-this.remindAfter)
+	res, err := this.cartRepo.FetchIdleCarts(cutoff)
+	if err != nil {
+		return nil, err
+	}
+	idleCarts, ok := res.([]*Cart)
+	if !ok {
+		return nil, errors.New("conversion failed, invalid type of cart list usecase model")
+	}
+
+	failures := make([]string, 0)
+	for _, cart := range idleCarts {
+		if !this.shouldRemind(cart, cutoff) {
+			continue
+		}
+		if err := this.remindCart(cart, now); err != nil {
+			failures = append(failures, fmt.Sprintf("cart %s: %v", cart.ID, err))
+			continue
+		}
+		reminded = append(reminded, cart)
+	}
+	return reminded, batchError("remind", failures)
+}
+
+// RunRecoverySweeper calls SendRecoveryReminders on every tick of the interval until ctx is done
+func (this *CartUsecase) RunRecoverySweeper(ctx context.Context, interval time.Duration, onError func(error)) {
+	runEvery(ctx, interval, func() error {
+		_, err := this.SendRecoveryReminders()
+		return err
+	}, onError)
+}
+
+func (this *CartUsecase) shouldRemind(cart *Cart, cutoff time.Time) bool {
+	//a guest cannot be reached
+	if userUsecase.IsGuestID(cart.UserID) {
+		return false
+	}
+	if cart.Status != Open || len(cart.Items) == 0 || cart.RemindersSent >= this.maxReminders {
+		return false
+	}
+	return cart.LastRemindedAt == nil || cart.LastRemindedAt.Before(cutoff)
+}
+
+func (this *CartUsecase) remindCart(cart *Cart, now time.Time) error {
+	u, err := this.userRepo.FindByUserID(cart.UserID)
+	if err != nil {
+		return err
+	}
+	user, ok := u.(*userUsecase.User)
+	if !ok {
+		return errors.New("conversion failed, invalid type of user usecase model")
+	}
+
+	n, err := renderReminder(user, cart, this.currency)
+	if err != nil {
+		return err
+	}
+	if err := this.notifier.Send(n); err != nil {
+		return err
+	}
+	//the reminder is out already, a failure to record it may only lead to one more reminder
+	if err := this.cartRepo.RecordReminder(cart.ID, now); err != nil {
+		return err
+	}
+	cart.RemindersSent++
+	cart.LastRemindedAt = &now
+	return nil
+}
+
+func reminderRecipient(user *userUsecase.User) (NotificationChannel, string, error) {
+	switch {
+	case user.Email != "":
+		return EmailChannel, user.Email, nil
+	case user.Phone != "":
+		return SMSChannel, user.Phone, nil
+	default:
+		return "", "", fmt.Errorf("user %s has neither an email nor a phone", user.ID)
+	}
+}
+
+func renderReminder(user *userUsecase.User, cart *Cart, currency string) (vo.Notification, error) {
+	channel, recipient, err := reminderRecipient(user)
+	if err != nil {
+		return vo.Notification{}, err
+	}
+	n := vo.Notification{Channel: channel, Recipient: recipient}
+	view := buildReminderView(user, cart, currency)
+
+	switch channel {
+	case EmailChannel:
+		if n.Subject, err = execute(emailSubjectTemplate, view); err != nil {
+			return n, err
+		}
+		n.Body, err = execute(emailBodyTemplate, view)
+	case SMSChannel:
+		n.Body, err = execute(smsTemplate, view)
+	}
+	return n, err
+}
@@ -0,0 +1,157 @@
+package carts
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	vo "github.com/yauritux/cartsvc/pkg/domain/valueobject"
+	. "github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+)
+
+// CartChange tells the buyer about a cart line which differs from the current catalog,
+// the prices are the unit prices net of the discount
+type CartChange struct {
+	SKU       string
+	Name      string
+	Kind      CartChangeKind
+	OldPrice  float64
+	NewPrice  float64
+	Qty       int
+	Available int
+	Notice    string
+	// Fulfillment and AvailableAt tell how a deferred line is shipped
+	Fulfillment Fulfillment
+	AvailableAt *time.Time
+}
+
+// CartRefresh is the cart once its lines are repriced, along with the changes found on the way
+type CartRefresh struct {
+	Cart    *Cart
+	Changes []*CartChange
+}
+
+// RefreshCart reprices the lines of the open cart against the current catalog. The lines whose
+// product is gone or whose stock dropped below their quantity are only flagged, the buyer decides
+// what to do with them, and so are the lines which started waiting for the stock.
+func (this *CartUsecase) RefreshCart(userID string) (interface{}, error) {
+	res, err := this.FetchUserCart(userID)
+	if err != nil {
+		return nil, err
+	}
+	cart := res.(*Cart)
+	if cart.Status != Open {
+		return nil, fmt.Errorf("cannot refresh a cart with status as %s", cart.Status)
+	}
+
+	changes, err := this.refreshCart(cart)
+	if err != nil {
+		return nil, err
+	}
+	return &CartRefresh{Cart: cart, Changes: changes}, nil
+}
+
+// refreshCart persists the new prices and the fulfillment of the cart lines and brings the given cart up to date
+func (this *CartUsecase) refreshCart(cart *Cart) ([]*CartChange, error) {
+	current := make(map[string]*vo.CartItem)
+	for _, v := range cart.Items {
+		line, err := this.priceItem(&CartItem{ID: v.ID, SKU: v.SKU, Qty: v.Qty})
+		if err != nil {
+			//a product gone since it was added is left out, the line gets flagged as unavailable
+			if _, ok := err.(*e.ErrNoData); ok {
+				continue
+			}
+			return nil, err
+		}
+		current[cartItemSKU(v)] = line
+	}
+
+	userCart := buildUserCart(cart)
+	snapshot, err := this.snapshotStock(userCart.ExpandStockDemand())
+	if err != nil {
+		return nil, err
+	}
+
+	repricing, err := userCart.RepriceCart(current, snapshot.orderable())
+	if err != nil {
+		return nil, err
+	}
+	//a line both repriced and flagged is persisted last as flagged, which carries the new price too
+	flagged, deferred := userCart.FlagFulfillment(snapshot.onHand, snapshot.policies)
+	now := this.clock.Now()
+	for _, v := range append(repricing.Repriced, flagged...) {
+		if err := this.cartRepo.UpdateItem(cart.ID, buildCartUsecaseItem(v), now); err != nil {
+			return nil, err
+		}
+	}
+	cart.Items = make([]*CartItem, 0)
+	for _, v := range userCart.FetchCartInfo().Items {
+		cart.Items = append(cart.Items, buildCartUsecaseItem(v))
+	}
+
+	changes := make([]*CartChange, 0)
+	for _, v := range append(repricing.Changes, deferred...) {
+		change := &CartChange{
+			SKU:         v.Line.SKU,
+			Name:        v.Line.ProdName,
+			Kind:        v.Kind,
+			OldPrice:    v.OldPrice - v.OldDisc,
+			NewPrice:    v.Line.Price - v.Line.Disc,
+			Qty:         v.Line.Qty,
+			Available:   v.Available,
+			Fulfillment: v.Line.Fulfillment,
+			AvailableAt: v.Line.AvailableAt,
+		}
+		change.Notice = this.changeNotice(change, formatItemOptions(v.Line.Options))
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+func (this *CartUsecase) changeNotice(c *CartChange, options string) string {
+	name := c.Name
+	if options != "" {
+		name = fmt.Sprintf("%s (%s)", c.Name, options)
+	}
+	switch c.Kind {
+	case PriceIncreased:
+		return fmt.Sprintf("the price of %s has risen from %s to %s", name,
+			formatAmount(c.OldPrice, this.currency), formatAmount(c.NewPrice, this.currency))
+	case PriceDecreased:
+		return fmt.Sprintf("the price of %s has dropped from %s to %s", name,
+			formatAmount(c.OldPrice, this.currency), formatAmount(c.NewPrice, this.currency))
+	case StockShort:
+		if c.Available == 0 {
+			return fmt.Sprintf("%s is out of stock, please remove it from the cart", name)
+		}
+		return fmt.Sprintf("only %d of %s left, please lower the quantity from %d", c.Available, name, c.Qty)
+	case Deferred:
+		return fulfillmentNotice(c, name)
+	default:
+		return fmt.Sprintf("%s is no longer available, please remove it from the cart", name)
+	}
+}
+
+// checkoutBlocker returns a conflict when the buyer should review the cart before paying for it,
+// a price drop alone does not need any review
+func checkoutBlocker(changes []*CartChange) error {
+	notices := make([]string, 0)
+	for _, v := range changes {
+		if v.Kind != PriceDecreased {
+			notices = append(notices, v.Notice)
+		}
+	}
+	if len(notices) == 0 {
+		return nil
+	}
+	return e.NewErrConflict(fmt.Sprintf("the cart has changed, please review it before checking out: %s",
+		strings.Join(notices, "; ")))
+}
+
+func cartItemSKU(item *CartItem) string {
+	if item.SKU != "" {
+		return item.SKU
+	}
+	return item.ID
+}
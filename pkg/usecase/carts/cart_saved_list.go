@@ -0,0 +1,240 @@
+package carts
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/yauritux/cartsvc/pkg/domain/aggregate"
+	"github.com/yauritux/cartsvc/pkg/domain/entity"
+	vo "github.com/yauritux/cartsvc/pkg/domain/valueobject"
+	. "github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+)
+
+// SavedList is the wishlist or the saved for later list of a user, the items keep the price they were saved at
+type SavedList struct {
+	UserID    string
+	Name      SavedListName
+	Items     []*CartItem
+	UpdatedAt time.Time
+}
+
+// MovedItem is the cart line resulting from moving a saved item into the cart, at the current price
+type MovedItem struct {
+	Item         *CartItem
+	SavedPrice   float64
+	SavedDisc    float64
+	PriceChanged bool
+}
+
+func (this *CartUsecase) FetchSavedList(userID string, name SavedListName) (interface{}, error) {
+	if err := this.authorize(userID); err != nil {
+		return nil, err
+	}
+	return this.fetchSavedList(userID, name)
+}
+
+// SaveToList parks the product into the list at its current price, regardless of its stock
+func (this *CartUsecase) SaveToList(userID string, name SavedListName, item interface{}) error {
+	if err := this.authorize(userID); err != nil {
+		return err
+	}
+	prodItem, ok := item.(*CartItem)
+	if !ok {
+		return errors.New("conversion failed, invalid type of product item usecase model")
+	}
+	list, err := this.fetchSavedList(userID, name)
+	if err != nil {
+		return err
+	}
+
+	line, err := this.priceItem(prodItem)
+	if err != nil {
+		return err
+	}
+	savedList := buildUserSavedList(list)
+	if _, err := savedList.SaveItem(line); err != nil {
+		return err
+	}
+	return this.saveList(savedList)
+}
+
+func (this *CartUsecase) RemoveFromList(userID string, name SavedListName, sku string) error {
+	if err := this.authorize(userID); err != nil {
+		return err
+	}
+	list, err := this.fetchSavedList(userID, name)
+	if err != nil {
+		return err
+	}
+
+	savedList := buildUserSavedList(list)
+	if _, err := savedList.TakeItem(sku); err != nil {
+		return err
+	}
+	return this.saveList(savedList)
+}
+
+// MoveToCart moves the saved item into the open cart of the user, the item is priced again and its
+// stock checked as if it was added afresh. The cart is restored when the list cannot be updated.
+func (this *CartUsecase) MoveToCart(userID string, name SavedListName, sku string) (interface{}, error) {
+	res, err := this.FetchUserCart(userID)
+	if err != nil {
+		return nil, err
+	}
+	cart := res.(*Cart)
+	if cart.Status != Open {
+		return nil, fmt.Errorf("cannot move item into a cart with status as %s", cart.Status)
+	}
+	list, err := this.fetchSavedList(userID, name)
+	if err != nil {
+		return nil, err
+	}
+
+	savedList := buildUserSavedList(list)
+	saved, err := savedList.TakeItem(sku)
+	if err != nil {
+		return nil, err
+	}
+	previous := findCartLine(cart, sku)
+
+	line, err := this.addItem(cart, &CartItem{ID: saved.ProdID, SKU: saved.SKU, Qty: saved.Qty})
+	if err != nil {
+		return nil, err
+	}
+	if err := this.saveList(savedList); err != nil {
+		this.restoreCartLine(cart.ID, sku, previous)
+		return nil, err
+	}
+
+	return &MovedItem{
+		Item:         buildCartUsecaseItem(line),
+		SavedPrice:   saved.Price,
+		SavedDisc:    saved.Disc,
+		PriceChanged: saved.Price-saved.Disc != line.Price-line.Disc,
+	}, nil
+}
+
+// MoveToSavedForLater parks the cart line into the saved for later list at the current price of the
+// product, the list is restored when the line cannot be removed from the cart
+func (this *CartUsecase) MoveToSavedForLater(userID string, sku string) error {
+	res, err := this.FetchUserCart(userID)
+	if err != nil {
+		return err
+	}
+	cart := res.(*Cart)
+	if cart.Status != Open {
+		return fmt.Errorf("cannot move item out of a cart with status as %s", cart.Status)
+	}
+	cartLine := findCartLine(cart, sku)
+	if cartLine == nil {
+		return e.NewErrNoData(fmt.Sprintf("cannot find cart item with ID %s", sku))
+	}
+
+	list, err := this.fetchSavedList(userID, SavedForLater)
+	if err != nil {
+		return err
+	}
+	previous := *list
+	previous.Items = append(make([]*CartItem, 0), list.Items...)
+
+	line, err := this.priceItem(&CartItem{ID: cartLine.ID, SKU: cartLine.SKU, Qty: cartLine.Qty})
+	if err != nil {
+		return err
+	}
+	savedList := buildUserSavedList(list)
+	if _, err := savedList.SaveItem(line); err != nil {
+		return err
+	}
+	if err := this.saveList(savedList); err != nil {
+		return err
+	}
+
+	if err := this.cartRepo.RemoveItem(cart.ID, sku, this.clock.Now()); err != nil {
+		//best effort, the item stays in the cart and the list is as it was
+		_ = this.savedListRepo.Save(&previous)
+		return err
+	}
+	return nil
+}
+
+// priceItem prices the requested product at its current price without checking its stock
+func (this *CartUsecase) priceItem(prodItem *CartItem) (*vo.CartItem, error) {
+	ucProduct, productEntity, variant, err := this.resolveItem(prodItem)
+	if err != nil {
+		return nil, err
+	}
+	if ucProduct.IsBundle() {
+		bundle, err := this.buildProductBundle(ucProduct, prodItem.Qty)
+		if err != nil {
+			return nil, err
+		}
+		return aggregate.NewBundleLine(bundle, prodItem.Qty), nil
+	}
+	return aggregate.NewProductLine(productEntity, variant, prodItem.Qty)
+}
+
+// restoreCartLine puts the cart line back as it was before a failed move, previous is nil
+// when the line was not in the cart
+func (this *CartUsecase) restoreCartLine(cartID string, sku string, previous *CartItem) {
+	//best effort, there's nothing left to roll back to if the restore fails
+	if previous == nil {
+		_ = this.cartRepo.RemoveItem(cartID, sku, this.clock.Now())
+		return
+	}
+	_ = this.cartRepo.UpdateItem(cartID, previous, this.clock.Now())
+}
+
+func (this *CartUsecase) fetchSavedList(userID string, name SavedListName) (*SavedList, error) {
+	if this.savedListRepo == nil {
+		return nil, errors.New("saved lists are not available, no saved list repository")
+	}
+	if userID == "" {
+		return nil, e.NewErrInvalidData("cannot fetch saved list, 'user_id' is missing")
+	}
+	res, err := this.savedListRepo.FetchSavedList(userID, string(name))
+	if err != nil {
+		return nil, err
+	}
+	list, ok := res.(*SavedList)
+	if !ok {
+		return nil, e.NewErrConversion("cannot fetch saved list, invalid type of saved list usecase model")
+	}
+	return list, nil
+}
+
+func (this *CartUsecase) saveList(savedList *aggregate.UserSavedList) error {
+	info := savedList.FetchListInfo()
+	list := &SavedList{
+		UserID:    info.UserID,
+		Name:      info.Name,
+		Items:     make([]*CartItem, 0),
+		UpdatedAt: this.clock.Now(),
+	}
+	for _, v := range info.Items {
+		list.Items = append(list.Items, buildCartUsecaseItem(v))
+	}
+	return this.savedListRepo.Save(list)
+}
+
+func findCartLine(cart *Cart, sku string) *CartItem {
+	for _, v := range cart.Items {
+		if v.SKU == sku || (v.SKU == "" && v.ID == sku) {
+			return v
+		}
+	}
+	return nil
+}
+
+func buildUserSavedList(list *SavedList) *aggregate.UserSavedList {
+	return aggregate.NewUserSavedList(
+		&entity.User{
+			UserID: list.UserID,
+		}, &entity.SavedList{
+			UserID:    list.UserID,
+			Name:      list.Name,
+			Items:     buildCartVOItems(list.Items),
+			UpdatedAt: list.UpdatedAt,
+		})
+}
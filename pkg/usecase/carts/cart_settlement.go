@@ -0,0 +1,140 @@
+package carts
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	. "github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+)
+
+// CartClosed is published once the payment of a checked out cart is settled, Net is the total of its lines
+// and Redeemed the part of it paid with loyalty points
+type CartClosed struct {
+	CartID   string
+	UserID   string
+	Items    []*CartItem
+	Net      float64
+	Redeemed float64
+	ClosedAt time.Time
+}
+
+// CartRefunded is published once a closed cart gets refunded to its owner
+type CartRefunded struct {
+	CartID     string
+	UserID     string
+	RefundedAt time.Time
+}
+
+// CloseCart records the payment of the user's checked out cart as settled
+func (this *CartUsecase) CloseCart(userID string, cartID string) (interface{}, error) {
+	cart, err := this.findOrder(userID, cartID)
+	if err != nil {
+		return nil, err
+	}
+	if cart.Status != PaymentProcessing {
+		return nil, fmt.Errorf("failed to close cart with ID of %s. It is %s, please settle the payment first", cart.ID, cart.Status)
+	}
+
+	closedAt := this.clock.Now()
+	if err := this.cartRepo.Close(cart.ID, closedAt); err != nil {
+		return nil, err
+	}
+	cart.Status = Closed
+	cart.ClosedAt = &closedAt
+
+	return cart, this.publishClosing(cart, closedAt)
+}
+
+// RepublishClosing publishes CartClosed once more for the user's closed cart, as of the time it was closed,
+// so that a subscriber which failed upon closing (e.g. the loyalty points not earned) catches up. The
+// subscribers ignore a cart they already handled.
+func (this *CartUsecase) RepublishClosing(userID string, cartID string) (interface{}, error) {
+	cart, err := this.findOrder(userID, cartID)
+	if err != nil {
+		return nil, err
+	}
+	if cart.Status != Closed {
+		return nil, fmt.Errorf("failed to publish the closing of cart with ID of %s. It is %s, please close it first", cart.ID, cart.Status)
+	}
+
+	//the carts closed before the closing got stamped were last active upon their checkout
+	closedAt := cart.LastActivityAt
+	if cart.ClosedAt != nil {
+		closedAt = *cart.ClosedAt
+	}
+	return cart, this.publishClosing(cart, closedAt)
+}
+
+func (this *CartUsecase) publishClosing(cart *Cart, closedAt time.Time) error {
+	totals := cart.Totals()
+	return this.publish("closing", cart.ID, &CartClosed{
+		CartID:   cart.ID,
+		UserID:   cart.UserID,
+		Items:    cart.Items,
+		Net:      totals.Net,
+		Redeemed: totals.Redeemed,
+		ClosedAt: closedAt,
+	})
+}
+
+// RefundCart gives the payment of the user's closed cart back, the loyalty ledger of the user is
+// then settled by the subscribers of CartRefunded
+func (this *CartUsecase) RefundCart(userID string, cartID string) (interface{}, error) {
+	cart, err := this.findOrder(userID, cartID)
+	if err != nil {
+		return nil, err
+	}
+	if cart.Status != Closed {
+		return nil, fmt.Errorf("failed to refund cart with ID of %s. It is %s, only a closed cart can be refunded", cart.ID, cart.Status)
+	}
+
+	if err := this.cartRepo.Refunded(cart.ID); err != nil {
+		return nil, err
+	}
+	cart.Status = Refunded
+
+	return cart, this.publish("refund", cart.ID, &CartRefunded{
+		CartID:     cart.ID,
+		UserID:     cart.UserID,
+		RefundedAt: this.clock.Now(),
+	})
+}
+
+// findOrder returns the checked out cart of the user, settling the payment being up to the trusted callers
+func (this *CartUsecase) findOrder(userID string, cartID string) (*Cart, error) {
+	if userID == "" || cartID == "" {
+		return nil, e.NewErrInvalidData("'user_id' and 'cart_id' are required")
+	}
+	if this.principal != nil && !this.principal.IsAdmin() {
+		return nil, e.NewErrForbidden(fmt.Sprintf("user %s is not allowed to settle the payment of cart %s",
+			this.principal.UserID, cartID))
+	}
+
+	res, err := this.cartRepo.FetchOrders(userID, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	orders, ok := res.([]*Cart)
+	if !ok {
+		return nil, errors.New("conversion failed, invalid type of cart list usecase model")
+	}
+	for _, cart := range orders {
+		if cart.ID == cartID {
+			return cart, nil
+		}
+	}
+	return nil, e.NewErrNoData(fmt.Sprintf("no order %s found for user %s", cartID, userID))
+}
+
+// publish hands the event to the publisher, a failure is reported while the cart keeps its new status
+func (this *CartUsecase) publish(action string, cartID string, event interface{}) error {
+	if this.publisher == nil {
+		return nil
+	}
+	if err := this.publisher.Publish(event); err != nil {
+		return fmt.Errorf("cart %s: cannot publish the %s: %v", cartID, action, err)
+	}
+	return nil
+}
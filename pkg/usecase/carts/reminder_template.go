@@ -0,0 +1,101 @@
+package carts
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	userUsecase "github.com/yauritux/cartsvc/pkg/usecase/users"
+)
+
+var (
+	emailSubjectTemplate = template.Must(template.New("email_subject").Parse(
+		`{{.Name}}, you left {{.Units}} item(s) in your cart`))
+
+	emailBodyTemplate = template.Must(template.New("email_body").Parse(`Hi {{.Name}},
+
+These items are still waiting in your cart:
+
+{{range .Lines}}  {{.Qty}} x {{.Name}}{{if .Options}} ({{.Options}}){{end}}  {{.Subtotal}}
+{{end}}
+  Gross     {{.Gross}}
+  Discount  {{.Discount}}
+  Total     {{.Total}}
+
+Complete your checkout before they run out of stock.
+`))
+
+	smsTemplate = template.Must(template.New("sms").Parse(
+		`Hi {{.Name}}, {{.Units}} item(s) worth {{.Total}} are still waiting in your cart. Complete your checkout before they run out of stock.`))
+)
+
+// reminderView holds the cart as shown to its owner, the amounts are already formatted
+type reminderView struct {
+	Name     string
+	Units    int
+	Lines    []*reminderLine
+	Gross    string
+	Discount string
+	Total    string
+}
+
+type reminderLine struct {
+	Name     string
+	Options  string
+	Qty      int
+	Subtotal string
+}
+
+func buildReminderView(user *userUsecase.User, cart *Cart, currency string) *reminderView {
+	name := user.Username
+	if name == "" {
+		name = user.ID
+	}
+	totals := cart.Totals()
+	view := &reminderView{
+		Name:     name,
+		Units:    totals.Units,
+		Lines:    make([]*reminderLine, 0),
+		Gross:    formatAmount(totals.Gross, currency),
+		Discount: formatAmount(totals.Discount, currency),
+		Total:    formatAmount(totals.Net, currency),
+	}
+	for _, v := range cart.Items {
+		view.Lines = append(view.Lines, &reminderLine{
+			Name:     v.Name,
+			Options:  formatItemOptions(v.Options),
+			Qty:      v.Qty,
+			Subtotal: formatAmount((v.Price-v.Disc)*float64(v.Qty), currency),
+		})
+	}
+	return view
+}
+
+func formatAmount(amount float64, currency string) string {
+	if currency == "" {
+		return fmt.Sprintf("%.2f", amount)
+	}
+	return fmt.Sprintf("%s %.2f", currency, amount)
+}
+
+func formatItemOptions(options map[string]string) string {
+	keys := make([]string, 0, len(options))
+	for k := range options {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k+": "+options[k])
+	}
+	return strings.Join(pairs, ", ")
+}
+
+func execute(t *template.Template, view *reminderView) (string, error) {
+	var sb strings.Builder
+	if err := t.Execute(&sb, view); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,209 @@
+package categories
+
+import (
+	"fmt"
+
+	"github.com/yauritux/cartsvc/pkg/domain/repository"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+	prodUsecase "github.com/yauritux/cartsvc/pkg/usecase/products"
+)
+
+type CategoryUsecase struct {
+	categoryRepo repository.CategoryRepository
+	prodRepo     repository.ProductRepository
+	products     *prodUsecase.ProductUsecase
+}
+
+type Category struct {
+	ID       string
+	Name     string
+	ParentID string
+}
+
+// CategoryNode is a category along with its subcategories within the category tree
+type CategoryNode struct {
+	ID       string
+	Name     string
+	Children []*CategoryNode
+}
+
+func NewCategoryUsecase(r1 repository.CategoryRepository, r2 repository.ProductRepository) *CategoryUsecase {
+	return &CategoryUsecase{
+		categoryRepo: r1,
+		prodRepo:     r2,
+		products:     prodUsecase.NewProductUsecase(r2),
+	}
+}
+
+func (cat *CategoryUsecase) CreateCategory(c interface{}) (interface{}, error) {
+	category, ok := c.(*Category)
+	if !ok {
+		return nil, e.NewErrConversion("cannot create category, invalid type of category usecase model")
+	}
+	if category.ID == "" {
+		return nil, e.NewErrInvalidData("invalid category, 'category_id' is missing")
+	}
+	if category.Name == "" {
+		return nil, e.NewErrInvalidData("invalid category, 'name' is missing")
+	}
+	if category.ParentID != "" {
+		if _, err := cat.categoryRepo.FindByCategoryID(category.ParentID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := cat.categoryRepo.Create(category); err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+// FetchCategoryTree returns the tree rooted at the given category,
+// or the whole forest of the top level categories when rootID is empty
+func (cat *CategoryUsecase) FetchCategoryTree(rootID string) (interface{}, error) {
+	categories, err := cat.fetchAll()
+	if err != nil {
+		return nil, err
+	}
+
+	children := make(map[string][]*Category)
+	var root *Category
+	for _, c := range categories {
+		children[c.ParentID] = append(children[c.ParentID], c)
+		if c.ID == rootID {
+			root = c
+		}
+	}
+
+	if rootID == "" {
+		return buildNodes(children[""], children, make(map[string]bool)), nil
+	}
+	if root == nil {
+		return nil, e.NewErrNoData("no category found for id " + rootID)
+	}
+	return buildNodes([]*Category{root}, children, make(map[string]bool)), nil
+}
+
+// ListProductsInCategory searches the products assigned to the category or any of its descendants
+func (cat *CategoryUsecase) ListProductsInCategory(categoryID string, query interface{}) (interface{}, error) {
+	q, ok := query.(*prodUsecase.ProductQuery)
+	if !ok {
+		return nil, e.NewErrConversion("cannot list products in category, invalid type of product query")
+	}
+
+	subtree, err := cat.subtreeIDs(categoryID)
+	if err != nil {
+		return nil, err
+	}
+	q.CategoryIDs = subtree
+
+	return cat.products.SearchProducts(q)
+}
+
+func (cat *CategoryUsecase) AssignProductCategories(productID string, categoryIDs []string) (interface{}, error) {
+	p, err := cat.prodRepo.FindByProductID(productID)
+	if err != nil {
+		return nil, err
+	}
+	product, ok := p.(*prodUsecase.Product)
+	if !ok {
+		return nil, e.NewErrConversion("cannot assign categories, got an invalid product type returned from the repository")
+	}
+
+	for _, id := range categoryIDs {
+		if _, err := cat.categoryRepo.FindByCategoryID(id); err != nil {
+			return nil, err
+		}
+	}
+	product.CategoryIDs = categoryIDs
+
+	if err := cat.prodRepo.Update(product); err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+// ProductInCategory implements the service.CategoryMatcher, a deleted product still matching the categories
+// it was assigned to since the orders keep referencing it
+func (cat *CategoryUsecase) ProductInCategory(productID string, categoryID string) (bool, error) {
+	p, err := cat.prodRepo.FindAnyByProductID(productID)
+	if err != nil {
+		return false, err
+	}
+	product, ok := p.(*prodUsecase.Product)
+	if !ok {
+		return false, e.NewErrConversion("cannot match category, got an invalid product type returned from the repository")
+	}
+
+	subtree, err := cat.subtreeIDs(categoryID)
+	if err != nil {
+		return false, err
+	}
+	for _, assigned := range product.CategoryIDs {
+		for _, id := range subtree {
+			if assigned == id {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+func (cat *CategoryUsecase) subtreeIDs(categoryID string) ([]string, error) {
+	categories, err := cat.fetchAll()
+	if err != nil {
+		return nil, err
+	}
+
+	children := make(map[string][]string)
+	found := false
+	for _, c := range categories {
+		children[c.ParentID] = append(children[c.ParentID], c.ID)
+		if c.ID == categoryID {
+			found = true
+		}
+	}
+	if !found {
+		return nil, e.NewErrNoData("no category found for id " + categoryID)
+	}
+
+	ids := []string{categoryID}
+	visited := map[string]bool{categoryID: true}
+	for i := 0; i < len(ids); i++ {
+		for _, child := range children[ids[i]] {
+			if !visited[child] {
+				visited[child] = true
+				ids = append(ids, child)
+			}
+		}
+	}
+	return ids, nil
+}
+
+func (cat *CategoryUsecase) fetchAll() ([]*Category, error) {
+	res, err := cat.categoryRepo.FetchAll()
+	if err != nil {
+		return nil, err
+	}
+	categories, ok := res.([]*Category)
+	if !ok {
+		return nil, e.NewErrConversion(fmt.Sprintf("cannot fetch categories, got an invalid type %T returned from the repository", res))
+	}
+	return categories, nil
+}
+
+func buildNodes(categories []*Category, children map[string][]*Category, visited map[string]bool) []*CategoryNode {
+	nodes := make([]*CategoryNode, 0)
+	for _, c := range categories {
+		if visited[c.ID] {
+			continue
+		}
+		visited[c.ID] = true
+		nodes = append(nodes, &CategoryNode{
+			ID:       c.ID,
+			Name:     c.Name,
+			Children: buildNodes(children[c.ID], children, visited),
+		})
+	}
+	return nodes
+}
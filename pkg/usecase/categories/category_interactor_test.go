@@ -0,0 +1,131 @@
+package categories
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/stretchr/testify/mock"
+	"github.com/yauritux/cartsvc/pkg/domain/service"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+	mockRepo "github.com/yauritux/cartsvc/pkg/sharedkernel/mock/repository"
+	prodUsecase "github.com/yauritux/cartsvc/pkg/usecase/products"
+)
+
+func TestCategoryUsecase(t *testing.T) {
+
+	taxonomy := func() []*Category {
+		return []*Category{
+			{ID: "weapons", Name: "Weapons"},
+			{ID: "throwing-weapons", Name: "Throwing Weapons", ParentID: "weapons"},
+			{ID: "stars", Name: "Throwing Stars", ParentID: "throwing-weapons"},
+			{ID: "melee-weapons", Name: "Melee Weapons", ParentID: "weapons"},
+			{ID: "apparel", Name: "Apparel"},
+		}
+	}
+
+	Convey("1. Given a user is browsing the category tree", t, func() {
+
+		categoryRepo := &mockRepo.MockCategoryRepository{}
+		prodRepo := &mockRepo.MockProductRepository{}
+		categoryRepo.On("FetchAll").Return(taxonomy(), nil)
+
+		Convey("-> Negative Scenarios", func() {
+			Convey("-> Should return an error for an unknown root category", func() {
+				uc := NewCategoryUsecase(categoryRepo, prodRepo)
+				res, err := uc.FetchCategoryTree("food")
+				So(res, ShouldBeNil)
+				So(err, ShouldHaveSameTypeAs, &e.ErrNoData{})
+			})
+		})
+
+		Convey("-> Positive Scenarios", func() {
+			Convey("-> Should return the whole forest when no root is given", func() {
+				uc := NewCategoryUsecase(categoryRepo, prodRepo)
+				res, err := uc.FetchCategoryTree("")
+				So(err, ShouldBeNil)
+				nodes := res.([]*CategoryNode)
+				So(nodes, ShouldHaveLength, 2)
+				So(nodes[0].ID, ShouldEqual, "weapons")
+				So(nodes[0].Children, ShouldHaveLength, 2)
+				So(nodes[0].Children[0].Children[0].ID, ShouldEqual, "stars")
+				So(nodes[1].ID, ShouldEqual, "apparel")
+			})
+			Convey("-> Should list the products within the whole subtree", func() {
+				prodRepo.On("Search", mock.Anything).Return(&prodUsecase.ProductPage{}, nil)
+				uc := NewCategoryUsecase(categoryRepo, prodRepo)
+				query := &prodUsecase.ProductQuery{}
+				_, err := uc.ListProductsInCategory("throwing-weapons", query)
+				So(err, ShouldBeNil)
+				So(query.CategoryIDs, ShouldResemble, []string{"throwing-weapons", "stars"})
+			})
+		})
+	})
+
+	Convey("2. Given a business rule matching the products of a category", t, func() {
+
+		categoryRepo := &mockRepo.MockCategoryRepository{}
+		prodRepo := &mockRepo.MockProductRepository{}
+		categoryRepo.On("FetchAll").Return(taxonomy(), nil)
+		prodRepo.On("FindAnyByProductID", "001").Return(&prodUsecase.Product{
+			ID: "001", Name: "Shuriken", CategoryIDs: []string{"stars"},
+		}, nil)
+		prodRepo.On("FindAnyByProductID", "404").Return(nil, e.NewErrNoData("no product found for id 404"))
+
+		var matcher service.CategoryMatcher = NewCategoryUsecase(categoryRepo, prodRepo)
+
+		Convey("-> Negative Scenarios", func() {
+			Convey("-> Should return an error for an unknown product", func() {
+				matched, err := matcher.ProductInCategory("404", "weapons")
+				So(matched, ShouldBeFalse)
+				So(err, ShouldHaveSameTypeAs, &e.ErrNoData{})
+			})
+			Convey("-> Should return an error for an unknown category", func() {
+				matched, err := matcher.ProductInCategory("001", "armors")
+				So(matched, ShouldBeFalse)
+				So(err, ShouldHaveSameTypeAs, &e.ErrNoData{})
+			})
+		})
+
+		Convey("-> Positive Scenarios", func() {
+			Convey("-> A product assigned to a descendant should match the ancestor category", func() {
+				matched, err := matcher.ProductInCategory("001", "weapons")
+				So(err, ShouldBeNil)
+				So(matched, ShouldBeTrue)
+			})
+			Convey("-> A product assigned to the category itself should match it", func() {
+				matched, err := matcher.ProductInCategory("001", "stars")
+				So(err, ShouldBeNil)
+				So(matched, ShouldBeTrue)
+			})
+			Convey("-> A product should not match a sibling category", func() {
+				matched, err := matcher.ProductInCategory("001", "melee-weapons")
+				So(err, ShouldBeNil)
+				So(matched, ShouldBeFalse)
+			})
+		})
+	})
+
+	Convey("3. Given an admin assigns categories to a product", t, func() {
+
+		categoryRepo := &mockRepo.MockCategoryRepository{}
+		prodRepo := &mockRepo.MockProductRepository{}
+		prodRepo.On("FindByProductID", "001").Return(&prodUsecase.Product{ID: "001", Name: "Shuriken"}, nil)
+
+		Convey("-> Should reject an unknown category", func() {
+			categoryRepo.On("FindByCategoryID", "food").Return(nil, e.NewErrNoData("no category found for id food"))
+			uc := NewCategoryUsecase(categoryRepo, prodRepo)
+			res, err := uc.AssignProductCategories("001", []string{"food"})
+			So(res, ShouldBeNil)
+			So(err, ShouldHaveSameTypeAs, &e.ErrNoData{})
+			prodRepo.AssertNotCalled(t, "Update", mock.Anything)
+		})
+		Convey("-> Should update the product categories", func() {
+			categoryRepo.On("FindByCategoryID", "stars").Return(&Category{ID: "stars"}, nil)
+			prodRepo.On("Update", mock.Anything).Return(nil)
+			uc := NewCategoryUsecase(categoryRepo, prodRepo)
+			res, err := uc.AssignProductCategories("001", []string{"stars"})
+			So(err, ShouldBeNil)
+			So(res.(*prodUsecase.Product).CategoryIDs, ShouldResemble, []string{"stars"})
+		})
+	})
+}
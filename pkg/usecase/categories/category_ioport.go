@@ -0,0 +1,9 @@
+package categories
+
+type CategoryInputPort interface {
+	CreateCategory(category interface{}) (interface{}, error)
+	FetchCategoryTree(rootID string) (interface{}, error)
+	ListProductsInCategory(categoryID string, query interface{}) (interface{}, error)
+	AssignProductCategories(productID string, categoryIDs []string) (interface{}, error)
+	ProductInCategory(productID string, categoryID string) (bool, error)
+}
@@ -0,0 +1,127 @@
+package loyalty
+
+import (
+	"fmt"
+	"math"
+
+	. "github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+	cartUsecase "github.com/yauritux/cartsvc/pkg/usecase/carts"
+)
+
+// EarningRule earns Points for every Per spent on the products of the category, or on any product when
+// CategoryID is empty. A cart line is only earned upon by the first rule matching its product.
+type EarningRule struct {
+	CategoryID string
+	Per        float64
+	Points     int
+}
+
+// HandleCartEvent earns the points of a closed cart, and settles the ledger of a refunded one: the points
+// redeemed for the cart are given back while the ones it earned are taken back, as far as they are left.
+// The other events are ignored.
+func (this *LoyaltyUsecase) HandleCartEvent(event interface{}) error {
+	switch ev := event.(type) {
+	case *cartUsecase.CartClosed:
+		return this.earn(ev)
+	case *cartUsecase.CartRefunded:
+		return this.reverse(ev)
+	}
+	return nil
+}
+
+func (this *LoyaltyUsecase) earn(ev *cartUsecase.CartClosed) error {
+	points, err := this.earnedPoints(ev)
+	if err != nil {
+		return fmt.Errorf("cannot earn the loyalty points of cart %s: %v", ev.CartID, err)
+	}
+	if points == 0 {
+		return nil
+	}
+
+	ledger, err := this.fetchLedger(ev.UserID)
+	if err != nil {
+		return err
+	}
+	for _, entry := range ledger.Entries {
+		if entry.CartID == ev.CartID && entry.Kind == Earned {
+			return nil
+		}
+	}
+	this.append(ledger, &LedgerEntry{
+		CartID: ev.CartID, Kind: Earned, Points: points, Remaining: points, ExpiresAt: this.expiry(),
+	})
+	return this.repo.SaveLedger(ledger)
+}
+
+// earnedPoints applies the rules to what the buyer paid for each line, the points being redeemed
+// are not earned upon
+func (this *LoyaltyUsecase) earnedPoints(ev *cartUsecase.CartClosed) (int, error) {
+	if ev.Net <= 0 || len(this.rules) == 0 {
+		return 0, nil
+	}
+	paid := (ev.Net - ev.Redeemed) / ev.Net
+
+	spent := make([]float64, len(this.rules))
+	for _, item := range ev.Items {
+		i, err := this.matchRule(item.ID)
+		if err != nil {
+			return 0, err
+		}
+		if i < 0 {
+			continue
+		}
+		spent[i] += (item.Price - item.Disc) * float64(item.Qty) * paid
+	}
+
+	points := 0
+	for i, r := range this.rules {
+		points += int(math.Floor(spent[i]/r.Per+1e-9)) * r.Points
+	}
+	return points, nil
+}
+
+// matchRule returns the index of the first rule matching the product, -1 when there's none
+func (this *LoyaltyUsecase) matchRule(productID string) (int, error) {
+	for i, r := range this.rules {
+		if r.CategoryID == "" {
+			return i, nil
+		}
+		if this.matcher == nil {
+			continue
+		}
+		ok, err := this.matcher.ProductInCategory(productID, r.CategoryID)
+		if err != nil {
+			return -1, err
+		}
+		if ok {
+			return i, nil
+		}
+	}
+	return -1, nil
+}
+
+func (this *LoyaltyUsecase) reverse(ev *cartUsecase.CartRefunded) error {
+	ledger, err := this.fetchLedger(ev.UserID)
+	if err != nil {
+		return err
+	}
+	this.expire(ledger)
+
+	//the points given back first may cover the earned ones already spent
+	changed := this.restore(ledger, ev.CartID)
+	for _, earned := range pending(ledger, ev.CartID, Earned) {
+		left := available(ledger)
+		points := earned.Points
+		if points > left {
+			points = left
+		}
+		rest := debit([]*LedgerEntry{earned}, points)
+		debit(lots(ledger), rest)
+		this.append(ledger, &LedgerEntry{CartID: ev.CartID, Kind: Reversed, Points: -points, RefID: earned.ID})
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	return this.repo.SaveLedger(ledger)
+}
@@ -0,0 +1,302 @@
+package loyalty
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/yauritux/cartsvc/pkg/domain/repository"
+	"github.com/yauritux/cartsvc/pkg/domain/service"
+	. "github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+	authUsecase "github.com/yauritux/cartsvc/pkg/usecase/auth"
+)
+
+type LoyaltyUsecase struct {
+	repo       repository.LoyaltyRepository
+	matcher    service.CategoryMatcher
+	rules      []*EarningRule
+	pointValue float64
+	validity   time.Duration
+	clock      service.Clock
+	principal  *authUsecase.Principal
+}
+
+// Ledger holds every move of the loyalty points of the user, the oldest first
+type Ledger struct {
+	UserID  string
+	Entries []*LedgerEntry
+}
+
+// LedgerEntry credits (positive Points) or debits (negative Points) the points of the user. A credit is a lot
+// of points spent in the order of their expiry, Remaining being what is left of it. RefID is the entry
+// undone by a reversal, or the lot an expiry debits.
+type LedgerEntry struct {
+	ID        string
+	CartID    string
+	Kind      LedgerEntryKind
+	Points    int
+	Remaining int
+	RefID     string
+	CreatedAt time.Time
+	ExpiresAt *time.Time
+}
+
+// Balance is what the user can redeem, Value being the discount the Points are worth. ExpiringPoints
+// are the ones lost at NextExpiry unless they are redeemed before.
+type Balance struct {
+	UserID         string
+	Points         int
+	Value          float64
+	NextExpiry     *time.Time
+	ExpiringPoints int
+	Entries        []*LedgerEntry
+}
+
+// Option configures the optional settings of the LoyaltyUsecase
+type Option func(*LoyaltyUsecase)
+
+// WithEarningRules sets how many points a closed cart earns, no point is earned without any rule
+func WithEarningRules(rules ...*EarningRule) Option {
+	return func(uc *LoyaltyUsecase) {
+		uc.rules = rules
+	}
+}
+
+// WithCategoryMatcher lets the earning rules target a category, such rules never match otherwise
+func WithCategoryMatcher(m service.CategoryMatcher) Option {
+	return func(uc *LoyaltyUsecase) {
+		uc.matcher = m
+	}
+}
+
+// WithPointValue sets the discount a single point is worth upon redemption, 1 by default
+func WithPointValue(value float64) Option {
+	return func(uc *LoyaltyUsecase) {
+		uc.pointValue = value
+	}
+}
+
+// WithPointsValidity sets how long the earned points remain redeemable, 0 never expires
+func WithPointsValidity(validity time.Duration) Option {
+	return func(uc *LoyaltyUsecase) {
+		uc.validity = validity
+	}
+}
+
+func WithClock(c service.Clock) Option {
+	return func(uc *LoyaltyUsecase) {
+		uc.clock = c
+	}
+}
+
+func NewLoyaltyUsecase(r repository.LoyaltyRepository, opts ...Option) *LoyaltyUsecase {
+	uc := &LoyaltyUsecase{repo: r, pointValue: 1, clock: service.SystemClock{}}
+	for _, opt := range opts {
+		opt(uc)
+	}
+	return uc
+}
+
+// ForPrincipal returns a copy of the use case only letting the principal query the balance it owns
+func (this *LoyaltyUsecase) ForPrincipal(p *authUsecase.Principal) *LoyaltyUsecase {
+	bound := *this
+	bound.principal = p
+	return &bound
+}
+
+func (this *LoyaltyUsecase) authorize(userID string) error {
+	if this.principal == nil || this.principal.CanAccess(userID) {
+		return nil
+	}
+	return e.NewErrForbidden(fmt.Sprintf("user %s is not allowed to access the loyalty points of user %s",
+		this.principal.UserID, userID))
+}
+
+// FetchBalance returns the points the user can redeem along with the ledger, the points whose validity
+// is over are expired first
+func (this *LoyaltyUsecase) FetchBalance(userID string) (interface{}, error) {
+	if userID == "" {
+		return nil, e.NewErrInvalidData("cannot fetch loyalty points, 'user_id' is missing")
+	}
+	if err := this.authorize(userID); err != nil {
+		return nil, err
+	}
+
+	ledger, err := this.fetchLedger(userID)
+	if err != nil {
+		return nil, err
+	}
+	if this.expire(ledger) {
+		if err := this.repo.SaveLedger(ledger); err != nil {
+			return nil, err
+		}
+	}
+
+	balance := &Balance{UserID: userID, Points: available(ledger), Entries: ledger.Entries}
+	balance.Value = float64(balance.Points) * this.pointValue
+	for _, lot := range lots(ledger) {
+		if lot.ExpiresAt == nil {
+			break
+		}
+		if balance.NextExpiry != nil && !lot.ExpiresAt.Equal(*balance.NextExpiry) {
+			break
+		}
+		balance.NextExpiry = lot.ExpiresAt
+		balance.ExpiringPoints += lot.Remaining
+	}
+	return balance, nil
+}
+
+// RedeemPoints implements the service.LoyaltyProgram, the points are debited from the lots expiring first
+func (this *LoyaltyUsecase) RedeemPoints(userID string, cartID string, points int, upTo float64) (float64, error) {
+	if points <= 0 {
+		return 0, e.NewErrInvalidData("cannot redeem points, the points to redeem should be greater than zero")
+	}
+	ledger, err := this.fetchLedger(userID)
+	if err != nil {
+		return 0, err
+	}
+	this.expire(ledger)
+
+	if left := available(ledger); points > left {
+		return 0, e.NewErrConflict(fmt.Sprintf("cannot redeem %d points, only %d left", points, left))
+	}
+	amount := float64(points) * this.pointValue
+	if amount > upTo {
+		return 0, e.NewErrInvalidData(fmt.Sprintf("cannot redeem %d points worth %.2f, more than the %.2f to pay", points, amount, upTo))
+	}
+
+	debit(lots(ledger), points)
+	this.append(ledger, &LedgerEntry{CartID: cartID, Kind: Redeemed, Points: -points})
+	if err := this.repo.SaveLedger(ledger); err != nil {
+		return 0, err
+	}
+	return amount, nil
+}
+
+// RestorePoints implements the service.LoyaltyProgram, the points redeemed for the cart are credited back
+// as a new lot. Restoring them twice has no effect.
+func (this *LoyaltyUsecase) RestorePoints(userID string, cartID string) error {
+	ledger, err := this.fetchLedger(userID)
+	if err != nil {
+		return err
+	}
+	if !this.restore(ledger, cartID) {
+		return nil
+	}
+	return this.repo.SaveLedger(ledger)
+}
+
+// restore credits back the redemptions of the cart not reversed yet, it tells whether there was any
+func (this *LoyaltyUsecase) restore(ledger *Ledger, cartID string) bool {
+	restored := false
+	for _, entry := range pending(ledger, cartID, Redeemed) {
+		this.append(ledger, &LedgerEntry{
+			CartID: cartID, Kind: Reversed, Points: -entry.Points, Remaining: -entry.Points, RefID: entry.ID,
+			ExpiresAt: this.expiry(),
+		})
+		restored = true
+	}
+	return restored
+}
+
+// expire debits the lots whose validity is over, it tells whether there was any
+func (this *LoyaltyUsecase) expire(ledger *Ledger) bool {
+	now := this.clock.Now()
+	expired := false
+	for _, lot := range lots(ledger) {
+		if lot.ExpiresAt == nil || lot.ExpiresAt.After(now) {
+			continue
+		}
+		this.append(ledger, &LedgerEntry{Kind: Expired, Points: -lot.Remaining, RefID: lot.ID})
+		lot.Remaining = 0
+		expired = true
+	}
+	return expired
+}
+
+func (this *LoyaltyUsecase) fetchLedger(userID string) (*Ledger, error) {
+	res, err := this.repo.FetchLedger(userID)
+	if err != nil {
+		return nil, err
+	}
+	ledger, ok := res.(*Ledger)
+	if !ok {
+		return nil, errors.New("conversion failed, invalid type of ledger usecase model")
+	}
+	return ledger, nil
+}
+
+func (this *LoyaltyUsecase) append(ledger *Ledger, entry *LedgerEntry) {
+	entry.ID = strconv.Itoa(len(ledger.Entries) + 1)
+	entry.CreatedAt = this.clock.Now()
+	ledger.Entries = append(ledger.Entries, entry)
+}
+
+func (this *LoyaltyUsecase) expiry() *time.Time {
+	if this.validity <= 0 {
+		return nil
+	}
+	expiresAt := this.clock.Now().Add(this.validity)
+	return &expiresAt
+}
+
+// lots returns the credits having points left, the ones expiring first come first and the ones never
+// expiring come last
+func lots(ledger *Ledger) []*LedgerEntry {
+	res := make([]*LedgerEntry, 0)
+	for _, entry := range ledger.Entries {
+		if entry.Remaining > 0 {
+			res = append(res, entry)
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		a, b := res[i].ExpiresAt, res[j].ExpiresAt
+		return a != nil && (b == nil || a.Before(*b))
+	})
+	return res
+}
+
+// debit takes the points out of the lots in their order, it returns the points they could not cover
+func debit(lots []*LedgerEntry, points int) int {
+	for _, lot := range lots {
+		if points == 0 {
+			break
+		}
+		taken := lot.Remaining
+		if taken > points {
+			taken = points
+		}
+		lot.Remaining -= taken
+		points -= taken
+	}
+	return points
+}
+
+func available(ledger *Ledger) int {
+	points := 0
+	for _, lot := range lots(ledger) {
+		points += lot.Remaining
+	}
+	return points
+}
+
+// pending returns the entries of the given kind recorded for the cart and not reversed yet
+func pending(ledger *Ledger, cartID string, kind LedgerEntryKind) []*LedgerEntry {
+	reversed := make(map[string]bool)
+	for _, entry := range ledger.Entries {
+		if entry.Kind == Reversed {
+			reversed[entry.RefID] = true
+		}
+	}
+	res := make([]*LedgerEntry, 0)
+	for _, entry := range ledger.Entries {
+		if entry.CartID == cartID && entry.Kind == kind && !reversed[entry.ID] {
+			res = append(res, entry)
+		}
+	}
+	return res
+}
@@ -0,0 +1,252 @@
+package loyalty
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/stretchr/testify/mock"
+	"github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+	mockRepo "github.com/yauritux/cartsvc/pkg/sharedkernel/mock/repository"
+	mockService "github.com/yauritux/cartsvc/pkg/sharedkernel/mock/service"
+	authUsecase "github.com/yauritux/cartsvc/pkg/usecase/auth"
+	cartUsecase "github.com/yauritux/cartsvc/pkg/usecase/carts"
+)
+
+func TestLoyaltyUsecase(t *testing.T) {
+
+	now := time.Date(2020, time.May, 1, 12, 0, 0, 0, time.UTC)
+	at := func(days int) *time.Time {
+		t := now.AddDate(0, 0, days)
+		return &t
+	}
+
+	Convey("1. Given a user redeems loyalty points upon checkout", t, func() {
+
+		repo := &mockRepo.MockLoyaltyRepository{}
+		clock := &mockService.MockClock{}
+		clock.On("Now").Return(now)
+		ledger := &Ledger{UserID: "123", Entries: []*LedgerEntry{
+			{ID: "1", CartID: "c0", Kind: enum.Earned, Points: 50, Remaining: 50, ExpiresAt: at(30)},
+			{ID: "2", CartID: "c1", Kind: enum.Earned, Points: 70, Remaining: 70, ExpiresAt: at(10)},
+		}}
+		repo.On("FetchLedger", "123").Return(ledger, nil)
+		repo.On("SaveLedger", ledger).Return(nil)
+		uc := NewLoyaltyUsecase(repo, WithPointValue(2), WithPointsValidity(365*24*time.Hour), WithClock(clock))
+
+		Convey("-> Negative Scenarios", func() {
+			Convey("-> Should refuse to redeem no point", func() {
+				_, err := uc.RedeemPoints("123", "u01", 0, 500)
+				So(err, ShouldHaveSameTypeAs, &e.ErrInvalidData{})
+			})
+			Convey("-> Should refuse to redeem more points than the user holds", func() {
+				_, err := uc.RedeemPoints("123", "u01", 150, 500)
+				So(err, ShouldHaveSameTypeAs, &e.ErrConflict{})
+				So(err.Error(), ShouldEqual, "cannot redeem 150 points, only 120 left")
+				repo.AssertNotCalled(t, "SaveLedger", mock.Anything)
+			})
+			Convey("-> Should refuse to redeem points worth more than what is left to pay", func() {
+				_, err := uc.RedeemPoints("123", "u01", 100, 150)
+				So(err, ShouldHaveSameTypeAs, &e.ErrInvalidData{})
+				So(err.Error(), ShouldEqual, "cannot redeem 100 points worth 200.00, more than the 150.00 to pay")
+			})
+			Convey("-> Should return the error raised by the system repository", func() {
+				failing := &mockRepo.MockLoyaltyRepository{}
+				failing.On("FetchLedger", "123").Return(nil, errors.New("Database error"))
+				_, err := NewLoyaltyUsecase(failing).RedeemPoints("123", "u01", 10, 500)
+				So(err.Error(), ShouldEqual, "Database error")
+			})
+		})
+
+		Convey("-> Positive Scenarios", func() {
+			Convey("-> Should debit the points expiring first and return their worth", func() {
+				amount, err := uc.RedeemPoints("123", "u01", 80, 500)
+				So(err, ShouldBeNil)
+				So(amount, ShouldEqual, 160)
+				So(ledger.Entries[0].Remaining, ShouldEqual, 40)
+				So(ledger.Entries[1].Remaining, ShouldEqual, 0)
+				So(ledger.Entries[2], ShouldResemble, &LedgerEntry{ID: "3", CartID: "u01", Kind: enum.Redeemed, Points: -80, CreatedAt: now})
+			})
+			Convey("-> Restoring the points should credit them back once", func() {
+				_, err := uc.RedeemPoints("123", "u01", 80, 500)
+				So(err, ShouldBeNil)
+				So(uc.RestorePoints("123", "u01"), ShouldBeNil)
+				So(uc.RestorePoints("123", "u01"), ShouldBeNil)
+				So(ledger.Entries, ShouldHaveLength, 4)
+				So(ledger.Entries[3], ShouldResemble, &LedgerEntry{
+					ID: "4", CartID: "u01", Kind: enum.Reversed, Points: 80, Remaining: 80, RefID: "3", CreatedAt: now, ExpiresAt: at(365),
+				})
+				repo.AssertNumberOfCalls(t, "SaveLedger", 2)
+			})
+		})
+	})
+
+	Convey("2. Given a user's cart gets closed", t, func() {
+
+		repo := &mockRepo.MockLoyaltyRepository{}
+		matcher := &mockService.MockCategoryMatcher{}
+		clock := &mockService.MockClock{}
+		clock.On("Now").Return(now)
+		matcher.On("ProductInCategory", "001", "weapons").Return(true, nil)
+		matcher.On("ProductInCategory", "003", "weapons").Return(false, nil)
+		ledger := &Ledger{UserID: "123", Entries: make([]*LedgerEntry, 0)}
+		repo.On("FetchLedger", "123").Return(ledger, nil)
+		repo.On("SaveLedger", ledger).Return(nil)
+		uc := NewLoyaltyUsecase(repo,
+			WithEarningRules(&EarningRule{CategoryID: "weapons", Per: 100, Points: 5}, &EarningRule{Per: 100, Points: 1}),
+			WithCategoryMatcher(matcher), WithPointsValidity(30*24*time.Hour), WithClock(clock),
+		)
+		closed := &cartUsecase.CartClosed{
+			CartID: "u01", UserID: "123", Net: 821, ClosedAt: now,
+			Items: []*cartUsecase.CartItem{
+				{ID: "001", Name: "Shuriken", Qty: 2, Price: 250.5},
+				{ID: "003", Name: "Ninja Gi", Qty: 1, Price: 330, Disc: 10},
+			},
+		}
+
+		Convey("-> Negative Scenarios", func() {
+			Convey("-> Should earn nothing without any earning rule", func() {
+				So(NewLoyaltyUsecase(repo).HandleCartEvent(closed), ShouldBeNil)
+				repo.AssertNotCalled(t, "SaveLedger", mock.Anything)
+			})
+			Convey("-> Should ignore the other events", func() {
+				So(uc.HandleCartEvent(&cartUsecase.CartExpired{CartID: "u01", UserID: "123"}), ShouldBeNil)
+				repo.AssertNotCalled(t, "FetchLedger", mock.Anything)
+			})
+			Convey("-> Should report a category which cannot be matched", func() {
+				failing := &mockService.MockCategoryMatcher{}
+				failing.On("ProductInCategory", "001", "weapons").Return(false, errors.New("no category found for id weapons"))
+				err := NewLoyaltyUsecase(repo, WithEarningRules(&EarningRule{CategoryID: "weapons", Per: 100, Points: 5}),
+					WithCategoryMatcher(failing)).HandleCartEvent(closed)
+				So(err.Error(), ShouldEqual, "cannot earn the loyalty points of cart u01: no category found for id weapons")
+			})
+		})
+
+		Convey("-> Positive Scenarios", func() {
+			Convey("-> Each line should earn upon the first rule matching its product", func() {
+				So(uc.HandleCartEvent(closed), ShouldBeNil)
+				So(ledger.Entries, ShouldResemble, []*LedgerEntry{{
+					ID: "1", CartID: "u01", Kind: enum.Earned, Points: 28, Remaining: 28, CreatedAt: now, ExpiresAt: at(30),
+				}})
+			})
+			Convey("-> The points redeemed for the cart should not be earned upon", func() {
+				closed.Redeemed = 410.5
+				So(uc.HandleCartEvent(closed), ShouldBeNil)
+				So(ledger.Entries[0].Points, ShouldEqual, 11)
+			})
+			Convey("-> A cart should only earn once", func() {
+				So(uc.HandleCartEvent(closed), ShouldBeNil)
+				So(uc.HandleCartEvent(closed), ShouldBeNil)
+				So(ledger.Entries, ShouldHaveLength, 1)
+				repo.AssertNumberOfCalls(t, "SaveLedger", 1)
+			})
+			Convey("-> A rule targeting a category should not match without a category matcher", func() {
+				So(NewLoyaltyUsecase(repo, WithEarningRules(uc.rules...), WithClock(clock)).HandleCartEvent(closed), ShouldBeNil)
+				So(ledger.Entries[0].Points, ShouldEqual, 8)
+				So(ledger.Entries[0].ExpiresAt, ShouldBeNil)
+			})
+		})
+	})
+
+	Convey("3. Given a closed cart gets refunded", t, func() {
+
+		repo := &mockRepo.MockLoyaltyRepository{}
+		clock := &mockService.MockClock{}
+		clock.On("Now").Return(now)
+		uc := NewLoyaltyUsecase(repo, WithClock(clock))
+		refunded := &cartUsecase.CartRefunded{CartID: "u01", UserID: "123", RefundedAt: now}
+
+		Convey("-> Negative Scenarios", func() {
+			Convey("-> The earned points already spent should only be taken back as far as they are left", func() {
+				ledger := &Ledger{UserID: "123", Entries: []*LedgerEntry{
+					{ID: "1", CartID: "u01", Kind: enum.Earned, Points: 50},
+					{ID: "2", CartID: "u02", Kind: enum.Earned, Points: 10, Remaining: 10},
+					{ID: "3", CartID: "u03", Kind: enum.Redeemed, Points: -50},
+				}}
+				repo.On("FetchLedger", "123").Return(ledger, nil)
+				repo.On("SaveLedger", ledger).Return(nil)
+				So(uc.HandleCartEvent(refunded), ShouldBeNil)
+				So(ledger.Entries[3], ShouldResemble, &LedgerEntry{ID: "4", CartID: "u01", Kind: enum.Reversed, Points: -10, RefID: "1", CreatedAt: now})
+				So(ledger.Entries[1].Remaining, ShouldEqual, 0)
+			})
+			Convey("-> A cart having neither earned nor redeemed any point should leave the ledger alone", func() {
+				repo.On("FetchLedger", "123").Return(&Ledger{UserID: "123", Entries: make([]*LedgerEntry, 0)}, nil)
+				So(uc.HandleCartEvent(refunded), ShouldBeNil)
+				repo.AssertNotCalled(t, "SaveLedger", mock.Anything)
+			})
+		})
+
+		Convey("-> Positive Scenarios", func() {
+			Convey("-> The redeemed points should be given back and the earned ones taken back, once", func() {
+				ledger := &Ledger{UserID: "123", Entries: []*LedgerEntry{
+					{ID: "1", CartID: "u00", Kind: enum.Earned, Points: 120, Remaining: 20},
+					{ID: "2", CartID: "u01", Kind: enum.Redeemed, Points: -100},
+					{ID: "3", CartID: "u01", Kind: enum.Earned, Points: 28, Remaining: 28},
+				}}
+				repo.On("FetchLedger", "123").Return(ledger, nil)
+				repo.On("SaveLedger", ledger).Return(nil)
+				So(uc.HandleCartEvent(refunded), ShouldBeNil)
+				So(uc.HandleCartEvent(refunded), ShouldBeNil)
+				So(ledger.Entries[3:], ShouldResemble, []*LedgerEntry{
+					{ID: "4", CartID: "u01", Kind: enum.Reversed, Points: 100, Remaining: 100, RefID: "2", CreatedAt: now},
+					{ID: "5", CartID: "u01", Kind: enum.Reversed, Points: -28, RefID: "3", CreatedAt: now},
+				})
+				So(available(ledger), ShouldEqual, 120)
+				repo.AssertNumberOfCalls(t, "SaveLedger", 1)
+			})
+		})
+	})
+
+	Convey("4. Given a user checks the loyalty points balance", t, func() {
+
+		repo := &mockRepo.MockLoyaltyRepository{}
+		clock := &mockService.MockClock{}
+		clock.On("Now").Return(now)
+		ledger := &Ledger{UserID: "123", Entries: []*LedgerEntry{
+			{ID: "1", CartID: "u00", Kind: enum.Earned, Points: 40, Remaining: 30, ExpiresAt: at(-1)},
+			{ID: "2", CartID: "u01", Kind: enum.Earned, Points: 25, Remaining: 25, ExpiresAt: at(20)},
+			{ID: "3", CartID: "u02", Kind: enum.Earned, Points: 15, Remaining: 15, ExpiresAt: at(20)},
+			{ID: "4", CartID: "u03", Kind: enum.Earned, Points: 60, Remaining: 60, ExpiresAt: at(90)},
+		}}
+		repo.On("FetchLedger", "123").Return(ledger, nil)
+		repo.On("SaveLedger", ledger).Return(nil)
+		uc := NewLoyaltyUsecase(repo, WithPointValue(0.5), WithClock(clock))
+
+		Convey("-> Negative Scenarios", func() {
+			Convey("-> Should not let a user check the points of another user", func() {
+				bound := uc.ForPrincipal(&authUsecase.Principal{UserID: "456", Role: enum.Customer})
+				res, err := bound.FetchBalance("123")
+				So(res, ShouldBeNil)
+				So(err, ShouldHaveSameTypeAs, &e.ErrForbidden{})
+				So(err.Error(), ShouldEqual, "user 456 is not allowed to access the loyalty points of user 123")
+			})
+			Convey("-> Should require the user", func() {
+				_, err := uc.FetchBalance("")
+				So(err, ShouldHaveSameTypeAs, &e.ErrInvalidData{})
+			})
+		})
+
+		Convey("-> Positive Scenarios", func() {
+			Convey("-> The points whose validity is over should be expired first", func() {
+				res, err := uc.ForPrincipal(&authUsecase.Principal{UserID: "123", Role: enum.Customer}).FetchBalance("123")
+				So(err, ShouldBeNil)
+				balance := res.(*Balance)
+				So(balance.Points, ShouldEqual, 100)
+				So(balance.Value, ShouldEqual, 50)
+				So(*balance.NextExpiry, ShouldEqual, *at(20))
+				So(balance.ExpiringPoints, ShouldEqual, 40)
+				So(balance.Entries[4], ShouldResemble, &LedgerEntry{ID: "5", Kind: enum.Expired, Points: -30, RefID: "1", CreatedAt: now})
+				repo.AssertCalled(t, "SaveLedger", ledger)
+			})
+			Convey("-> The ledger should be left alone once nothing is left to expire", func() {
+				ledger.Entries = ledger.Entries[1:]
+				res, err := uc.FetchBalance("123")
+				So(err, ShouldBeNil)
+				So(res.(*Balance).Points, ShouldEqual, 100)
+				repo.AssertNotCalled(t, "SaveLedger", mock.Anything)
+			})
+		})
+	})
+}
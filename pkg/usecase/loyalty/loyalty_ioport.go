@@ -0,0 +1,8 @@
+package loyalty
+
+type LoyaltyInputPort interface {
+	FetchBalance(userID string) (interface{}, error)
+	RedeemPoints(userID string, cartID string, points int, upTo float64) (float64, error)
+	RestorePoints(userID string, cartID string) error
+	HandleCartEvent(event interface{}) error
+}
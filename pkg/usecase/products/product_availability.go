@@ -0,0 +1,71 @@
+package products
+
+import (
+	"fmt"
+	"time"
+
+	. "github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+)
+
+// StockPolicy lets the product be ordered beyond its stock, either as a backorder shipped once the stock is
+// replenished or as a pre-order shipped upon the release at AvailableAt. Limit caps the units waiting for
+// the stock (zero means no cap), Committed counts the ones checked out so far.
+type StockPolicy struct {
+	Mode        Fulfillment
+	AvailableAt *time.Time
+	Limit       int
+	Committed   int
+}
+
+// SetStockPolicy lets the product be backordered or pre-ordered, a nil policy lifts it. The units already
+// checked out keep waiting for the stock, they are still counted against the limit of the new policy.
+func (prod *ProductUsecase) SetStockPolicy(id string, policy *StockPolicy) (interface{}, error) {
+	p, err := prod.FindByProductID(id)
+	if err != nil {
+		return nil, err
+	}
+	product := p.(*Product)
+
+	if policy != nil {
+		if policy.Mode == PreOrder && policy.AvailableAt != nil && !policy.AvailableAt.After(prod.clock.Now()) {
+			return nil, e.NewErrInvalidData("invalid stock policy, the pre-order is released in the past")
+		}
+		policy.Committed = 0
+		if product.StockPolicy != nil {
+			policy.Committed = product.StockPolicy.Committed
+		}
+	}
+	product.StockPolicy = policy
+	if err := validateProduct(product); err != nil {
+		return nil, err
+	}
+
+	if err := prod.repo.Update(product); err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+func validateStockPolicy(p *Product) error {
+	policy := p.StockPolicy
+	if policy == nil {
+		return nil
+	}
+	if p.IsBundle() {
+		return e.NewErrInvalidData("invalid stock policy, a bundle ships out of the stock of its components")
+	}
+	switch policy.Mode {
+	case Backorder:
+	case PreOrder:
+		if policy.AvailableAt == nil {
+			return e.NewErrInvalidData("invalid stock policy, 'available_at' is missing for a pre-order")
+		}
+	default:
+		return e.NewErrInvalidData(fmt.Sprintf("invalid stock policy, unknown mode %s, should be backorder or preorder", policy.Mode))
+	}
+	if policy.Limit < 0 || policy.Committed < 0 {
+		return e.NewErrInvalidData("invalid stock policy, 'limit' cannot be negative")
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package products
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/yauritux/cartsvc/pkg/domain/aggregate"
+	"github.com/yauritux/cartsvc/pkg/domain/entity"
+	vo "github.com/yauritux/cartsvc/pkg/domain/valueobject"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+)
+
+// ShowProduct returns the product as it is listed, a bundle carrying the price, the discount and the stock
+// made out of its components at the current time
+func (prod *ProductUsecase) ShowProduct(id string) (interface{}, error) {
+	product, err := prod.findProduct(id)
+	if err != nil {
+		return nil, err
+	}
+	if !product.IsBundle() {
+		return product, nil
+	}
+
+	components := make([]*Product, 0)
+	for _, c := range product.Components {
+		component, err := prod.findProduct(c.ProductID)
+		if err != nil {
+			return nil, err
+		}
+		components = append(components, component)
+	}
+	return PriceBundle(product, components, prod.clock.Now())
+}
+
+// PriceBundle returns a copy of the bundle carrying the price, the discount and the stock made out of
+// the given components, each of them priced at the given time for the units a bundle consumes
+func PriceBundle(bundle *Product, components []*Product, now time.Time) (*Product, error) {
+	consumed := make(map[string]int)
+	for _, c := range bundle.Components {
+		consumed[c.ProductID] += c.Qty
+	}
+	entities := make([]*entity.Product, 0)
+	for _, p := range components {
+		entities = append(entities, aggregate.PriceProductAt(BuildProductEntity(p), consumed[p.ID], now))
+	}
+	productBundle, err := aggregate.NewProductBundle(BuildProductEntity(bundle), entities)
+	if err != nil {
+		return nil, e.NewErrInvalidData(fmt.Sprintf("cannot price bundle %s, %s", bundle.ID, err.Error()))
+	}
+
+	priced := *bundle
+	priced.Price, priced.Disc = productBundle.Price()
+	priced.Stock = productBundle.Available()
+	return &priced, nil
+}
+
+// BuildProductEntity maps the product into the domain entity the aggregates work on
+func BuildProductEntity(p *Product) *entity.Product {
+	product := &entity.Product{
+		ID:             p.ID,
+		Name:           p.Name,
+		Type:           p.Type,
+		Stock:          p.Stock,
+		WarehouseStock: p.WarehouseStock,
+		Price:          p.Price,
+		Disc:           p.Disc,
+		CategoryIDs:    p.CategoryIDs,
+		Variants:       make([]*entity.Variant, 0),
+	}
+	for _, v := range p.Variants {
+		product.Variants = append(product.Variants, &entity.Variant{
+			SKU:            v.SKU,
+			Options:        v.Options,
+			Stock:          v.Stock,
+			WarehouseStock: v.WarehouseStock,
+			Price:          v.Price,
+			Disc:           v.Disc,
+		})
+	}
+	for _, c := range p.Components {
+		product.Components = append(product.Components, &vo.BundleComponent{
+			ProdID: c.ProductID,
+			SKU:    c.SKU,
+			Qty:    c.Qty,
+		})
+	}
+	if p.BundlePricing != nil {
+		product.BundlePricing = &vo.BundlePricing{Mode: p.BundlePricing.Mode, Value: p.BundlePricing.Value}
+	}
+	if l := p.PurchaseLimit; l != nil {
+		product.PurchaseLimit = &vo.PurchaseLimit{MaxPerOrder: l.MaxPerOrder, MaxPerCustomer: l.MaxPerCustomer, Period: l.Period}
+	}
+	if s := p.StockPolicy; s != nil {
+		product.StockPolicy = &vo.StockPolicy{Mode: s.Mode, AvailableAt: s.AvailableAt, Limit: s.Limit, Committed: s.Committed}
+	}
+	for _, s := range p.PriceSchedules {
+		product.PriceSchedules = append(product.PriceSchedules, &vo.PriceSchedule{
+			ID:       s.ID,
+			SKU:      s.SKU,
+			Price:    s.Price,
+			Disc:     s.Disc,
+			StartsAt: s.StartsAt,
+			EndsAt:   s.EndsAt,
+			Quantity: s.Quantity,
+			Sold:     s.Sold,
+		})
+	}
+	return product
+}
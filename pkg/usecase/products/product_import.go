@@ -0,0 +1,171 @@
+package products
+
+import (
+	"fmt"
+
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+)
+
+type ImportMode string
+
+const (
+	// InsertOnly rejects the rows of the products which already exist in the catalog
+	InsertOnly ImportMode = "insert"
+	// Upsert creates the new products and overwrites the existing ones
+	Upsert ImportMode = "upsert"
+)
+
+type ImportAction string
+
+const (
+	Created ImportAction = "created"
+	Updated ImportAction = "updated"
+	Failed  ImportAction = "failed"
+)
+
+// ImportRow is a product read from an import file, Err is set when the row could not even be parsed
+type ImportRow struct {
+	Line    int
+	Product *Product
+	Err     error
+}
+
+type ImportResult struct {
+	Line      int
+	ProductID string
+	Action    ImportAction
+	Err       error
+}
+
+type ImportReport struct {
+	DryRun  bool
+	Created int
+	Updated int
+	Failed  int
+	Results []*ImportResult
+}
+
+// ImportProducts validates then saves every row independently, a failing row is reported
+// without aborting the others. Nothing is saved on a dry run, yet the report tells what would happen.
+func (prod *ProductUsecase) ImportProducts(rows []*ImportRow, mode ImportMode, dryRun bool) (interface{}, error) {
+	switch mode {
+	case "":
+		mode = InsertOnly
+	case InsertOnly, Upsert:
+	default:
+		return nil, e.NewErrInvalidData(fmt.Sprintf("unknown import mode %s", mode))
+	}
+
+	report := &ImportReport{DryRun: dryRun, Results: make([]*ImportResult, 0)}
+	imported := make(map[string]*Product)
+	lookup := func(id string) (*Product, error) {
+		if p, ok := imported[id]; ok {
+			return p, nil
+		}
+		return prod.findProduct(id)
+	}
+
+	for _, row := range rows {
+		result := &ImportResult{Line: row.Line}
+		if row.Product != nil {
+			result.ProductID = row.Product.ID
+		}
+		action, err := prod.importRow(row, mode, dryRun, imported, lookup)
+		if err != nil {
+			result.Action = Failed
+			result.Err = err
+			report.Failed++
+		} else {
+			result.Action = action
+			if action == Created {
+				report.Created++
+			} else {
+				report.Updated++
+			}
+			imported[row.Product.ID] = row.Product
+		}
+		report.Results = append(report.Results, result)
+	}
+	return report, nil
+}
+
+func (prod *ProductUsecase) importRow(row *ImportRow, mode ImportMode, dryRun bool,
+	imported map[string]*Product, lookup func(id string) (*Product, error)) (ImportAction, error) {
+	if row.Err != nil {
+		return Failed, row.Err
+	}
+	if row.Product == nil {
+		return Failed, e.NewErrInvalidData("empty row")
+	}
+	p := row.Product
+	if err := validateProduct(p); err != nil {
+		return Failed, err
+	}
+	if _, ok := imported[p.ID]; ok {
+		return Failed, e.NewErrDuplicateData(fmt.Sprintf("product %s is imported more than once", p.ID))
+	}
+	if err := verifyComponents(p, lookup); err != nil {
+		return Failed, err
+	}
+
+	action := Created
+	if existing, err := prod.repo.FindByProductID(p.ID); err == nil {
+		if mode != Upsert {
+			return Failed, e.NewErrDuplicateData("product " + p.ID + " already exists")
+		}
+		action = Updated
+		//the catalog files do not carry the price schedules, an upsert keeps the scheduled ones
+		if current, ok := existing.(*Product); ok && len(p.PriceSchedules) == 0 {
+			p.PriceSchedules = current.PriceSchedules
+			if err := validatePriceSchedules(p); err != nil {
+				return Failed, err
+			}
+		}
+		if current, ok := existing.(*Product); ok {
+			keepWarehouseStock(p, current)
+			//nor the stock policy, which also counts the units waiting for the stock
+			p.StockPolicy = current.StockPolicy
+		}
+	} else if _, notFound := err.(*e.ErrNoData); !notFound {
+		return Failed, err
+	}
+
+	if dryRun {
+		return action, nil
+	}
+	if action == Created {
+		return action, prod.repo.Create(p)
+	}
+	return action, prod.repo.Update(p)
+}
+
+// ExportProducts pages through the whole catalog, the deleted products are left out
+func (prod *ProductUsecase) ExportProducts() (interface{}, error) {
+	products := make([]*Product, 0)
+	cursor := ""
+	for {
+		res, err := prod.SearchProducts(&ProductQuery{Cursor: cursor, Limit: maxPageSize})
+		if err != nil {
+			return nil, err
+		}
+		page := res.(*ProductPage)
+		products = append(products, page.Items...)
+		if page.NextCursor == "" {
+			return products, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// keepWarehouseStock keeps the stock spread among the warehouses when the row leaves it out along with
+// an unchanged stock, a changed stock lands in the main warehouse
+func keepWarehouseStock(p *Product, current *Product) {
+	if len(p.WarehouseStock) == 0 && p.Stock == current.Stock {
+		p.WarehouseStock = current.WarehouseStock
+	}
+	for _, v := range p.Variants {
+		if cv := current.FindVariant(v.SKU); cv != nil && len(v.WarehouseStock) == 0 && v.Stock == cv.Stock {
+			v.WarehouseStock = cv.WarehouseStock
+		}
+	}
+}
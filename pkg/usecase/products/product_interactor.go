@@ -2,24 +2,118 @@ package products
 
 import (
 	"fmt"
+	"time"
 
+	"github.com/yauritux/cartsvc/pkg/domain/entity"
 	"github.com/yauritux/cartsvc/pkg/domain/repository"
+	"github.com/yauritux/cartsvc/pkg/domain/service"
+	. "github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
 )
 
 type ProductUsecase struct {
-	repo repository.ProductRepository
+	repo          repository.ProductRepository
+	warehouseRepo repository.WarehouseRepository
+	clock         service.Clock
+	publisher     service.EventPublisher
 }
 
 type Product struct {
-	ID    string
-	Name  string
-	Stock int
-	Price float64
-	Disc  float64
+	ID            string
+	Name          string
+	Type          ProductType
+	Stock         int
+	Price         float64
+	Disc          float64
+	CategoryIDs   []string
+	Variants      []*Variant
+	Components    []*BundleComponent
+	BundlePricing *BundlePricing
+	PurchaseLimit *PurchaseLimit
+	// PriceSchedules are the scheduled price changes and flash sales of the product
+	PriceSchedules []*PriceSchedule
+	// WarehouseStock spreads the Stock among the warehouses, it all sits in the main warehouse when empty
+	WarehouseStock map[string]int
+	// StockPolicy lets the product be backordered or pre-ordered, it sells its stock only when nil
+	StockPolicy *StockPolicy
 }
 
-func NewProductUsecase(r repository.ProductRepository) *ProductUsecase {
-	return &ProductUsecase{r}
+type Variant struct {
+	SKU            string
+	Options        map[string]string
+	Stock          int
+	Price          float64
+	Disc           float64
+	WarehouseStock map[string]int
+}
+
+// BundleComponent is a product (or one of its variants, identified by the SKU)
+// consumed by a single unit of a bundle product
+type BundleComponent struct {
+	ProductID string
+	SKU       string
+	Qty       int
+}
+
+// BundlePricing overrides the default bundle price, which is the sum of its components
+type BundlePricing struct {
+	Mode  BundlePricingMode
+	Value float64
+}
+
+// PurchaseLimit caps the units a customer can buy, MaxPerCustomer counts the units checked out
+// within the last Period (ever when the Period is zero). A zero maximum means no limit.
+type PurchaseLimit struct {
+	MaxPerOrder    int
+	MaxPerCustomer int
+	Period         time.Duration
+}
+
+// IsBundle tells whether the product is sold as a bundle of other products
+func (p *Product) IsBundle() bool {
+	return p.Type == BundleProduct
+}
+
+// FindVariant returns the variant of the product identified by the SKU, or nil if there's none
+func (p *Product) FindVariant(sku string) *Variant {
+	for _, v := range p.Variants {
+		if v.SKU == sku {
+			return v
+		}
+	}
+	return nil
+}
+
+// Option configures the optional collaborators of the ProductUsecase
+type Option func(*ProductUsecase)
+
+// WithClock sets the clock the price schedules are checked against
+func WithClock(c service.Clock) Option {
+	return func(uc *ProductUsecase) {
+		uc.clock = c
+	}
+}
+
+// WithWarehouseRepository lets the stock be adjusted per warehouse, the warehouses being checked against it
+func WithWarehouseRepository(r repository.WarehouseRepository) Option {
+	return func(uc *ProductUsecase) {
+		uc.warehouseRepo = r
+	}
+}
+
+// WithEventPublisher publishes the restocks (see ProductRestocked), e.g. for the subscribers to be notified
+func WithEventPublisher(p service.EventPublisher) Option {
+	return func(uc *ProductUsecase) {
+		uc.publisher = p
+	}
+}
+
+func NewProductUsecase(r repository.ProductRepository, opts ...Option) *ProductUsecase {
+	uc := &ProductUsecase{repo: r, clock: service.SystemClock{}}
+	for _, opt := range opts {
+		opt(uc)
+	}
+	return uc
 }
 
 func (prod *ProductUsecase) FindByProductID(id string) (interface{}, error) {
@@ -35,3 +129,331 @@ func (prod *ProductUsecase) FindByProductID(id string) (interface{}, error) {
 
 	return productFound, nil
 }
+
+func (prod *ProductUsecase) CreateProduct(p interface{}) (interface{}, error) {
+	newProduct, ok := p.(*Product)
+	if !ok {
+		return nil, e.NewErrConversion("cannot create product, invalid type of product usecase model")
+	}
+	if err := validateProduct(newProduct); err != nil {
+		return nil, err
+	}
+	if err := prod.verifyComponents(newProduct); err != nil {
+		return nil, err
+	}
+
+	if err := prod.repo.Create(newProduct); err != nil {
+		return nil, err
+	}
+	return newProduct, nil
+}
+
+func (prod *ProductUsecase) UpdateProduct(p interface{}) (interface{}, error) {
+	updatedProduct, ok := p.(*Product)
+	if !ok {
+		return nil, e.NewErrConversion("cannot update product, invalid type of product usecase model")
+	}
+	if err := validateProduct(updatedProduct); err != nil {
+		return nil, err
+	}
+	if _, err := prod.FindByProductID(updatedProduct.ID); err != nil {
+		return nil, err
+	}
+	if err := prod.verifyComponents(updatedProduct); err != nil {
+		return nil, err
+	}
+
+	if err := prod.repo.Update(updatedProduct); err != nil {
+		return nil, err
+	}
+	return updatedProduct, nil
+}
+
+// DeleteProduct soft deletes the product, it is no longer available for purchase (the open carts holding it
+// get the line flagged as unavailable upon refresh) yet kept by the repository for the orders referencing it
+func (prod *ProductUsecase) DeleteProduct(id string) error {
+	if id == "" {
+		return e.NewErrInvalidData("cannot delete product, 'product_id' is missing")
+	}
+	return prod.repo.Delete(id)
+}
+
+// AdjustStock increases (positive delta) or decreases (negative delta) the product's stock,
+// held by the main warehouse
+func (prod *ProductUsecase) AdjustStock(id string, delta int) (interface{}, error) {
+	p, err := prod.FindByProductID(id)
+	if err != nil {
+		return nil, err
+	}
+	product := p.(*Product)
+
+	if err := product.AdjustWarehouseStock("", entity.MainWarehouse, delta); err != nil {
+		return nil, err
+	}
+	return prod.saveStock(product, "", delta)
+}
+
+func (prod *ProductUsecase) SetPrice(id string, price float64, disc float64) (interface{}, error) {
+	p, err := prod.FindByProductID(id)
+	if err != nil {
+		return nil, err
+	}
+	product := p.(*Product)
+
+	product.Price = price
+	product.Disc = disc
+	if err := validateProduct(product); err != nil {
+		return nil, err
+	}
+
+	if err := prod.repo.Update(product); err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+// SetPurchaseLimit caps the units of the product a customer can buy, a nil limit lifts it
+func (prod *ProductUsecase) SetPurchaseLimit(id string, limit *PurchaseLimit) (interface{}, error) {
+	p, err := prod.FindByProductID(id)
+	if err != nil {
+		return nil, err
+	}
+	product := p.(*Product)
+
+	product.PurchaseLimit = limit
+	if err := validateProduct(product); err != nil {
+		return nil, err
+	}
+
+	if err := prod.repo.Update(product); err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+func (prod *ProductUsecase) AdjustVariantStock(id string, sku string, delta int) (interface{}, error) {
+	p, err := prod.FindByProductID(id)
+	if err != nil {
+		return nil, err
+	}
+	product := p.(*Product)
+
+	if product.FindVariant(sku) == nil {
+		return nil, e.NewErrNoData(fmt.Sprintf("no variant %s found for product %s", sku, id))
+	}
+	if err := product.AdjustWarehouseStock(sku, entity.MainWarehouse, delta); err != nil {
+		return nil, err
+	}
+	return prod.saveStock(product, sku, delta)
+}
+
+func (prod *ProductUsecase) SetVariantPrice(id string, sku string, price float64, disc float64) (interface{}, error) {
+	p, err := prod.FindByProductID(id)
+	if err != nil {
+		return nil, err
+	}
+	product := p.(*Product)
+
+	variant := product.FindVariant(sku)
+	if variant == nil {
+		return nil, e.NewErrNoData(fmt.Sprintf("no variant %s found for product %s", sku, id))
+	}
+	variant.Price = price
+	variant.Disc = disc
+	if err := validateProduct(product); err != nil {
+		return nil, err
+	}
+
+	if err := prod.repo.Update(product); err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+func (prod *ProductUsecase) ListProducts(cursor string, limit int) (interface{}, error) {
+	return prod.SearchProducts(&ProductQuery{Cursor: cursor, Limit: limit})
+}
+
+func (prod *ProductUsecase) SearchProducts(q interface{}) (interface{}, error) {
+	query, ok := q.(*ProductQuery)
+	if !ok {
+		return nil, e.NewErrConversion("cannot search products, invalid type of product query")
+	}
+
+	switch query.SortBy {
+	case "":
+		query.SortBy = SortByName
+	case SortByName, SortByPrice:
+	default:
+		return nil, e.NewErrInvalidData(fmt.Sprintf("cannot sort products by %s", query.SortBy))
+	}
+	if query.MinPrice != nil && query.MaxPrice != nil && *query.MinPrice > *query.MaxPrice {
+		return nil, e.NewErrInvalidData("invalid price range, 'min_price' is greater than 'max_price'")
+	}
+	if query.Limit <= 0 {
+		query.Limit = defaultPageSize
+	}
+	if query.Limit > maxPageSize {
+		query.Limit = maxPageSize
+	}
+	query.PricedAt = prod.clock.Now()
+
+	res, err := prod.repo.Search(query)
+	if err != nil {
+		return nil, err
+	}
+	page, ok := res.(*ProductPage)
+	if !ok {
+		return nil, e.NewErrConversion("cannot search products, got an invalid product page returned from the repository")
+	}
+	return page, nil
+}
+
+func validateProduct(p *Product) error {
+	if p.ID == "" {
+		return e.NewErrInvalidData("invalid product, 'product_id' is missing")
+	}
+	if p.Name == "" {
+		return e.NewErrInvalidData("invalid product, 'name' is missing")
+	}
+	if p.Stock < 0 {
+		return e.NewErrInvalidData("invalid product, 'stock' cannot be negative")
+	}
+	if p.Price < 0 {
+		return e.NewErrInvalidData("invalid product, 'price' cannot be negative")
+	}
+	if p.Disc < 0 || p.Disc > p.Price {
+		return e.NewErrInvalidData("invalid product, 'disc' should be between zero and the price")
+	}
+	if err := validateWarehouseStock("product", p.Stock, p.WarehouseStock); err != nil {
+		return err
+	}
+
+	skus := make(map[string]bool)
+	for _, v := range p.Variants {
+		if v.SKU == "" {
+			return e.NewErrInvalidData("invalid product variant, 'sku' is missing")
+		}
+		if skus[v.SKU] {
+			return e.NewErrInvalidData("invalid product variant, duplicate sku " + v.SKU)
+		}
+		skus[v.SKU] = true
+		if v.Stock < 0 {
+			return e.NewErrInvalidData("invalid product variant " + v.SKU + ", 'stock' cannot be negative")
+		}
+		if v.Price < 0 {
+			return e.NewErrInvalidData("invalid product variant " + v.SKU + ", 'price' cannot be negative")
+		}
+		if v.Disc < 0 || v.Disc > v.Price {
+			return e.NewErrInvalidData("invalid product variant " + v.SKU + ", 'disc' should be between zero and the price")
+		}
+		if err := validateWarehouseStock("product variant "+v.SKU, v.Stock, v.WarehouseStock); err != nil {
+			return err
+		}
+	}
+	if err := validatePurchaseLimit(p.PurchaseLimit); err != nil {
+		return err
+	}
+	if err := validatePriceSchedules(p); err != nil {
+		return err
+	}
+	if err := validateStockPolicy(p); err != nil {
+		return err
+	}
+	return validateBundle(p)
+}
+
+func validatePurchaseLimit(limit *PurchaseLimit) error {
+	if limit == nil {
+		return nil
+	}
+	if limit.MaxPerOrder < 0 || limit.MaxPerCustomer < 0 {
+		return e.NewErrInvalidData("invalid purchase limit, the maximum cannot be negative")
+	}
+	if limit.Period < 0 {
+		return e.NewErrInvalidData("invalid purchase limit, 'period' cannot be negative")
+	}
+	if limit.Period > 0 && limit.MaxPerCustomer == 0 {
+		return e.NewErrInvalidData("invalid purchase limit, 'period' only applies to the maximum per customer")
+	}
+	if limit.MaxPerCustomer > 0 && limit.MaxPerOrder > limit.MaxPerCustomer {
+		return e.NewErrInvalidData("invalid purchase limit, the maximum per order exceeds the maximum per customer")
+	}
+	return nil
+}
+
+func validateBundle(p *Product) error {
+	switch p.Type {
+	case "", SimpleProduct:
+		if len(p.Components) > 0 || p.BundlePricing != nil {
+			return e.NewErrInvalidData("invalid product, only a bundle can have components")
+		}
+		return nil
+	case BundleProduct:
+	default:
+		return e.NewErrInvalidData(fmt.Sprintf("invalid product, unknown product type %s", p.Type))
+	}
+
+	if len(p.Components) == 0 {
+		return e.NewErrInvalidData("invalid bundle, 'components' is missing")
+	}
+	if len(p.Variants) > 0 {
+		return e.NewErrInvalidData("invalid bundle, a bundle cannot have variants")
+	}
+	for _, c := range p.Components {
+		if c.ProductID == "" {
+			return e.NewErrInvalidData("invalid bundle component, 'product_id' is missing")
+		}
+		if c.ProductID == p.ID {
+			return e.NewErrInvalidData("invalid bundle component, a bundle cannot contain itself")
+		}
+		if c.Qty <= 0 {
+			return e.NewErrInvalidData("invalid bundle component " + c.ProductID + ", 'qty' should be greater than zero")
+		}
+	}
+
+	if pricing := p.BundlePricing; pricing != nil {
+		switch pricing.Mode {
+		case FixedBundlePrice:
+			if pricing.Value < 0 {
+				return e.NewErrInvalidData("invalid bundle pricing, fixed price cannot be negative")
+			}
+		case PercentOffBundle:
+			if pricing.Value < 0 || pricing.Value > 100 {
+				return e.NewErrInvalidData("invalid bundle pricing, percentage should be between 0 and 100")
+			}
+		default:
+			return e.NewErrInvalidData(fmt.Sprintf("invalid bundle pricing, unknown mode %s", pricing.Mode))
+		}
+	}
+	return nil
+}
+
+// verifyComponents makes sure every component of the bundle refers to an existing non bundle product
+func (prod *ProductUsecase) verifyComponents(p *Product) error {
+	return verifyComponents(p, prod.findProduct)
+}
+
+func (prod *ProductUsecase) findProduct(id string) (*Product, error) {
+	found, err := prod.FindByProductID(id)
+	if err != nil {
+		return nil, err
+	}
+	return found.(*Product), nil
+}
+
+func verifyComponents(p *Product, lookup func(id string) (*Product, error)) error {
+	for _, c := range p.Components {
+		component, err := lookup(c.ProductID)
+		if err != nil {
+			return err
+		}
+		if component.IsBundle() {
+			return e.NewErrInvalidData(fmt.Sprintf("invalid bundle, %s cannot contain another bundle %s", p.ID, c.ProductID))
+		}
+		if c.SKU != "" && c.SKU != component.ID && component.FindVariant(c.SKU) == nil {
+			return e.NewErrNoData(fmt.Sprintf("no variant %s found for product %s", c.SKU, c.ProductID))
+		}
+	}
+	return nil
+}
@@ -4,11 +4,15 @@ import (
 	"errors"
 	"reflect"
 	"testing"
+	"time"
 
 	. "github.com/smartystreets/goconvey/convey"
 	"github.com/stretchr/testify/mock"
 	"github.com/yauritux/cartsvc/pkg/domain/entity"
+	"github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
 	mockProductRepo "github.com/yauritux/cartsvc/pkg/sharedkernel/mock/repository"
+	mockService "github.com/yauritux/cartsvc/pkg/sharedkernel/mock/service"
 )
 
 func TestProductUsecase(t *testing.T) {
@@ -58,6 +62,538 @@ func TestProductUsecase(t *testing.T) {
 					So(reflect.DeepEqual(res, sProduct), ShouldBeTrue)
 				})
 			})
+			Convey("-> Found a bundle", func() {
+				Convey("-> Should show the bundle at the price and the stock of its components", func() {
+					now := time.Date(2020, time.May, 1, 12, 0, 0, 0, time.UTC)
+					clock := &mockService.MockClock{}
+					clock.On("Now").Return(now)
+					prodRepo.On("FindByProductID", "001").Return(&Product{ID: "001", Name: "Shuriken", Stock: 10, Price: 250}, nil)
+					prodRepo.On("FindByProductID", "002").Return(&Product{ID: "002", Name: "Sai", Stock: 3, Price: 200,
+						PriceSchedules: []*PriceSchedule{{ID: "1", Price: 150, StartsAt: now, EndsAt: now.Add(time.Hour)}}}, nil)
+					prodRepo.On("FindByProductID", "004").Return(&Product{ID: "004", Name: "Ninja Training Set", Type: enum.BundleProduct,
+						Components:    []*BundleComponent{{ProductID: "001", Qty: 2}, {ProductID: "002", Qty: 1}},
+						BundlePricing: &BundlePricing{Mode: enum.PercentOffBundle, Value: 10}}, nil)
+					uc := NewProductUsecase(prodRepo, WithClock(clock))
+					res, err := uc.ShowProduct("004")
+					So(err, ShouldBeNil)
+					bundle := res.(*Product)
+					So(bundle.Price, ShouldEqual, 650)
+					So(bundle.Disc, ShouldEqual, 65)
+					So(bundle.Stock, ShouldEqual, 3)
+				})
+			})
+		})
+	})
+
+	Convey("2. Given an admin is managing the product catalog", t, func() {
+
+		prodRepo := &mockProductRepo.MockProductRepository{}
+		shuriken := func() *Product {
+			return &Product{ID: "001", Name: "Shuriken", Stock: 10, Price: 250.5, Disc: 0}
+		}
+
+		Convey("-> Negative Scenarios", func() {
+			Convey("-> Should reject a product with a negative price", func() {
+				uc := NewProductUsecase(prodRepo)
+				p := shuriken()
+				p.Price = -1
+				res, err := uc.CreateProduct(p)
+				So(res, ShouldBeNil)
+				So(err, ShouldHaveSameTypeAs, &e.ErrInvalidData{})
+				So(err.Error(), ShouldEqual, "invalid product, 'price' cannot be negative")
+				prodRepo.AssertNotCalled(t, "Create", mock.Anything)
+			})
+			Convey("-> Should reject a stock adjustment leading to a negative stock", func() {
+				prodRepo.On("FindByProductID", "001").Return(shuriken(), nil)
+				uc := NewProductUsecase(prodRepo)
+				res, err := uc.AdjustStock("001", -11)
+				So(res, ShouldBeNil)
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "cannot adjust stock of product 001 by -11, only 10 left")
+				prodRepo.AssertNotCalled(t, "Update", mock.Anything)
+			})
+			Convey("-> Should reject a discount greater than the price", func() {
+				prodRepo.On("FindByProductID", "001").Return(shuriken(), nil)
+				uc := NewProductUsecase(prodRepo)
+				res, err := uc.SetPrice("001", 100, 150)
+				So(res, ShouldBeNil)
+				So(err, ShouldHaveSameTypeAs, &e.ErrInvalidData{})
+			})
+			Convey("-> Should reject a bundle containing another bundle", func() {
+				prodRepo.On("FindByProductID", "004").Return(&Product{
+					ID: "004", Name: "Ninja Training Set", Type: enum.BundleProduct,
+					Components: []*BundleComponent{{ProductID: "001", Qty: 1}},
+				}, nil)
+				uc := NewProductUsecase(prodRepo)
+				res, err := uc.CreateProduct(&Product{
+					ID: "005", Name: "Ninja Deluxe Set", Type: enum.BundleProduct,
+					Components: []*BundleComponent{{ProductID: "004", Qty: 1}},
+				})
+				So(res, ShouldBeNil)
+				So(err, ShouldHaveSameTypeAs, &e.ErrInvalidData{})
+				So(err.Error(), ShouldEqual, "invalid bundle, 005 cannot contain another bundle 004")
+				prodRepo.AssertNotCalled(t, "Create", mock.Anything)
+			})
+			Convey("-> Should reject a bundle percentage off above 100", func() {
+				uc := NewProductUsecase(prodRepo)
+				res, err := uc.CreateProduct(&Product{
+					ID: "005", Name: "Ninja Deluxe Set", Type: enum.BundleProduct,
+					Components:    []*BundleComponent{{ProductID: "001", Qty: 1}},
+					BundlePricing: &BundlePricing{Mode: enum.PercentOffBundle, Value: 120},
+				})
+				So(res, ShouldBeNil)
+				So(err.Error(), ShouldEqual, "invalid bundle pricing, percentage should be between 0 and 100")
+			})
+			Convey("-> Should reject a purchase limit per order above the limit per customer", func() {
+				prodRepo.On("FindByProductID", "001").Return(shuriken(), nil)
+				uc := NewProductUsecase(prodRepo)
+				res, err := uc.SetPurchaseLimit("001", &PurchaseLimit{MaxPerOrder: 5, MaxPerCustomer: 3})
+				So(res, ShouldBeNil)
+				So(err, ShouldHaveSameTypeAs, &e.ErrInvalidData{})
+				So(err.Error(), ShouldEqual, "invalid purchase limit, the maximum per order exceeds the maximum per customer")
+				prodRepo.AssertNotCalled(t, "Update", mock.Anything)
+			})
+			Convey("-> Should reject a purchase limit period without a limit per customer", func() {
+				prodRepo.On("FindByProductID", "001").Return(shuriken(), nil)
+				uc := NewProductUsecase(prodRepo)
+				_, err := uc.SetPurchaseLimit("001", &PurchaseLimit{MaxPerOrder: 5, Period: time.Hour})
+				So(err.Error(), ShouldEqual, "invalid purchase limit, 'period' only applies to the maximum per customer")
+			})
+			Convey("-> Should not update a deleted product", func() {
+				prodRepo.On("FindByProductID", "001").Return(nil, e.NewErrNoData("no product found for id 001"))
+				uc := NewProductUsecase(prodRepo)
+				res, err := uc.UpdateProduct(shuriken())
+				So(res, ShouldBeNil)
+				So(err, ShouldHaveSameTypeAs, &e.ErrNoData{})
+			})
+		})
+
+		Convey("-> Positive Scenarios", func() {
+			Convey("-> Should create a new product", func() {
+				prodRepo.On("Create", mock.Anything).Return(nil)
+				uc := NewProductUsecase(prodRepo)
+				res, err := uc.CreateProduct(shuriken())
+				So(err, ShouldBeNil)
+				So(res.(*Product).ID, ShouldEqual, "001")
+			})
+			Convey("-> Should adjust the stock", func() {
+				prodRepo.On("FindByProductID", "001").Return(shuriken(), nil)
+				prodRepo.On("Update", mock.Anything).Return(nil)
+				uc := NewProductUsecase(prodRepo)
+				res, err := uc.AdjustStock("001", -4)
+				So(err, ShouldBeNil)
+				So(res.(*Product).Stock, ShouldEqual, 6)
+			})
+			Convey("-> Should set the price", func() {
+				prodRepo.On("FindByProductID", "001").Return(shuriken(), nil)
+				prodRepo.On("Update", mock.Anything).Return(nil)
+				uc := NewProductUsecase(prodRepo)
+				res, err := uc.SetPrice("001", 199.99, 10)
+				So(err, ShouldBeNil)
+				So(res.(*Product).Price, ShouldEqual, 199.99)
+				So(res.(*Product).Disc, ShouldEqual, 10)
+			})
+			Convey("-> Should set and lift the purchase limit", func() {
+				prodRepo.On("FindByProductID", "001").Return(shuriken(), nil)
+				prodRepo.On("Update", mock.Anything).Return(nil)
+				uc := NewProductUsecase(prodRepo)
+				limit := &PurchaseLimit{MaxPerOrder: 5, MaxPerCustomer: 10, Period: 7 * 24 * time.Hour}
+				res, err := uc.SetPurchaseLimit("001", limit)
+				So(err, ShouldBeNil)
+				So(res.(*Product).PurchaseLimit, ShouldResemble, limit)
+				res, err = uc.SetPurchaseLimit("001", nil)
+				So(err, ShouldBeNil)
+				So(res.(*Product).PurchaseLimit, ShouldBeNil)
+			})
+			Convey("-> Should soft delete the product", func() {
+				prodRepo.On("Delete", "001").Return(nil)
+				uc := NewProductUsecase(prodRepo)
+				So(uc.DeleteProduct("001"), ShouldBeNil)
+			})
+		})
+	})
+
+	Convey("3. Given a user is searching the product catalog", t, func() {
+
+		prodRepo := &mockProductRepo.MockProductRepository{}
+
+		Convey("-> Negative Scenarios", func() {
+			Convey("-> Should reject an unknown sort field", func() {
+				uc := NewProductUsecase(prodRepo)
+				res, err := uc.SearchProducts(&ProductQuery{SortBy: "stock"})
+				So(res, ShouldBeNil)
+				So(err, ShouldHaveSameTypeAs, &e.ErrInvalidData{})
+			})
+			Convey("-> Should reject an inverted price range", func() {
+				min, max := 500.0, 100.0
+				uc := NewProductUsecase(prodRepo)
+				res, err := uc.SearchProducts(&ProductQuery{MinPrice: &min, MaxPrice: &max})
+				So(res, ShouldBeNil)
+				So(err.Error(), ShouldEqual, "invalid price range, 'min_price' is greater than 'max_price'")
+			})
+		})
+
+		Convey("-> Positive Scenarios", func() {
+			Convey("-> Should apply the default sort field and page size, pricing the bundles at the current time", func() {
+				now := time.Date(2020, time.May, 1, 12, 0, 0, 0, time.UTC)
+				clock := &mockService.MockClock{}
+				clock.On("Now").Return(now)
+				page := &ProductPage{Items: []*Product{{ID: "001", Name: "Shuriken"}}}
+				prodRepo.On("Search", &ProductQuery{SortBy: SortByName, Limit: defaultPageSize, PricedAt: now}).Return(page, nil)
+				uc := NewProductUsecase(prodRepo, WithClock(clock))
+				res, err := uc.ListProducts("", 0)
+				So(err, ShouldBeNil)
+				So(res, ShouldEqual, page)
+			})
+			Convey("-> Should cap the page size", func() {
+				prodRepo.On("Search", mock.Anything).Return(&ProductPage{}, nil)
+				uc := NewProductUsecase(prodRepo)
+				query := &ProductQuery{Keyword: "ninja", Limit: 1000}
+				_, err := uc.SearchProducts(query)
+				So(err, ShouldBeNil)
+				So(query.Limit, ShouldEqual, maxPageSize)
+			})
+		})
+	})
+
+	Convey("4. Given an admin imports a product catalog file", t, func() {
+
+		prodRepo := &mockProductRepo.MockProductRepository{}
+		prodRepo.On("FindByProductID", "001").Return(&Product{ID: "001", Name: "Shuriken", Stock: 10, Price: 250.5}, nil)
+		prodRepo.On("FindByProductID", mock.Anything).Return(nil, e.NewErrNoData("product not found"))
+		rows := func() []*ImportRow {
+			return []*ImportRow{
+				{Line: 2, Product: &Product{ID: "001", Name: "Shuriken", Stock: 20, Price: 250.5}},
+				{Line: 3, Product: &Product{ID: "005", Name: "Kunai", Stock: 300, Price: 90}},
+				{Line: 4, Product: &Product{
+					ID: "007", Name: "Kunai Set", Type: enum.BundleProduct,
+					Components: []*BundleComponent{{ProductID: "005", Qty: 3}},
+				}},
+				{Line: 5, Product: &Product{ID: "006", Name: "Tabi", Price: -1}},
+				{Line: 6, Err: e.NewErrInvalidData("invalid stock 'abc', should be a number")},
+			}
+		}
+
+		Convey("-> Negative Scenarios", func() {
+			Convey("-> Should reject an unknown import mode", func() {
+				uc := NewProductUsecase(prodRepo)
+				res, err := uc.ImportProducts(rows(), ImportMode("merge"), false)
+				So(res, ShouldBeNil)
+				So(err, ShouldHaveSameTypeAs, &e.ErrInvalidData{})
+			})
+			Convey("-> Should report the failing rows without aborting the others", func() {
+				prodRepo.On("Create", mock.Anything).Return(nil)
+				uc := NewProductUsecase(prodRepo)
+				res, err := uc.ImportProducts(rows(), InsertOnly, false)
+				So(err, ShouldBeNil)
+				report := res.(*ImportReport)
+				So(report.Created, ShouldEqual, 2)
+				So(report.Failed, ShouldEqual, 3)
+				So(report.Results[0].Err, ShouldHaveSameTypeAs, &e.ErrDuplicateData{})
+				So(report.Results[3].Err.Error(), ShouldEqual, "invalid product, 'price' cannot be negative")
+				So(report.Results[4].Line, ShouldEqual, 6)
+				prodRepo.AssertNumberOfCalls(t, "Create", 2)
+			})
+		})
+
+		Convey("-> Positive Scenarios", func() {
+			Convey("-> Should overwrite the existing products on upsert", func() {
+				prodRepo.On("Create", mock.Anything).Return(nil)
+				prodRepo.On("Update", mock.Anything).Return(nil)
+				uc := NewProductUsecase(prodRepo)
+				res, err := uc.ImportProducts(rows()[:3], Upsert, false)
+				So(err, ShouldBeNil)
+				report := res.(*ImportReport)
+				So(report.Updated, ShouldEqual, 1)
+				So(report.Created, ShouldEqual, 2)
+				So(report.Results[0].Action, ShouldEqual, Updated)
+			})
+			Convey("-> Should not save anything on a dry run", func() {
+				uc := NewProductUsecase(prodRepo)
+				res, err := uc.ImportProducts(rows()[:3], Upsert, true)
+				So(err, ShouldBeNil)
+				So(res.(*ImportReport).Failed, ShouldEqual, 0)
+				prodRepo.AssertNotCalled(t, "Create", mock.Anything)
+				prodRepo.AssertNotCalled(t, "Update", mock.Anything)
+			})
+		})
+	})
+
+	Convey("5. Given an admin schedules the price of a product", t, func() {
+
+		prodRepo := &mockProductRepo.MockProductRepository{}
+		clock := &mockService.MockClock{}
+		now := time.Date(2020, time.May, 1, 12, 0, 0, 0, time.UTC)
+		clock.On("Now").Return(now)
+		gi := func() *Product {
+			return &Product{
+				ID: "003", Name: "Ninja Gi", Price: 320,
+				Variants: []*Variant{{SKU: "003-BLK-M", Stock: 5, Price: 320}},
+				PriceSchedules: []*PriceSchedule{
+					{ID: "1", Disc: 20, StartsAt: now.Add(-48 * time.Hour), EndsAt: now.Add(-24 * time.Hour)},
+					{ID: "2", SKU: "003-BLK-M", Price: 250, StartsAt: now, EndsAt: now.Add(time.Hour)},
+				},
+			}
+		}
+
+		Convey("-> Negative Scenarios", func() {
+			Convey("-> Should reject a schedule ending in the past", func() {
+				prodRepo.On("FindByProductID", "003").Return(gi(), nil)
+				uc := NewProductUsecase(prodRepo, WithClock(clock))
+				res, err := uc.SchedulePrice("003", &PriceSchedule{Price: 200, StartsAt: now.Add(-2 * time.Hour), EndsAt: now.Add(-time.Hour)})
+				So(res, ShouldBeNil)
+				So(err, ShouldHaveSameTypeAs, &e.ErrInvalidData{})
+				So(err.Error(), ShouldEqual, "invalid price schedule, it ends in the past")
+			})
+			Convey("-> Should reject a schedule overlapping another one of the same SKU", func() {
+				prodRepo.On("FindByProductID", "003").Return(gi(), nil)
+				uc := NewProductUsecase(prodRepo, WithClock(clock))
+				_, err := uc.SchedulePrice("003", &PriceSchedule{SKU: "003-BLK-M", Price: 200, StartsAt: now.Add(30 * time.Minute), EndsAt: now.Add(2 * time.Hour)})
+				So(err.Error(), ShouldEqual, "invalid price schedule 3, it overlaps the price schedule 2")
+				prodRepo.AssertNotCalled(t, "Update", mock.Anything)
+			})
+			Convey("-> Should reject a discount above the regular price", func() {
+				prodRepo.On("FindByProductID", "003").Return(gi(), nil)
+				uc := NewProductUsecase(prodRepo, WithClock(clock))
+				_, err := uc.SchedulePrice("003", &PriceSchedule{Disc: 400, StartsAt: now, EndsAt: now.Add(time.Hour)})
+				So(err.Error(), ShouldEqual, "invalid price schedule 3, 'disc' should be between zero and the price")
+			})
+			Convey("-> Should not cancel an unknown schedule", func() {
+				prodRepo.On("FindByProductID", "003").Return(gi(), nil)
+				uc := NewProductUsecase(prodRepo, WithClock(clock))
+				_, err := uc.CancelPriceSchedule("003", "9")
+				So(err, ShouldHaveSameTypeAs, &e.ErrNoData{})
+			})
+		})
+
+		Convey("-> Positive Scenarios", func() {
+			Convey("-> Should add the schedule and drop the ended ones", func() {
+				prodRepo.On("FindByProductID", "003").Return(gi(), nil)
+				prodRepo.On("Update", mock.Anything).Return(nil)
+				uc := NewProductUsecase(prodRepo, WithClock(clock))
+				res, err := uc.SchedulePrice("003", &PriceSchedule{Disc: 20, StartsAt: now, EndsAt: now.Add(time.Hour), Quantity: 10, Sold: 4})
+				So(err, ShouldBeNil)
+				schedules := res.(*Product).PriceSchedules
+				So(schedules, ShouldHaveLength, 2)
+				So(schedules[0].ID, ShouldEqual, "2")
+				So(schedules[1].ID, ShouldEqual, "3")
+				So(schedules[1].Sold, ShouldEqual, 0)
+			})
+			Convey("-> Should cancel a schedule", func() {
+				prodRepo.On("FindByProductID", "003").Return(gi(), nil)
+				prodRepo.On("Update", mock.Anything).Return(nil)
+				uc := NewProductUsecase(prodRepo, WithClock(clock))
+				res, err := uc.CancelPriceSchedule("003", "2")
+				So(err, ShouldBeNil)
+				So(res.(*Product).PriceSchedules, ShouldHaveLength, 1)
+			})
+		})
+	})
+
+	Convey("6. Given an admin spreads the stock of a product among the warehouses", t, func() {
+
+		prodRepo := &mockProductRepo.MockProductRepository{}
+		warehouseRepo := &mockProductRepo.MockWarehouseRepository{}
+		warehouseRepo.On("FindByWarehouseID", "main").Return(&Warehouse{ID: "main"}, nil)
+		warehouseRepo.On("FindByWarehouseID", "medan").Return(&Warehouse{ID: "medan"}, nil)
+		warehouseRepo.On("FindByWarehouseID", "bandung").Return(nil, e.NewErrNoData("no warehouse found for id bandung"))
+		gi := func() *Product {
+			return &Product{
+				ID: "003", Name: "Ninja Gi", Price: 320,
+				Variants: []*Variant{{SKU: "003-BLK-M", Stock: 5, Price: 320, WarehouseStock: map[string]int{"main": 3, "medan": 2}}},
+			}
+		}
+
+		Convey("-> Negative Scenarios", func() {
+			Convey("-> Should reject an unknown warehouse", func() {
+				uc := NewProductUsecase(prodRepo, WithWarehouseRepository(warehouseRepo))
+				res, err := uc.AdjustWarehouseStock("003", "003-BLK-M", "bandung", 5)
+				So(res, ShouldBeNil)
+				So(err, ShouldHaveSameTypeAs, &e.ErrNoData{})
+				prodRepo.AssertNotCalled(t, "Update", mock.Anything)
+			})
+			Convey("-> Should not take out more than the stock of the warehouse", func() {
+				prodRepo.On("FindByProductID", "003").Return(gi(), nil)
+				uc := NewProductUsecase(prodRepo, WithWarehouseRepository(warehouseRepo))
+				res, err := uc.AdjustWarehouseStock("003", "003-BLK-M", "medan", -3)
+				So(res, ShouldBeNil)
+				So(err.Error(), ShouldEqual, "cannot adjust stock of variant 003-BLK-M by -3, only 2 left in warehouse medan")
+			})
+			Convey("-> Should reject warehouse stock not adding up to the stock", func() {
+				p := gi()
+				p.Variants[0].WarehouseStock["medan"] = 1
+				uc := NewProductUsecase(prodRepo)
+				res, err := uc.CreateProduct(p)
+				So(res, ShouldBeNil)
+				So(err, ShouldHaveSameTypeAs, &e.ErrInvalidData{})
+				prodRepo.AssertNotCalled(t, "Create", mock.Anything)
+			})
+		})
+
+		Convey("-> Positive Scenarios", func() {
+			Convey("-> Should spread the stock of the main warehouse upon the first other warehouse", func() {
+				prodRepo.On("FindByProductID", "001").Return(&Product{ID: "001", Name: "Shuriken", Stock: 10, Price: 250.5}, nil)
+				prodRepo.On("Update", mock.Anything).Return(nil)
+				uc := NewProductUsecase(prodRepo, WithWarehouseRepository(warehouseRepo))
+				res, err := uc.AdjustWarehouseStock("001", "", "medan", 4)
+				So(err, ShouldBeNil)
+				So(res.(*Product).Stock, ShouldEqual, 14)
+				So(res.(*Product).WarehouseStock, ShouldResemble, map[string]int{"main": 10, "medan": 4})
+			})
+			Convey("-> Should take the stock adjusted without a warehouse out of the main warehouse", func() {
+				prodRepo.On("FindByProductID", "003").Return(gi(), nil)
+				prodRepo.On("Update", mock.Anything).Return(nil)
+				uc := NewProductUsecase(prodRepo)
+				res, err := uc.AdjustVariantStock("003", "003-BLK-M", -3)
+				So(err, ShouldBeNil)
+				So(res.(*Product).Variants[0].Stock, ShouldEqual, 2)
+				So(res.(*Product).Variants[0].WarehouseStock, ShouldResemble, map[string]int{"medan": 2})
+			})
+			Convey("-> Should list the main warehouse alone without a warehouse repository", func() {
+				res, err := NewProductUsecase(prodRepo).ListWarehouses()
+				So(err, ShouldBeNil)
+				So(res.([]*Warehouse), ShouldHaveLength, 1)
+				So(res.([]*Warehouse)[0].ID, ShouldEqual, "main")
+			})
+		})
+	})
+
+	Convey("7. Given an admin lets a product be ordered beyond its stock", t, func() {
+
+		prodRepo := &mockProductRepo.MockProductRepository{}
+		clock := &mockService.MockClock{}
+		now := time.Date(2020, time.May, 1, 12, 0, 0, 0, time.UTC)
+		releasedAt := now.Add(30 * 24 * time.Hour)
+		clock.On("Now").Return(now)
+		prodRepo.On("Update", mock.Anything).Return(nil)
+		shuriken := func() *Product {
+			return &Product{
+				ID: "001", Name: "Shuriken", Stock: 10, Price: 250.5,
+				StockPolicy: &StockPolicy{Mode: enum.Backorder, Limit: 20, Committed: 4},
+			}
+		}
+
+		Convey("-> Negative Scenarios", func() {
+			Convey("-> Should require the release of a pre-order", func() {
+				prodRepo.On("FindByProductID", "001").Return(shuriken(), nil)
+				uc := NewProductUsecase(prodRepo, WithClock(clock))
+				res, err := uc.SetStockPolicy("001", &StockPolicy{Mode: enum.PreOrder})
+				So(res, ShouldBeNil)
+				So(err, ShouldHaveSameTypeAs, &e.ErrInvalidData{})
+				So(err.Error(), ShouldEqual, "invalid stock policy, 'available_at' is missing for a pre-order")
+				past := now.Add(-time.Hour)
+				_, err = uc.SetStockPolicy("001", &StockPolicy{Mode: enum.PreOrder, AvailableAt: &past})
+				So(err.Error(), ShouldEqual, "invalid stock policy, the pre-order is released in the past")
+				prodRepo.AssertNotCalled(t, "Update", mock.Anything)
+			})
+			Convey("-> Should reject an unknown mode", func() {
+				prodRepo.On("FindByProductID", "001").Return(shuriken(), nil)
+				_, err := NewProductUsecase(prodRepo).SetStockPolicy("001", &StockPolicy{Mode: enum.InStock})
+				So(err.Error(), ShouldEqual, "invalid stock policy, unknown mode in_stock, should be backorder or preorder")
+			})
+			Convey("-> Should reject a stock policy on a bundle", func() {
+				bundle := &Product{
+					ID: "004", Name: "Ninja Starter Kit", Type: enum.BundleProduct,
+					Components:  []*BundleComponent{{ProductID: "001", Qty: 2}},
+					StockPolicy: &StockPolicy{Mode: enum.Backorder},
+				}
+				res, err := NewProductUsecase(prodRepo).CreateProduct(bundle)
+				So(res, ShouldBeNil)
+				So(err.Error(), ShouldEqual, "invalid stock policy, a bundle ships out of the stock of its components")
+			})
+		})
+
+		Convey("-> Positive Scenarios", func() {
+			Convey("-> Should keep counting the units already waiting for the stock", func() {
+				prodRepo.On("FindByProductID", "001").Return(shuriken(), nil)
+				uc := NewProductUsecase(prodRepo, WithClock(clock))
+				res, err := uc.SetStockPolicy("001", &StockPolicy{Mode: enum.PreOrder, AvailableAt: &releasedAt, Limit: 50, Committed: 99})
+				So(err, ShouldBeNil)
+				policy := res.(*Product).StockPolicy
+				So(policy.Mode, ShouldEqual, enum.PreOrder)
+				So(*policy.AvailableAt, ShouldEqual, releasedAt)
+				So(policy.Committed, ShouldEqual, 4)
+			})
+			Convey("-> Should lift the stock policy", func() {
+				prodRepo.On("FindByProductID", "001").Return(shuriken(), nil)
+				res, err := NewProductUsecase(prodRepo).SetStockPolicy("001", nil)
+				So(err, ShouldBeNil)
+				So(res.(*Product).StockPolicy, ShouldBeNil)
+				prodRepo.AssertCalled(t, "Update", res)
+			})
+		})
+	})
+
+	Convey("8. Given some users wait for a product to be restocked", t, func() {
+
+		prodRepo := &mockProductRepo.MockProductRepository{}
+		publisher := &mockService.MockEventPublisher{}
+		clock := &mockService.MockClock{}
+		now := time.Date(2020, time.May, 1, 12, 0, 0, 0, time.UTC)
+		clock.On("Now").Return(now)
+		ninjaGi := func() *Product {
+			return &Product{
+				ID: "003", Name: "Ninja Gi", Stock: 40,
+				Variants: []*Variant{
+					{SKU: "003-BLK-M", Options: map[string]string{"color": "black", "size": "M"}, Stock: 40, Price: 320},
+					{SKU: "003-NVY-M", Options: map[string]string{"color": "navy", "size": "M"}, Stock: 0, Price: 335},
+				},
+			}
+		}
+
+		Convey("-> Negative Scenarios", func() {
+			Convey("-> Should not publish anything when the stock fails to be saved", func() {
+				prodRepo.On("FindByProductID", "003").Return(ninjaGi(), nil)
+				prodRepo.On("Update", mock.Anything).Return(errors.New("Database error"))
+				uc := NewProductUsecase(prodRepo, WithEventPublisher(publisher))
+				res, err := uc.AdjustVariantStock("003", "003-NVY-M", 5)
+				So(res, ShouldBeNil)
+				So(err.Error(), ShouldEqual, "Database error")
+				publisher.AssertNotCalled(t, "Publish", mock.Anything)
+			})
+			Convey("-> Should return the adjusted product along with a failure to publish", func() {
+				prodRepo.On("FindByProductID", "003").Return(ninjaGi(), nil)
+				prodRepo.On("Update", mock.Anything).Return(nil)
+				publisher.On("Publish", mock.Anything).Return(errors.New("mailbox is full"))
+				uc := NewProductUsecase(prodRepo, WithEventPublisher(publisher))
+				res, err := uc.AdjustVariantStock("003", "003-NVY-M", 5)
+				So(res.(*Product).FindVariant("003-NVY-M").Stock, ShouldEqual, 5)
+				So(err.Error(), ShouldEqual, "stock of 003-NVY-M adjusted, but cannot publish the restock: mailbox is full")
+			})
+		})
+
+		Convey("-> Positive Scenarios", func() {
+			Convey("-> Should publish the restock of the variant once saved", func() {
+				prodRepo.On("FindByProductID", "003").Return(ninjaGi(), nil)
+				prodRepo.On("Update", mock.Anything).Return(nil)
+				publisher.On("Publish", mock.Anything).Return(nil)
+				uc := NewProductUsecase(prodRepo, WithEventPublisher(publisher), WithClock(clock))
+				_, err := uc.AdjustWarehouseStock("003", "003-NVY-M", "main", 5)
+				So(err, ShouldBeNil)
+				publisher.AssertCalled(t, "Publish", &ProductRestocked{
+					ProductID: "003", SKU: "003-NVY-M", Name: "Ninja Gi", Added: 5, Stock: 5, RestockedAt: now,
+				})
+			})
+			Convey("-> Should publish the restock of a product without variants under its ID", func() {
+				prodRepo.On("FindByProductID", "001").Return(&Product{ID: "001", Name: "Shuriken", Price: 250.5}, nil)
+				prodRepo.On("Update", mock.Anything).Return(nil)
+				publisher.On("Publish", mock.Anything).Return(nil)
+				uc := NewProductUsecase(prodRepo, WithEventPublisher(publisher), WithClock(clock))
+				_, err := uc.AdjustStock("001", 3)
+				So(err, ShouldBeNil)
+				publisher.AssertCalled(t, "Publish", &ProductRestocked{
+					ProductID: "001", SKU: "001", Name: "Shuriken", Added: 3, Stock: 3, RestockedAt: now,
+				})
+			})
+			Convey("-> Should not publish anything when the stock is taken out", func() {
+				prodRepo.On("FindByProductID", "003").Return(ninjaGi(), nil)
+				prodRepo.On("Update", mock.Anything).Return(nil)
+				uc := NewProductUsecase(prodRepo, WithEventPublisher(publisher))
+				res, err := uc.AdjustVariantStock("003", "003-BLK-M", -5)
+				So(err, ShouldBeNil)
+				So(res.(*Product).FindVariant("003-BLK-M").Stock, ShouldEqual, 35)
+				publisher.AssertNotCalled(t, "Publish", mock.Anything)
+			})
 		})
 	})
 }
@@ -0,0 +1,23 @@
+package products
+
+type ProductInputPort interface {
+	FindByProductID(id string) (interface{}, error)
+	ShowProduct(id string) (interface{}, error)
+	CreateProduct(product interface{}) (interface{}, error)
+	UpdateProduct(product interface{}) (interface{}, error)
+	DeleteProduct(id string) error
+	AdjustStock(id string, delta int) (interface{}, error)
+	SetPrice(id string, price float64, disc float64) (interface{}, error)
+	SetPurchaseLimit(id string, limit *PurchaseLimit) (interface{}, error)
+	SchedulePrice(id string, schedule *PriceSchedule) (interface{}, error)
+	CancelPriceSchedule(id string, scheduleID string) (interface{}, error)
+	SetStockPolicy(id string, policy *StockPolicy) (interface{}, error)
+	AdjustVariantStock(id string, sku string, delta int) (interface{}, error)
+	AdjustWarehouseStock(id string, sku string, warehouseID string, delta int) (interface{}, error)
+	ListWarehouses() (interface{}, error)
+	SetVariantPrice(id string, sku string, price float64, disc float64) (interface{}, error)
+	ListProducts(cursor string, limit int) (interface{}, error)
+	SearchProducts(query interface{}) (interface{}, error)
+	ImportProducts(rows []*ImportRow, mode ImportMode, dryRun bool) (interface{}, error)
+	ExportProducts() (interface{}, error)
+}
@@ -0,0 +1,37 @@
+package products
+
+import "time"
+
+type SortField string
+
+const (
+	SortByName  SortField = "name"
+	SortByPrice SortField = "price"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// ProductQuery is the search criteria of the product listing, every criteria is optional
+type ProductQuery struct {
+	Keyword     string
+	MinPrice    *float64
+	MaxPrice    *float64
+	InStockOnly bool
+	// CategoryIDs matches the products assigned to any of the given categories
+	CategoryIDs []string
+	SortBy      SortField
+	Descending  bool
+	// Cursor is the opaque position returned as NextCursor of the previous page
+	Cursor string
+	Limit  int
+	// PricedAt is the time the bundles are priced at out of their components, set by the use case
+	PricedAt time.Time
+}
+
+type ProductPage struct {
+	Items      []*Product
+	NextCursor string
+}
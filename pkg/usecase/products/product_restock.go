@@ -0,0 +1,44 @@
+package products
+
+import (
+	"fmt"
+	"time"
+)
+
+// ProductRestocked is published whenever some units are added to the stock of a product, SKU being
+// the product ID for a product without variants. Stock is the whole stock of the SKU once restocked.
+type ProductRestocked struct {
+	ProductID   string
+	SKU         string
+	Name        string
+	Added       int
+	Stock       int
+	RestockedAt time.Time
+}
+
+// saveStock persists the stock adjusted by delta, the restock is published once the stock has been saved.
+// A failure to publish is returned along with the saved product.
+func (prod *ProductUsecase) saveStock(product *Product, sku string, delta int) (interface{}, error) {
+	if err := prod.repo.Update(product); err != nil {
+		return nil, err
+	}
+	if delta <= 0 || prod.publisher == nil {
+		return product, nil
+	}
+
+	event := &ProductRestocked{
+		ProductID:   product.ID,
+		SKU:         product.ID,
+		Name:        product.Name,
+		Added:       delta,
+		Stock:       product.Stock,
+		RestockedAt: prod.clock.Now(),
+	}
+	if v := product.FindVariant(sku); v != nil {
+		event.SKU, event.Stock = v.SKU, v.Stock
+	}
+	if err := prod.publisher.Publish(event); err != nil {
+		return product, fmt.Errorf("stock of %s adjusted, but cannot publish the restock: %v", event.SKU, err)
+	}
+	return product, nil
+}
@@ -0,0 +1,174 @@
+package products
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+)
+
+// PriceSchedule overrides the price of the product, or of one of its variants when SKU is set, from StartsAt
+// until EndsAt. A zero Price keeps the regular price and only applies the discount. A flash sale sells at most
+// Quantity units at the scheduled price (zero means no cap), Sold counts the units checked out so far.
+type PriceSchedule struct {
+	ID       string
+	SKU      string
+	Price    float64
+	Disc     float64
+	StartsAt time.Time
+	EndsAt   time.Time
+	Quantity int
+	Sold     int
+}
+
+// SchedulePrice adds the price schedule to the product, the schedules already ended are dropped along the way
+func (prod *ProductUsecase) SchedulePrice(id string, schedule *PriceSchedule) (interface{}, error) {
+	if schedule == nil {
+		return nil, e.NewErrInvalidData("cannot schedule price, the schedule is missing")
+	}
+	p, err := prod.FindByProductID(id)
+	if err != nil {
+		return nil, err
+	}
+	product := p.(*Product)
+
+	now := prod.clock.Now()
+	if !schedule.EndsAt.After(now) {
+		return nil, e.NewErrInvalidData("invalid price schedule, it ends in the past")
+	}
+	schedule.ID = nextScheduleID(product.PriceSchedules)
+	schedule.Sold = 0
+
+	schedules := make([]*PriceSchedule, 0)
+	for _, s := range product.PriceSchedules {
+		if s.EndsAt.After(now) {
+			schedules = append(schedules, s)
+		}
+	}
+	product.PriceSchedules = append(schedules, schedule)
+	if err := validateProduct(product); err != nil {
+		return nil, err
+	}
+
+	if err := prod.repo.Update(product); err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+// CancelPriceSchedule removes the price schedule from the product, the carts holding the scheduled
+// price get the regular one back upon their next refresh
+func (prod *ProductUsecase) CancelPriceSchedule(id string, scheduleID string) (interface{}, error) {
+	p, err := prod.FindByProductID(id)
+	if err != nil {
+		return nil, err
+	}
+	product := p.(*Product)
+
+	schedules := make([]*PriceSchedule, 0)
+	for _, s := range product.PriceSchedules {
+		if s.ID != scheduleID {
+			schedules = append(schedules, s)
+		}
+	}
+	if len(schedules) == len(product.PriceSchedules) {
+		return nil, e.NewErrNoData(fmt.Sprintf("no price schedule %s found for product %s", scheduleID, id))
+	}
+	product.PriceSchedules = schedules
+
+	if err := prod.repo.Update(product); err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+func validatePriceSchedules(p *Product) error {
+	if len(p.PriceSchedules) == 0 {
+		return nil
+	}
+	if p.IsBundle() {
+		return e.NewErrInvalidData("invalid price schedule, a bundle is priced out of its components")
+	}
+
+	ids := make(map[string]bool)
+	for i, s := range p.PriceSchedules {
+		if s.ID == "" {
+			return e.NewErrInvalidData("invalid price schedule, 'id' is missing")
+		}
+		if ids[s.ID] {
+			return e.NewErrInvalidData("invalid price schedule, duplicate id " + s.ID)
+		}
+		ids[s.ID] = true
+
+		regularPrice, ok := scheduledSKUPrice(p, s.SKU)
+		if !ok {
+			return e.NewErrInvalidData(fmt.Sprintf("invalid price schedule %s, no variant %s found", s.ID, s.SKU))
+		}
+		if !s.EndsAt.After(s.StartsAt) {
+			return e.NewErrInvalidData(fmt.Sprintf("invalid price schedule %s, 'ends_at' should be after 'starts_at'", s.ID))
+		}
+		if s.Price < 0 {
+			return e.NewErrInvalidData(fmt.Sprintf("invalid price schedule %s, 'price' cannot be negative", s.ID))
+		}
+		if s.Price > 0 {
+			regularPrice = s.Price
+		}
+		if s.Disc < 0 || s.Disc > regularPrice {
+			return e.NewErrInvalidData(fmt.Sprintf("invalid price schedule %s, 'disc' should be between zero and the price", s.ID))
+		}
+		if s.Quantity < 0 || s.Sold < 0 {
+			return e.NewErrInvalidData(fmt.Sprintf("invalid price schedule %s, 'quantity' cannot be negative", s.ID))
+		}
+		if s.Quantity > 0 && s.Sold > s.Quantity {
+			return e.NewErrInvalidData(fmt.Sprintf("invalid price schedule %s, more units sold than the quantity", s.ID))
+		}
+
+		for _, o := range p.PriceSchedules[:i] {
+			if scheduleTarget(p, o.SKU) == scheduleTarget(p, s.SKU) && s.StartsAt.Before(o.EndsAt) && o.StartsAt.Before(s.EndsAt) {
+				return e.NewErrInvalidData(fmt.Sprintf("invalid price schedule %s, it overlaps the price schedule %s", s.ID, o.ID))
+			}
+		}
+	}
+	return nil
+}
+
+// scheduledSKUPrice returns the regular price the schedule of the SKU applies to, the lowest of the variants
+// for a schedule of the whole product. It's false when the product has no such SKU.
+func scheduledSKUPrice(p *Product, sku string) (float64, bool) {
+	if scheduleTarget(p, sku) != "" {
+		v := p.FindVariant(sku)
+		if v == nil {
+			return 0, false
+		}
+		return v.Price, true
+	}
+	if len(p.Variants) == 0 {
+		return p.Price, true
+	}
+	lowest := p.Variants[0].Price
+	for _, v := range p.Variants {
+		if v.Price < lowest {
+			lowest = v.Price
+		}
+	}
+	return lowest, true
+}
+
+// scheduleTarget is the SKU a schedule is dedicated to, empty for the whole product
+func scheduleTarget(p *Product, sku string) string {
+	if sku == p.ID {
+		return ""
+	}
+	return sku
+}
+
+func nextScheduleID(schedules []*PriceSchedule) string {
+	last := 0
+	for _, s := range schedules {
+		if n, err := strconv.Atoi(s.ID); err == nil && n > last {
+			last = n
+		}
+	}
+	return strconv.Itoa(last + 1)
+}
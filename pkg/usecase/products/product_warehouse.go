@@ -0,0 +1,115 @@
+package products
+
+import (
+	"fmt"
+
+	"github.com/yauritux/cartsvc/pkg/domain/entity"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+)
+
+// Warehouse is a place the orders are shipped from
+type Warehouse struct {
+	ID       string
+	Name     string
+	City     string
+	Province string
+	Region   string
+	Postal   string
+	Country  string
+}
+
+// AdjustWarehouseStock increases (positive delta) or decreases (negative delta) the stock held by the warehouse,
+// the SKU identifies the variant and is left empty for a product without variants
+func (prod *ProductUsecase) AdjustWarehouseStock(id string, sku string, warehouseID string, delta int) (interface{}, error) {
+	if warehouseID == "" {
+		return nil, e.NewErrInvalidData("cannot adjust stock, 'warehouse_id' is missing")
+	}
+	if prod.warehouseRepo != nil {
+		if _, err := prod.warehouseRepo.FindByWarehouseID(warehouseID); err != nil {
+			return nil, err
+		}
+	}
+	p, err := prod.FindByProductID(id)
+	if err != nil {
+		return nil, err
+	}
+	product := p.(*Product)
+
+	if err := product.AdjustWarehouseStock(sku, warehouseID, delta); err != nil {
+		return nil, err
+	}
+	return prod.saveStock(product, sku, delta)
+}
+
+// AdjustWarehouseStock applies the delta to the stock of the product (or of its variant identified by the SKU)
+// held by the warehouse, the stock sitting in the main warehouse gets spread upon the first other warehouse
+func (p *Product) AdjustWarehouseStock(sku string, warehouseID string, delta int) error {
+	stock, levels, what := &p.Stock, &p.WarehouseStock, "product "+p.ID
+	if sku != "" && sku != p.ID {
+		variant := p.FindVariant(sku)
+		if variant == nil {
+			return e.NewErrNoData(fmt.Sprintf("no variant %s found for product %s", sku, p.ID))
+		}
+		stock, levels, what = &variant.Stock, &variant.WarehouseStock, "variant "+sku
+	}
+
+	if len(*levels) == 0 && warehouseID != entity.MainWarehouse {
+		*levels = map[string]int{entity.MainWarehouse: *stock}
+	}
+	level := *stock
+	if len(*levels) > 0 {
+		level = (*levels)[warehouseID]
+	}
+	if level+delta < 0 {
+		msg := fmt.Sprintf("cannot adjust stock of %s by %d, only %d left", what, delta, level)
+		if len(*levels) > 0 {
+			msg += " in warehouse " + warehouseID
+		}
+		return e.NewErrInvalidData(msg)
+	}
+
+	*stock += delta
+	if len(*levels) > 0 {
+		(*levels)[warehouseID] += delta
+		if (*levels)[warehouseID] == 0 {
+			delete(*levels, warehouseID)
+		}
+	}
+	return nil
+}
+
+// ListWarehouses returns the warehouses the orders are shipped from
+func (prod *ProductUsecase) ListWarehouses() (interface{}, error) {
+	if prod.warehouseRepo == nil {
+		return []*Warehouse{{ID: entity.MainWarehouse, Name: "Main Warehouse"}}, nil
+	}
+	res, err := prod.warehouseRepo.FetchAll()
+	if err != nil {
+		return nil, err
+	}
+	warehouses, ok := res.([]*Warehouse)
+	if !ok {
+		return nil, e.NewErrConversion("cannot list warehouses, invalid type of warehouse usecase model")
+	}
+	return warehouses, nil
+}
+
+func validateWarehouseStock(what string, stock int, levels map[string]int) error {
+	if len(levels) == 0 {
+		return nil
+	}
+	total := 0
+	for id, units := range levels {
+		if id == "" {
+			return e.NewErrInvalidData(fmt.Sprintf("invalid %s, the warehouse of its stock is missing", what))
+		}
+		if units < 0 {
+			return e.NewErrInvalidData(fmt.Sprintf("invalid %s, the stock of warehouse %s cannot be negative", what, id))
+		}
+		total += units
+	}
+	if total != stock {
+		return e.NewErrInvalidData(fmt.Sprintf("invalid %s, the warehouse stock should add up to 'stock'", what))
+	}
+	return nil
+}
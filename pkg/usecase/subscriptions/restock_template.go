@@ -0,0 +1,47 @@
+package subscriptions
+
+import (
+	"strings"
+	"text/template"
+
+	prodUsecase "github.com/yauritux/cartsvc/pkg/usecase/products"
+	userUsecase "github.com/yauritux/cartsvc/pkg/usecase/users"
+)
+
+var (
+	emailSubjectTemplate = template.Must(template.New("email_subject").Parse(
+		`{{.Product}} is back in stock`))
+
+	emailBodyTemplate = template.Must(template.New("email_body").Parse(`Hi {{.Name}},
+
+{{.Product}} is back in stock, {{.Stock}} unit(s) are waiting for you.
+
+Add it to your cart before it runs out again.
+`))
+
+	smsTemplate = template.Must(template.New("sms").Parse(
+		`Hi {{.Name}}, {{.Product}} is back in stock. Add it to your cart before it runs out again.`))
+)
+
+// restockView holds the restock as told to a subscriber
+type restockView struct {
+	Name    string
+	Product string
+	Stock   int
+}
+
+func buildRestockView(user *userUsecase.User, s *Subscription, restock *prodUsecase.ProductRestocked) *restockView {
+	name := user.Username
+	if name == "" {
+		name = user.ID
+	}
+	return &restockView{Name: name, Product: s.ProductName, Stock: restock.Stock}
+}
+
+func execute(t *template.Template, view *restockView) (string, error) {
+	var sb strings.Builder
+	if err := t.Execute(&sb, view); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,265 @@
+package subscriptions
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yauritux/cartsvc/pkg/domain/repository"
+	"github.com/yauritux/cartsvc/pkg/domain/service"
+	vo "github.com/yauritux/cartsvc/pkg/domain/valueobject"
+	. "github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+	authUsecase "github.com/yauritux/cartsvc/pkg/usecase/auth"
+	prodUsecase "github.com/yauritux/cartsvc/pkg/usecase/products"
+	userUsecase "github.com/yauritux/cartsvc/pkg/usecase/users"
+)
+
+type SubscriptionUsecase struct {
+	repo      repository.SubscriptionRepository
+	prodRepo  repository.ProductRepository
+	userRepo  repository.UserRepository
+	notifier  service.Notifier
+	clock     service.Clock
+	principal *authUsecase.Principal
+}
+
+// Subscription asks for the user to be notified once the SKU is back in stock, the SKU being the product ID
+// for a product without variants. The subscription is over once the user has been notified.
+type Subscription struct {
+	UserID      string
+	ProductID   string
+	SKU         string
+	ProductName string
+	CreatedAt   time.Time
+}
+
+// Option configures the optional collaborators of the SubscriptionUsecase
+type Option func(*SubscriptionUsecase)
+
+// WithNotifier sends the restock notifications, the subscriptions wait for one to be given otherwise
+func WithNotifier(n service.Notifier) Option {
+	return func(uc *SubscriptionUsecase) {
+		uc.notifier = n
+	}
+}
+
+func WithClock(c service.Clock) Option {
+	return func(uc *SubscriptionUsecase) {
+		uc.clock = c
+	}
+}
+
+func NewSubscriptionUsecase(r repository.SubscriptionRepository, prodRepo repository.ProductRepository,
+	userRepo repository.UserRepository, opts ...Option) *SubscriptionUsecase {
+	uc := &SubscriptionUsecase{repo: r, prodRepo: prodRepo, userRepo: userRepo, clock: service.SystemClock{}}
+	for _, opt := range opts {
+		opt(uc)
+	}
+	return uc
+}
+
+// ForPrincipal returns a copy of the use case only letting the principal manage the subscriptions it owns
+func (this *SubscriptionUsecase) ForPrincipal(p *authUsecase.Principal) *SubscriptionUsecase {
+	bound := *this
+	bound.principal = p
+	return &bound
+}
+
+func (this *SubscriptionUsecase) authorize(userID string) error {
+	if this.principal == nil || this.principal.CanAccess(userID) {
+		return nil
+	}
+	return e.NewErrForbidden(fmt.Sprintf("user %s is not allowed to access the subscriptions of user %s",
+		this.principal.UserID, userID))
+}
+
+// Subscribe asks for the user to be notified once the out of stock product (or its variant identified
+// by the SKU) gets restocked. Subscribing again to the same SKU keeps a single subscription.
+func (this *SubscriptionUsecase) Subscribe(userID string, productID string, sku string) (interface{}, error) {
+	if userID == "" {
+		return nil, e.NewErrInvalidData("cannot subscribe, 'user_id' is missing")
+	}
+	if err := this.authorize(userID); err != nil {
+		return nil, err
+	}
+	//a guest cannot be reached
+	if userUsecase.IsGuestID(userID) {
+		return nil, e.NewErrForbidden("a guest cannot be notified of a restock, please login first")
+	}
+	user, err := this.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if _, _, err := recipient(user); err != nil {
+		return nil, e.NewErrInvalidData("cannot subscribe, " + err.Error())
+	}
+
+	res, err := this.prodRepo.FindByProductID(productID)
+	if err != nil {
+		return nil, err
+	}
+	product, ok := res.(*prodUsecase.Product)
+	if !ok {
+		return nil, e.NewErrConversion("cannot subscribe, invalid type of product usecase model")
+	}
+	if product.IsBundle() {
+		return nil, e.NewErrInvalidData(fmt.Sprintf("cannot subscribe to bundle %s, subscribe to its components instead", product.ID))
+	}
+
+	subscription := &Subscription{UserID: userID, ProductID: product.ID, SKU: product.ID, ProductName: product.Name, CreatedAt: this.clock.Now()}
+	stock := product.Stock
+	switch {
+	case sku != "" && sku != product.ID:
+		v := product.FindVariant(sku)
+		if v == nil {
+			return nil, e.NewErrNoData(fmt.Sprintf("no variant %s found for product %s", sku, product.ID))
+		}
+		subscription.SKU, stock = v.SKU, v.Stock
+		if options := formatOptions(v.Options); options != "" {
+			subscription.ProductName += " (" + options + ")"
+		}
+	case len(product.Variants) > 0:
+		return nil, e.NewErrInvalidData(fmt.Sprintf("cannot subscribe to product %s, please choose one of its variants", product.ID))
+	}
+	if stock > 0 {
+		return nil, e.NewErrConflict(fmt.Sprintf("%s is in stock, no need to wait for a restock", subscription.ProductName))
+	}
+
+	if err := this.repo.Save(subscription); err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// Unsubscribe stops waiting for the SKU to be restocked
+func (this *SubscriptionUsecase) Unsubscribe(userID string, sku string) error {
+	if err := this.authorize(userID); err != nil {
+		return err
+	}
+	return this.repo.Remove(userID, sku)
+}
+
+// FetchSubscriptions returns the restocks the user is waiting for, the oldest subscription first
+func (this *SubscriptionUsecase) FetchSubscriptions(userID string) (interface{}, error) {
+	if err := this.authorize(userID); err != nil {
+		return nil, err
+	}
+	res, err := this.repo.FetchUserSubscriptions(userID)
+	if err != nil {
+		return nil, err
+	}
+	subscriptions, ok := res.([]*Subscription)
+	if !ok {
+		return nil, e.NewErrConversion("cannot fetch subscriptions, invalid type of subscription usecase model")
+	}
+	return subscriptions, nil
+}
+
+// HandleRestock notifies the subscribers of the SKU restocked by the ProductRestocked event, any other event
+// is ignored. A subscription is over once its user has been notified, the ones failing are kept for the next
+// restock and reported altogether once every subscriber has been visited.
+func (this *SubscriptionUsecase) HandleRestock(event interface{}) error {
+	restock, ok := event.(*prodUsecase.ProductRestocked)
+	if !ok || restock.Stock <= 0 || this.notifier == nil {
+		return nil
+	}
+	res, err := this.repo.FetchSKUSubscriptions(restock.SKU)
+	if err != nil {
+		return err
+	}
+	subscriptions, ok := res.([]*Subscription)
+	if !ok {
+		return errors.New("conversion failed, invalid type of subscription usecase model")
+	}
+
+	failures := make([]string, 0)
+	for _, s := range subscriptions {
+		if err := this.notify(s, restock); err != nil {
+			failures = append(failures, fmt.Sprintf("user %s: %v", s.UserID, err))
+			continue
+		}
+		//the notification is out already, a failure to drop the subscription may only lead to one more notification
+		if err := this.repo.Remove(s.UserID, s.SKU); err != nil {
+			failures = append(failures, fmt.Sprintf("user %s: %v", s.UserID, err))
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("failed to notify %d subscriber(s) of %s: %s", len(failures), restock.SKU, strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+func (this *SubscriptionUsecase) notify(s *Subscription, restock *prodUsecase.ProductRestocked) error {
+	user, err := this.findUser(s.UserID)
+	if err != nil {
+		return err
+	}
+	n, err := renderRestock(user, s, restock)
+	if err != nil {
+		return err
+	}
+	return this.notifier.Send(n)
+}
+
+func (this *SubscriptionUsecase) findUser(userID string) (*userUsecase.User, error) {
+	if this.userRepo == nil {
+		return nil, errors.New("cannot reach the subscribers without any user repository")
+	}
+	u, err := this.userRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	user, ok := u.(*userUsecase.User)
+	if !ok {
+		return nil, errors.New("conversion failed, invalid type of user usecase model")
+	}
+	return user, nil
+}
+
+// recipient reaches the user by email, or by SMS when there's no email
+func recipient(user *userUsecase.User) (NotificationChannel, string, error) {
+	switch {
+	case user.Email != "":
+		return EmailChannel, user.Email, nil
+	case user.Phone != "":
+		return SMSChannel, user.Phone, nil
+	default:
+		return "", "", fmt.Errorf("user %s has neither an email nor a phone", user.ID)
+	}
+}
+
+func renderRestock(user *userUsecase.User, s *Subscription, restock *prodUsecase.ProductRestocked) (vo.Notification, error) {
+	channel, to, err := recipient(user)
+	if err != nil {
+		return vo.Notification{}, err
+	}
+	n := vo.Notification{Channel: channel, Recipient: to}
+	view := buildRestockView(user, s, restock)
+
+	switch channel {
+	case EmailChannel:
+		if n.Subject, err = execute(emailSubjectTemplate, view); err != nil {
+			return n, err
+		}
+		n.Body, err = execute(emailBodyTemplate, view)
+	case SMSChannel:
+		n.Body, err = execute(smsTemplate, view)
+	}
+	return n, err
+}
+
+func formatOptions(options map[string]string) string {
+	keys := make([]string, 0, len(options))
+	for k := range options {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k+": "+options[k])
+	}
+	return strings.Join(pairs, ", ")
+}
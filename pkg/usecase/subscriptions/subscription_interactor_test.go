@@ -0,0 +1,168 @@
+package subscriptions
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/stretchr/testify/mock"
+	vo "github.com/yauritux/cartsvc/pkg/domain/valueobject"
+	"github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
+	mockRepo "github.com/yauritux/cartsvc/pkg/sharedkernel/mock/repository"
+	mockService "github.com/yauritux/cartsvc/pkg/sharedkernel/mock/service"
+	authUsecase "github.com/yauritux/cartsvc/pkg/usecase/auth"
+	prodUsecase "github.com/yauritux/cartsvc/pkg/usecase/products"
+	userUsecase "github.com/yauritux/cartsvc/pkg/usecase/users"
+)
+
+func TestSubscriptionUsecase(t *testing.T) {
+
+	now := time.Date(2020, time.May, 1, 12, 0, 0, 0, time.UTC)
+	ninjaGi := func() *prodUsecase.Product {
+		return &prodUsecase.Product{
+			ID: "003", Name: "Ninja Gi", Stock: 40,
+			Variants: []*prodUsecase.Variant{
+				{SKU: "003-BLK-M", Options: map[string]string{"color": "black", "size": "M"}, Stock: 40, Price: 320},
+				{SKU: "003-NVY-M", Options: map[string]string{"color": "navy", "size": "M"}, Stock: 0, Price: 335},
+			},
+		}
+	}
+	yauri := &userUsecase.User{ID: "123", Username: "yauritux", Email: "yauritux@gmail.com"}
+	hanzo := &userUsecase.User{ID: "456", Username: "hanzo", Phone: "+81000000"}
+
+	Convey("1. Given a user hits an out of stock product", t, func() {
+
+		repo := &mockRepo.MockSubscriptionRepository{}
+		prodRepo := &mockRepo.MockProductRepository{}
+		userRepo := &mockRepo.MockUserRepository{}
+		clock := &mockService.MockClock{}
+		clock.On("Now").Return(now)
+		userRepo.On("FindByUserID", "123").Return(yauri, nil)
+		prodRepo.On("FindByProductID", "003").Return(ninjaGi(), nil)
+		repo.On("Save", mock.Anything).Return(nil)
+		uc := NewSubscriptionUsecase(repo, prodRepo, userRepo, WithClock(clock))
+
+		Convey("-> Negative Scenarios", func() {
+			Convey("-> Should not let a guest subscribe", func() {
+				res, err := uc.Subscribe(userUsecase.GuestIDPrefix+"abc", "003", "003-NVY-M")
+				So(res, ShouldBeNil)
+				So(err, ShouldHaveSameTypeAs, &e.ErrForbidden{})
+				So(err.Error(), ShouldEqual, "a guest cannot be notified of a restock, please login first")
+			})
+			Convey("-> Should not let a user subscribe for another user", func() {
+				bound := uc.ForPrincipal(&authUsecase.Principal{UserID: "456", Role: enum.Customer})
+				_, err := bound.Subscribe("123", "003", "003-NVY-M")
+				So(err, ShouldHaveSameTypeAs, &e.ErrForbidden{})
+				So(err.Error(), ShouldEqual, "user 456 is not allowed to access the subscriptions of user 123")
+			})
+			Convey("-> Should not let a user who cannot be reached subscribe", func() {
+				userRepo.On("FindByUserID", "789").Return(&userUsecase.User{ID: "789"}, nil)
+				_, err := uc.Subscribe("789", "003", "003-NVY-M")
+				So(err, ShouldHaveSameTypeAs, &e.ErrInvalidData{})
+				So(err.Error(), ShouldEqual, "cannot subscribe, user 789 has neither an email nor a phone")
+			})
+			Convey("-> Should require a variant of a product having variants", func() {
+				_, err := uc.Subscribe("123", "003", "")
+				So(err, ShouldHaveSameTypeAs, &e.ErrInvalidData{})
+				So(err.Error(), ShouldEqual, "cannot subscribe to product 003, please choose one of its variants")
+				_, err = uc.Subscribe("123", "003", "003-RED-M")
+				So(err, ShouldHaveSameTypeAs, &e.ErrNoData{})
+			})
+			Convey("-> Should not subscribe to a bundle", func() {
+				prodRepo.On("FindByProductID", "004").Return(&prodUsecase.Product{ID: "004", Name: "Ninja Starter Kit", Type: enum.BundleProduct}, nil)
+				_, err := uc.Subscribe("123", "004", "")
+				So(err.Error(), ShouldEqual, "cannot subscribe to bundle 004, subscribe to its components instead")
+			})
+			Convey("-> Should not subscribe to a product in stock", func() {
+				_, err := uc.Subscribe("123", "003", "003-BLK-M")
+				So(err, ShouldHaveSameTypeAs, &e.ErrConflict{})
+				So(err.Error(), ShouldEqual, "Ninja Gi (color: black, size: M) is in stock, no need to wait for a restock")
+				repo.AssertNotCalled(t, "Save", mock.Anything)
+			})
+		})
+
+		Convey("-> Positive Scenarios", func() {
+			Convey("-> Should subscribe the user to the restock of the variant", func() {
+				res, err := uc.Subscribe("123", "003", "003-NVY-M")
+				So(err, ShouldBeNil)
+				expected := &Subscription{
+					UserID: "123", ProductID: "003", SKU: "003-NVY-M", ProductName: "Ninja Gi (color: navy, size: M)", CreatedAt: now,
+				}
+				So(res, ShouldResemble, expected)
+				repo.AssertCalled(t, "Save", expected)
+			})
+			Convey("-> Should subscribe to a product without variants under its ID", func() {
+				prodRepo.On("FindByProductID", "001").Return(&prodUsecase.Product{ID: "001", Name: "Shuriken"}, nil)
+				res, err := uc.Subscribe("123", "001", "")
+				So(err, ShouldBeNil)
+				So(res.(*Subscription).SKU, ShouldEqual, "001")
+			})
+			Convey("-> Should list and remove the subscriptions of the user", func() {
+				subscriptions := []*Subscription{{UserID: "123", ProductID: "003", SKU: "003-NVY-M", CreatedAt: now}}
+				repo.On("FetchUserSubscriptions", "123").Return(subscriptions, nil)
+				repo.On("Remove", "123", "003-NVY-M").Return(nil)
+				res, err := uc.FetchSubscriptions("123")
+				So(err, ShouldBeNil)
+				So(res, ShouldResemble, subscriptions)
+				So(uc.Unsubscribe("123", "003-NVY-M"), ShouldBeNil)
+				repo.AssertCalled(t, "Remove", "123", "003-NVY-M")
+			})
+		})
+	})
+
+	Convey("2. Given a product some users wait for gets restocked", t, func() {
+
+		repo := &mockRepo.MockSubscriptionRepository{}
+		prodRepo := &mockRepo.MockProductRepository{}
+		userRepo := &mockRepo.MockUserRepository{}
+		notifier := &mockService.MockNotifier{}
+		userRepo.On("FindByUserID", "123").Return(yauri, nil)
+		userRepo.On("FindByUserID", "456").Return(hanzo, nil)
+		repo.On("FetchSKUSubscriptions", "003-NVY-M").Return([]*Subscription{
+			{UserID: "123", ProductID: "003", SKU: "003-NVY-M", ProductName: "Ninja Gi (color: navy, size: M)", CreatedAt: now},
+			{UserID: "456", ProductID: "003", SKU: "003-NVY-M", ProductName: "Ninja Gi (color: navy, size: M)", CreatedAt: now},
+		}, nil)
+		repo.On("Remove", mock.Anything, mock.Anything).Return(nil)
+		restock := &prodUsecase.ProductRestocked{ProductID: "003", SKU: "003-NVY-M", Name: "Ninja Gi", Added: 5, Stock: 5, RestockedAt: now}
+		uc := NewSubscriptionUsecase(repo, prodRepo, userRepo, WithNotifier(notifier))
+
+		Convey("-> Negative Scenarios", func() {
+			Convey("-> Should keep the subscriptions failing to be notified for the next restock", func() {
+				notifier.On("Send", mock.MatchedBy(func(n vo.Notification) bool {
+					return n.Channel == enum.EmailChannel
+				})).Return(errors.New("mailbox is full"))
+				notifier.On("Send", mock.Anything).Return(nil)
+				err := uc.HandleRestock(restock)
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "failed to notify 1 subscriber(s) of 003-NVY-M: user 123: mailbox is full")
+				repo.AssertNotCalled(t, "Remove", "123", "003-NVY-M")
+				repo.AssertCalled(t, "Remove", "456", "003-NVY-M")
+			})
+			Convey("-> Should ignore any other event", func() {
+				So(uc.HandleRestock("cart expired"), ShouldBeNil)
+				repo.AssertNotCalled(t, "FetchSKUSubscriptions", mock.Anything)
+			})
+		})
+
+		Convey("-> Positive Scenarios", func() {
+			Convey("-> Should notify every subscriber by email, or by SMS without any email, then drop the subscriptions", func() {
+				notifier.On("Send", mock.Anything).Return(nil)
+				So(uc.HandleRestock(restock), ShouldBeNil)
+				notifier.AssertCalled(t, "Send", vo.Notification{
+					Channel: enum.EmailChannel, Recipient: "yauritux@gmail.com",
+					Subject: "Ninja Gi (color: navy, size: M) is back in stock",
+					Body: "Hi yauritux,\n\nNinja Gi (color: navy, size: M) is back in stock, 5 unit(s) are waiting for you.\n\n" +
+						"Add it to your cart before it runs out again.\n",
+				})
+				notifier.AssertCalled(t, "Send", vo.Notification{
+					Channel: enum.SMSChannel, Recipient: "+81000000",
+					Body: "Hi hanzo, Ninja Gi (color: navy, size: M) is back in stock. Add it to your cart before it runs out again.",
+				})
+				repo.AssertCalled(t, "Remove", "123", "003-NVY-M")
+				repo.AssertCalled(t, "Remove", "456", "003-NVY-M")
+			})
+		})
+	})
+}
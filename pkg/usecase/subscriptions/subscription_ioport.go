@@ -0,0 +1,8 @@
+package subscriptions
+
+type SubscriptionInputPort interface {
+	Subscribe(userID string, productID string, sku string) (interface{}, error)
+	Unsubscribe(userID string, sku string) error
+	FetchSubscriptions(userID string) (interface{}, error)
+	HandleRestock(event interface{}) error
+}
@@ -2,13 +2,27 @@ package users
 
 import (
 	"errors"
+	"strings"
 
 	"github.com/yauritux/cartsvc/pkg/domain/repository"
+	"github.com/yauritux/cartsvc/pkg/domain/service"
+	vo "github.com/yauritux/cartsvc/pkg/domain/valueobject"
 	. "github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
 )
 
+// GuestIDPrefix tells the guests apart from the registered users, no user id may start with it
+const GuestIDPrefix = "guest-"
+
+// IsGuestID tells whether the user id belongs to a guest rather than to a registered user
+func IsGuestID(userID string) bool {
+	return strings.HasPrefix(userID, GuestIDPrefix)
+}
+
 type UserUsecase struct {
-	repo repository.UserRepository
+	repo          repository.UserRepository
+	addrValidator service.AddressValidator
+	hasher        service.PasswordHasher
 }
 
 type User struct {
@@ -18,6 +32,10 @@ type User struct {
 	Phone        string
 	BillingAddr  *Address
 	ShippingAddr *Address
+	Role         UserRole
+	PasswordHash string
+	// Password is only used as the registration input, it is never stored
+	Password string
 }
 
 type Address struct {
@@ -30,8 +48,27 @@ type Address struct {
 	AddressType AddressType
 }
 
-func NewUserUsecase(r repository.UserRepository) *UserUsecase {
-	return &UserUsecase{r}
+// Option configures the optional collaborators of the UserUsecase
+type Option func(*UserUsecase)
+
+func WithAddressValidator(v service.AddressValidator) Option {
+	return func(uc *UserUsecase) {
+		uc.addrValidator = v
+	}
+}
+
+func WithPasswordHasher(h service.PasswordHasher) Option {
+	return func(uc *UserUsecase) {
+		uc.hasher = h
+	}
+}
+
+func NewUserUsecase(r repository.UserRepository, opts ...Option) *UserUsecase {
+	uc := &UserUsecase{repo: r}
+	for _, opt := range opts {
+		opt(uc)
+	}
+	return uc
 }
 
 func (user *UserUsecase) FetchCurrentUser(id string) (interface{}, error) {
@@ -47,3 +84,106 @@ func (user *UserUsecase) FetchCurrentUser(id string) (interface{}, error) {
 
 	return currUser, nil
 }
+
+// RegisterUser registers a customer, whatever role the caller asked for
+func (user *UserUsecase) RegisterUser(u interface{}) (interface{}, error) {
+	return user.register(u, Customer)
+}
+
+// RegisterAdmin registers an administrator, it is meant for trusted callers only (e.g. the local CLI)
+// and must never be exposed to the public
+func (user *UserUsecase) RegisterAdmin(u interface{}) (interface{}, error) {
+	return user.register(u, Admin)
+}
+
+func (user *UserUsecase) register(u interface{}, role UserRole) (interface{}, error) {
+	newUser, ok := u.(*User)
+	if !ok {
+		return nil, e.NewErrConversion("cannot register user, invalid type of user usecase model")
+	}
+	if newUser.ID == "" {
+		return nil, e.NewErrInvalidData("cannot register user, 'user_id' is missing")
+	}
+	if IsGuestID(newUser.ID) {
+		return nil, e.NewErrInvalidData("cannot register user, 'user_id' cannot start with " + GuestIDPrefix)
+	}
+	if newUser.Email == "" {
+		return nil, e.NewErrInvalidData("cannot register user, 'email' is missing")
+	}
+
+	existing, err := user.repo.FindByUserID(newUser.ID)
+	if err != nil {
+		if _, ok := err.(*e.ErrNoData); !ok {
+			return nil, err
+		}
+	}
+	if existingUser, ok := existing.(*User); ok && existingUser != nil {
+		return nil, e.NewErrDuplicateData("cannot register user, user " + newUser.ID + " is already registered")
+	}
+
+	if user.hasher != nil {
+		if newUser.Password == "" {
+			return nil, e.NewErrInvalidData("cannot register user, 'password' is missing")
+		}
+		if newUser.PasswordHash, err = user.hasher.Hash(newUser.Password); err != nil {
+			return nil, err
+		}
+		newUser.Password = ""
+	}
+	newUser.Role = role
+
+	if newUser.BillingAddr != nil {
+		newUser.BillingAddr.AddressType = BillingAddress
+		if newUser.BillingAddr, err = user.normalizeAddress(newUser.BillingAddr); err != nil {
+			return nil, err
+		}
+	}
+	if newUser.ShippingAddr != nil {
+		newUser.ShippingAddr.AddressType = ShippingAddress
+		if newUser.ShippingAddr, err = user.normalizeAddress(newUser.ShippingAddr); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := user.repo.Create(newUser); err != nil {
+		return nil, err
+	}
+
+	return newUser, nil
+}
+
+func (user *UserUsecase) normalizeAddress(addr *Address) (*Address, error) {
+	if user.addrValidator == nil {
+		return addr, nil
+	}
+	normalized, err := user.addrValidator.Validate(BuildBuyerAddress(addr))
+	if err != nil {
+		return nil, err
+	}
+	return buildAddressUsecaseModel(normalized), nil
+}
+
+// BuildBuyerAddress converts the address usecase model into its domain value object
+func BuildBuyerAddress(addr *Address) vo.BuyerAddress {
+	return vo.BuyerAddress{
+		StreetName: addr.Street,
+		City:       addr.City,
+		Postal:     addr.Postal,
+		Province:   addr.Province,
+		Region:     addr.Region,
+		Country:    addr.Country,
+		Type:       addr.AddressType,
+	}
+}
+
+func buildAddressUsecaseModel(addr vo.BuyerAddress) *Address {
+	return &Address{
+		Street:      addr.StreetName,
+		City:        addr.City,
+		Postal:      addr.Postal,
+		Province:    addr.Province,
+		Region:      addr.Region,
+		Country:     addr.Country,
+		AddressType: addr.Type,
+	}
+}
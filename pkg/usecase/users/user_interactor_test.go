@@ -8,7 +8,11 @@ import (
 	. "github.com/smartystreets/goconvey/convey"
 	"github.com/stretchr/testify/mock"
 	"github.com/yauritux/cartsvc/pkg/domain/entity"
+	vo "github.com/yauritux/cartsvc/pkg/domain/valueobject"
+	"github.com/yauritux/cartsvc/pkg/sharedkernel/enum"
+	e "github.com/yauritux/cartsvc/pkg/sharedkernel/error"
 	mockUserRepo "github.com/yauritux/cartsvc/pkg/sharedkernel/mock/repository"
+	mockService "github.com/yauritux/cartsvc/pkg/sharedkernel/mock/service"
 )
 
 func TestUserUsecase(t *testing.T) {
@@ -57,4 +61,105 @@ func TestUserUsecase(t *testing.T) {
 			})
 		})
 	})
+
+	Convey("2. When registering a new user", t, func() {
+
+		userRepo := &mockUserRepo.MockUserRepository{}
+		addrValidator := &mockService.MockAddressValidator{}
+
+		newUser := func() *User {
+			return &User{
+				ID:       "ninja",
+				Username: "Hattori Hanzo",
+				Email:    "hanzo@gmail.com",
+				ShippingAddr: &Address{
+					Street:  "kalibata raya no.1",
+					City:    "jakarta",
+					Postal:  "12750",
+					Country: "indonesia",
+				},
+			}
+		}
+
+		Convey("-> Negative Scenarios", func() {
+			Convey("-> Should return an error when the user id is missing", func() {
+				uc := NewUserUsecase(userRepo, WithAddressValidator(addrValidator))
+				u := newUser()
+				u.ID = ""
+				res, err := uc.RegisterUser(u)
+				So(res, ShouldBeNil)
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "cannot register user, 'user_id' is missing")
+			})
+			Convey("-> Should return an error when the user id is reserved to the guests", func() {
+				uc := NewUserUsecase(userRepo, WithAddressValidator(addrValidator))
+				u := newUser()
+				u.ID = "guest-ninja"
+				res, err := uc.RegisterUser(u)
+				So(res, ShouldBeNil)
+				So(err, ShouldHaveSameTypeAs, &e.ErrInvalidData{})
+			})
+			Convey("-> Should return an error when the user is already registered", func() {
+				userRepo.On("FindByUserID", "ninja").Return(&User{ID: "ninja"}, nil)
+				uc := NewUserUsecase(userRepo, WithAddressValidator(addrValidator))
+				res, err := uc.RegisterUser(newUser())
+				So(res, ShouldBeNil)
+				So(err, ShouldHaveSameTypeAs, &e.ErrDuplicateData{})
+			})
+			Convey("-> Should return an error when the address is rejected by the validator", func() {
+				userRepo.On("FindByUserID", "ninja").Return(nil, e.NewErrNoData("no user found"))
+				addrValidator.On("Validate", mock.Anything).Return(
+					vo.BuyerAddress{}, e.NewErrInvalidData("postal code 12750 does not belong to province Bali"),
+				)
+				uc := NewUserUsecase(userRepo, WithAddressValidator(addrValidator))
+				res, err := uc.RegisterUser(newUser())
+				So(res, ShouldBeNil)
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "postal code 12750 does not belong to province Bali")
+				userRepo.AssertNotCalled(t, "Create", mock.Anything)
+			})
+		})
+
+		Convey("-> Positive Scenarios", func() {
+			Convey("-> Should store the user with a normalized address", func() {
+				userRepo.On("FindByUserID", "ninja").Return(nil, nil)
+				addrValidator.On("Validate", mock.Anything).Return(vo.BuyerAddress{
+					StreetName: "kalibata raya no.1",
+					City:       "Jakarta",
+					Postal:     "12750",
+					Province:   "DKI Jakarta",
+					Region:     "Jakarta Selatan",
+					Country:    "Indonesia",
+					Type:       enum.ShippingAddress,
+				}, nil)
+				userRepo.On("Create", mock.Anything).Return(nil)
+				uc := NewUserUsecase(userRepo, WithAddressValidator(addrValidator))
+				res, err := uc.RegisterUser(newUser())
+				So(err, ShouldBeNil)
+				registered := res.(*User)
+				So(registered.ShippingAddr.Province, ShouldEqual, "DKI Jakarta")
+				So(registered.ShippingAddr.Region, ShouldEqual, "Jakarta Selatan")
+				So(registered.ShippingAddr.AddressType, ShouldEqual, enum.ShippingAddress)
+				userRepo.AssertCalled(t, "Create", registered)
+			})
+			Convey("-> Should always register the user as a customer", func() {
+				userRepo.On("FindByUserID", "ninja").Return(nil, nil)
+				userRepo.On("Create", mock.Anything).Return(nil)
+				uc := NewUserUsecase(userRepo)
+				u := newUser()
+				u.Role = enum.Admin
+				res, err := uc.RegisterUser(u)
+				So(err, ShouldBeNil)
+				So(res.(*User).Role, ShouldEqual, enum.Customer)
+			})
+			Convey("-> Should register an administrator through the privileged path only", func() {
+				userRepo.On("FindByUserID", "ninja").Return(nil, nil)
+				userRepo.On("Create", mock.Anything).Return(nil)
+				uc := NewUserUsecase(userRepo)
+				res, err := uc.RegisterAdmin(newUser())
+				So(err, ShouldBeNil)
+				So(res.(*User).Role, ShouldEqual, enum.Admin)
+			})
+		})
+	})
 }
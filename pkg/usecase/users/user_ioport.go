@@ -2,6 +2,8 @@ package users
 
 type UserInputPort interface {
 	FetchCurrentUser(id string) (interface{}, error)
+	RegisterUser(user interface{}) (interface{}, error)
+	RegisterAdmin(user interface{}) (interface{}, error)
 	BuildUserUsecaseModel(interface{}) *User
 }
 